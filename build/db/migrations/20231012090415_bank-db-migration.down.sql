@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS "accrual_postings";
+
+DROP TABLE IF EXISTS "interest_accruals";
+
+DROP TABLE IF EXISTS "fee_schedules";
+
+DROP TABLE IF EXISTS "interest_rates";
+
+ALTER TABLE IF EXISTS "accounts" DROP COLUMN IF EXISTS "product";
@@ -0,0 +1,78 @@
+ALTER TABLE "accounts" ADD COLUMN "product" varchar NOT NULL DEFAULT 'checking';
+
+CREATE TABLE "interest_rates" (
+  "product" varchar PRIMARY KEY,
+  "annual_rate_bps" int NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "interest_rates_annual_rate_bps_check" CHECK ("annual_rate_bps" >= 0)
+);
+
+CREATE TABLE "fee_schedules" (
+  "id" bigserial PRIMARY KEY,
+  "product" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "amount" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "fee_schedules_amount_check" CHECK ("amount" > 0),
+  CONSTRAINT "fee_schedules_product_name_key" UNIQUE ("product", "name")
+);
+
+CREATE TABLE "interest_accruals" (
+  "account_id" bigint NOT NULL,
+  "accrual_date" date NOT NULL,
+  "balance" bigint NOT NULL,
+  "annual_rate_bps" int NOT NULL,
+  "amount_micros" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("account_id", "accrual_date")
+);
+
+CREATE TABLE "accrual_postings" (
+  "id" bigserial PRIMARY KEY,
+  "account_id" bigint NOT NULL,
+  "kind" varchar NOT NULL,
+  "fee_schedule_id" bigint,
+  "period_start" date NOT NULL,
+  "amount" bigint NOT NULL,
+  "transfer_id" bigint,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "accrual_postings_kind_check" CHECK ("kind" IN ('interest', 'fee'))
+);
+
+CREATE INDEX ON "fee_schedules" ("product");
+
+-- A period is posted at most once per account, and per fee schedule for fees
+CREATE UNIQUE INDEX ON "accrual_postings" ("account_id", "kind", COALESCE("fee_schedule_id", 0), "period_start");
+
+COMMENT ON COLUMN "accounts"."product" IS 'decides interest rate and fee schedules of the account';
+
+COMMENT ON COLUMN "interest_rates"."annual_rate_bps" IS 'annual interest rate in basis points, 150 is 1.50%';
+
+COMMENT ON COLUMN "fee_schedules"."amount" IS 'monthly fee in the currency of the charged account';
+
+COMMENT ON COLUMN "interest_accruals"."balance" IS 'end of day balance';
+
+COMMENT ON COLUMN "interest_accruals"."amount_micros" IS 'accrued interest in millionths of the currency minor unit';
+
+COMMENT ON COLUMN "accrual_postings"."period_start" IS 'first day of the posted month';
+
+COMMENT ON COLUMN "accrual_postings"."transfer_id" IS 'empty when the amount rounds to zero';
+
+ALTER TABLE "interest_accruals" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "accrual_postings" ADD FOREIGN KEY ("account_id") REFERENCES "accounts" ("id");
+
+ALTER TABLE "accrual_postings" ADD FOREIGN KEY ("fee_schedule_id") REFERENCES "fee_schedules" ("id");
+
+ALTER TABLE "accrual_postings" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+-- Bank owned users owning the accounts interest is paid from and fees are paid to, they can not log in.
+-- They are kept by the down migration since their accounts are referenced by transfers.
+INSERT INTO "users" ("username", "hashed_password", "full_name", "email") VALUES
+  ('bank_interest', '', 'Bank interest', 'interest@bank.invalid'),
+  ('bank_fees', '', 'Bank fees', 'fees@bank.invalid')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO "interest_rates" ("product", "annual_rate_bps") VALUES
+  ('checking', 0),
+  ('savings', 150);
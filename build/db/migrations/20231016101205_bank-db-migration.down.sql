@@ -0,0 +1,19 @@
+ALTER TABLE IF EXISTS "fee_schedules" DROP CONSTRAINT IF EXISTS "fee_schedules_product_fkey";
+
+ALTER TABLE IF EXISTS "accounts" DROP CONSTRAINT IF EXISTS "accounts_product_fkey";
+
+DROP INDEX IF EXISTS "accounts_owner_currency_idx";
+
+ALTER TABLE "accounts" ADD CONSTRAINT "owner_currency_key" UNIQUE ("owner", "currency");
+
+CREATE TABLE "interest_rates" (
+  "product" varchar PRIMARY KEY,
+  "annual_rate_bps" int NOT NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "interest_rates_annual_rate_bps_check" CHECK ("annual_rate_bps" >= 0)
+);
+
+INSERT INTO "interest_rates" ("product", "annual_rate_bps", "updated_at")
+SELECT "name", "interest_rate_bps", "updated_at" FROM "account_products";
+
+DROP TABLE IF EXISTS "account_products";
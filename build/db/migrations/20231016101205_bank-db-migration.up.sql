@@ -0,0 +1,63 @@
+CREATE TABLE "account_products" (
+  "name" varchar PRIMARY KEY,
+  "description" varchar NOT NULL DEFAULT '',
+  "overdraft_limit" bigint NOT NULL DEFAULT 0,
+  "max_withdrawal" bigint NOT NULL DEFAULT 0,
+  "monthly_withdrawals" int NOT NULL DEFAULT 0,
+  "interest_rate_bps" int NOT NULL DEFAULT 0,
+  "multiple_per_currency" boolean NOT NULL DEFAULT false,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "account_products_overdraft_limit_check" CHECK ("overdraft_limit" >= 0),
+  CONSTRAINT "account_products_max_withdrawal_check" CHECK ("max_withdrawal" >= 0),
+  CONSTRAINT "account_products_monthly_withdrawals_check" CHECK ("monthly_withdrawals" >= 0),
+  CONSTRAINT "account_products_interest_rate_bps_check" CHECK ("interest_rate_bps" >= 0)
+);
+
+COMMENT ON COLUMN "account_products"."overdraft_limit" IS 'how far below zero the available balance may go';
+
+COMMENT ON COLUMN "account_products"."max_withdrawal" IS 'largest outgoing transfer, 0 for no limit';
+
+COMMENT ON COLUMN "account_products"."monthly_withdrawals" IS 'outgoing transfers per calendar month, 0 for no limit';
+
+COMMENT ON COLUMN "account_products"."interest_rate_bps" IS 'annual interest rate in basis points, 150 is 1.50%';
+
+COMMENT ON COLUMN "account_products"."multiple_per_currency" IS 'whether an owner can have several accounts of the product in a currency';
+
+INSERT INTO "account_products" (
+  "name",
+  "description",
+  "monthly_withdrawals",
+  "multiple_per_currency"
+) VALUES
+  ('checking', 'Everyday account for payments and transfers', 0, false),
+  ('savings', 'Interest bearing account with a limited number of withdrawals', 6, true),
+  ('escrow', 'Money held on behalf of a third party', 0, true),
+  ('internal', 'Accounts owned by the bank', 0, true);
+
+-- Products referenced before products were introduced get the default rules
+INSERT INTO "account_products" ("name")
+SELECT "product" FROM "accounts"
+UNION SELECT "product" FROM "interest_rates"
+UNION SELECT "product" FROM "fee_schedules"
+ON CONFLICT DO NOTHING;
+
+UPDATE "account_products"
+SET
+  "interest_rate_bps" = "interest_rates"."annual_rate_bps",
+  "updated_at" = "interest_rates"."updated_at"
+FROM "interest_rates"
+WHERE "interest_rates"."product" = "account_products"."name";
+
+UPDATE "accounts" SET "product" = 'internal' WHERE "owner" IN ('bank_interest', 'bank_fees');
+
+DROP TABLE "interest_rates";
+
+-- Whether an owner can have several accounts in a currency depends on the product
+ALTER TABLE "accounts" DROP CONSTRAINT "owner_currency_key";
+
+CREATE INDEX ON "accounts" ("owner", "currency");
+
+ALTER TABLE "accounts" ADD FOREIGN KEY ("product") REFERENCES "account_products" ("name");
+
+ALTER TABLE "fee_schedules" ADD FOREIGN KEY ("product") REFERENCES "account_products" ("name");
@@ -0,0 +1,13 @@
+ALTER TABLE "accounts" ADD COLUMN "status" varchar NOT NULL DEFAULT 'active';
+
+ALTER TABLE "accounts" ADD COLUMN "frozen_at" timestamptz;
+
+ALTER TABLE "accounts" ADD COLUMN "closed_at" timestamptz;
+
+ALTER TABLE "accounts" ADD CONSTRAINT "accounts_status_check" CHECK ("status" IN ('active', 'frozen', 'closed'));
+
+COMMENT ON COLUMN "accounts"."status" IS 'active, frozen or closed, money only moves in and out of active accounts';
+
+COMMENT ON COLUMN "accounts"."frozen_at" IS 'when the account was frozen, empty unless frozen';
+
+COMMENT ON COLUMN "accounts"."closed_at" IS 'when the account was closed, closed accounts stay closed';
@@ -0,0 +1,38 @@
+CREATE TABLE "transfer_limits" (
+  "id" bigserial PRIMARY KEY,
+  "product" varchar UNIQUE,
+  "owner" varchar UNIQUE,
+  "max_amount" bigint NOT NULL DEFAULT 0,
+  "account_daily" bigint NOT NULL DEFAULT 0,
+  "account_monthly" bigint NOT NULL DEFAULT 0,
+  "user_daily" bigint NOT NULL DEFAULT 0,
+  "user_monthly" bigint NOT NULL DEFAULT 0,
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "transfer_limits_scope_check" CHECK (("product" IS NULL) <> ("owner" IS NULL)),
+  CONSTRAINT "transfer_limits_max_amount_check" CHECK ("max_amount" >= 0),
+  CONSTRAINT "transfer_limits_account_daily_check" CHECK ("account_daily" >= 0),
+  CONSTRAINT "transfer_limits_account_monthly_check" CHECK ("account_monthly" >= 0),
+  CONSTRAINT "transfer_limits_user_daily_check" CHECK ("user_daily" >= 0),
+  CONSTRAINT "transfer_limits_user_monthly_check" CHECK ("user_monthly" >= 0)
+);
+
+COMMENT ON COLUMN "transfer_limits"."product" IS 'limits of the accounts of the product, empty for a user override';
+
+COMMENT ON COLUMN "transfer_limits"."owner" IS 'user override replacing the limits of the products of the user, empty for product limits';
+
+COMMENT ON COLUMN "transfer_limits"."max_amount" IS 'largest outgoing transfer, 0 for no limit';
+
+COMMENT ON COLUMN "transfer_limits"."account_daily" IS 'outgoing amount of an account per UTC day, 0 for no limit';
+
+COMMENT ON COLUMN "transfer_limits"."account_monthly" IS 'outgoing amount of an account per UTC calendar month, 0 for no limit';
+
+COMMENT ON COLUMN "transfer_limits"."user_daily" IS 'outgoing amount of the accounts of a user in a currency per UTC day, 0 for no limit';
+
+COMMENT ON COLUMN "transfer_limits"."user_monthly" IS 'outgoing amount of the accounts of a user in a currency per UTC calendar month, 0 for no limit';
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("product") REFERENCES "account_products" ("name");
+
+ALTER TABLE "transfer_limits" ADD FOREIGN KEY ("owner") REFERENCES "users" ("username");
+
+CREATE INDEX ON "entries" ("account_id", "created_at");
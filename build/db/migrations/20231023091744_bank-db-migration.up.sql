@@ -0,0 +1,39 @@
+ALTER TABLE "entries" ADD COLUMN "transfer_id" bigint;
+
+COMMENT ON COLUMN "entries"."transfer_id" IS 'transfer booking the entry, every transfer has one debit and one credit entry';
+
+-- Link existing entries to their transfers. A transfer and its entries are created in the same
+-- transaction in the same order, the n:th entry of an account, amount and time belongs to the n:th
+-- transfer leg of the same account, amount and time.
+WITH "ranked_entries" AS (
+  SELECT
+    "id",
+    "account_id",
+    "amount",
+    "created_at",
+    row_number() OVER (PARTITION BY "account_id", "amount", "created_at" ORDER BY "id") AS "n"
+  FROM "entries"
+), "legs" AS (
+  SELECT "id" AS "transfer_id", "from_account_id" AS "account_id", -"amount" AS "amount", "created_at"
+  FROM "transfers"
+  UNION ALL
+  SELECT "id", "to_account_id", "amount", "created_at"
+  FROM "transfers"
+), "ranked_legs" AS (
+  SELECT
+    "transfer_id",
+    "account_id",
+    "amount",
+    "created_at",
+    row_number() OVER (PARTITION BY "account_id", "amount", "created_at" ORDER BY "transfer_id") AS "n"
+  FROM "legs"
+)
+UPDATE "entries"
+SET "transfer_id" = "ranked_legs"."transfer_id"
+FROM "ranked_entries"
+JOIN "ranked_legs" USING ("account_id", "amount", "created_at", "n")
+WHERE "entries"."id" = "ranked_entries"."id";
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("transfer_id") REFERENCES "transfers" ("id");
+
+CREATE INDEX ON "entries" ("transfer_id");
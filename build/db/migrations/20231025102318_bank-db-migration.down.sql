@@ -0,0 +1,5 @@
+ALTER TABLE IF EXISTS "entries" DROP COLUMN IF EXISTS "journal_id";
+
+ALTER TABLE IF EXISTS "transfers" DROP COLUMN IF EXISTS "journal_id";
+
+DROP TABLE IF EXISTS "journals";
@@ -0,0 +1,76 @@
+CREATE TABLE "journals" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "journals" IS 'one row per business operation moving money, its transfers and entries reference it';
+
+COMMENT ON COLUMN "journals"."kind" IS 'transfer, scheduled_transfer, reversal, hold_capture, account_sweep, accrual or legacy';
+
+ALTER TABLE "transfers" ADD COLUMN "journal_id" bigint;
+
+ALTER TABLE "entries" ADD COLUMN "journal_id" bigint;
+
+-- One journal per existing transfer, of the kind of operation that created it
+CREATE TEMPORARY TABLE "transfer_journals" AS
+SELECT
+  "transfers"."id" AS "transfer_id",
+  nextval(pg_get_serial_sequence('journals', 'id')) AS "journal_id",
+  CASE
+    WHEN "transfers"."reversal_of" IS NOT NULL THEN 'reversal'
+    WHEN EXISTS (SELECT 1 FROM "accrual_postings" WHERE "transfer_id" = "transfers"."id") THEN 'accrual'
+    WHEN EXISTS (SELECT 1 FROM "holds" WHERE "transfer_id" = "transfers"."id") THEN 'hold_capture'
+    WHEN EXISTS (SELECT 1 FROM "scheduled_transfer_runs" WHERE "transfer_id" = "transfers"."id") THEN 'scheduled_transfer'
+    ELSE 'transfer'
+  END AS "kind",
+  "transfers"."created_at"
+FROM "transfers";
+
+INSERT INTO "journals" ("id", "kind", "created_at")
+SELECT "journal_id", "kind", "created_at" FROM "transfer_journals";
+
+UPDATE "transfers"
+SET "journal_id" = "transfer_journals"."journal_id"
+FROM "transfer_journals"
+WHERE "transfers"."id" = "transfer_journals"."transfer_id";
+
+DROP TABLE "transfer_journals";
+
+-- Entries were linked to their transfers by account, amount and created_at matching
+UPDATE "entries"
+SET "journal_id" = "transfers"."journal_id"
+FROM "transfers"
+WHERE "entries"."transfer_id" = "transfers"."id";
+
+-- Entries no transfer could be matched to get a legacy journal per point in time, entries
+-- created by the same database transaction share the time
+CREATE TEMPORARY TABLE "legacy_journals" AS
+SELECT
+  "created_at",
+  nextval(pg_get_serial_sequence('journals', 'id')) AS "journal_id"
+FROM "entries"
+WHERE "journal_id" IS NULL
+GROUP BY "created_at";
+
+INSERT INTO "journals" ("id", "kind", "created_at")
+SELECT "journal_id", 'legacy', "created_at" FROM "legacy_journals";
+
+UPDATE "entries"
+SET "journal_id" = "legacy_journals"."journal_id"
+FROM "legacy_journals"
+WHERE "entries"."journal_id" IS NULL AND "entries"."created_at" = "legacy_journals"."created_at";
+
+DROP TABLE "legacy_journals";
+
+ALTER TABLE "transfers" ALTER COLUMN "journal_id" SET NOT NULL;
+
+ALTER TABLE "entries" ALTER COLUMN "journal_id" SET NOT NULL;
+
+ALTER TABLE "transfers" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
+
+ALTER TABLE "entries" ADD FOREIGN KEY ("journal_id") REFERENCES "journals" ("id");
+
+CREATE INDEX ON "transfers" ("journal_id");
+
+CREATE INDEX ON "entries" ("journal_id");
@@ -0,0 +1,44 @@
+CREATE TABLE "audit_events" (
+  "id" bigserial PRIMARY KEY,
+  "occurred_at" timestamptz NOT NULL,
+  "actor" varchar NOT NULL,
+  "ip" varchar NOT NULL DEFAULT '',
+  "request_id" varchar NOT NULL DEFAULT '',
+  "action" varchar NOT NULL,
+  "entity_type" varchar NOT NULL,
+  "entity_id" varchar NOT NULL,
+  "before" json,
+  "after" json,
+  "prev_hash" varchar NOT NULL,
+  "hash" varchar UNIQUE NOT NULL
+);
+
+COMMENT ON TABLE "audit_events" IS 'append-only log of state changes, written in the transaction making the change';
+
+COMMENT ON COLUMN "audit_events"."actor" IS 'user making the change, anonymous for unauthenticated requests and system for background jobs';
+
+COMMENT ON COLUMN "audit_events"."before" IS 'state before the change, empty when created; json keeps the hashed text as is';
+
+COMMENT ON COLUMN "audit_events"."after" IS 'state after the change';
+
+COMMENT ON COLUMN "audit_events"."prev_hash" IS 'hash of the previous event, empty for the first event';
+
+COMMENT ON COLUMN "audit_events"."hash" IS 'sha256 of the previous hash and the event, changing or removing an event breaks the chain';
+
+CREATE INDEX ON "audit_events" ("entity_type", "entity_id");
+
+CREATE INDEX ON "audit_events" ("actor");
+
+CREATE FUNCTION "audit_events_append_only"() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "audit_events_append_only"
+BEFORE UPDATE OR DELETE ON "audit_events"
+FOR EACH ROW EXECUTE FUNCTION "audit_events_append_only"();
+
+CREATE TRIGGER "audit_events_no_truncate"
+BEFORE TRUNCATE ON "audit_events"
+FOR EACH STATEMENT EXECUTE FUNCTION "audit_events_append_only"();
@@ -0,0 +1,27 @@
+CREATE TABLE "outbox" (
+  "id" bigserial PRIMARY KEY,
+  "aggregate_type" varchar NOT NULL,
+  "aggregate_id" varchar NOT NULL,
+  "event_type" varchar NOT NULL,
+  "event_version" int NOT NULL,
+  "payload" jsonb NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "attempts" int NOT NULL DEFAULT 0,
+  "next_attempt_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" varchar NOT NULL DEFAULT '',
+  "published_at" timestamptz
+);
+
+COMMENT ON TABLE "outbox" IS 'domain events, written in the transaction making the change and relayed to publishers afterwards';
+
+COMMENT ON COLUMN "outbox"."aggregate_id" IS 'events of an aggregate are published in order';
+
+COMMENT ON COLUMN "outbox"."event_version" IS 'version of the payload schema of the event type';
+
+COMMENT ON COLUMN "outbox"."next_attempt_at" IS 'failed publishing is retried with exponential backoff';
+
+COMMENT ON COLUMN "outbox"."published_at" IS 'empty until published';
+
+CREATE INDEX ON "outbox" ("aggregate_type", "aggregate_id", "id") WHERE "published_at" IS NULL;
+
+CREATE INDEX ON "outbox" ("next_attempt_at") WHERE "published_at" IS NULL;
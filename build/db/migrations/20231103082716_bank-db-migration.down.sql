@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS "accounts_notify_balance" ON "accounts";
+
+DROP FUNCTION IF EXISTS "accounts_notify_balance"();
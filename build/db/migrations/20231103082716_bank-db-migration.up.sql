@@ -0,0 +1,14 @@
+CREATE FUNCTION "accounts_notify_balance"() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('account_balance', NEW."id"::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+COMMENT ON FUNCTION "accounts_notify_balance"() IS 'notifies listeners of account_balance with the account id, delivered when the transaction commits';
+
+CREATE TRIGGER "accounts_notify_balance"
+AFTER UPDATE OF "balance" ON "accounts"
+FOR EACH ROW
+WHEN (OLD."balance" IS DISTINCT FROM NEW."balance")
+EXECUTE FUNCTION "accounts_notify_balance"();
@@ -0,0 +1,9 @@
+ALTER TABLE "verify_emails" ADD COLUMN "attempts" int NOT NULL DEFAULT 0;
+
+ALTER TABLE "verify_emails" ADD COLUMN "next_attempt_at" timestamptz NOT NULL DEFAULT (now());
+
+ALTER TABLE "verify_emails" ADD COLUMN "last_error" varchar NOT NULL DEFAULT '';
+
+CREATE INDEX ON "verify_emails" ("next_attempt_at") WHERE "sent_at" IS NULL;
+
+DROP TABLE IF EXISTS "jobs";
@@ -0,0 +1,37 @@
+CREATE TABLE "jobs" (
+  "id" bigserial PRIMARY KEY,
+  "kind" varchar NOT NULL,
+  "payload" jsonb NOT NULL,
+  "priority" int NOT NULL DEFAULT 0,
+  "status" varchar NOT NULL DEFAULT 'pending',
+  "unique_key" varchar,
+  "attempts" int NOT NULL DEFAULT 0,
+  "max_attempts" int NOT NULL,
+  "run_at" timestamptz NOT NULL DEFAULT (now()),
+  "last_error" varchar NOT NULL DEFAULT '',
+  "finished_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  CONSTRAINT "jobs_status_check" CHECK ("status" IN ('pending', 'succeeded', 'dead')),
+  CONSTRAINT "jobs_max_attempts_check" CHECK ("max_attempts" > 0)
+);
+
+COMMENT ON COLUMN "jobs"."kind" IS 'task of the job, workers only claim kinds they have handlers for';
+
+COMMENT ON COLUMN "jobs"."priority" IS 'due jobs of higher priority run first';
+
+COMMENT ON COLUMN "jobs"."status" IS 'pending until run, dead once out of attempts';
+
+COMMENT ON COLUMN "jobs"."unique_key" IS 'at most one pending job of a kind has the key, null for jobs that are not unique';
+
+COMMENT ON COLUMN "jobs"."run_at" IS 'delayed jobs run once due, failed jobs are retried with exponential backoff';
+
+CREATE UNIQUE INDEX "jobs_unique_key" ON "jobs" ("kind", "unique_key") WHERE "status" = 'pending';
+
+CREATE INDEX ON "jobs" ("priority" DESC, "run_at") WHERE "status" = 'pending';
+
+-- Verification emails are sent by jobs, which retry failed sends
+ALTER TABLE "verify_emails" DROP COLUMN "attempts";
+
+ALTER TABLE "verify_emails" DROP COLUMN "next_attempt_at";
+
+ALTER TABLE "verify_emails" DROP COLUMN "last_error";
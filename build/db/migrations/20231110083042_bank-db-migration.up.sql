@@ -0,0 +1,18 @@
+CREATE TABLE "sessions" (
+  "id" varchar PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "refresh_token" varchar NOT NULL,
+  "user_agent" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "is_blocked" bool NOT NULL DEFAULT false,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON COLUMN "sessions"."id" IS 'id of the payload of the refresh token';
+
+COMMENT ON COLUMN "sessions"."is_blocked" IS 'a blocked session can not renew access tokens, e.g. once the password changes';
+
+ALTER TABLE "sessions" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "sessions" ("username");
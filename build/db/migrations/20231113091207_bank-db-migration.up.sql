@@ -0,0 +1,31 @@
+CREATE TABLE "password_resets" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "email" varchar NOT NULL,
+  "token_hash" varchar UNIQUE,
+  "is_used" bool NOT NULL DEFAULT false,
+  "sent_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "expired_at" timestamptz NOT NULL DEFAULT (now() + interval '30 minutes')
+);
+
+COMMENT ON COLUMN "password_resets"."email" IS 'address the token is sent to, the token is invalid once the user changes email';
+
+COMMENT ON COLUMN "password_resets"."token_hash" IS 'sha256 of the reset token, null until the token is sent, the token itself is only in the email';
+
+ALTER TABLE "password_resets" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "password_resets" ("username");
+
+CREATE TABLE "password_reset_requests" (
+  "id" bigserial PRIMARY KEY,
+  "email" varchar NOT NULL,
+  "client_ip" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "password_reset_requests" IS 'requested password resets, also of unknown emails, rate limited per email and client ip';
+
+CREATE INDEX ON "password_reset_requests" (lower("email"), "created_at");
+
+CREATE INDEX ON "password_reset_requests" ("client_ip", "created_at");
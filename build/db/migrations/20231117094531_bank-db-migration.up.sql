@@ -0,0 +1,32 @@
+CREATE TABLE "user_totps" (
+  "username" varchar PRIMARY KEY,
+  "encrypted_secret" bytea NOT NULL,
+  "is_enabled" bool NOT NULL DEFAULT false,
+  "last_used_step" bigint NOT NULL DEFAULT 0,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "enabled_at" timestamptz
+);
+
+COMMENT ON COLUMN "user_totps"."encrypted_secret" IS 'TOTP secret encrypted with the key from config, authenticated with the username';
+
+COMMENT ON COLUMN "user_totps"."is_enabled" IS 'false until confirmed with a first code, login and transfers above the threshold then require a code';
+
+COMMENT ON COLUMN "user_totps"."last_used_step" IS 'time step of the last accepted code, codes of earlier or the same steps are rejected';
+
+ALTER TABLE "user_totps" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE TABLE "recovery_codes" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "recovery_codes" IS 'single-use codes replacing a TOTP code at login, e.g. when the authenticator is lost';
+
+COMMENT ON COLUMN "recovery_codes"."code_hash" IS 'sha256 of the recovery code, the code itself is only shown once when TOTP is enabled';
+
+ALTER TABLE "recovery_codes" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("username", "code_hash");
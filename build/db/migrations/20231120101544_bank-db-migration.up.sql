@@ -0,0 +1,20 @@
+CREATE TABLE "login_throttles" (
+  "scope" varchar NOT NULL,
+  "key" varchar NOT NULL,
+  "failures" int NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now()),
+  "locked_until" timestamptz,
+  PRIMARY KEY ("scope", "key")
+);
+
+COMMENT ON TABLE "login_throttles" IS 'failed logins per username and per client ip, reset by a successful login';
+
+COMMENT ON COLUMN "login_throttles"."scope" IS 'username or client_ip';
+
+COMMENT ON COLUMN "login_throttles"."failures" IS 'consecutive failed logins, counting again from one after a failure free window';
+
+COMMENT ON COLUMN "login_throttles"."locked_until" IS 'logins of the key are refused until then';
+
+ALTER TABLE "login_throttles" ADD CONSTRAINT "login_throttles_scope_check" CHECK ("scope" IN ('username', 'client_ip'));
+
+CREATE INDEX ON "login_throttles" ("locked_until");
@@ -0,0 +1,25 @@
+CREATE TABLE "api_keys" (
+  "id" bigserial PRIMARY KEY,
+  "username" varchar NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar NOT NULL,
+  "key_hash" varchar UNIQUE NOT NULL,
+  "scopes" varchar[] NOT NULL,
+  "expired_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+COMMENT ON TABLE "api_keys" IS 'keys of machine clients acting as the user, limited to the scopes';
+
+COMMENT ON COLUMN "api_keys"."prefix" IS 'start of the key, shown to tell keys apart';
+
+COMMENT ON COLUMN "api_keys"."key_hash" IS 'sha256 of the key, the key itself is only shown once when created';
+
+COMMENT ON COLUMN "api_keys"."scopes" IS 'routes the key may call, e.g. read:accounts or write:transfers';
+
+COMMENT ON COLUMN "api_keys"."revoked_at" IS 'the key is refused from then on';
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("username") REFERENCES "users" ("username");
+
+CREATE INDEX ON "api_keys" ("username");
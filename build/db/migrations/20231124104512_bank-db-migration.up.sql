@@ -0,0 +1,16 @@
+CREATE TABLE "rate_limit_buckets" (
+  "key" varchar PRIMARY KEY,
+  "tokens" float8 NOT NULL,
+  "updated_at" timestamptz NOT NULL,
+  "full_at" timestamptz NOT NULL
+);
+
+COMMENT ON TABLE "rate_limit_buckets" IS 'token buckets of rate limited clients, shared by the replicas of the api';
+
+COMMENT ON COLUMN "rate_limit_buckets"."key" IS 'client of the bucket, e.g. ip:10.0.0.1 or user:alice, with the route of route limits';
+
+COMMENT ON COLUMN "rate_limit_buckets"."tokens" IS 'requests left at updated_at, refilled at the rate of the limit';
+
+COMMENT ON COLUMN "rate_limit_buckets"."full_at" IS 'the bucket is full again from then on, and can be deleted';
+
+CREATE INDEX ON "rate_limit_buckets" ("full_at");
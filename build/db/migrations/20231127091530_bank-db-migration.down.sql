@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS "account_product_rates_record" ON "account_products";
+
+DROP FUNCTION IF EXISTS "account_product_rates_record"();
+
+DROP TABLE IF EXISTS "account_product_rates";
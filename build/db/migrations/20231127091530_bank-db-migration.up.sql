@@ -0,0 +1,31 @@
+CREATE TABLE "account_product_rates" (
+  "product" varchar NOT NULL REFERENCES "account_products" ("name") ON DELETE CASCADE,
+  "annual_rate_bps" int NOT NULL,
+  "valid_from" timestamptz NOT NULL,
+  PRIMARY KEY ("product", "valid_from")
+);
+
+COMMENT ON TABLE "account_product_rates" IS 'history of the interest rates of account products, written by a trigger on account_products';
+
+COMMENT ON COLUMN "account_product_rates"."annual_rate_bps" IS 'annual interest rate in basis points, 150 is 1.50%';
+
+COMMENT ON COLUMN "account_product_rates"."valid_from" IS 'the rate applies from then on until the next rate of the product';
+
+-- The rates before the history are not known, the current rates apply from the last change of the products
+INSERT INTO "account_product_rates" ("product", "annual_rate_bps", "valid_from")
+SELECT "name", "interest_rate_bps", "updated_at" FROM "account_products";
+
+CREATE FUNCTION "account_product_rates_record"() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'INSERT' OR NEW."interest_rate_bps" IS DISTINCT FROM OLD."interest_rate_bps" THEN
+    INSERT INTO "account_product_rates" ("product", "annual_rate_bps", "valid_from")
+    VALUES (NEW."name", NEW."interest_rate_bps", now())
+    ON CONFLICT ("product", "valid_from") DO UPDATE SET "annual_rate_bps" = EXCLUDED."annual_rate_bps";
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "account_product_rates_record"
+AFTER INSERT OR UPDATE ON "account_products"
+FOR EACH ROW EXECUTE FUNCTION "account_product_rates_record"();
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "accounts_internal_owner_currency_key";
@@ -0,0 +1,3 @@
+-- One internal account per bank owner and currency, opened on first use by concurrent accrual runs
+CREATE UNIQUE INDEX "accounts_internal_owner_currency_key" ON "accounts" ("owner", "currency")
+WHERE "product" = 'internal';
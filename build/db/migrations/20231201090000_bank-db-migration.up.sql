@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "role_changed_at" timestamptz NOT NULL DEFAULT('0001-01-01 00:00:00Z');
+
+COMMENT ON COLUMN "users"."role_changed_at" IS 'access tokens issued before a change of the password or the role are refused';
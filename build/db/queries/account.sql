@@ -2,11 +2,23 @@
 INSERT INTO accounts (
   owner,
   balance,
-  currency
+  currency,
+  product
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 ) RETURNING *;
 
+-- name: CreateInternalAccount :exec
+-- Opens the internal account of a bank owner in a currency unless it exists, waiting for concurrent openings
+INSERT INTO accounts (
+  owner,
+  balance,
+  currency,
+  product
+) VALUES (
+  $1, 0, $2, 'internal'
+) ON CONFLICT (owner, currency) WHERE product = 'internal' DO NOTHING;
+
 -- name: GetAccount :one
 SELECT * FROM accounts
 WHERE id = $1 LIMIT 1;
@@ -16,6 +28,18 @@ SELECT * FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE;
 
+-- name: GetOwnerAccount :one
+SELECT * FROM accounts
+WHERE owner = $1 AND currency = $2 LIMIT 1;
+
+-- name: CountOwnerAccounts :one
+SELECT COUNT(*) FROM accounts
+WHERE owner = $1 AND currency = $2 AND product = $3 AND status <> 'closed';
+
+-- name: CountUserAccounts :one
+SELECT COUNT(*) FROM accounts
+WHERE owner = $1 AND status <> 'closed';
+
 -- name: ListAccounts :many
 SELECT * FROM accounts
 WHERE owner = $1
@@ -23,6 +47,13 @@ ORDER BY id
 LIMIT $2
 OFFSET $3;
 
+-- name: ListAccountsAfter :many
+-- Keyset pagination over all accounts, for batch jobs
+SELECT * FROM accounts
+WHERE id > $1
+ORDER BY id
+LIMIT $2;
+
 -- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
@@ -35,6 +66,55 @@ SET balance = balance + sqlc.arg(amount)
 WHERE id = sqlc.arg(id)
 RETURNING *;
 
--- name: DeleteAccount :exec
-DELETE FROM accounts
-WHERE id = $1;
+-- name: MarkAccountFrozen :one
+UPDATE accounts
+SET
+  status = 'frozen',
+  frozen_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkAccountActive :one
+UPDATE accounts
+SET
+  status = 'active',
+  frozen_at = NULL
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkAccountClosed :one
+UPDATE accounts
+SET
+  status = 'closed',
+  frozen_at = NULL,
+  closed_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListAccountBalancesAfter :many
+-- Keyset pagination over all accounts with the balance their entries add up to
+SELECT
+  accounts.id,
+  accounts.owner,
+  accounts.currency,
+  accounts.balance,
+  COALESCE(SUM(entries.amount), 0)::bigint AS entries_balance
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+WHERE accounts.id > sqlc.arg(id)
+GROUP BY accounts.id
+ORDER BY accounts.id
+LIMIT sqlc.arg('limit');
+
+-- name: GetAccountBalanceEvent :one
+-- Balance of an account with its latest entry, read together so the balance includes the entry
+SELECT
+  a.id,
+  a.owner,
+  a.balance,
+  a.currency,
+  COALESCE(MAX(e.id), 0)::bigint AS entry_id
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $1
+GROUP BY a.id;
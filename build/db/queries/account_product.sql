@@ -0,0 +1,39 @@
+-- name: CreateAccountProduct :one
+INSERT INTO account_products (
+  name,
+  description,
+  overdraft_limit,
+  max_withdrawal,
+  monthly_withdrawals,
+  interest_rate_bps,
+  multiple_per_currency
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7
+) RETURNING *;
+
+-- name: GetAccountProduct :one
+SELECT * FROM account_products
+WHERE name = $1 LIMIT 1;
+
+-- name: ListAccountProducts :many
+SELECT * FROM account_products
+ORDER BY name;
+
+-- name: UpdateAccountProduct :one
+UPDATE account_products
+SET
+  description = COALESCE(sqlc.narg(description), description),
+  overdraft_limit = COALESCE(sqlc.narg(overdraft_limit), overdraft_limit),
+  max_withdrawal = COALESCE(sqlc.narg(max_withdrawal), max_withdrawal),
+  monthly_withdrawals = COALESCE(sqlc.narg(monthly_withdrawals), monthly_withdrawals),
+  interest_rate_bps = COALESCE(sqlc.narg(interest_rate_bps), interest_rate_bps),
+  multiple_per_currency = COALESCE(sqlc.narg(multiple_per_currency), multiple_per_currency),
+  updated_at = now()
+WHERE
+  name = sqlc.arg(name)
+RETURNING *;
+
+-- name: ListAccountProductRates :many
+SELECT * FROM account_product_rates
+WHERE product = $1
+ORDER BY valid_from;
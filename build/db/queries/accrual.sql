@@ -0,0 +1,75 @@
+-- name: CreateFeeSchedule :one
+INSERT INTO fee_schedules (
+  product,
+  name,
+  amount
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: ListFeeSchedules :many
+SELECT * FROM fee_schedules
+WHERE product = $1
+ORDER BY id;
+
+-- name: CreateInterestAccrual :one
+INSERT INTO interest_accruals (
+  account_id,
+  accrual_date,
+  balance,
+  annual_rate_bps,
+  amount_micros
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: GetLastInterestAccrual :one
+SELECT * FROM interest_accruals
+WHERE account_id = $1
+ORDER BY accrual_date DESC
+LIMIT 1;
+
+-- name: ListUnpostedInterestPeriods :many
+-- Months with accrued interest before the given date that have not been posted
+SELECT DISTINCT date_trunc('month', a.accrual_date)::date AS period_start
+FROM interest_accruals a
+WHERE a.account_id = $1
+  AND a.accrual_date < sqlc.arg(before)
+  AND NOT EXISTS (
+    SELECT 1 FROM accrual_postings p
+    WHERE p.account_id = a.account_id
+      AND p.kind = 'interest'
+      AND p.period_start = date_trunc('month', a.accrual_date)::date
+  )
+ORDER BY period_start;
+
+-- name: SumInterestAccruals :one
+SELECT COALESCE(SUM(amount_micros), 0)::bigint AS amount_micros
+FROM interest_accruals
+WHERE account_id = $1
+  AND accrual_date >= sqlc.arg(period_start)
+  AND accrual_date < sqlc.arg(period_end);
+
+-- name: CreateAccrualPosting :one
+INSERT INTO accrual_postings (
+  account_id,
+  kind,
+  fee_schedule_id,
+  period_start,
+  amount,
+  transfer_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListAccrualPostings :many
+SELECT * FROM accrual_postings
+WHERE account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: ListFeeAccrualPostings :many
+SELECT * FROM accrual_postings
+WHERE account_id = $1 AND kind = 'fee'
+ORDER BY id;
@@ -0,0 +1,44 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (
+  username,
+  name,
+  prefix,
+  key_hash,
+  scopes,
+  expired_at
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetAPIKeyForUpdate :one
+SELECT * FROM api_keys
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetAPIKeyByHash :one
+SELECT
+  k.id,
+  k.username,
+  k.prefix,
+  k.scopes,
+  k.expired_at,
+  k.revoked_at,
+  k.created_at,
+  u.role
+FROM api_keys k
+JOIN users u ON u.username = k.username
+WHERE k.key_hash = $1 LIMIT 1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys
+WHERE username = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: MarkAPIKeyRevoked :one
+UPDATE api_keys
+SET
+  revoked_at = now()
+WHERE id = $1
+RETURNING *;
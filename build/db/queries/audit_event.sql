@@ -0,0 +1,42 @@
+-- name: CreateAuditEvent :one
+INSERT INTO audit_events (
+  occurred_at,
+  actor,
+  ip,
+  request_id,
+  action,
+  entity_type,
+  entity_id,
+  before,
+  after,
+  prev_hash,
+  hash
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+) RETURNING *;
+
+-- name: LockAuditChain :exec
+-- Serializes appends to the hash chain until the transaction ends
+SELECT pg_advisory_xact_lock(hashtext('audit_events'));
+
+-- name: GetLastAuditEvent :one
+SELECT * FROM audit_events
+ORDER BY id DESC
+LIMIT 1;
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE
+  (sqlc.narg(entity_type)::varchar IS NULL OR entity_type = sqlc.narg(entity_type)) AND
+  (sqlc.narg(entity_id)::varchar IS NULL OR entity_id = sqlc.narg(entity_id)) AND
+  (sqlc.narg(actor)::varchar IS NULL OR actor = sqlc.narg(actor))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListAuditEventsAfter :many
+-- Keyset pagination over the chain in order
+SELECT * FROM audit_events
+WHERE id > sqlc.arg(id)
+ORDER BY id
+LIMIT sqlc.arg('limit');
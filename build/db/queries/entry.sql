@@ -1,9 +1,11 @@
 -- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transfer_id,
+  journal_id
 ) VALUES (
-  $1, $2
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetEntry :one
@@ -15,4 +17,71 @@ SELECT * FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
-OFFSET $3;
\ No newline at end of file
+OFFSET $3;
+
+-- name: ListAccountHistory :many
+-- Entries of the account with the journal and the other account of the transfer booking them
+SELECT
+  entries.id,
+  entries.amount,
+  entries.created_at,
+  entries.journal_id,
+  journals.kind AS journal_kind,
+  entries.transfer_id,
+  counterparty.id AS counterparty_account_id,
+  counterparty.owner AS counterparty_owner
+FROM entries
+JOIN journals ON journals.id = entries.journal_id
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+LEFT JOIN accounts AS counterparty ON counterparty.id = CASE
+  WHEN transfers.from_account_id = entries.account_id THEN transfers.to_account_id
+  ELSE transfers.from_account_id
+END
+WHERE entries.account_id = $1
+ORDER BY entries.id
+LIMIT $2
+OFFSET $3;
+
+-- name: GetEntriesAmountSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+FROM entries
+WHERE account_id = $1 AND created_at >= sqlc.arg(since);
+
+-- name: GetOutgoingAmountSince :one
+-- Outgoing amount of transfers, scheduled transfers and hold captures, fees and reversals do not count
+SELECT COALESCE(-SUM(entries.amount), 0)::bigint AS amount
+FROM entries
+JOIN journals ON journals.id = entries.journal_id
+WHERE entries.account_id = sqlc.arg(account_id)
+  AND entries.amount < 0
+  AND entries.created_at >= sqlc.arg(since)
+  AND journals.kind IN ('transfer', 'scheduled_transfer', 'hold_capture');
+
+-- name: GetOwnerOutgoingAmountSince :one
+-- Outgoing amount of all accounts of the owner in the currency, counted like GetOutgoingAmountSince
+SELECT COALESCE(-SUM(entries.amount), 0)::bigint AS amount
+FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+JOIN journals ON journals.id = entries.journal_id
+WHERE accounts.owner = sqlc.arg(owner)
+  AND accounts.currency = sqlc.arg(currency)
+  AND entries.amount < 0
+  AND entries.created_at >= sqlc.arg(since)
+  AND journals.kind IN ('transfer', 'scheduled_transfer', 'hold_capture');
+
+-- name: SumAccountEntries :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+FROM entries
+WHERE account_id = $1;
+
+-- name: ListCurrencyEntryTotals :many
+-- Every transfer debits and credits the same amount, the entries of a currency net to zero
+SELECT accounts.currency, SUM(entries.amount)::bigint AS total
+FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+GROUP BY accounts.currency
+ORDER BY accounts.currency;
+
+-- name: CountUnlinkedEntries :one
+SELECT COUNT(*) FROM entries
+WHERE transfer_id IS NULL;
@@ -0,0 +1,54 @@
+-- name: CreateHold :one
+INSERT INTO holds (
+  account_id,
+  amount,
+  expires_at
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetHold :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1;
+
+-- name: GetHoldForUpdate :one
+SELECT * FROM holds
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListHolds :many
+SELECT * FROM holds
+WHERE account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: MarkHoldCaptured :one
+UPDATE holds
+SET
+  status = 'captured',
+  captured_amount = sqlc.arg(captured_amount),
+  transfer_id = sqlc.arg(transfer_id),
+  updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: MarkHoldReleased :one
+UPDATE holds
+SET
+  status = 'released',
+  updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: ExpireHolds :execrows
+UPDATE holds
+SET
+  status = 'expired',
+  updated_at = now()
+WHERE status = 'active' AND expires_at <= now();
+
+-- name: GetHeldAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS held_amount
+FROM holds
+WHERE account_id = $1 AND status = 'active' AND expires_at > now();
@@ -0,0 +1,69 @@
+-- name: CreateJob :one
+-- A unique job is not created while a pending job of the kind has the key, no row is returned then
+INSERT INTO jobs (
+  kind,
+  payload,
+  priority,
+  unique_key,
+  max_attempts,
+  run_at
+) VALUES (
+  $1, $2, $3, $4, $5, COALESCE(sqlc.narg(run_at), now())
+)
+ON CONFLICT (kind, unique_key) WHERE status = 'pending' DO NOTHING
+RETURNING *;
+
+-- name: GetJob :one
+SELECT * FROM jobs
+WHERE id = $1 LIMIT 1;
+
+-- name: ClaimJob :one
+-- Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+SELECT * FROM jobs
+WHERE status = 'pending' AND run_at <= now() AND kind = ANY(sqlc.arg(kinds)::varchar[])
+ORDER BY priority DESC, run_at
+LIMIT 1
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: MarkJobSucceeded :one
+UPDATE jobs
+SET
+  status = 'succeeded',
+  attempts = attempts + 1,
+  last_error = '',
+  finished_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RetryJob :one
+-- Records a failed attempt, the job runs again at run_at unless dead
+UPDATE jobs
+SET
+  status = $1,
+  attempts = attempts + 1,
+  last_error = $2,
+  run_at = $3,
+  finished_at = CASE WHEN $1 = 'dead' THEN now() END
+WHERE id = $4
+RETURNING *;
+
+-- name: ListJobs :many
+SELECT * FROM jobs
+WHERE
+  (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status)) AND
+  (sqlc.narg(kind)::varchar IS NULL OR kind = sqlc.narg(kind))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: RequeueJob :one
+-- Runs a dead job again from the first attempt, e.g. once the failure is fixed
+UPDATE jobs
+SET
+  status = 'pending',
+  attempts = 0,
+  last_error = '',
+  run_at = now(),
+  finished_at = NULL
+WHERE id = $1 AND status = 'dead'
+RETURNING *;
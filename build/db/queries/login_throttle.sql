@@ -0,0 +1,41 @@
+-- name: ListLoginThrottlesByKeys :many
+SELECT * FROM login_throttles
+WHERE (scope = 'username' AND key = sqlc.arg(username))
+   OR (scope = 'client_ip' AND key = sqlc.arg(client_ip));
+
+-- name: CountLoginFailure :one
+INSERT INTO login_throttles (
+  scope,
+  key,
+  failures
+) VALUES (
+  sqlc.arg(scope), sqlc.arg(key), 1
+) ON CONFLICT (scope, key) DO UPDATE
+SET
+  failures = CASE WHEN login_throttles.last_failed_at > sqlc.arg(since) THEN login_throttles.failures + 1 ELSE 1 END,
+  last_failed_at = now()
+RETURNING *;
+
+-- name: LockLoginThrottle :one
+UPDATE login_throttles
+SET
+  locked_until = $3
+WHERE scope = $1 AND key = $2
+RETURNING *;
+
+-- name: ResetLoginThrottle :exec
+DELETE FROM login_throttles
+WHERE scope = 'username' AND key = sqlc.arg(username);
+
+-- name: DeleteLoginThrottle :one
+DELETE FROM login_throttles
+WHERE scope = $1 AND key = $2
+RETURNING *;
+
+-- name: ListLoginThrottles :many
+-- Throttles with the most recent failures first, only those locked out now when locked is true
+SELECT * FROM login_throttles
+WHERE NOT sqlc.arg(locked)::bool OR locked_until > now()
+ORDER BY last_failed_at DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
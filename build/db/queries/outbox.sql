@@ -0,0 +1,51 @@
+-- name: CreateOutboxEvent :one
+INSERT INTO outbox (
+  aggregate_type,
+  aggregate_id,
+  event_type,
+  event_version,
+  payload
+) VALUES (
+  $1, $2, $3, $4, $5
+) RETURNING *;
+
+-- name: ClaimOutboxEvent :one
+-- Claims the oldest pending event that is due, later events of its aggregate wait until it is published.
+-- Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+SELECT * FROM outbox o
+WHERE
+  published_at IS NULL AND
+  next_attempt_at <= now() AND
+  NOT EXISTS (
+    SELECT 1 FROM outbox earlier
+    WHERE
+      earlier.aggregate_type = o.aggregate_type AND
+      earlier.aggregate_id = o.aggregate_id AND
+      earlier.published_at IS NULL AND
+      earlier.id < o.id
+  )
+ORDER BY id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkOutboxEventPublished :one
+UPDATE outbox
+SET
+  attempts = attempts + 1,
+  last_error = '',
+  published_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: RetryOutboxEvent :one
+UPDATE outbox
+SET
+  attempts = attempts + 1,
+  last_error = sqlc.arg(last_error),
+  next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CountPendingOutboxEvents :one
+SELECT COUNT(*) FROM outbox
+WHERE published_at IS NULL;
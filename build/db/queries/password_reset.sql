@@ -0,0 +1,56 @@
+-- name: CreatePasswordResetRequest :exec
+INSERT INTO password_reset_requests (
+  email,
+  client_ip
+) VALUES (
+  $1, $2
+);
+
+-- name: CountPasswordResetRequestsByEmail :one
+SELECT COUNT(*) FROM password_reset_requests
+WHERE lower(email) = lower(sqlc.arg(email)) AND created_at > sqlc.arg(since);
+
+-- name: CountPasswordResetRequestsByClientIP :one
+SELECT COUNT(*) FROM password_reset_requests
+WHERE client_ip = sqlc.arg(client_ip) AND created_at > sqlc.arg(since);
+
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (
+  username,
+  email
+) VALUES (
+  $1, $2
+) RETURNING *;
+
+-- name: GetPasswordResetToSend :one
+SELECT
+  p.id,
+  p.username,
+  p.email,
+  p.is_used,
+  p.sent_at,
+  p.expired_at,
+  u.full_name
+FROM password_resets p
+JOIN users u ON u.username = p.username
+WHERE p.id = $1 LIMIT 1
+FOR NO KEY UPDATE OF p;
+
+-- name: SetPasswordResetToken :one
+UPDATE password_resets
+SET
+  token_hash = $2,
+  sent_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetPasswordResetByTokenForUpdate :one
+SELECT * FROM password_resets
+WHERE token_hash = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: InvalidateUserPasswordResets :execrows
+UPDATE password_resets
+SET
+  is_used = TRUE
+WHERE username = $1 AND NOT is_used;
@@ -0,0 +1,25 @@
+-- name: LockRateLimitBucket :one
+-- Locks the bucket of the key, created full when missing
+INSERT INTO rate_limit_buckets (
+  key,
+  tokens,
+  updated_at,
+  full_at
+) VALUES (
+  sqlc.arg(key), sqlc.arg(tokens), sqlc.arg(now), sqlc.arg(now)
+) ON CONFLICT (key) DO UPDATE
+SET
+  key = EXCLUDED.key
+RETURNING *;
+
+-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets
+SET
+  tokens = $2,
+  updated_at = $3,
+  full_at = $4
+WHERE key = $1;
+
+-- name: DeleteFullRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE full_at <= now();
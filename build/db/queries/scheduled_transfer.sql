@@ -0,0 +1,103 @@
+-- name: CreateScheduledTransfer :one
+INSERT INTO scheduled_transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  recurrence,
+  start_at,
+  next_occurrence_at,
+  next_attempt_at
+) VALUES (
+  sqlc.arg(from_account_id),
+  sqlc.arg(to_account_id),
+  sqlc.arg(amount),
+  sqlc.arg(recurrence),
+  sqlc.arg(start_at),
+  sqlc.arg(next_occurrence_at),
+  sqlc.arg(next_occurrence_at)
+) RETURNING *;
+
+-- name: GetScheduledTransfer :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1;
+
+-- name: GetScheduledTransferForUpdate :one
+SELECT * FROM scheduled_transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: ListScheduledTransfers :many
+SELECT * FROM scheduled_transfers
+WHERE from_account_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: UpdateScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  amount = COALESCE(sqlc.narg(amount), amount),
+  recurrence = COALESCE(sqlc.narg(recurrence), recurrence),
+  status = COALESCE(sqlc.narg(status), status),
+  next_occurrence_at = COALESCE(sqlc.narg(next_occurrence_at), next_occurrence_at),
+  next_attempt_at = COALESCE(sqlc.narg(next_occurrence_at), next_attempt_at),
+  attempts = CASE WHEN sqlc.narg(next_occurrence_at)::timestamptz IS NULL THEN attempts ELSE 0 END,
+  updated_at = now()
+WHERE
+  id = sqlc.arg(id)
+RETURNING *;
+
+-- name: ClaimDueScheduledTransfer :one
+-- Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+SELECT * FROM scheduled_transfers
+WHERE status = 'active' AND next_attempt_at <= now()
+ORDER BY next_attempt_at
+LIMIT 1
+FOR NO KEY UPDATE SKIP LOCKED;
+
+-- name: AdvanceScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  status = sqlc.arg(status),
+  next_occurrence_at = sqlc.arg(next_occurrence_at),
+  next_attempt_at = sqlc.arg(next_occurrence_at),
+  attempts = 0,
+  updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RetryScheduledTransfer :one
+UPDATE scheduled_transfers
+SET
+  attempts = attempts + 1,
+  next_attempt_at = sqlc.arg(next_attempt_at),
+  updated_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: CreateScheduledTransferRun :one
+INSERT INTO scheduled_transfer_runs (
+  scheduled_transfer_id,
+  occurrence_at,
+  attempt,
+  status,
+  transfer_id,
+  error
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: ListScheduledTransferRuns :many
+SELECT * FROM scheduled_transfer_runs
+WHERE scheduled_transfer_id = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+
+-- name: CancelAccountScheduledTransfers :execrows
+UPDATE scheduled_transfers
+SET
+  status = 'cancelled',
+  updated_at = now()
+WHERE (from_account_id = sqlc.arg(account_id) OR to_account_id = sqlc.arg(account_id)) AND status IN ('active', 'paused');
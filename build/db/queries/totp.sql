@@ -0,0 +1,60 @@
+-- name: CreateUserTOTP :one
+INSERT INTO user_totps (
+  username,
+  encrypted_secret
+) VALUES (
+  $1, $2
+) ON CONFLICT (username) DO UPDATE
+SET
+  encrypted_secret = EXCLUDED.encrypted_secret,
+  is_enabled = FALSE,
+  last_used_step = 0,
+  created_at = now(),
+  enabled_at = NULL
+RETURNING *;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1;
+
+-- name: GetUserTOTPForUpdate :one
+SELECT * FROM user_totps
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: EnableUserTOTP :one
+UPDATE user_totps
+SET
+  is_enabled = TRUE,
+  last_used_step = $2,
+  enabled_at = now()
+WHERE username = $1
+RETURNING *;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE user_totps
+SET
+  last_used_step = $2
+WHERE username = $1 AND is_enabled AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totps
+WHERE username = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+  username,
+  code_hash
+) VALUES (
+  $1, $2
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET
+  used_at = now()
+WHERE username = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE username = $1;
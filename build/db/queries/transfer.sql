@@ -2,9 +2,10 @@
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  journal_id
 ) VALUES (
-  $1, $2, $3
+  $1, $2, $3, $4
 ) RETURNING *;
 
 -- name: GetTransfer :one
@@ -18,4 +19,53 @@ WHERE
     to_account_id = $2
 ORDER BY id
 LIMIT $3
-OFFSET $4;
\ No newline at end of file
+OFFSET $4;
+
+-- name: GetTransferForUpdate :one
+SELECT * FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: CreateReversal :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  reversal_of,
+  reversal_reason,
+  journal_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING *;
+
+-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS reversed_amount
+FROM transfers
+WHERE reversal_of = sqlc.arg(transfer_id)::bigint;
+
+
+-- name: CountWithdrawalsSince :one
+-- Reversals are not withdrawals of the account
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1 AND created_at >= sqlc.arg(since) AND reversal_of IS NULL;
+
+-- name: ListTransferEntryCountsAfter :many
+-- Keyset pagination over all transfers with the entries booking them
+SELECT
+  transfers.id,
+  transfers.from_account_id,
+  transfers.to_account_id,
+  transfers.amount,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.from_account_id AND entries.amount = -transfers.amount
+  ) AS debits,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.to_account_id AND entries.amount = transfers.amount
+  ) AS credits,
+  COUNT(entries.id) AS entries
+FROM transfers
+LEFT JOIN entries ON entries.transfer_id = transfers.id
+WHERE transfers.id > sqlc.arg(id)
+GROUP BY transfers.id
+ORDER BY transfers.id
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,66 @@
+-- name: GetTransferLimit :one
+-- A user override takes precedence over the limits of the product
+SELECT * FROM transfer_limits
+WHERE owner = sqlc.arg(owner)::varchar OR product = sqlc.arg(product)::varchar
+ORDER BY owner IS NULL
+LIMIT 1;
+
+-- name: ListTransferLimits :many
+SELECT * FROM transfer_limits
+ORDER BY id;
+
+-- name: SetProductTransferLimit :one
+INSERT INTO transfer_limits (
+  product,
+  max_amount,
+  account_daily,
+  account_monthly,
+  user_daily,
+  user_monthly
+) VALUES (
+  sqlc.arg(product)::varchar,
+  sqlc.arg(max_amount),
+  sqlc.arg(account_daily),
+  sqlc.arg(account_monthly),
+  sqlc.arg(user_daily),
+  sqlc.arg(user_monthly)
+)
+ON CONFLICT (product) DO UPDATE
+SET
+  max_amount = EXCLUDED.max_amount,
+  account_daily = EXCLUDED.account_daily,
+  account_monthly = EXCLUDED.account_monthly,
+  user_daily = EXCLUDED.user_daily,
+  user_monthly = EXCLUDED.user_monthly,
+  updated_at = now()
+RETURNING *;
+
+-- name: SetUserTransferLimit :one
+INSERT INTO transfer_limits (
+  owner,
+  max_amount,
+  account_daily,
+  account_monthly,
+  user_daily,
+  user_monthly
+) VALUES (
+  sqlc.arg(owner)::varchar,
+  sqlc.arg(max_amount),
+  sqlc.arg(account_daily),
+  sqlc.arg(account_monthly),
+  sqlc.arg(user_daily),
+  sqlc.arg(user_monthly)
+)
+ON CONFLICT (owner) DO UPDATE
+SET
+  max_amount = EXCLUDED.max_amount,
+  account_daily = EXCLUDED.account_daily,
+  account_monthly = EXCLUDED.account_monthly,
+  user_daily = EXCLUDED.user_daily,
+  user_monthly = EXCLUDED.user_monthly,
+  updated_at = now()
+RETURNING *;
+
+-- name: DeleteUserTransferLimit :exec
+DELETE FROM transfer_limits
+WHERE owner = sqlc.arg(owner)::varchar;
@@ -18,7 +18,32 @@ SET
   hashed_password = COALESCE(sqlc.narg(hashed_password), hashed_password),
   password_changed_at = COALESCE(sqlc.narg(password_changed_at), password_changed_at),
   full_name = COALESCE(sqlc.narg(full_name), full_name),
-  email = COALESCE(sqlc.narg(email), email)
+  email = COALESCE(sqlc.narg(email), email),
+  is_email_verified = COALESCE(sqlc.narg(is_email_verified), is_email_verified),
+  role = COALESCE(sqlc.narg(role), role),
+  role_changed_at = CASE WHEN COALESCE(sqlc.narg(role), role) <> role THEN now() ELSE role_changed_at END
 WHERE
   username = sqlc.arg(username)
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: GetUserAuthChangedAt :one
+-- Access tokens issued before the last change of the password or the role of the user are refused
+SELECT GREATEST(password_changed_at, role_changed_at)::timestamptz AS changed_at
+FROM users
+WHERE username = $1;
+
+-- name: GetUserForUpdate :one
+SELECT * FROM users
+WHERE username = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetUserByEmail :one
+SELECT * FROM users
+WHERE email = $1 LIMIT 1;
+
+-- name: RehashUserPassword :execrows
+-- Replaces the hash of the password of a user by another hash of the same password, unless the password changed meanwhile
+UPDATE users
+SET
+  hashed_password = sqlc.arg(new_hashed_password)
+WHERE username = sqlc.arg(username) AND hashed_password = sqlc.arg(hashed_password);
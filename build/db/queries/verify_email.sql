@@ -0,0 +1,42 @@
+-- name: CreateVerifyEmail :one
+INSERT INTO verify_emails (
+  username,
+  email,
+  secret_code
+) VALUES (
+  $1, $2, $3
+) RETURNING *;
+
+-- name: GetVerifyEmailForUpdate :one
+SELECT * FROM verify_emails
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE;
+
+-- name: GetVerifyEmailToSend :one
+SELECT
+  v.id,
+  v.username,
+  v.email,
+  v.secret_code,
+  v.is_used,
+  v.sent_at,
+  v.expired_at,
+  u.full_name
+FROM verify_emails v
+JOIN users u ON u.username = v.username
+WHERE v.id = $1 LIMIT 1
+FOR NO KEY UPDATE OF v;
+
+-- name: MarkVerifyEmailSent :one
+UPDATE verify_emails
+SET
+  sent_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkVerifyEmailUsed :one
+UPDATE verify_emails
+SET
+  is_used = TRUE
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,103 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (
+  owner,
+  url,
+  event_types,
+  secret
+) VALUES (
+  $1, $2, $3, $4
+) RETURNING *;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE owner = $1
+ORDER BY id
+LIMIT $2
+OFFSET $3;
+
+-- name: DeleteWebhookSubscription :exec
+DELETE FROM webhook_subscriptions
+WHERE id = $1;
+
+-- name: CreateWebhookDeliveries :exec
+-- Fans an outbox event out to the matching subscriptions of the owners
+INSERT INTO webhook_deliveries (subscription_id, event_id)
+SELECT id, sqlc.arg(event_id)::bigint FROM webhook_subscriptions
+WHERE
+  owner = ANY(sqlc.arg(owners)::varchar[]) AND
+  (cardinality(event_types) = 0 OR sqlc.arg(event_type)::varchar = ANY(event_types))
+ON CONFLICT (subscription_id, event_id) DO NOTHING;
+
+-- name: ClaimWebhookDelivery :one
+-- Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+SELECT
+  d.id,
+  d.subscription_id,
+  d.attempts,
+  s.url,
+  s.secret,
+  o.id AS event_id,
+  o.aggregate_type,
+  o.aggregate_id,
+  o.event_type,
+  o.event_version,
+  o.payload,
+  o.created_at
+FROM webhook_deliveries d
+JOIN webhook_subscriptions s ON s.id = d.subscription_id
+JOIN outbox o ON o.id = d.event_id
+WHERE d.status = 'pending' AND d.next_attempt_at <= now()
+ORDER BY d.next_attempt_at
+LIMIT 1
+FOR NO KEY UPDATE OF d SKIP LOCKED;
+
+-- name: MarkWebhookDeliverySucceeded :one
+UPDATE webhook_deliveries
+SET
+  status = 'succeeded',
+  attempts = attempts + 1,
+  last_error = '',
+  response_status = sqlc.arg(response_status),
+  delivered_at = now()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RetryWebhookDelivery :one
+-- Records a failed attempt, the delivery is retried at next_attempt_at unless dead
+UPDATE webhook_deliveries
+SET
+  status = sqlc.arg(status),
+  attempts = attempts + 1,
+  last_error = sqlc.arg(last_error),
+  response_status = sqlc.arg(response_status),
+  next_attempt_at = sqlc.arg(next_attempt_at)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1 LIMIT 1;
+
+-- name: ListWebhookDeliveries :many
+SELECT * FROM webhook_deliveries
+WHERE
+  subscription_id = sqlc.arg(subscription_id) AND
+  (sqlc.narg(status)::varchar IS NULL OR status = sqlc.narg(status))
+ORDER BY id DESC
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ReplayWebhookDelivery :one
+-- Delivers again from the first attempt, e.g. a dead delivery once the receiver is fixed
+UPDATE webhook_deliveries
+SET
+  status = 'pending',
+  attempts = 0,
+  last_error = '',
+  next_attempt_at = now()
+WHERE id = $1
+RETURNING *;
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/interest"
+	"go.uber.org/zap"
+)
+
+// newAccrualPolicy returns the accrual policy configured by cfg.
+func newAccrualPolicy(cfg util.Config) (bank.AccrualPolicy, error) {
+	basis, err := interest.ParseBasis(cfg.AccrualBasis)
+	if err != nil {
+		return bank.AccrualPolicy{}, err
+	}
+
+	rounding, err := interest.ParseRounding(cfg.AccrualRounding)
+	if err != nil {
+		return bank.AccrualPolicy{}, err
+	}
+
+	return bank.AccrualPolicy{Basis: basis, Rounding: rounding}, nil
+}
+
+// runAccruals periodically accrues interest and charges fees for all accounts, until ctx is done.
+// Runs only pick up days and months not handled before, so several replicas can run side by side.
+func runAccruals(ctx context.Context, b bank.Bank, policy bank.AccrualPolicy, cfg util.Config, logger *zap.Logger) {
+	ticker := time.NewTicker(cfg.AccrualInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			accrueAccounts(ctx, b, policy, cfg.AccrualBatchSize, logger)
+		}
+	}
+}
+
+// accrueAccounts runs the accruals of all accounts as of now, in batches of accounts.
+func accrueAccounts(ctx context.Context, b bank.Bank, policy bank.AccrualPolicy, batchSize int32, logger *zap.Logger) {
+	asOf := time.Now()
+
+	var afterID int64
+
+	for ctx.Err() == nil {
+		accounts, err := b.ListAccountsAfter(ctx, db.ListAccountsAfterParams{
+			ID:    afterID,
+			Limit: batchSize,
+		})
+		if err != nil {
+			logger.Error("accruals: list accounts", zap.Error(err))
+			return
+		}
+
+		if len(accounts) == 0 {
+			return
+		}
+
+		for _, account := range accounts {
+			result, err := b.Accrue(ctx, bank.AccrueParams{
+				AccountID: account.ID,
+				AsOf:      asOf,
+				Policy:    policy,
+			})
+			if err != nil {
+				// Retried by the next run
+				logger.Error("accruals: accrue", zap.Int64("account_id", account.ID), zap.Error(err))
+				continue
+			}
+
+			for _, posting := range result.Postings {
+				logger.Info(
+					"accruals: posted",
+					zap.Int64("account_id", posting.AccountID),
+					zap.String("kind", posting.Kind),
+					zap.Time("period_start", posting.PeriodStart.Time),
+					zap.Int64("amount", posting.Amount),
+				)
+			}
+		}
+
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+// runBalanceListener listens to balance notifications on a dedicated connection, until ctx is done. A failed
+// connection is replaced, and subscribers are sent the current balances as notifications may have been missed.
+func runBalanceListener(ctx context.Context, hub *balance.Hub, cfg util.Config, logger *zap.Logger) {
+	const maxDelay = time.Minute
+
+	delay := time.Second
+
+	for ctx.Err() == nil {
+		started := time.Now()
+
+		err := listenBalances(ctx, hub, cfg.DBSource)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that lasted a while failed for a new reason
+		if time.Since(started) > maxDelay {
+			delay = time.Second
+		}
+
+		logger.Error("balance listener: listen", zap.Error(err), zap.Duration("retry_in", delay))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxDelay {
+			delay = maxDelay
+		}
+	}
+}
+
+// listenBalances connects and listens until the connection fails.
+func listenBalances(ctx context.Context, hub *balance.Hub, dbSource string) error {
+	conn, err := pgx.Connect(ctx, dbSource)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	return hub.Listen(ctx, conn)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"go.uber.org/zap"
+)
+
+// runHoldExpiry periodically expires active holds that passed their expiry time, until ctx is done.
+// Expired holds stop reserving money as soon as they pass their expiry time, this only records it.
+func runHoldExpiry(ctx context.Context, b bank.Bank, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := b.ExpireHolds(ctx)
+			if err != nil {
+				logger.Error("hold expiry: expire holds", zap.Error(err))
+				continue
+			}
+
+			if expired > 0 {
+				logger.Info("hold expiry: expired holds", zap.Int64("expired", expired))
+			}
+		}
+	}
+}
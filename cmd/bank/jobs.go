@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/mail"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"go.uber.org/zap"
+)
+
+// newMailer returns the mailer configured by cfg.
+func newMailer(cfg util.Config, logger *zap.Logger) (mail.Mailer, error) {
+	switch cfg.Mailer {
+	case "log":
+		// Only development runs are debugged from the log, elsewhere email must reach its recipients
+		if cfg.Environment != "development" {
+			return nil, fmt.Errorf("mailer log: only allowed in development, not %q", cfg.Environment)
+		}
+		return mail.NewLogMailer(logger), nil
+	case "file":
+		return mail.NewFileMailer(cfg.MailFile)
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("mailer smtp: no host")
+		}
+		return mail.NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+// newJobRegistry registers the tasks run by the job workers.
+func newJobRegistry(cfg util.Config, logger *zap.Logger) (*jobs.Registry, error) {
+	mailer, err := newMailer(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, mail.VerifyEmailTask, mail.SendVerifyEmail(mailer, cfg.MailFrom, cfg.VerifyEmailURL))
+	jobs.Register(registry, mail.PasswordResetTask, mail.SendPasswordReset(mailer, cfg.MailFrom, cfg.PasswordResetURL))
+	jobs.Register(registry, mail.AccountLockedTask, mail.SendAccountLocked(mailer, cfg.MailFrom))
+
+	return registry, nil
+}
+
+// runJobWorkers runs the configured number of job workers until ctx is done, each polling for due jobs.
+func runJobWorkers(ctx context.Context, b bank.Bank, registry *jobs.Registry, cfg util.Config, logger *zap.Logger) {
+	for i := 0; i < cfg.JobConcurrency; i++ {
+		go runJobWorker(ctx, b, registry, cfg, logger.With(zap.Int("worker", i)))
+	}
+}
+
+// runJobWorker periodically runs all due jobs, until ctx is done.
+func runJobWorker(ctx context.Context, b bank.Bank, registry *jobs.Registry, cfg util.Config, logger *zap.Logger) {
+	ticker := time.NewTicker(cfg.JobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := jobs.Run(ctx, b, registry, cfg.JobRetryDelay)
+			if err != nil {
+				logger.Error("jobs: run", zap.Error(err))
+			}
+
+			if result.Failed > 0 || result.Dead > 0 {
+				logger.Warn(
+					"jobs: jobs failed",
+					zap.Int("succeeded", result.Succeeded),
+					zap.Int("failed", result.Failed),
+					zap.Int("dead", result.Dead),
+				)
+			}
+		}
+	}
+}
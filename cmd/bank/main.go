@@ -10,6 +10,7 @@ import (
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/cmd"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/api"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -53,13 +54,77 @@ func main() {
 		logger.Fatal("initializing: ping db", zap.Error(err))
 	}
 
+	// Subcommands run against the database as it is and exit, e.g. `bank reconcile -repair`
+	if len(os.Args) > 1 {
+		var code int
+
+		switch os.Args[1] {
+		case "reconcile":
+			code = reconcileCommand(ctx, bank.NewBank(connPool), cfg, os.Args[2:], logger)
+		case "verify-audit":
+			code = verifyAuditCommand(ctx, bank.NewBank(connPool), os.Args[2:], logger)
+		default:
+			logger.Fatal("initializing: unknown command", zap.String("command", os.Args[1]))
+		}
+
+		logger.Sync()
+		os.Exit(code)
+	}
+
 	runDBMigration(cfg.MigrationURL, cfg.DBSource, logger)
 
 	// Set up the bank
 	bank := bank.NewBank(connPool)
 
+	// Expire holds in the background
+	go runHoldExpiry(ctx, bank, cfg.HoldExpiryInterval, logger)
+
+	// Delete full rate limit buckets of the postgres store in the background
+	if cfg.RateLimitStore == "postgres" {
+		go runRateLimitCleanup(ctx, bank, cfg.RateLimitCleanupInterval, logger)
+	}
+
+	// Execute scheduled transfers in the background
+	go runScheduledTransfers(ctx, bank, cfg, logger)
+
+	// Accrue interest and charge fees in the background
+	accrualPolicy, err := newAccrualPolicy(cfg)
+	if err != nil {
+		logger.Fatal("initializing: accrual policy", zap.Error(err))
+	}
+
+	go runAccruals(ctx, bank, accrualPolicy, cfg, logger)
+
+	// Check the ledger for consistency in the background
+	go runReconciliation(ctx, bank, cfg, logger)
+
+	// Publish domain events of the outbox in the background
+	publisher, err := newPublisher(cfg)
+	if err != nil {
+		logger.Fatal("initializing: outbox publisher", zap.Error(err))
+	}
+
+	if publisher != nil {
+		go runOutboxRelay(ctx, bank, publisher, cfg, logger)
+	}
+
+	// Deliver webhooks in the background
+	go runWebhookDeliveries(ctx, bank, cfg, logger)
+
+	// Run jobs of the queue in the background, e.g. sending verification emails of new users
+	registry, err := newJobRegistry(cfg, logger)
+	if err != nil {
+		logger.Fatal("initializing: job registry", zap.Error(err))
+	}
+
+	runJobWorkers(ctx, bank, registry, cfg, logger)
+
+	// Stream balance changes to subscribers, notified on a dedicated connection
+	balances := balance.NewHub(bank, cfg.BalanceEventBuffer)
+	go runBalanceListener(ctx, balances, cfg, logger)
+
 	// Set up the API server for the bank
-	server, err := api.NewServer(cfg, bank)
+	server, err := api.NewServer(cfg, bank, balances)
 	if err != nil {
 		logger.Fatal("initializing: api server", zap.Error(err))
 	}
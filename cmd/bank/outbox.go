@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/outbox"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"go.uber.org/zap"
+)
+
+// newPublisher returns the outbox publisher configured by cfg, nil when relaying is off.
+func newPublisher(cfg util.Config) (outbox.Publisher, error) {
+	switch cfg.OutboxPublisher {
+	case "":
+		return nil, nil
+	case "memory":
+		return outbox.NewMemoryPublisher(), nil
+	case "file":
+		return outbox.NewFilePublisher(cfg.OutboxFile)
+	case "http":
+		if cfg.OutboxURL == "" {
+			return nil, fmt.Errorf("outbox publisher http: no url")
+		}
+		return outbox.NewHTTPPublisher(cfg.OutboxURL, 10*time.Second), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", cfg.OutboxPublisher)
+	}
+}
+
+// runOutboxRelay periodically publishes due events of the outbox, until ctx is done.
+func runOutboxRelay(ctx context.Context, b bank.Bank, publisher outbox.Publisher, cfg util.Config, logger *zap.Logger) {
+	ticker := time.NewTicker(cfg.OutboxInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := outbox.Relay(ctx, b, publisher, cfg.OutboxRetryDelay)
+			if err != nil {
+				logger.Error("outbox: relay", zap.Error(err))
+			}
+
+			if result.Failed > 0 {
+				logger.Warn("outbox: publishing failed", zap.Int("published", result.Published), zap.Int("failed", result.Failed))
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"go.uber.org/zap"
+)
+
+// runRateLimitCleanup periodically deletes the rate limit buckets of the postgres store that are full again,
+// until ctx is done. A full bucket is the same as a missing one.
+func runRateLimitCleanup(ctx context.Context, b bank.Bank, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := b.DeleteFullRateLimitBuckets(ctx)
+			if err != nil {
+				logger.Error("rate limit cleanup: delete full buckets", zap.Error(err))
+				continue
+			}
+
+			if deleted > 0 {
+				logger.Debug("rate limit cleanup: deleted full buckets", zap.Int64("deleted", deleted))
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/reconcile"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"go.uber.org/zap"
+)
+
+// reconcileCommand runs `bank reconcile`, a single reconciliation run writing its report as JSON to stdout.
+// It returns the exit code, 1 when the ledger is left inconsistent and 2 when the run fails.
+func reconcileCommand(ctx context.Context, b bank.Bank, cfg util.Config, args []string, logger *zap.Logger) int {
+	flags := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	repair := flags.Bool("repair", false, "set the balance of drifting accounts to the sum of their entries")
+	batchSize := flags.Int("batch-size", int(cfg.ReconcileBatchSize), "accounts and transfers read per query")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := reconcile.Run(ctx, b, reconcile.Options{
+		BatchSize: int32(*batchSize),
+		Repair:    *repair,
+	})
+	if err != nil {
+		logger.Error("reconcile: run", zap.Error(err))
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(report); err != nil {
+		logger.Error("reconcile: write report", zap.Error(err))
+		return 2
+	}
+
+	if !report.Consistent() {
+		return 1
+	}
+
+	return 0
+}
+
+// runReconciliation periodically checks the ledger without repairing it, until ctx is done. Problems are
+// logged and the outcome is published as metrics.
+func runReconciliation(ctx context.Context, b bank.Bank, cfg util.Config, logger *zap.Logger) {
+	ticker := time.NewTicker(cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := reconcile.Run(ctx, b, reconcile.Options{BatchSize: cfg.ReconcileBatchSize})
+			if err != nil {
+				logger.Error("reconcile: run", zap.Error(err))
+				continue
+			}
+
+			if !report.Consistent() {
+				logger.Warn(
+					"reconcile: ledger inconsistent",
+					zap.Int("account_drift", len(report.AccountDrift)),
+					zap.Int("transfer_mismatches", len(report.TransferMismatches)),
+					zap.Int("currency_drift", len(report.CurrencyDrift)),
+					zap.Int64("unlinked_entries", report.UnlinkedEntries),
+				)
+			}
+		}
+	}
+}
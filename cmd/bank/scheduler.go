@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"go.uber.org/zap"
+)
+
+// runScheduledTransfers periodically executes all due scheduled transfers, until ctx is done.
+// Several replicas can run side by side, each due occurrence is claimed by one of them.
+func runScheduledTransfers(ctx context.Context, b bank.Bank, cfg util.Config, logger *zap.Logger) {
+	policy := bank.RetryPolicy{
+		MaxAttempts: cfg.ScheduledTransferMaxAttempts,
+		Delay:       cfg.ScheduledTransferRetryDelay,
+	}
+
+	ticker := time.NewTicker(cfg.ScheduledTransferInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runDueScheduledTransfers(ctx, b, policy, logger)
+		}
+	}
+}
+
+// runDueScheduledTransfers executes scheduled transfers until none is due.
+func runDueScheduledTransfers(ctx context.Context, b bank.Bank, policy bank.RetryPolicy, logger *zap.Logger) {
+	for ctx.Err() == nil {
+		result, err := b.RunScheduledTransfer(ctx, policy)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return
+		}
+		if err != nil {
+			logger.Error("scheduled transfers: run", zap.Error(err))
+			return
+		}
+
+		fields := []zap.Field{
+			zap.Int64("scheduled_transfer_id", result.Run.ScheduledTransferID),
+			zap.Time("occurrence_at", result.Run.OccurrenceAt),
+			zap.Int32("attempt", result.Run.Attempt),
+			zap.String("status", result.Run.Status),
+		}
+
+		if result.Run.Status == bank.RunStatusFailed {
+			logger.Warn("scheduled transfers: run failed", append(fields, zap.String("error", result.Run.Error))...)
+			continue
+		}
+
+		logger.Info("scheduled transfers: run succeeded", append(fields, zap.Int64("transfer_id", result.Run.TransferID.Int64))...)
+	}
+}
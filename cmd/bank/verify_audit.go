@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/audit"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"go.uber.org/zap"
+)
+
+// verifyAuditCommand runs `bank verify-audit`, verifying the hash chain of the audit log and writing the
+// result as JSON to stdout. It returns the exit code, 1 when the chain is broken and 2 when the run fails.
+func verifyAuditCommand(ctx context.Context, b bank.Bank, args []string, logger *zap.Logger) int {
+	flags := flag.NewFlagSet("verify-audit", flag.ContinueOnError)
+	batchSize := flags.Int("batch-size", 1000, "audit events read per query")
+
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	result, err := audit.Verify(ctx, b, int32(*batchSize))
+	if err != nil {
+		logger.Error("verify-audit: run", zap.Error(err))
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(result); err != nil {
+		logger.Error("verify-audit: write result", zap.Error(err))
+		return 2
+	}
+
+	if !result.Valid {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/webhook"
+	"go.uber.org/zap"
+)
+
+// runWebhookDeliveries periodically delivers all due webhook deliveries, until ctx is done.
+// Several replicas can run side by side, each delivery is claimed by one of them.
+func runWebhookDeliveries(ctx context.Context, b bank.Bank, cfg util.Config, logger *zap.Logger) {
+	sender := webhook.NewSender(cfg.WebhookTimeout)
+	policy := bank.RetryPolicy{
+		MaxAttempts: cfg.WebhookMaxAttempts,
+		Delay:       cfg.WebhookRetryDelay,
+	}
+
+	ticker := time.NewTicker(cfg.WebhookInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := webhook.Run(ctx, b, sender, policy)
+			if err != nil {
+				logger.Error("webhooks: run", zap.Error(err))
+			}
+
+			if result.Failed > 0 || result.Dead > 0 {
+				logger.Warn(
+					"webhooks: deliveries failed",
+					zap.Int("delivered", result.Delivered),
+					zap.Int("failed", result.Failed),
+					zap.Int("dead", result.Dead),
+				)
+			}
+		}
+	}
+}
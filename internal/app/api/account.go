@@ -1,15 +1,34 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
 )
 
+// accountResponse shows the ledger balance together with the available balance, the ledger balance
+// minus money reserved by active holds.
+type accountResponse struct {
+	db.Account
+	AvailableBalance int64 `json:"available_balance"`
+}
+
+func newAccountResponse(account db.Account, held int64) accountResponse {
+	return accountResponse{
+		Account:          account,
+		AvailableBalance: account.Balance - held,
+	}
+}
+
 type createAccountRequest struct {
 	Owner    string `json:"owner" binding:"required"`
 	Currency string `json:"currency" binding:"required"`
+	// Decides the rules of the account, checking when empty
+	Product string `json:"product" binding:"omitempty,oneof=checking savings escrow"`
 }
 
 func (server *Server) createAccount(ctx *gin.Context) {
@@ -19,16 +38,25 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
-	arg := db.CreateAccountParams{
+	if !authorizeOwner(ctx, req.Owner, permOpenAnyAccount) {
+		return
+	}
+
+	arg := bank.OpenAccountParams{
 		Owner:    req.Owner,
 		Currency: req.Currency,
-		Balance:  0,
+		Product:  req.Product,
 	}
 
-	account, err := server.bank.CreateAccount(ctx, arg)
+	if arg.Product == "" {
+		arg.Product = bank.ProductChecking
+	}
+
+	account, err := server.bank.OpenAccount(ctx, arg)
 	if err != nil {
-		errCode := db.ErrorCode(err)
-		if errCode == db.ForeignKeyViolation || errCode == db.UniqueViolation {
+		// Unknown owners are refused the same way as duplicate accounts
+		if errors.Is(err, db.ErrRecordNotFound) || errors.Is(err, bank.ErrDuplicateAccount) ||
+			errors.Is(err, bank.ErrEmailNotVerified) {
 			ctx.JSON(http.StatusForbidden, errorResponse(err))
 			return
 		}
@@ -36,5 +64,225 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		return
 	}
 
+	// A new account has no holds
+	ctx.JSON(http.StatusOK, newAccountResponse(account, 0))
+}
+
+type getAccountRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) getAccount(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, ok := server.authorizeAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	held, err := server.bank.GetHeldAmount(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAccountResponse(account, held))
+}
+
+func (server *Server) freezeAccount(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.bank.FreezeAccount(ctx, req.ID)
+	if err != nil {
+		accountErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, account)
+}
+
+func (server *Server) unfreezeAccount(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, err := server.bank.UnfreezeAccount(ctx, req.ID)
+	if err != nil {
+		accountErrorResponse(ctx, err)
+		return
+	}
+
 	ctx.JSON(http.StatusOK, account)
 }
+
+type closeAccountRequest struct {
+	// Account receiving the remaining balance, required unless the balance is zero
+	SweepToAccountID int64 `json:"sweep_to_account_id" binding:"omitempty,min=1"`
+}
+
+func (server *Server) closeAccount(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The body is optional, without it the account must be empty
+	var req closeAccountRequest
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+
+	result, err := server.bank.CloseAccount(ctx, bank.CloseAccountParams{
+		AccountID:        uri.ID,
+		SweepToAccountID: req.SweepToAccountID,
+	})
+	if err != nil {
+		accountErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// counterpartyResponse is the other account of the transfer booking an entry
+type counterpartyResponse struct {
+	AccountID int64  `json:"account_id"`
+	Owner     string `json:"owner"`
+}
+
+// accountEntryResponse is an entry in the history of an account
+type accountEntryResponse struct {
+	ID        int64     `json:"id"`
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	// Business operation booking the entry
+	JournalID   int64  `json:"journal_id"`
+	JournalKind string `json:"journal_kind"`
+	// Empty for entries not booked by a transfer
+	TransferID   *int64                `json:"transfer_id,omitempty"`
+	Counterparty *counterpartyResponse `json:"counterparty,omitempty"`
+}
+
+func newAccountEntryResponse(row db.ListAccountHistoryRow) accountEntryResponse {
+	rsp := accountEntryResponse{
+		ID:          row.ID,
+		Amount:      row.Amount,
+		CreatedAt:   row.CreatedAt,
+		JournalID:   row.JournalID,
+		JournalKind: row.JournalKind,
+	}
+
+	if row.TransferID.Valid {
+		rsp.TransferID = &row.TransferID.Int64
+	}
+
+	if row.CounterpartyAccountID.Valid {
+		rsp.Counterparty = &counterpartyResponse{
+			AccountID: row.CounterpartyAccountID.Int64,
+			Owner:     row.CounterpartyOwner.String,
+		}
+	}
+
+	return rsp
+}
+
+func (server *Server) listAccountEntries(ctx *gin.Context) {
+	var uri getAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.authorizeAccount(ctx, uri.ID); !ok {
+		return
+	}
+
+	rows, err := server.bank.ListAccountHistory(ctx, db.ListAccountHistoryParams{
+		AccountID: uri.ID,
+		Limit:     req.PageSize,
+		Offset:    (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	entries := make([]accountEntryResponse, 0, len(rows))
+	for _, row := range rows {
+		entries = append(entries, newAccountEntryResponse(row))
+	}
+
+	ctx.JSON(http.StatusOK, entries)
+}
+
+func (server *Server) getAccountLimits(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.authorizeAccount(ctx, req.ID); !ok {
+		return
+	}
+
+	usage, err := server.bank.GetTransferLimitUsage(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, usage)
+}
+
+// accountErrorResponse maps errors from the account lifecycle operations to a response.
+func accountErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, bank.ErrInvalidStatusTransition),
+		errors.Is(err, bank.ErrAccountHasHolds),
+		errors.Is(err, bank.ErrNonZeroBalance),
+		errors.Is(err, bank.ErrCurrencyMismatch),
+		errors.Is(err, bank.ErrAccountFrozen),
+		errors.Is(err, bank.ErrAccountClosed),
+		errors.Is(err, bank.ErrInsufficientFunds):
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
+
+func (server *Server) listAccountProducts(ctx *gin.Context) {
+	products, err := server.bank.ListAccountProducts(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, products)
+}
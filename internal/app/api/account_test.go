@@ -3,15 +3,19 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 )
@@ -32,15 +36,15 @@ func TestCreateAccountAPI(t *testing.T) {
 				"currency": account.Currency,
 				"owner":    account.Owner,
 			},
-			buildStubs: func(bank *mockdb.MockBank) {
-				arg := db.CreateAccountParams{
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := bank.OpenAccountParams{
 					Owner:    account.Owner,
 					Currency: account.Currency,
-					Balance:  0,
+					Product:  bank.ProductChecking,
 				}
 
-				bank.EXPECT().
-					CreateAccount(gomock.Any(), gomock.Eq(arg)).
+				store.EXPECT().
+					OpenAccount(gomock.Any(), gomock.Eq(arg)).
 					Times(1).
 					Return(account, nil)
 			},
@@ -49,6 +53,77 @@ func TestCreateAccountAPI(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			name: "create-savings-account-OK",
+			body: gin.H{
+				"currency": account.Currency,
+				"owner":    account.Owner,
+				"product":  "savings",
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := bank.OpenAccountParams{
+					Owner:    account.Owner,
+					Currency: account.Currency,
+					Product:  bank.ProductSavings,
+				}
+
+				store.EXPECT().
+					OpenAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(account, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "create-account-duplicate",
+			body: gin.H{
+				"currency": account.Currency,
+				"owner":    account.Owner,
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					OpenAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrDuplicateAccount))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "create-account-email-not-verified",
+			body: gin.H{
+				"currency": account.Currency,
+				"owner":    account.Owner,
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					OpenAccount(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrEmailNotVerified))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "create-account-internal-product",
+			body: gin.H{
+				"currency": account.Currency,
+				"owner":    account.Owner,
+				"product":  "internal",
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					OpenAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
 	}
 
 	for i := range testCases {
@@ -71,6 +146,112 @@ func TestCreateAccountAPI(t *testing.T) {
 			url := "/accounts"
 			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
 			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetAccountAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	held := account.Balance / 2
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetHeldAmount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(held, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchAccount(t, recorder.Body, account)
+			},
+		},
+		{
+			name:      "AvailableBalance",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetHeldAmount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(held, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var rsp accountResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rsp))
+				require.Equal(t, account.Balance, rsp.Balance)
+				require.Equal(t, account.Balance-held, rsp.AvailableBalance)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetHeldAmount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			accountID: 0,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d", tc.accountID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
 
 			server.router.ServeHTTP(recorder, request)
 			tc.checkResponse(recorder)
@@ -84,6 +265,7 @@ func randomAccount(owner string) db.Account {
 		Owner:    owner,
 		Balance:  random.Money(),
 		Currency: random.Currency(),
+		Product:  "checking",
 	}
 }
 
@@ -96,3 +278,351 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	require.NoError(t, err)
 	require.Equal(t, account, gotAccount)
 }
+
+func TestListAccountProductsAPI(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	products := []db.AccountProduct{
+		{Name: bank.ProductChecking},
+		{Name: bank.ProductSavings, MonthlyWithdrawals: 6, InterestRateBps: 150, MultiplePerCurrency: true},
+	}
+
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().
+		ListAccountProducts(gomock.Any()).
+		Times(1).
+		Return(products, nil)
+
+	server := newTestServer(t, store)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/account_products", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var got []db.AccountProduct
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+	require.Equal(t, products, got)
+}
+
+func TestAccountLifecycleAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	other := randomAccount(user.Username)
+
+	testCases := []struct {
+		name          string
+		url           string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "FreezeOK",
+			url:  fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			buildStubs: func(store *mockdb.MockBank) {
+				frozen := account
+				frozen.Status = bank.AccountStatusFrozen
+
+				store.EXPECT().
+					FreezeAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(frozen, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.Account
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, bank.AccountStatusFrozen, got.Status)
+			},
+		},
+		{
+			name: "FreezeNotFound",
+			url:  fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					FreezeAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "UnfreezeActive",
+			url:  fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					UnfreezeAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(db.Account{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrInvalidStatusTransition))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "CloseWithSweep",
+			url:  fmt.Sprintf("/admin/accounts/%d/close", account.ID),
+			body: gin.H{
+				"sweep_to_account_id": other.ID,
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := bank.CloseAccountParams{
+					AccountID:        account.ID,
+					SweepToAccountID: other.ID,
+				}
+
+				store.EXPECT().
+					CloseAccount(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(bank.CloseAccountResult{Account: account}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "CloseWithoutBody",
+			url:  fmt.Sprintf("/admin/accounts/%d/close", account.ID),
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CloseAccount(gomock.Any(), gomock.Eq(bank.CloseAccountParams{AccountID: account.ID})).
+					Times(1).
+					Return(bank.CloseAccountResult{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrNonZeroBalance))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			var body io.Reader
+			if tc.body != nil {
+				data, err := json.Marshal(tc.body)
+				require.NoError(t, err)
+				body = bytes.NewReader(data)
+			}
+
+			request, err := http.NewRequest(http.MethodPost, tc.url, body)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "operator", bank.RoleAdmin, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestGetAccountLimitsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	usage := bank.TransferLimitUsage{
+		AccountID: account.ID,
+		MaxAmount: 500,
+		Windows: []bank.LimitUsage{
+			{
+				Limit:     bank.LimitAccountDaily,
+				Max:       1000,
+				Used:      400,
+				Remaining: 600,
+			},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		accountID     int64
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "OK",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetTransferLimitUsage(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(usage, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got bank.TransferLimitUsage
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, usage, got)
+			},
+		},
+		{
+			name:      "NotFound",
+			accountID: account.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetTransferLimitUsage(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(bank.TransferLimitUsage{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "InvalidID",
+			accountID: 0,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetTransferLimitUsage(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(account, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/limits", tc.accountID)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListAccountEntriesAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	other := randomAccount(user.Username)
+
+	rows := []db.ListAccountHistoryRow{
+		{
+			ID:                    1,
+			Amount:                -100,
+			JournalID:             7,
+			JournalKind:           bank.JournalKindTransfer,
+			TransferID:            pgtype.Int8{Int64: 3, Valid: true},
+			CounterpartyAccountID: pgtype.Int8{Int64: other.ID, Valid: true},
+			CounterpartyOwner:     pgtype.Text{String: other.Owner, Valid: true},
+		},
+		{
+			ID:          2,
+			Amount:      100,
+			JournalID:   8,
+			JournalKind: "legacy",
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=2&page_size=5",
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := db.ListAccountHistoryParams{
+					AccountID: account.ID,
+					Limit:     5,
+					Offset:    5,
+				}
+
+				store.EXPECT().
+					ListAccountHistory(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(rows, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []accountEntryResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 2)
+
+				require.Equal(t, int64(3), *got[0].TransferID)
+				require.Equal(t, &counterpartyResponse{AccountID: other.ID, Owner: other.Owner}, got[0].Counterparty)
+
+				require.Nil(t, got[1].TransferID)
+				require.Nil(t, got[1].Counterparty)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=100",
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ListAccountHistory(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Any()).
+				AnyTimes().
+				Return(account, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/accounts/%d/entries?%s", account.ID, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
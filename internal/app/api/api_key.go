@@ -0,0 +1,238 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+)
+
+// Scopes of api keys, each opening routes to keys with the scope
+const (
+	scopeReadAccounts   = "read:accounts"
+	scopeWriteTransfers = "write:transfers"
+)
+
+const (
+	// apiKeyPrefixLength is the length of the start of a key, stored to tell keys apart
+	apiKeyPrefixLength = 8
+	// apiKeySecretLength is the length of the rest of a key
+	apiKeySecretLength = 32
+)
+
+var errInvalidAPIKey = errors.New("api key is invalid, revoked or expired")
+
+// verifyAPIKey returns the api key, unless it is unknown, revoked or expired.
+func verifyAPIKey(ctx *gin.Context, apiKeys bank.Bank, key string) (db.GetAPIKeyByHashRow, error) {
+	apiKey, err := apiKeys.GetAPIKeyByHash(ctx, password.HashToken(key))
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return apiKey, errInvalidAPIKey
+		}
+		return apiKey, err
+	}
+
+	if apiKey.RevokedAt.Valid || time.Now().After(apiKey.ExpiredAt) {
+		return apiKey, errInvalidAPIKey
+	}
+
+	return apiKey, nil
+}
+
+// newAPIKeyPayload returns the payload of requests authenticated with the api key, which grants access like
+// an access token of its user, limited to its scopes.
+func newAPIKeyPayload(apiKey db.GetAPIKeyByHashRow) *token.Payload {
+	return &token.Payload{
+		ID:        apiKey.Prefix,
+		Type:      token.TypeAccess,
+		Username:  apiKey.Username,
+		Role:      apiKey.Role,
+		IssuedAt:  apiKey.CreatedAt,
+		ExpiredAt: apiKey.ExpiredAt,
+	}
+}
+
+// requireScope aborts requests authenticated with an api key with forbidden unless the key has the scope.
+// Requests with an access token are let through. It must run after authMiddleware.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		scopes, ok := ctx.Get(authorizationScopesKey)
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		for _, s := range scopes.([]string) {
+			if s == scope {
+				ctx.Next()
+				return
+			}
+		}
+
+		err := fmt.Errorf("api key is not permitted to %s", scope)
+		ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+	}
+}
+
+// apiKeyResponse leaves out the key, it is only shown when the key is created
+type apiKeyResponse struct {
+	ID        int64      `json:"id"`
+	Username  string     `json:"username"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiredAt time.Time  `json:"expired_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	rsp := apiKeyResponse{
+		ID:        apiKey.ID,
+		Username:  apiKey.Username,
+		Name:      apiKey.Name,
+		Prefix:    apiKey.Prefix,
+		Scopes:    apiKey.Scopes,
+		ExpiredAt: apiKey.ExpiredAt,
+		CreatedAt: apiKey.CreatedAt,
+	}
+
+	if apiKey.RevokedAt.Valid {
+		rsp.RevokedAt = &apiKey.RevokedAt.Time
+	}
+
+	return rsp
+}
+
+type createAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"required,max=64"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read:accounts write:transfers"`
+	// Days until the key expires
+	ExpiresInDays int `json:"expires_in_days" binding:"required,min=1,max=365"`
+}
+
+type createAPIKeyResponse struct {
+	apiKeyResponse
+	// Sent as Authorization: ApiKey <key>, only shown this once
+	Key string `json:"key"`
+}
+
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeSelf(ctx, uri.Username) {
+		return
+	}
+
+	prefix := random.String(apiKeyPrefixLength)
+	key := prefix + "." + random.String(apiKeySecretLength)
+
+	apiKey, err := server.bank.IssueAPIKey(ctx, db.CreateAPIKeyParams{
+		Username:  uri.Username,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   password.HashToken(key),
+		Scopes:    req.Scopes,
+		ExpiredAt: time.Now().AddDate(0, 0, req.ExpiresInDays),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createAPIKeyResponse{
+		apiKeyResponse: newAPIKeyResponse(apiKey),
+		Key:            key,
+	})
+}
+
+type listAPIKeysRequest struct {
+	pageRequest
+}
+
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listAPIKeysRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeOwner(ctx, uri.Username, permManageAnyAPIKey) {
+		return
+	}
+
+	apiKeys, err := server.bank.ListAPIKeys(ctx, db.ListAPIKeysParams{
+		Username: uri.Username,
+		Limit:    req.PageSize,
+		Offset:   (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		rsp = append(rsp, newAPIKeyResponse(apiKey))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type apiKeyURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+	ID       int64  `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var uri apiKeyURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeOwner(ctx, uri.Username, permManageAnyAPIKey) {
+		return
+	}
+
+	apiKey, err := server.bank.RevokeAPIKey(ctx, bank.RevokeAPIKeyParams{
+		ID:       uri.ID,
+		Username: uri.Username,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, bank.ErrAPIKeyRevoked):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
@@ -0,0 +1,514 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func addAPIKeyAuthorization(request *http.Request, key string) {
+	request.Header.Set(authorizationHeaderKey, fmt.Sprintf("ApiKey %s", key))
+}
+
+// randomAPIKey returns a valid key of the user with the scopes, as found by its hash, and the key
+func randomAPIKey(username string, scopes ...string) (apiKey db.GetAPIKeyByHashRow, key string) {
+	prefix := random.String(apiKeyPrefixLength)
+	key = prefix + "." + random.String(apiKeySecretLength)
+
+	apiKey = db.GetAPIKeyByHashRow{
+		ID:        random.Int(1000) + 1,
+		Username:  username,
+		Prefix:    prefix,
+		Scopes:    scopes,
+		ExpiredAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+		Role:      bank.RoleCustomer,
+	}
+	return
+}
+
+func TestCreateAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	testCases := []struct {
+		name          string
+		username      string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			body:     gin.H{"name": "batch", "scopes": []string{scopeReadAccounts, scopeWriteTransfers}, "expires_in_days": 30},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, "batch", arg.Name)
+						require.Len(t, arg.Prefix, apiKeyPrefixLength)
+						require.Equal(t, []string{scopeReadAccounts, scopeWriteTransfers}, arg.Scopes)
+						require.WithinDuration(t, time.Now().AddDate(0, 0, 30), arg.ExpiredAt, time.Minute)
+						return db.ApiKey{
+							ID:        1,
+							Username:  arg.Username,
+							Name:      arg.Name,
+							Prefix:    arg.Prefix,
+							KeyHash:   arg.KeyHash,
+							Scopes:    arg.Scopes,
+							ExpiredAt: arg.ExpiredAt,
+						}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got struct {
+					Prefix  string `json:"prefix"`
+					Key     string `json:"key"`
+					KeyHash string `json:"key_hash"`
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				// The key is shown once, its hash never
+				require.True(t, strings.HasPrefix(got.Key, got.Prefix+"."))
+				require.Len(t, got.Key, apiKeyPrefixLength+1+apiKeySecretLength)
+				require.Empty(t, got.KeyHash)
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: "other",
+			body:     gin.H{"name": "batch", "scopes": []string{scopeReadAccounts}, "expires_in_days": 30},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			// Keys can not create more keys
+			name:     "APIKeyAuthorization",
+			username: user.Username,
+			body:     gin.H{"name": "batch", "scopes": []string{scopeReadAccounts}, "expires_in_days": 30},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				_, key := randomAPIKey(user.Username, scopeReadAccounts, scopeWriteTransfers)
+				addAPIKeyAuthorization(request, key)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAPIKeyByHash(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:     "UnknownScope",
+			username: user.Username,
+			body:     gin.H{"name": "batch", "scopes": []string{"write:users"}, "expires_in_days": 30},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "NoScopes",
+			username: user.Username,
+			body:     gin.H{"name": "batch", "scopes": []string{}, "expires_in_days": 30},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:     "TooLongExpiry",
+			username: user.Username,
+			body:     gin.H{"name": "batch", "scopes": []string{scopeReadAccounts}, "expires_in_days": 366},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					IssueAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/users/%s/api_keys", tc.username)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestListAPIKeysAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey := db.ApiKey{
+		ID:        1,
+		Username:  user.Username,
+		Name:      "batch",
+		Prefix:    random.String(apiKeyPrefixLength),
+		KeyHash:   password.HashToken(random.String(32)),
+		Scopes:    []string{scopeReadAccounts},
+		ExpiredAt: time.Now().Add(time.Hour),
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Eq(db.ListAPIKeysParams{Username: user.Username, Limit: 5, Offset: 0})).
+					Times(1).
+					Return([]db.ApiKey{apiKey}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.NotContains(t, recorder.Body.String(), apiKey.KeyHash)
+
+				var got []apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 1)
+				require.Equal(t, apiKey.Prefix, got[0].Prefix)
+				require.Nil(t, got[0].RevokedAt)
+			},
+		},
+		{
+			name:  "InvalidPageSize",
+			query: "page_id=1&page_size=100",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			query:     "page_id=1&page_size=5",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ListAPIKeys(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/%s/api_keys?%s", user.Username, tc.query)
+			request, err := http.NewRequest(http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRevokeAPIKeyAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	apiKey := db.ApiKey{
+		ID:        1,
+		Username:  user.Username,
+		Name:      "batch",
+		Prefix:    random.String(apiKeyPrefixLength),
+		Scopes:    []string{scopeReadAccounts},
+		ExpiredAt: time.Now().Add(time.Hour),
+		RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	}
+
+	testCases := []struct {
+		name          string
+		username      string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Eq(bank.RevokeAPIKeyParams{ID: apiKey.ID, Username: user.Username})).
+					Times(1).
+					Return(apiKey, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got apiKeyResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.NotNil(t, got.RevokedAt)
+			},
+		},
+		{
+			name:     "OtherUser",
+			username: "other",
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:     "AlreadyRevoked",
+			username: user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RevokeAPIKey(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ApiKey{}, bank.ErrAPIKeyRevoked)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/%s/api_keys/%d", tc.username, apiKey.ID)
+			request, err := http.NewRequest(http.MethodDelete, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+// TestAPIKeyRoutes checks that api keys reach the routes of their scopes only
+func TestAPIKeyRoutes(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	testCases := []struct {
+		name       string
+		method     string
+		url        string
+		scopes     []string
+		buildStubs func(store *mockdb.MockBank)
+		status     int
+	}{
+		{
+			name:   "GetAccount",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			scopes: []string{scopeReadAccounts},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(account, nil)
+				store.EXPECT().GetHeldAmount(gomock.Any(), gomock.Eq(account.ID)).Times(1).Return(int64(0), nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name:   "GetAccountWithoutScope",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/accounts/%d", account.ID),
+			scopes: []string{scopeWriteTransfers},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name:   "CreateTransferWithoutScope",
+			method: http.MethodPost,
+			url:    "/transfers",
+			scopes: []string{scopeReadAccounts},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().Transfer(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			// Routes without a scope are refused to keys
+			name:   "CreateAccount",
+			method: http.MethodPost,
+			url:    "/accounts",
+			scopes: []string{scopeReadAccounts, scopeWriteTransfers},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().OpenAccount(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "AdminRoute",
+			method: http.MethodGet,
+			url:    "/admin/jobs?page_id=1&page_size=5",
+			scopes: []string{scopeReadAccounts, scopeWriteTransfers},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().ListJobs(gomock.Any(), gomock.Any()).Times(0)
+			},
+			status: http.StatusUnauthorized,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			apiKey, key := randomAPIKey(user.Username, tc.scopes...)
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Eq(password.HashToken(key))).AnyTimes().Return(apiKey, nil)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader([]byte("{}")))
+			require.NoError(t, err)
+
+			addAPIKeyAuthorization(request, key)
+			server.router.ServeHTTP(recorder, request)
+			require.Equal(t, tc.status, recorder.Code, recorder.Body.String())
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/audit"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// requestIDHeader carries the ID of a request, given by the client or made up by the server
+const requestIDHeader = "X-Request-ID"
+
+// auditActor tells the bank who makes the changes of a request, recorded in the audit log.
+func auditActor() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestID := ctx.GetHeader(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+
+		ctx.Header(requestIDHeader, requestID)
+
+		actor := audit.Actor{
+			Name:      audit.Anonymous,
+			IP:        ctx.ClientIP(),
+			RequestID: requestID,
+		}
+		ctx.Request = ctx.Request.WithContext(audit.WithActor(ctx.Request.Context(), actor))
+
+		ctx.Next()
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	// The request ID is only for tracing, a failure leaves it empty
+	_, _ = rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+type auditEventResponse struct {
+	ID         int64           `json:"id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Actor      string          `json:"actor"`
+	IP         string          `json:"ip"`
+	RequestID  string          `json:"request_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+func newAuditEventResponse(event db.AuditEvent) auditEventResponse {
+	return auditEventResponse{
+		ID:         event.ID,
+		OccurredAt: event.OccurredAt,
+		Actor:      event.Actor,
+		IP:         event.Ip,
+		RequestID:  event.RequestID,
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Before:     event.Before,
+		After:      event.After,
+		PrevHash:   event.PrevHash,
+		Hash:       event.Hash,
+	}
+}
+
+type listAuditEventsRequest struct {
+	EntityType string `form:"entity_type"`
+	EntityID   string `form:"entity_id"`
+	Actor      string `form:"actor"`
+	pageRequest
+}
+
+func (server *Server) listAuditEvents(ctx *gin.Context) {
+	var req listAuditEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	events, err := server.bank.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		EntityType: optionalText(req.EntityType),
+		EntityID:   optionalText(req.EntityID),
+		Actor:      optionalText(req.Actor),
+		Limit:      req.PageSize,
+		Offset:     (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]auditEventResponse, 0, len(events))
+	for _, event := range events {
+		rsp = append(rsp, newAuditEventResponse(event))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+// optionalText leaves out an empty filter
+func optionalText(s string) pgtype.Text {
+	return pgtype.Text{String: s, Valid: s != ""}
+}
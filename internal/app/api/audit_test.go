@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestListAuditEventsAPI(t *testing.T) {
+	events := []db.AuditEvent{
+		{
+			ID:         2,
+			OccurredAt: time.Now().UTC(),
+			Actor:      "anonymous",
+			Action:     "account.freeze",
+			EntityType: "account",
+			EntityID:   "1",
+			Before:     []byte(`{"status":"active"}`),
+			After:      []byte(`{"status":"frozen"}`),
+			PrevHash:   "a",
+			Hash:       "b",
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "entity_type=account&entity_id=1&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := db.ListAuditEventsParams{
+					EntityType: pgtype.Text{String: "account", Valid: true},
+					EntityID:   pgtype.Text{String: "1", Valid: true},
+					Limit:      5,
+					Offset:     0,
+				}
+
+				store.EXPECT().
+					ListAuditEvents(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(events, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []auditEventResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 1)
+				require.Equal(t, "account.freeze", got[0].Action)
+				require.JSONEq(t, `{"status":"frozen"}`, string(got[0].After))
+			},
+		},
+		{
+			name:  "InvalidPageID",
+			query: "page_id=0&page_size=5",
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ListAuditEvents(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/audit_events?"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "operator", bank.RoleAdmin, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+
+			// Every response tells the ID of its request
+			require.NotEmpty(t, recorder.Header().Get(requestIDHeader))
+		})
+	}
+}
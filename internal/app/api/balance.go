@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
+)
+
+const (
+	// lastEventIDHeader is sent by reconnecting event stream clients
+	lastEventIDHeader = "Last-Event-ID"
+	// Comments keep idle event streams open through proxies
+	keepAliveInterval = 15 * time.Second
+	// Clients wait before reconnecting, in milliseconds
+	reconnectDelay = 3000
+)
+
+// streamAccountEvents streams the balance of an account as server-sent events.
+// The ID of an event is the latest entry of the account. A client reconnecting with Last-Event-ID gets the
+// current balance unless it is already up to date. Clients falling behind are disconnected, and catch up by
+// reconnecting.
+func (server *Server) streamAccountEvents(ctx *gin.Context) {
+	var req getAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	account, ok := server.authorizeAccount(ctx, req.ID)
+	if !ok {
+		return
+	}
+
+	// Subscribe before reading the current balance, so no change in between is missed
+	subscription := server.balances.Subscribe(account.ID)
+	defer subscription.Close()
+
+	current, err := server.bank.GetAccountBalanceEvent(ctx, account.ID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+
+	fmt.Fprintf(ctx.Writer, "retry: %d\n\n", reconnectDelay)
+
+	last := balance.Event{
+		AccountID: current.ID,
+		Balance:   current.Balance,
+		Currency:  current.Currency,
+		EntryID:   current.EntryID,
+	}
+
+	if ctx.GetHeader(lastEventIDHeader) != strconv.FormatInt(last.EntryID, 10) {
+		if err := writeBalanceEvent(ctx, last); err != nil {
+			return
+		}
+	}
+
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(ctx.Writer, ": keep-alive\n\n")
+		case event, ok := <-subscription.Events():
+			if !ok {
+				// Dropped for falling behind
+				return
+			}
+
+			// Notifications of one change can arrive after the change was sent
+			if event == last {
+				continue
+			}
+
+			if err := writeBalanceEvent(ctx, event); err != nil {
+				return
+			}
+			last = event
+		}
+
+		ctx.Writer.Flush()
+	}
+}
+
+func writeBalanceEvent(ctx *gin.Context, event balance.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(ctx.Writer, "id: %d\nevent: balance\ndata: %s\n\n", event.EntryID, data)
+	return err
+}
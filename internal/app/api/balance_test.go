@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestStreamAccountEventsAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	account.ID++
+
+	before := db.GetAccountBalanceEventRow{ID: account.ID, Owner: account.Owner, Balance: 100, Currency: account.Currency, EntryID: 7}
+	after := db.GetAccountBalanceEventRow{ID: account.ID, Owner: account.Owner, Balance: 150, Currency: account.Currency, EntryID: 8}
+
+	testCases := []struct {
+		name          string
+		username      string
+		lastEventID   string
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recorder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				gomock.InOrder(
+					store.EXPECT().GetAccountBalanceEvent(gomock.Any(), gomock.Eq(account.ID)).Return(before, nil),
+					store.EXPECT().GetAccountBalanceEvent(gomock.Any(), gomock.Eq(account.ID)).Return(after, nil),
+				)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				require.Equal(t, "text/event-stream", recorder.Header().Get("Content-Type"))
+				require.Contains(t, recorder.Body.String(), "id: 7\nevent: balance\n")
+				require.Contains(t, recorder.Body.String(), "id: 8\nevent: balance\n")
+			},
+		},
+		{
+			name:        "Reconnect",
+			username:    user.Username,
+			lastEventID: "7",
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				gomock.InOrder(
+					store.EXPECT().GetAccountBalanceEvent(gomock.Any(), gomock.Eq(account.ID)).Return(before, nil),
+					store.EXPECT().GetAccountBalanceEvent(gomock.Any(), gomock.Eq(account.ID)).Return(after, nil),
+				)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				// Up to date with the current balance
+				require.NotContains(t, recorder.Body.String(), "id: 7\n")
+				require.Contains(t, recorder.Body.String(), "id: 8\n")
+			},
+		},
+		{
+			name:     "UnauthorizedUser",
+			username: "unauthorized",
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				store.EXPECT().GetAccountBalanceEvent(gomock.Any(), gomock.Any()).Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:     "NotFound",
+			username: user.Username,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(db.Account{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			url := fmt.Sprintf("/accounts/%d/events", account.ID)
+			request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, tc.username, bank.RoleCustomer, time.Minute)
+			if tc.lastEventID != "" {
+				request.Header.Set(lastEventIDHeader, tc.lastEventID)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				server.router.ServeHTTP(recorder, request)
+			}()
+
+			// Publish a change once subscribed, then disconnect
+			for server.balances.Subscribers(account.ID) == 0 {
+				select {
+				case <-done:
+					tc.checkResponse(recorder)
+					return
+				case <-time.After(time.Millisecond):
+				}
+			}
+
+			require.NoError(t, server.balances.Publish(ctx, account.ID))
+
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			<-done
+
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+type holdURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type placeHoldRequest struct {
+	AccountID int64 `json:"account_id" binding:"required,min=1"`
+	Amount    int64 `json:"amount" binding:"required,gt=0"`
+	// Seconds until the hold expires, zero or omitted uses the configured hold duration, which is also the
+	// longest a hold may last
+	ExpiresInSeconds int64 `json:"expires_in_seconds" binding:"min=0"`
+	// Code of the TOTP of the user, required for amounts above the TOTP threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+func (server *Server) placeHold(ctx *gin.Context) {
+	var req placeHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Money held for long is as good as frozen, holds never last longer than configured
+	if maxSeconds := int64(server.config.HoldDuration / time.Second); req.ExpiresInSeconds > maxSeconds {
+		err := fmt.Errorf("expires_in_seconds must be at most %d", maxSeconds)
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// Holds reserve money of the account, no role may place them on accounts of other users
+	account, ok := server.getTransferAccount(ctx, req.AccountID)
+	if !ok {
+		return
+	}
+
+	if !authorizeSelf(ctx, account.Owner) {
+		return
+	}
+
+	// The held money is as good as moved, holds are confirmed like transfers
+	if !server.checkTransferTOTP(ctx, account.Owner, req.Amount, req.TOTPCode) {
+		return
+	}
+
+	expiresIn := server.config.HoldDuration
+	if req.ExpiresInSeconds > 0 {
+		expiresIn = time.Duration(req.ExpiresInSeconds) * time.Second
+	}
+
+	arg := bank.PlaceHoldParams{
+		AccountID: req.AccountID,
+		Amount:    req.Amount,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}
+
+	hold, err := server.bank.PlaceHold(ctx, arg)
+	if err != nil {
+		holdErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hold)
+}
+
+type captureHoldRequest struct {
+	ToAccountID int64 `json:"to_account_id" binding:"required,min=1"`
+	// Amount to capture, zero or omitted captures the full amount of the hold
+	Amount int64 `json:"amount" binding:"min=0"`
+	// Code of the TOTP of the user, required for amounts above the TOTP threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+func (server *Server) captureHold(ctx *gin.Context) {
+	var uri holdURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req captureHoldRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hold, account, ok := server.authorizeHold(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	amount := req.Amount
+	if amount == 0 {
+		amount = hold.Amount
+	}
+
+	if !server.checkTransferTOTP(ctx, account.Owner, amount, req.TOTPCode) {
+		return
+	}
+
+	arg := bank.CaptureHoldParams{
+		HoldID:      uri.ID,
+		ToAccountID: req.ToAccountID,
+		Amount:      req.Amount,
+	}
+
+	result, err := server.bank.CaptureHold(ctx, arg)
+	if err != nil {
+		holdErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (server *Server) releaseHold(ctx *gin.Context) {
+	var uri holdURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, _, ok := server.authorizeHold(ctx, uri.ID); !ok {
+		return
+	}
+
+	hold, err := server.bank.ReleaseHold(ctx, uri.ID)
+	if err != nil {
+		holdErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, hold)
+}
+
+// authorizeHold returns a hold and its account, responding with not found or forbidden unless the hold is on
+// an account of the authenticated user. Capturing moves the held money, no role may capture or release holds
+// of other users.
+func (server *Server) authorizeHold(ctx *gin.Context, holdID int64) (db.Hold, db.Account, bool) {
+	hold, err := server.bank.GetHold(ctx, holdID)
+	if err != nil {
+		holdErrorResponse(ctx, err)
+		return hold, db.Account{}, false
+	}
+
+	account, ok := server.getTransferAccount(ctx, hold.AccountID)
+	if !ok {
+		return hold, account, false
+	}
+
+	return hold, account, authorizeSelf(ctx, account.Owner)
+}
+
+// holdErrorResponse maps errors from the hold operations to a response.
+func holdErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, bank.ErrInsufficientFunds),
+		errors.Is(err, bank.ErrHoldNotActive),
+		errors.Is(err, bank.ErrHoldExpired),
+		errors.Is(err, bank.ErrCaptureExceedsHold),
+		errors.Is(err, bank.ErrCurrencyMismatch),
+		errors.Is(err, bank.ErrAccountFrozen),
+		errors.Is(err, bank.ErrAccountClosed),
+		errors.Is(err, bank.ErrWithdrawalLimit),
+		db.ErrorCode(err) == db.ForeignKeyViolation:
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	case errors.Is(err, bank.ErrLimitExceeded):
+		limitErrorResponse(ctx, err)
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
@@ -0,0 +1,468 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHoldAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+	merchant := randomAccount(user.Username)
+	hold := randomHold(account)
+
+	other, _ := randomUser(t)
+
+	getHold := func(store *mockdb.MockBank) {
+		store.EXPECT().
+			GetHold(gomock.Any(), gomock.Eq(hold.ID)).
+			Times(1).
+			Return(hold, nil)
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+			Times(1).
+			Return(account, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "PlaceOK",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id":         account.ID,
+				"amount":             hold.Amount,
+				"expires_in_seconds": 60,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ any, arg bank.PlaceHoldParams) (db.Hold, error) {
+						require.Equal(t, account.ID, arg.AccountID)
+						require.Equal(t, hold.Amount, arg.Amount)
+						require.WithinDuration(t, time.Now().Add(time.Minute), arg.ExpiresAt, time.Second)
+						return hold, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "PlaceInsufficientFunds",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id": account.ID,
+				"amount":     hold.Amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.Hold{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrInsufficientFunds))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "PlaceInvalidAmount",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id": account.ID,
+				"amount":     -1,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "PlaceAboveThresholdMissingTOTPCode",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id": account.ID,
+				"amount":     1001,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// Holds never last longer than the hold duration of the test server
+			name:   "PlaceExpiresTooLate",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id":         account.ID,
+				"amount":             hold.Amount,
+				"expires_in_seconds": int64(time.Hour/time.Second) + 1,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureOK",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body: gin.H{
+				"to_account_id": merchant.ID,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				arg := bank.CaptureHoldParams{
+					HoldID:      hold.ID,
+					ToAccountID: merchant.ID,
+				}
+
+				store.EXPECT().
+					CaptureHold(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(bank.CaptureHoldResult{Hold: hold}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureAboveThresholdMissingTOTPCode",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body: gin.H{
+				"to_account_id": merchant.ID,
+				"amount":        1001,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().
+					CaptureHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureExpired",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body: gin.H{
+				"to_account_id": merchant.ID,
+				"amount":        hold.Amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				store.EXPECT().
+					CaptureHold(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.CaptureHoldResult{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrHoldExpired))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureLimitExceeded",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body: gin.H{
+				"to_account_id": merchant.ID,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				store.EXPECT().
+					CaptureHold(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.CaptureHoldResult{}, fmt.Errorf("exec tx:transaction err: %w", &bank.LimitError{Limit: bank.LimitAccountDaily, Max: 1000, Remaining: 250}))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+				var got bank.LimitError
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, bank.LimitError{Limit: bank.LimitAccountDaily, Max: 1000, Remaining: 250}, got)
+			},
+		},
+		{
+			name:   "ReleaseOK",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/release", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				store.EXPECT().
+					ReleaseHold(gomock.Any(), gomock.Eq(hold.ID)).
+					Times(1).
+					Return(hold, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ReleaseNotFound",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/release", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetHold(gomock.Any(), gomock.Eq(hold.ID)).
+					Times(1).
+					Return(db.Hold{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					ReleaseHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "PlaceNoAuthorization",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id": account.ID,
+				"amount":     hold.Amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "PlaceOtherUsersAccount",
+			method: http.MethodPost,
+			url:    "/holds",
+			body: gin.H{
+				"account_id": account.ID,
+				"amount":     hold.Amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+					Times(1).
+					Return(account, nil)
+				store.EXPECT().
+					PlaceHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureNoAuthorization",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body: gin.H{
+				"to_account_id": merchant.ID,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetHold(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					CaptureHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "CaptureOtherUsersHold",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/capture", hold.ID),
+			body: gin.H{
+				"to_account_id": merchant.ID,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				store.EXPECT().
+					CaptureHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "ReleaseNoAuthorization",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/holds/%d/release", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetHold(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ReleaseHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "ReleaseOtherUsersHold",
+			method: http.MethodPost,
+			url:    fmt.Sprintf("/holds/%d/release", hold.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getHold(store)
+				store.EXPECT().
+					ReleaseHold(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomHold(account db.Account) db.Hold {
+	return db.Hold{
+		ID:        random.Int(1000) + 1,
+		AccountID: account.ID,
+		Amount:    random.Int(1000) + 1,
+		Status:    bank.HoldStatusActive,
+		ExpiresAt: time.Now().Add(time.Hour).UTC(),
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+type jobResponse struct {
+	ID          int64      `json:"id"`
+	Kind        string     `json:"kind"`
+	Priority    int32      `json:"priority"`
+	Status      string     `json:"status"`
+	UniqueKey   string     `json:"unique_key,omitempty"`
+	Attempts    int32      `json:"attempts"`
+	MaxAttempts int32      `json:"max_attempts"`
+	RunAt       time.Time  `json:"run_at"`
+	LastError   string     `json:"last_error,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// newJobResponse leaves out the payload, it may hold secrets such as verification codes
+func newJobResponse(job db.Job) jobResponse {
+	rsp := jobResponse{
+		ID:          job.ID,
+		Kind:        job.Kind,
+		Priority:    job.Priority,
+		Status:      job.Status,
+		UniqueKey:   job.UniqueKey.String,
+		Attempts:    job.Attempts,
+		MaxAttempts: job.MaxAttempts,
+		RunAt:       job.RunAt,
+		LastError:   job.LastError,
+		CreatedAt:   job.CreatedAt,
+	}
+
+	if job.FinishedAt.Valid {
+		rsp.FinishedAt = &job.FinishedAt.Time
+	}
+
+	return rsp
+}
+
+type listJobsRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	Kind   string `form:"kind"`
+	pageRequest
+}
+
+func (server *Server) listJobs(ctx *gin.Context) {
+	var req listJobsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	jobs, err := server.bank.ListJobs(ctx, db.ListJobsParams{
+		Status: optionalText(req.Status),
+		Kind:   optionalText(req.Kind),
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]jobResponse, 0, len(jobs))
+	for _, job := range jobs {
+		rsp = append(rsp, newJobResponse(job))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type requeueJobRequest struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+// requeueJob runs a dead job again from its first attempt
+func (server *Server) requeueJob(ctx *gin.Context) {
+	var req requeueJobRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	job, err := server.bank.RequeueJob(ctx, req.ID)
+	if err != nil {
+		if !errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		// Tell a job that is not dead from a missing job
+		job, err = server.bank.GetJob(ctx, req.ID)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusForbidden, errorResponse(fmt.Errorf("job is %s: %w", job.Status, bank.ErrInvalidStatusTransition)))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newJobResponse(job))
+}
@@ -0,0 +1,210 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomJob(status string) db.Job {
+	return db.Job{
+		ID:          7,
+		Kind:        "email:verify",
+		Payload:     []byte(`{"verify_email_id":1}`),
+		Status:      status,
+		Attempts:    6,
+		MaxAttempts: 6,
+		RunAt:       time.Now().UTC(),
+		LastError:   "mailbox unavailable",
+		FinishedAt:  pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+		CreatedAt:   time.Now().UTC(),
+	}
+}
+
+func TestListJobsAPI(t *testing.T) {
+	dead := randomJob(bank.JobDead)
+
+	testCases := []struct {
+		name          string
+		query         string
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			query: "status=dead&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := db.ListJobsParams{
+					Status: pgtype.Text{String: bank.JobDead, Valid: true},
+					Limit:  5,
+					Offset: 0,
+				}
+
+				store.EXPECT().
+					ListJobs(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.Job{dead}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got []map[string]any
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got, 1)
+				require.Equal(t, bank.JobDead, got[0]["status"])
+				require.Equal(t, dead.LastError, got[0]["last_error"])
+				require.NotContains(t, got[0], "payload")
+			},
+		},
+		{
+			name:  "InvalidStatus",
+			query: "status=running&page_id=1&page_size=5",
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ListJobs(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			request, err := http.NewRequest(http.MethodGet, "/admin/jobs?"+tc.query, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "operator", bank.RoleAdmin, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestRequeueJobAPI(t *testing.T) {
+	dead := randomJob(bank.JobDead)
+
+	requeued := dead
+	requeued.Status = bank.JobPending
+	requeued.Attempts = 0
+	requeued.LastError = ""
+	requeued.FinishedAt = pgtype.Timestamptz{}
+
+	testCases := []struct {
+		name          string
+		jobID         int64
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:  "OK",
+			jobID: dead.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequeueJob(gomock.Any(), gomock.Eq(dead.ID)).
+					Times(1).
+					Return(requeued, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got jobResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, bank.JobPending, got.Status)
+				require.Zero(t, got.Attempts)
+				require.Nil(t, got.FinishedAt)
+			},
+		},
+		{
+			name:  "NotDead",
+			jobID: dead.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequeueJob(gomock.Any(), gomock.Eq(dead.ID)).
+					Times(1).
+					Return(db.Job{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetJob(gomock.Any(), gomock.Eq(dead.ID)).
+					Times(1).
+					Return(requeued, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:  "NotFound",
+			jobID: dead.ID,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequeueJob(gomock.Any(), gomock.Eq(dead.ID)).
+					Times(1).
+					Return(db.Job{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					GetJob(gomock.Any(), gomock.Eq(dead.ID)).
+					Times(1).
+					Return(db.Job{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:  "InvalidID",
+			jobID: 0,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequeueJob(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/admin/jobs/%d/requeue", tc.jobID)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "operator", bank.RoleAdmin, time.Minute)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/mail"
+)
+
+// loginPolicy returns the throttling of failed logins from config.
+func (server *Server) loginPolicy() bank.LoginPolicy {
+	return bank.LoginPolicy{
+		Window:         server.config.LoginFailureWindow,
+		DelayAt:        server.config.LoginDelayThreshold,
+		Delay:          server.config.LoginDelay,
+		MaxDelay:       server.config.LoginMaxDelay,
+		LockAt:         server.config.LoginLockoutThreshold,
+		LockAtClientIP: server.config.LoginLockoutThresholdPerIP,
+		LockDuration:   server.config.LoginLockoutDuration,
+	}
+}
+
+// checkLogin responds with too many requests, telling when to retry, unless failed logins of the username
+// or the client IP allow a login now.
+func (server *Server) checkLogin(ctx *gin.Context, username string) bool {
+	err := server.bank.CheckLogin(ctx, bank.CheckLoginParams{
+		Username: username,
+		ClientIP: ctx.ClientIP(),
+		Policy:   server.loginPolicy(),
+	})
+	if err == nil {
+		return true
+	}
+
+	var throttled *bank.LoginThrottledError
+	if errors.As(err, &throttled) {
+		retryAfter := int64(math.Ceil(throttled.RetryAfter.Seconds()))
+		ctx.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+		ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+		return false
+	}
+
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	return false
+}
+
+// recordLoginFailure counts a failed login of the username from the client IP, telling a user by email
+// once locked out.
+func (server *Server) recordLoginFailure(ctx *gin.Context, username string) error {
+	return server.bank.RecordLoginFailure(ctx, bank.RecordLoginFailureParams{
+		Username: username,
+		ClientIP: ctx.ClientIP(),
+		Policy:   server.loginPolicy(),
+		// The email is sent by a job, which only runs once the lockout is committed
+		AfterLock: func(ctx context.Context, q db.Querier, user db.User, lockedUntil time.Time) error {
+			_, err := mail.AccountLockedTask.Enqueue(ctx, q, mail.AccountLockedPayload{
+				Username:    user.Username,
+				LockedUntil: lockedUntil,
+			}, jobs.EnqueueOptions{})
+			return err
+		},
+	})
+}
+
+// loginFailureResponse responds to a failed login with the status, once the failure is counted.
+func (server *Server) loginFailureResponse(ctx *gin.Context, username string, status int, err error) {
+	if err := server.recordLoginFailure(ctx, username); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(status, errorResponse(err))
+}
+
+type loginThrottleResponse struct {
+	Scope        string     `json:"scope"`
+	Key          string     `json:"key"`
+	Failures     int32      `json:"failures"`
+	LastFailedAt time.Time  `json:"last_failed_at"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+}
+
+func newLoginThrottleResponse(throttle db.LoginThrottle) loginThrottleResponse {
+	rsp := loginThrottleResponse{
+		Scope:        throttle.Scope,
+		Key:          throttle.Key,
+		Failures:     throttle.Failures,
+		LastFailedAt: throttle.LastFailedAt,
+	}
+
+	if throttle.LockedUntil.Valid {
+		rsp.LockedUntil = &throttle.LockedUntil.Time
+	}
+
+	return rsp
+}
+
+type listLoginThrottlesRequest struct {
+	// Only usernames and client IPs locked out now
+	Locked bool `form:"locked"`
+	pageRequest
+}
+
+func (server *Server) listLoginThrottles(ctx *gin.Context) {
+	var req listLoginThrottlesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	throttles, err := server.bank.ListLoginThrottles(ctx, db.ListLoginThrottlesParams{
+		Locked: req.Locked,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]loginThrottleResponse, 0, len(throttles))
+	for _, throttle := range throttles {
+		rsp = append(rsp, newLoginThrottleResponse(throttle))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+func (server *Server) unlockUser(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.bank.UnlockUser(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
@@ -3,22 +3,70 @@ package api
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
 )
 
 func newTestServer(t *testing.T, bank bank.Bank) *Server {
-	config := util.Config{}
+	if store, ok := bank.(*mockdb.MockBank); ok {
+		acceptAccessTokens(store)
+	}
 
-	server, err := NewServer(config, bank)
+	server, err := NewServer(newTestConfig(), bank, balance.NewHub(bank, 2))
 	require.NoError(t, err)
 
 	return server
 }
 
+// acceptAccessTokens accepts the access tokens of any user, as if no password or role ever changed. Expectations
+// on the store set before take precedence.
+func acceptAccessTokens(store *mockdb.MockBank) {
+	store.EXPECT().
+		GetUserAuthChangedAt(gomock.Any(), gomock.Any()).
+		AnyTimes().
+		Return(time.Time{}, nil)
+}
+
+// newTestConfig returns the config of test servers, without rate limits
+func newTestConfig() util.Config {
+	// Passwords are hashed like password.HashPassword does
+	hashParams := password.DefaultParams()
+
+	return util.Config{
+		TokenSymmetricKey:         random.String(32),
+		AccessTokenDuration:       time.Minute,
+		RefreshTokenDuration:      time.Hour,
+		TOTPEncryptionKey:         random.String(32),
+		TOTPIssuer:                "Bank",
+		MFATokenDuration:          time.Minute,
+		HoldDuration:              time.Hour,
+		TransferTOTPThreshold:     1000,
+		LoginFailureWindow:        time.Hour,
+		LoginDelayThreshold:       3,
+		LoginDelay:                time.Second,
+		LoginMaxDelay:             30 * time.Second,
+		LoginLockoutThreshold:     10,
+		LoginLockoutDuration:      15 * time.Minute,
+		PasswordHashAlgorithm:     hashParams.Algorithm,
+		PasswordArgon2Memory:      hashParams.Argon2Memory,
+		PasswordArgon2Iterations:  hashParams.Argon2Iterations,
+		PasswordArgon2Parallelism: hashParams.Argon2Parallelism,
+		PasswordBcryptCost:        hashParams.BcryptCost,
+		PasswordMinLength:         8,
+		PasswordMaxLength:         64,
+		PasswordMinScore:          password.ScoreSomewhatGuessable,
+	}
+}
+
 func TestMain(m *testing.M) {
 	gin.SetMode(gin.TestMode)
 
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/audit"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+)
+
+const (
+	authorizationHeaderKey  = "authorization"
+	authorizationTypeBearer = "bearer"
+	authorizationTypeAPIKey = "apikey"
+	authorizationPayloadKey = "authorization_payload"
+	// Scopes of the api key of a request authenticated with a key
+	authorizationScopesKey = "authorization_scopes"
+	// Subject of the client certificate of a request of an internal caller
+	authorizationClientKey = "authorization_client"
+	// Payload of an internal caller of the configured subjects, authenticated by its client certificate
+	authorizationInternalKey = "authorization_internal"
+	// Prefix of the subjects of internal callers as actors in the audit log, e.g. internal:payments
+	internalActorPrefix = "internal:"
+)
+
+// errTokenRevoked refuses access tokens of deleted users, or issued before the password or the role changed
+var errTokenRevoked = errors.New("access token was revoked, log in again")
+
+// internalCallers are the internal callers authenticated by the subject of their client certificate, all
+// with the permissions of the same role
+type internalCallers struct {
+	subjects map[string]bool
+	role     string
+}
+
+// newInternalCallers creates the internal callers of config, nil when there are none. The subjects must be
+// verified against a client CA, and the role must be known.
+func newInternalCallers(config util.Config) (*internalCallers, error) {
+	subjects := make(map[string]bool)
+	for _, subject := range strings.Split(config.TLSInternalSubjects, ",") {
+		if subject = strings.TrimSpace(subject); subject != "" {
+			subjects[subject] = true
+		}
+	}
+
+	if len(subjects) == 0 {
+		return nil, nil
+	}
+
+	if config.TLSClientCAFile == "" {
+		return nil, errors.New("internal subjects require a client CA")
+	}
+
+	if _, ok := rolePermissions[config.TLSInternalRole]; !ok {
+		return nil, fmt.Errorf("unknown internal role %q", config.TLSInternalRole)
+	}
+
+	return &internalCallers{subjects: subjects, role: config.TLSInternalRole}, nil
+}
+
+// clientCertMiddleware identifies internal callers by the subject of their client certificate, verified
+// against the client CA on mutual TLS, and makes the caller the actor of the request in the audit log,
+// until a user authenticates. Requests without a verified certificate are left anonymous. Callers of the
+// subjects of internal are authenticated by authMiddleware unless the request has an authorization header.
+func clientCertMiddleware(internal *internalCallers) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		subject, ok := clientCertSubject(ctx.Request)
+		if ok {
+			ctx.Set(authorizationClientKey, subject)
+
+			actor := audit.ActorFrom(ctx.Request.Context())
+			actor.Name = internalActorPrefix + subject
+			ctx.Request = ctx.Request.WithContext(audit.WithActor(ctx.Request.Context(), actor))
+
+			if internal != nil && internal.subjects[subject] {
+				cert := ctx.Request.TLS.VerifiedChains[0][0]
+				ctx.Set(authorizationInternalKey, &token.Payload{
+					ID:        internalActorPrefix + subject,
+					Type:      token.TypeAccess,
+					Username:  internalActorPrefix + subject,
+					Role:      internal.role,
+					IssuedAt:  cert.NotBefore,
+					ExpiredAt: cert.NotAfter,
+				})
+			}
+		}
+
+		ctx.Next()
+	}
+}
+
+// clientCertSubject returns the common name of the subject of the verified client certificate of the request.
+func clientCertSubject(request *http.Request) (string, bool) {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 {
+		return "", false
+	}
+
+	subject := request.TLS.VerifiedChains[0][0].Subject.CommonName
+
+	return subject, subject != ""
+}
+
+// authMiddleware requires a valid bearer access token, or with apiKeys a valid api key, and makes the user
+// of the token or key the actor of the request in the audit log. Access tokens issued before the password
+// or the role of the user last changed are refused. Routes open to api keys must require a scope of the
+// key with requireScope. Requests of internal callers without an authorization header are authenticated by
+// their client certificate, with the role of the internal callers. They are never the owner of a resource.
+func authMiddleware(tokenMaker token.Maker, b bank.Bank, apiKeys bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
+		if len(authorizationHeader) == 0 {
+			if internal, ok := ctx.Get(authorizationInternalKey); ok {
+				ctx.Set(authorizationPayloadKey, internal)
+				ctx.Next()
+				return
+			}
+
+			err := errors.New("authorization header is not provided")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		fields := strings.Fields(authorizationHeader)
+		if len(fields) < 2 {
+			err := errors.New("invalid authorization header format")
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		var payload *token.Payload
+
+		authorizationType := strings.ToLower(fields[0])
+		switch {
+		case authorizationType == authorizationTypeBearer:
+			var err error
+			payload, err = tokenMaker.VerifyToken(fields[1], token.TypeAccess)
+			if err != nil {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+				return
+			}
+
+			// Tokens are not stored, a token outliving a change of the password or the role is refused here
+			changedAt, err := b.GetUserAuthChangedAt(ctx, payload.Username)
+			if err != nil {
+				if errors.Is(err, db.ErrRecordNotFound) {
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errTokenRevoked))
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+			if payload.IssuedAt.Before(changedAt) {
+				ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(errTokenRevoked))
+				return
+			}
+		case authorizationType == authorizationTypeAPIKey && apiKeys:
+			key, err := verifyAPIKey(ctx, b, fields[1])
+			if err != nil {
+				if errors.Is(err, errInvalidAPIKey) {
+					ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+					return
+				}
+				ctx.AbortWithStatusJSON(http.StatusInternalServerError, errorResponse(err))
+				return
+			}
+
+			ctx.Set(authorizationScopesKey, key.Scopes)
+			payload = newAPIKeyPayload(key)
+		default:
+			err := fmt.Errorf("unsupported authorization type %s", authorizationType)
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+
+		ctx.Set(authorizationPayloadKey, payload)
+
+		actor := audit.ActorFrom(ctx.Request.Context())
+		actor.Name = payload.Username
+		ctx.Request = ctx.Request.WithContext(audit.WithActor(ctx.Request.Context(), actor))
+
+		ctx.Next()
+	}
+}
+
+// authPayload returns the payload of the access token, or the api key, of an authenticated request.
+func authPayload(ctx *gin.Context) *token.Payload {
+	return ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/mail"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+)
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type forgotPasswordResponse struct {
+	Message string `json:"message"`
+}
+
+// forgotPassword sends a password reset to the user of the email. The response does not tell whether the
+// email belongs to a user.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	err := server.bank.RequestPasswordReset(ctx, bank.RequestPasswordResetParams{
+		Email:    req.Email,
+		ClientIP: ctx.ClientIP(),
+		Limits: bank.PasswordResetLimits{
+			Window:         server.config.PasswordResetWindow,
+			MaxPerEmail:    server.config.PasswordResetMaxPerEmail,
+			MaxPerClientIP: server.config.PasswordResetMaxPerIP,
+		},
+		// The reset is sent by a job, which only runs once the reset is committed
+		AfterCreate: func(ctx context.Context, q db.Querier, reset db.PasswordReset) error {
+			_, err := mail.PasswordResetTask.Enqueue(ctx, q, mail.PasswordResetPayload{PasswordResetID: reset.ID}, jobs.EnqueueOptions{})
+			return err
+		},
+	})
+	if err != nil {
+		if errors.Is(err, bank.ErrTooManyPasswordResets) {
+			ctx.JSON(http.StatusTooManyRequests, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, forgotPasswordResponse{
+		Message: "if the email belongs to a user, a password reset has been sent to it",
+	})
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required,len=32"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
+
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// The user is only known from the token within the reset, which checks the rest of the policy
+	hashedPassword, ok := server.hashPassword(ctx, req.NewPassword, password.Identity{})
+	if !ok {
+		return
+	}
+
+	result, err := server.bank.ResetPassword(ctx, bank.ResetPasswordParams{
+		TokenHash:      password.HashToken(req.Token),
+		HashedPassword: hashedPassword,
+		ValidatePassword: func(user db.User) error {
+			return server.passwordPolicy.Validate(req.NewPassword, password.Identity{Username: user.Username, Email: user.Email})
+		},
+	})
+	if err != nil {
+		if errors.Is(err, password.ErrPolicyViolation) {
+			passwordPolicyResponse(ctx, err)
+			return
+		}
+		if errors.Is(err, bank.ErrInvalidPasswordReset) || errors.Is(err, bank.ErrPasswordResetExpired) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changePasswordResponse{
+		User:            newUserResponse(result.User),
+		RevokedSessions: result.RevokedSessions,
+	})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/mail"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestForgotPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	clientIP := "10.0.0.1"
+
+	// Known and unknown emails get the same response
+	var accepted string
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockBank) {
+				// The hook runs with the querier of the transaction, mocked by its own controller
+				tx := mockdb.NewMockBank(gomock.NewController(t))
+
+				store.EXPECT().
+					RequestPasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(ctx context.Context, arg bank.RequestPasswordResetParams) error {
+						require.Equal(t, user.Email, arg.Email)
+						require.Equal(t, clientIP, arg.ClientIP)
+						return arg.AfterCreate(ctx, tx, db.PasswordReset{ID: 3, Username: user.Username, Email: user.Email})
+					})
+				tx.EXPECT().
+					CreateJob(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.CreateJobParams) (db.Job, error) {
+						require.Equal(t, mail.PasswordResetTask.Kind, arg.Kind)
+						require.JSONEq(t, `{"password_reset_id":3}`, string(arg.Payload))
+						return db.Job{ID: 1, Kind: arg.Kind, Payload: arg.Payload}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				accepted = recorder.Body.String()
+			},
+		},
+		{
+			name: "UnknownEmail",
+			body: gin.H{"email": random.Email()},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequestPasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusAccepted, recorder.Code)
+				require.Equal(t, accepted, recorder.Body.String())
+			},
+		},
+		{
+			name: "TooManyRequests",
+			body: gin.H{"email": user.Email},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequestPasswordReset(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.ErrTooManyPasswordResets)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidEmail",
+			body: gin.H{"email": "invalid-email"},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					RequestPasswordReset(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/forgot_password", bytes.NewReader(data))
+			require.NoError(t, err)
+			request.RemoteAddr = clientIP + ":1234"
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestResetPasswordAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	token := random.String(mail.PasswordResetTokenLength)
+	newPassword := random.String(12)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.ResetPasswordParams) (bank.ChangePasswordResult, error) {
+						require.Equal(t, password.HashToken(token), arg.TokenHash)
+						require.NoError(t, password.CheckPassword(newPassword, arg.HashedPassword))
+						return bank.ChangePasswordResult{User: user, RevokedSessions: 1}, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got changePasswordResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, user.Username, got.User.Username)
+				require.Equal(t, int64(1), got.RevokedSessions)
+			},
+		},
+		{
+			name: "InvalidToken",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.ChangePasswordResult{}, bank.ErrInvalidPasswordReset)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "Expired",
+			body: gin.H{"token": token, "new_password": newPassword},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.ChangePasswordResult{}, bank.ErrPasswordResetExpired)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "PasswordContainsUsername",
+			body: gin.H{"token": token, "new_password": user.Username + "-2024"},
+			buildStubs: func(store *mockdb.MockBank) {
+				// The user of the token is only known within the transaction
+				store.EXPECT().
+					ResetPassword(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.ResetPasswordParams) (bank.ChangePasswordResult, error) {
+						return bank.ChangePasswordResult{}, arg.ValidatePassword(user)
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+				requireViolations(t, recorder.Body, password.RuleContainsUsername)
+			},
+		},
+		{
+			name: "ShortPassword",
+			body: gin.H{"token": token, "new_password": "short"},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ResetPassword(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/reset_password", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
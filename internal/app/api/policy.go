@@ -0,0 +1,123 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// permission allows an operation on resources of any user, without it users only operate on their own
+// resources.
+type permission string
+
+const (
+	permReadAnyAccount   permission = "account:read_any"
+	permOpenAnyAccount   permission = "account:open_any"
+	permFreezeAccount    permission = "account:freeze"
+	permCloseAccount     permission = "account:close"
+	permUpdateAnyUser    permission = "user:update_any"
+	permSetUserRole      permission = "user:set_role"
+	permReadAuditLog     permission = "audit:read"
+	permManageJobs       permission = "job:manage"
+	permReadLogins       permission = "login:read"
+	permUnlockUser       permission = "user:unlock"
+	permManageAnyAPIKey  permission = "api_key:manage_any"
+	permReverseTransfer  permission = "transfer:reverse"
+	permManageAnyWebhook permission = "webhook:manage_any"
+	permReadMetrics      permission = "metrics:read"
+)
+
+// rolePermissions declares the permissions of each role
+var rolePermissions = map[string][]permission{
+	bank.RoleCustomer: {},
+	bank.RoleSupport: {
+		permReadAnyAccount,
+		permReadLogins,
+	},
+	bank.RoleAdmin: {
+		permReadAnyAccount,
+		permOpenAnyAccount,
+		permFreezeAccount,
+		permCloseAccount,
+		permUpdateAnyUser,
+		permSetUserRole,
+		permReadAuditLog,
+		permManageJobs,
+		permReadLogins,
+		permUnlockUser,
+		permManageAnyAPIKey,
+		permReverseTransfer,
+		permManageAnyWebhook,
+		permReadMetrics,
+	},
+}
+
+// hasPermission tells whether the role has the permission, unknown roles have no permissions.
+func hasPermission(role string, perm permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// requirePermission aborts requests with forbidden unless the role of the authenticated user has the
+// permission. It must run after authMiddleware.
+func requirePermission(perm permission) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		payload := authPayload(ctx)
+		if !hasPermission(payload.Role, perm) {
+			err := fmt.Errorf("role %q is not permitted to %s", payload.Role, perm)
+			ctx.AbortWithStatusJSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// authorizeOwner responds with forbidden unless the authenticated user is the owner of a resource, or the
+// role of the authenticated user has the permission for resources of any user.
+func authorizeOwner(ctx *gin.Context, owner string, perm permission) bool {
+	payload := authPayload(ctx)
+	if owner == payload.Username || hasPermission(payload.Role, perm) {
+		return true
+	}
+
+	err := errors.New("resource doesn't belong to the authenticated user")
+	ctx.JSON(http.StatusForbidden, errorResponse(err))
+	return false
+}
+
+// authorizeSelf responds with forbidden unless the authenticated user is the user, for credentials that no
+// role may set up for other users.
+func authorizeSelf(ctx *gin.Context, username string) bool {
+	if username == authPayload(ctx).Username {
+		return true
+	}
+
+	err := errors.New("resource doesn't belong to the authenticated user")
+	ctx.JSON(http.StatusForbidden, errorResponse(err))
+	return false
+}
+
+// authorizeAccount returns the account of the request, responding with not found or forbidden unless the
+// authenticated user owns the account or may read any account.
+func (server *Server) authorizeAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.bank.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+
+	return account, authorizeOwner(ctx, account.Owner, permReadAnyAccount)
+}
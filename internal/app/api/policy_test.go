@@ -0,0 +1,353 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHasPermission(t *testing.T) {
+	require.False(t, hasPermission(bank.RoleCustomer, permReadAnyAccount))
+	require.True(t, hasPermission(bank.RoleSupport, permReadAnyAccount))
+	require.False(t, hasPermission(bank.RoleSupport, permFreezeAccount))
+	require.True(t, hasPermission(bank.RoleAdmin, permFreezeAccount))
+	require.False(t, hasPermission("", permReadAnyAccount))
+	require.False(t, hasPermission("root", permReadAnyAccount))
+}
+
+// TestPolicy checks the roles permitted on each protected route, requested by a user who does not own the
+// resource. Handlers of forbidden requests must not reach the bank.
+func TestPolicy(t *testing.T) {
+	owner, _ := randomUser(t)
+	account := randomAccount(owner.Username)
+	account.ID++
+
+	testCases := []struct {
+		name      string
+		method    string
+		url       string
+		body      gin.H
+		permitted []string
+		// Status of permitted requests, OK when zero
+		status int
+		// Permitted requests call the bank the times
+		buildStubs func(store *mockdb.MockBank, times int)
+	}{
+		{
+			name:      "GetAccount",
+			method:    http.MethodGet,
+			url:       fmt.Sprintf("/accounts/%d", account.ID),
+			permitted: []string{bank.RoleSupport, bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				store.EXPECT().GetHeldAmount(gomock.Any(), gomock.Eq(account.ID)).Times(times).Return(int64(0), nil)
+			},
+		},
+		{
+			name:      "GetAccountLimits",
+			method:    http.MethodGet,
+			url:       fmt.Sprintf("/accounts/%d/limits", account.ID),
+			permitted: []string{bank.RoleSupport, bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				store.EXPECT().GetTransferLimitUsage(gomock.Any(), gomock.Eq(account.ID)).Times(times).Return(bank.TransferLimitUsage{}, nil)
+			},
+		},
+		{
+			name:      "ListAccountEntries",
+			method:    http.MethodGet,
+			url:       fmt.Sprintf("/accounts/%d/entries?page_id=1&page_size=5", account.ID),
+			permitted: []string{bank.RoleSupport, bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				store.EXPECT().ListAccountHistory(gomock.Any(), gomock.Any()).Times(times).Return([]db.ListAccountHistoryRow{}, nil)
+			},
+		},
+		{
+			name:      "CreateAccount",
+			method:    http.MethodPost,
+			url:       "/accounts",
+			body:      gin.H{"owner": owner.Username, "currency": account.Currency},
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().OpenAccount(gomock.Any(), gomock.Any()).Times(times).Return(account, nil)
+			},
+		},
+		{
+			name:      "UpdateUser",
+			method:    http.MethodPatch,
+			url:       fmt.Sprintf("/users/%s", owner.Username),
+			body:      gin.H{"full_name": "New Name"},
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().UpdateProfile(gomock.Any(), gomock.Any()).Times(times).Return(owner, nil)
+			},
+		},
+		{
+			// Admins need not know the current password of another user
+			name:      "ChangePassword",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/users/%s/password", owner.Username),
+			body:      gin.H{"new_password": "xk8#Lq2!vz"},
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(owner.Username)).Times(times).Return(owner, nil)
+				store.EXPECT().ChangePassword(gomock.Any(), gomock.Any()).Times(times).Return(bank.ChangePasswordResult{User: owner}, nil)
+			},
+		},
+		{
+			// Admins need no code to disable TOTP of another user
+			name:      "DisableTOTP",
+			method:    http.MethodDelete,
+			url:       fmt.Sprintf("/users/%s/totp", owner.Username),
+			body:      gin.H{},
+			permitted: []string{bank.RoleAdmin},
+			status:    http.StatusNoContent,
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetUserTOTP(gomock.Any(), gomock.Eq(owner.Username)).Times(times).Return(db.UserTotp{Username: owner.Username, IsEnabled: true}, nil)
+				store.EXPECT().DisableTOTP(gomock.Any(), gomock.Eq(owner.Username)).Times(times).Return(nil)
+			},
+		},
+		{
+			name:      "FreezeAccount",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/admin/accounts/%d/freeze", account.ID),
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().FreezeAccount(gomock.Any(), gomock.Eq(account.ID)).Times(times).Return(account, nil)
+			},
+		},
+		{
+			name:      "UnfreezeAccount",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/admin/accounts/%d/unfreeze", account.ID),
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().UnfreezeAccount(gomock.Any(), gomock.Eq(account.ID)).Times(times).Return(account, nil)
+			},
+		},
+		{
+			name:      "CloseAccount",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/admin/accounts/%d/close", account.ID),
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().CloseAccount(gomock.Any(), gomock.Any()).Times(times).Return(bank.CloseAccountResult{Account: account}, nil)
+			},
+		},
+		{
+			name:      "SetUserRole",
+			method:    http.MethodPut,
+			url:       fmt.Sprintf("/admin/users/%s/role", owner.Username),
+			body:      gin.H{"role": bank.RoleSupport},
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().SetUserRole(gomock.Any(), gomock.Any()).Times(times).Return(owner, nil)
+			},
+		},
+		{
+			name:      "UnlockUser",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/admin/users/%s/unlock", owner.Username),
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().UnlockUser(gomock.Any(), gomock.Eq(owner.Username)).Times(times).Return(owner, nil)
+			},
+		},
+		{
+			name:      "ListLoginThrottles",
+			method:    http.MethodGet,
+			url:       "/admin/login_throttles?page_id=1&page_size=5&locked=true",
+			permitted: []string{bank.RoleSupport, bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				arg := db.ListLoginThrottlesParams{Locked: true, Limit: 5, Offset: 0}
+				store.EXPECT().ListLoginThrottles(gomock.Any(), gomock.Eq(arg)).Times(times).Return([]db.LoginThrottle{}, nil)
+			},
+		},
+		{
+			name:      "ListAPIKeys",
+			method:    http.MethodGet,
+			url:       fmt.Sprintf("/users/%s/api_keys?page_id=1&page_size=5", owner.Username),
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().ListAPIKeys(gomock.Any(), gomock.Any()).Times(times).Return([]db.ApiKey{}, nil)
+			},
+		},
+		{
+			// Admins revoke keys of other users, e.g. when a key leaked
+			name:      "RevokeAPIKey",
+			method:    http.MethodDelete,
+			url:       fmt.Sprintf("/users/%s/api_keys/1", owner.Username),
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().RevokeAPIKey(gomock.Any(), gomock.Any()).Times(times).Return(db.ApiKey{ID: 1}, nil)
+			},
+		},
+		{
+			// No role may create keys acting as another user
+			name:      "CreateAPIKey",
+			method:    http.MethodPost,
+			url:       fmt.Sprintf("/users/%s/api_keys", owner.Username),
+			body:      gin.H{"name": "batch", "scopes": []string{scopeReadAccounts}, "expires_in_days": 30},
+			permitted: []string{},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().IssueAPIKey(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:      "ListAuditEvents",
+			method:    http.MethodGet,
+			url:       "/admin/audit_events?page_id=1&page_size=5",
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().ListAuditEvents(gomock.Any(), gomock.Any()).Times(times).Return([]db.AuditEvent{}, nil)
+			},
+		},
+		{
+			name:      "ListJobs",
+			method:    http.MethodGet,
+			url:       "/admin/jobs?page_id=1&page_size=5",
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().ListJobs(gomock.Any(), gomock.Any()).Times(times).Return([]db.Job{}, nil)
+			},
+		},
+		{
+			name:      "RequeueJob",
+			method:    http.MethodPost,
+			url:       "/admin/jobs/1/requeue",
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().RequeueJob(gomock.Any(), gomock.Eq(int64(1))).Times(times).Return(db.Job{ID: 1}, nil)
+			},
+		},
+		{
+			name:      "ReverseTransfer",
+			method:    http.MethodPost,
+			url:       "/transfers/1/reversal",
+			body:      gin.H{"reason": "wrong recipient"},
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().ReverseTransfer(gomock.Any(), gomock.Any()).Times(times).Return(bank.ReverseTransferResult{}, nil)
+			},
+		},
+		{
+			// No role may reserve money of another user
+			name:      "PlaceHold",
+			method:    http.MethodPost,
+			url:       "/holds",
+			body:      gin.H{"account_id": account.ID, "amount": 10},
+			permitted: []string{},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				store.EXPECT().PlaceHold(gomock.Any(), gomock.Any()).Times(0)
+			},
+		},
+		{
+			name:      "ListScheduledTransfers",
+			method:    http.MethodGet,
+			url:       fmt.Sprintf("/scheduled_transfers?account_id=%d&page_id=1&page_size=5", account.ID),
+			permitted: []string{bank.RoleSupport, bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.ID)).Return(account, nil)
+				store.EXPECT().ListScheduledTransfers(gomock.Any(), gomock.Any()).Times(times).Return([]db.ScheduledTransfer{}, nil)
+			},
+		},
+		{
+			name:      "ListWebhookDeliveries",
+			method:    http.MethodGet,
+			url:       "/webhooks/1/deliveries?page_id=1&page_size=5",
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				store.EXPECT().GetWebhookSubscription(gomock.Any(), gomock.Eq(int64(1))).Return(db.WebhookSubscription{ID: 1, Owner: owner.Username}, nil)
+				store.EXPECT().ListWebhookDeliveries(gomock.Any(), gomock.Any()).Times(times).Return([]db.WebhookDelivery{}, nil)
+			},
+		},
+		{
+			name:      "ReadMetrics",
+			method:    http.MethodGet,
+			url:       "/admin/debug/vars",
+			permitted: []string{bank.RoleAdmin},
+			buildStubs: func(store *mockdb.MockBank, times int) {
+				// Metrics are of the process, the bank is not called
+			},
+		},
+	}
+
+	roles := []string{bank.RoleCustomer, bank.RoleSupport, bank.RoleAdmin, "unknown"}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		for _, role := range roles {
+			role := role
+
+			permitted := false
+			for _, p := range tc.permitted {
+				permitted = permitted || p == role
+			}
+
+			t.Run(tc.name+"/"+role, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				times := 0
+				if permitted {
+					times = 1
+				}
+
+				store := mockdb.NewMockBank(ctrl)
+				tc.buildStubs(store, times)
+
+				server := newTestServer(t, store)
+				recorder := httptest.NewRecorder()
+
+				var body io.Reader
+				if tc.body != nil {
+					data, err := json.Marshal(tc.body)
+					require.NoError(t, err)
+					body = bytes.NewReader(data)
+				}
+
+				request, err := http.NewRequest(tc.method, tc.url, body)
+				require.NoError(t, err)
+
+				addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "staff", role, time.Minute)
+				server.router.ServeHTTP(recorder, request)
+
+				if permitted {
+					status := tc.status
+					if status == 0 {
+						status = http.StatusOK
+					}
+					require.Equal(t, status, recorder.Code, recorder.Body.String())
+				} else {
+					require.Equal(t, http.StatusForbidden, recorder.Code, recorder.Body.String())
+				}
+			})
+		}
+	}
+}
+
+func TestMetricsNoAuthorization(t *testing.T) {
+	server := newTestServer(t, nil)
+	recorder := httptest.NewRecorder()
+
+	request, err := http.NewRequest(http.MethodGet, "/admin/debug/vars", nil)
+	require.NoError(t, err)
+
+	server.router.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/ratelimit"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+)
+
+// Stores of rate limit buckets
+const (
+	rateLimitStoreMemory   = "memory"
+	rateLimitStorePostgres = "postgres"
+	rateLimitStoreOff      = "off"
+)
+
+// rateLimiter limits the requests of each client with a default limit shared by the routes of the client,
+// and separate limits of some routes
+type rateLimiter struct {
+	store        ratelimit.Store
+	defaultLimit ratelimit.Limit
+	// Limits by method and path of the route, e.g. POST /users
+	routeLimits map[string]ratelimit.Limit
+	// Limit per client IP of requests to authenticated routes, taken before authentication, nil when off
+	preAuthLimit *ratelimit.Limit
+}
+
+// newRateLimiter creates the rate limiter from config, nil when rate limiting is off.
+func newRateLimiter(config util.Config, bank bank.Bank) (*rateLimiter, error) {
+	var store ratelimit.Store
+	switch config.RateLimitStore {
+	case rateLimitStoreMemory:
+		store = ratelimit.NewMemoryStore()
+	case rateLimitStorePostgres:
+		store = ratelimit.StoreFunc(bank.TakeRateLimit)
+	case rateLimitStoreOff, "":
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", config.RateLimitStore)
+	}
+
+	defaultLimit, err := ratelimit.ParseLimit(config.RateLimitDefault)
+	if err != nil {
+		return nil, err
+	}
+
+	routeLimits, err := parseRouteLimits(config.RateLimitRoutes)
+	if err != nil {
+		return nil, err
+	}
+
+	limiter := &rateLimiter{
+		store:        store,
+		defaultLimit: defaultLimit,
+		routeLimits:  routeLimits,
+	}
+
+	if config.RateLimitPreAuth != "" {
+		preAuthLimit, err := ratelimit.ParseLimit(config.RateLimitPreAuth)
+		if err != nil {
+			return nil, err
+		}
+		limiter.preAuthLimit = &preAuthLimit
+	}
+
+	return limiter, nil
+}
+
+// parseRouteLimits parses comma separated limits of routes, e.g. POST /users=10/h,POST /transfers=60/m.
+func parseRouteLimits(s string) (map[string]ratelimit.Limit, error) {
+	limits := make(map[string]ratelimit.Limit)
+
+	for _, routeLimit := range strings.Split(s, ",") {
+		if strings.TrimSpace(routeLimit) == "" {
+			continue
+		}
+
+		route, value, ok := strings.Cut(routeLimit, "=")
+		method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !hasPath {
+			return nil, fmt.Errorf("invalid route rate limit %q, expected METHOD /path=requests/period", routeLimit)
+		}
+
+		limit, err := ratelimit.ParseLimit(value)
+		if err != nil {
+			return nil, err
+		}
+
+		limits[routeKey(method, strings.TrimSpace(path))] = limit
+	}
+
+	return limits, nil
+}
+
+func routeKey(method string, path string) string {
+	return strings.ToUpper(method) + " " + path
+}
+
+// checkRoutes returns an error when a route limit is of none of the routes, e.g. misspelled in config.
+func (l *rateLimiter) checkRoutes(routes gin.RoutesInfo) error {
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[routeKey(route.Method, route.Path)] = true
+	}
+
+	for route := range l.routeLimits {
+		if !known[route] {
+			return fmt.Errorf("rate limit of unknown route %s", route)
+		}
+	}
+
+	return nil
+}
+
+// rateLimitClient returns the client of the request, its api key or user once authenticated, else the
+// internal caller of its client certificate or its IP.
+func rateLimitClient(ctx *gin.Context) string {
+	value, ok := ctx.Get(authorizationPayloadKey)
+	if internal, isInternal := ctx.Get(authorizationInternalKey); !ok || (isInternal && internal == value) {
+		if subject, ok := ctx.Get(authorizationClientKey); ok {
+			return "client:" + subject.(string)
+		}
+		return "ip:" + ctx.ClientIP()
+	}
+
+	payload := value.(*token.Payload)
+	if _, ok := ctx.Get(authorizationScopesKey); ok {
+		return "api_key:" + payload.ID
+	}
+	return "user:" + payload.Username
+}
+
+// rateLimit aborts requests of clients out of requests with too many requests, telling when to retry. All
+// responses tell the limit in RateLimit headers. On authenticated routes it must run after authMiddleware.
+func (server *Server) rateLimit() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limiter := server.rateLimiter
+		if limiter == nil {
+			ctx.Next()
+			return
+		}
+
+		key := rateLimitClient(ctx)
+		limit := limiter.defaultLimit
+
+		route := routeKey(ctx.Request.Method, ctx.FullPath())
+		if routeLimit, ok := limiter.routeLimits[route]; ok {
+			key += " " + route
+			limit = routeLimit
+		}
+
+		result, err := limiter.store.Take(ctx, key, limit)
+		if err != nil {
+			// An unavailable store does not take the api down with it
+			ctx.Next()
+			return
+		}
+
+		setRateLimitHeaders(ctx, result)
+
+		if !result.Allowed {
+			abortRateLimited(ctx, result, limit)
+			return
+		}
+
+		ctx.Next()
+	}
+}
+
+// rateLimitPreAuth aborts requests to authenticated routes from client IPs out of requests, before their
+// credentials are checked, so that requests with bad credentials are limited too and do not look up api keys
+// without bounds. It must run before authMiddleware, the RateLimit headers of allowed requests are left to
+// rateLimit.
+func (server *Server) rateLimitPreAuth() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		limiter := server.rateLimiter
+		if limiter == nil || limiter.preAuthLimit == nil {
+			ctx.Next()
+			return
+		}
+
+		limit := *limiter.preAuthLimit
+
+		result, err := limiter.store.Take(ctx, "pre_auth "+rateLimitClient(ctx), limit)
+		if err != nil || result.Allowed {
+			ctx.Next()
+			return
+		}
+
+		setRateLimitHeaders(ctx, result)
+		abortRateLimited(ctx, result, limit)
+	}
+}
+
+func setRateLimitHeaders(ctx *gin.Context, result ratelimit.Result) {
+	ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	ctx.Header("RateLimit-Reset", strconv.FormatInt(seconds(result.Reset), 10))
+}
+
+func abortRateLimited(ctx *gin.Context, result ratelimit.Result, limit ratelimit.Limit) {
+	ctx.Header("Retry-After", strconv.FormatInt(seconds(result.RetryAfter), 10))
+	err := fmt.Errorf("rate limit of %s exceeded", limit)
+	ctx.AbortWithStatusJSON(http.StatusTooManyRequests, errorResponse(err))
+}
+
+// seconds rounds the duration up to whole seconds
+func seconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
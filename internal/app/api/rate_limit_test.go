@@ -0,0 +1,282 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/ratelimit"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestParseRouteLimits(t *testing.T) {
+	limits, err := parseRouteLimits("POST /users=10/h, post /transfers = 60/m,")
+	require.NoError(t, err)
+	require.Equal(t, map[string]ratelimit.Limit{
+		"POST /users":     {Requests: 10, Per: time.Hour},
+		"POST /transfers": {Requests: 60, Per: time.Minute},
+	}, limits)
+
+	limits, err = parseRouteLimits("")
+	require.NoError(t, err)
+	require.Empty(t, limits)
+
+	for _, s := range []string{"/users=10/h", "POST /users", "POST /users=10"} {
+		_, err := parseRouteLimits(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestNewRateLimiter(t *testing.T) {
+	config := newTestConfig()
+	config.RateLimitStore = "redis"
+	config.RateLimitDefault = "10/m"
+	_, err := NewServer(config, nil, nil)
+	require.Error(t, err)
+
+	config.RateLimitStore = rateLimitStoreMemory
+	config.RateLimitDefault = "10"
+	_, err = NewServer(config, nil, nil)
+	require.Error(t, err)
+
+	// Limits of routes must be of known routes
+	config.RateLimitDefault = "10/m"
+	config.RateLimitRoutes = "POST /user=1/m"
+	_, err = NewServer(config, nil, nil)
+	require.Error(t, err)
+
+	config.RateLimitRoutes = "POST /users=1/m"
+	server, err := NewServer(config, nil, nil)
+	require.NoError(t, err)
+	require.NotNil(t, server.rateLimiter)
+
+	config.RateLimitStore = rateLimitStoreOff
+	server, err = NewServer(config, nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, server.rateLimiter)
+}
+
+func TestRateLimitAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	account := randomAccount(user.Username)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().ListAccountProducts(gomock.Any()).AnyTimes().Return([]db.AccountProduct{}, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).AnyTimes().Return(account, nil)
+	store.EXPECT().GetHeldAmount(gomock.Any(), gomock.Any()).AnyTimes().Return(int64(0), nil)
+	store.EXPECT().AddUser(gomock.Any(), gomock.Any()).Times(0)
+	acceptAccessTokens(store)
+
+	config := newTestConfig()
+	config.RateLimitStore = rateLimitStoreMemory
+	config.RateLimitDefault = "2/m"
+	config.RateLimitRoutes = "POST /users=1/h"
+
+	server, err := NewServer(config, store, balance.NewHub(store, 2))
+	require.NoError(t, err)
+
+	serve := func(method string, url string, clientIP string, setupAuth func(request *http.Request)) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(method, url, nil)
+		require.NoError(t, err)
+
+		request.RemoteAddr = clientIP + ":1234"
+		if setupAuth != nil {
+			setupAuth(request)
+		}
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Clients use up the default limit across routes
+	recorder := serve(http.MethodGet, "/account_products", "10.0.0.1", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("RateLimit-Reset"))
+
+	recorder = serve(http.MethodGet, "/account_products", "10.0.0.1", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+
+	recorder = serve(http.MethodGet, "/account_products", "10.0.0.1", nil)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	require.Equal(t, "30", recorder.Header().Get("Retry-After"))
+
+	// Other clients have their own limits
+	recorder = serve(http.MethodGet, "/account_products", "10.0.0.2", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// Routes with their own limit are limited apart from the default limit
+	recorder = serve(http.MethodPost, "/users", "10.0.0.1", nil)
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Equal(t, "1", recorder.Header().Get("RateLimit-Limit"))
+
+	recorder = serve(http.MethodPost, "/users", "10.0.0.1", nil)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "3600", recorder.Header().Get("Retry-After"))
+
+	// Authenticated requests are limited per user, not per client IP
+	asUser := func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+	}
+	for i := 0; i < 2; i++ {
+		recorder = serve(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), "10.0.0.1", asUser)
+		require.Equal(t, http.StatusOK, recorder.Code)
+	}
+
+	recorder = serve(http.MethodGet, fmt.Sprintf("/accounts/%d", account.ID), "10.0.0.3", asUser)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
+
+func TestRateLimitTrustedProxies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().ListAccountProducts(gomock.Any()).AnyTimes().Return([]db.AccountProduct{}, nil)
+
+	newServer := func(trustedProxies string) *Server {
+		config := newTestConfig()
+		config.RateLimitStore = rateLimitStoreMemory
+		config.RateLimitDefault = "1/m"
+		config.TrustedProxies = trustedProxies
+
+		server, err := NewServer(config, store, nil)
+		require.NoError(t, err)
+		return server
+	}
+
+	serve := func(server *Server, remoteIP string, forwardedFor string) int {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/account_products", nil)
+		require.NoError(t, err)
+
+		request.RemoteAddr = remoteIP + ":1234"
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// No proxy is trusted by default, clients can not dodge their limit by forwarding other IPs
+	server := newServer("")
+	require.Equal(t, http.StatusOK, serve(server, "192.0.2.1", "10.0.0.1"))
+	require.Equal(t, http.StatusTooManyRequests, serve(server, "192.0.2.1", "10.0.0.2"))
+
+	// Clients behind trusted proxies are limited by the forwarded IP
+	server = newServer("10.0.0.0/8, 172.16.0.1")
+	require.Equal(t, http.StatusOK, serve(server, "10.1.1.1", "192.0.2.1"))
+	require.Equal(t, http.StatusOK, serve(server, "10.1.1.1", "192.0.2.2"))
+	require.Equal(t, http.StatusTooManyRequests, serve(server, "172.16.0.1", "192.0.2.1"))
+	require.Equal(t, http.StatusOK, serve(server, "192.0.2.3", "192.0.2.4"))
+	require.Equal(t, http.StatusTooManyRequests, serve(server, "192.0.2.3", "192.0.2.5"))
+
+	config := newTestConfig()
+	config.TrustedProxies = "10.0.0.0/33"
+	_, err := NewServer(config, store, nil)
+	require.Error(t, err)
+}
+
+func TestRateLimitClient(t *testing.T) {
+	apiKey, key := randomAPIKey("user", scopeReadAccounts)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).AnyTimes().Return(apiKey, nil)
+	store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).AnyTimes().Return(randomAccount("user"), nil)
+	store.EXPECT().GetHeldAmount(gomock.Any(), gomock.Any()).AnyTimes().Return(int64(0), nil)
+	acceptAccessTokens(store)
+
+	config := newTestConfig()
+	config.RateLimitStore = rateLimitStoreMemory
+	config.RateLimitDefault = "1/m"
+
+	server, err := NewServer(config, store, balance.NewHub(store, 2))
+	require.NoError(t, err)
+
+	serve := func(setupAuth func(request *http.Request)) int {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/accounts/1", nil)
+		require.NoError(t, err)
+
+		setupAuth(request)
+		server.router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	withKey := func(request *http.Request) { addAPIKeyAuthorization(request, key) }
+	asUser := func(request *http.Request) {
+		addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, "user", bank.RoleCustomer, time.Minute)
+	}
+
+	// An api key is limited apart from the access tokens of its user
+	require.Equal(t, http.StatusOK, serve(withKey))
+	require.Equal(t, http.StatusTooManyRequests, serve(withKey))
+	require.Equal(t, http.StatusOK, serve(asUser))
+	require.Equal(t, http.StatusTooManyRequests, serve(asUser))
+}
+
+func TestRateLimitPreAuth(t *testing.T) {
+	_, key := randomAPIKey("user", scopeReadAccounts)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// Bogus api keys over the limit are refused before they are looked up
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().GetAPIKeyByHash(gomock.Any(), gomock.Any()).Times(1).Return(db.GetAPIKeyByHashRow{}, db.ErrRecordNotFound)
+
+	config := newTestConfig()
+	config.RateLimitStore = rateLimitStoreMemory
+	config.RateLimitDefault = "10/m"
+	config.RateLimitPreAuth = "2/m"
+
+	server, err := NewServer(config, store, balance.NewHub(store, 2))
+	require.NoError(t, err)
+
+	serve := func(remoteIP string, setupAuth func(request *http.Request)) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request, err := http.NewRequest(http.MethodGet, "/accounts/1", nil)
+		require.NoError(t, err)
+
+		request.RemoteAddr = remoteIP + ":1234"
+		setupAuth(request)
+		server.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	withKey := func(request *http.Request) { addAPIKeyAuthorization(request, key) }
+	withBadToken := func(request *http.Request) {
+		request.Header.Set(authorizationHeaderKey, authorizationTypeBearer+" bad")
+	}
+
+	require.Equal(t, http.StatusUnauthorized, serve("192.0.2.1", withKey).Code)
+	require.Equal(t, http.StatusUnauthorized, serve("192.0.2.1", withBadToken).Code)
+
+	recorder := serve("192.0.2.1", withKey)
+	require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	require.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"))
+	require.NotEmpty(t, recorder.Header().Get("Retry-After"))
+
+	// Other IPs have their own limit
+	require.Equal(t, http.StatusUnauthorized, serve("192.0.2.2", withBadToken).Code)
+
+	config.RateLimitPreAuth = "2"
+	_, err = NewServer(config, store, nil)
+	require.Error(t, err)
+}
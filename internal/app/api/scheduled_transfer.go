@@ -0,0 +1,293 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/recurrence"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type scheduledTransferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type pageRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=10"`
+}
+
+type createScheduledTransferRequest struct {
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64 `json:"amount" binding:"required,gt=0"`
+	// Cron expression or RRULE, empty schedules a single transfer at start_at
+	Recurrence string    `json:"recurrence"`
+	StartAt    time.Time `json:"start_at" binding:"required"`
+	// Code of the TOTP of the user, required for amounts above the TOTP threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+func (server *Server) createScheduledTransfer(ctx *gin.Context) {
+	var req createScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	// No role may move money of other users
+	fromAccount, ok := server.getTransferAccount(ctx, req.FromAccountID)
+	if !ok {
+		return
+	}
+
+	if !authorizeSelf(ctx, fromAccount.Owner) {
+		return
+	}
+
+	// Each occurrence runs unattended, the code is asked for once when scheduling
+	if !server.checkTransferTOTP(ctx, fromAccount.Owner, req.Amount, req.TOTPCode) {
+		return
+	}
+
+	arg := bank.ScheduleTransferParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Recurrence:    req.Recurrence,
+		StartAt:       req.StartAt,
+	}
+
+	scheduled, err := server.bank.ScheduleTransfer(ctx, arg)
+	if err != nil {
+		scheduledTransferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (server *Server) getScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, fromAccount, ok := server.getScheduledTransferAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if !authorizeOwner(ctx, fromAccount.Owner, permReadAnyAccount) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+// getScheduledTransferAccount returns a scheduled transfer and the account it is from, responding with not found
+// unless both exist.
+func (server *Server) getScheduledTransferAccount(ctx *gin.Context, id int64) (db.ScheduledTransfer, db.Account, bool) {
+	scheduled, err := server.bank.GetScheduledTransfer(ctx, id)
+	if err != nil {
+		scheduledTransferErrorResponse(ctx, err)
+		return scheduled, db.Account{}, false
+	}
+
+	fromAccount, ok := server.getTransferAccount(ctx, scheduled.FromAccountID)
+
+	return scheduled, fromAccount, ok
+}
+
+type listScheduledTransfersRequest struct {
+	AccountID int64 `form:"account_id" binding:"required,min=1"`
+	pageRequest
+}
+
+func (server *Server) listScheduledTransfers(ctx *gin.Context) {
+	var req listScheduledTransfersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.authorizeAccount(ctx, req.AccountID); !ok {
+		return
+	}
+
+	arg := db.ListScheduledTransfersParams{
+		FromAccountID: req.AccountID,
+		Limit:         req.PageSize,
+		Offset:        (req.PageID - 1) * req.PageSize,
+	}
+
+	scheduled, err := server.bank.ListScheduledTransfers(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+type updateScheduledTransferRequest struct {
+	Amount     *int64  `json:"amount" binding:"omitempty,gt=0"`
+	Recurrence *string `json:"recurrence"`
+	Status     *string `json:"status" binding:"omitempty,oneof=active paused cancelled"`
+	// Code of the TOTP of the user, required for amounts above the TOTP threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+func (server *Server) updateScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req updateScheduledTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	scheduled, fromAccount, ok := server.getScheduledTransferAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if !authorizeSelf(ctx, fromAccount.Owner) {
+		return
+	}
+
+	// Pausing or cancelling moves no money, changing the amount, the recurrence or resuming needs a code
+	// like scheduling did
+	if req.Amount != nil || req.Recurrence != nil || (req.Status != nil && *req.Status == bank.ScheduledTransferStatusActive) {
+		amount := scheduled.Amount
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+
+		if !server.checkTransferTOTP(ctx, fromAccount.Owner, amount, req.TOTPCode) {
+			return
+		}
+	}
+
+	arg := bank.ChangeScheduledTransferParams{ID: uri.ID}
+	if req.Amount != nil {
+		arg.Amount = pgtype.Int8{Int64: *req.Amount, Valid: true}
+	}
+	if req.Recurrence != nil {
+		arg.Recurrence = pgtype.Text{String: *req.Recurrence, Valid: true}
+	}
+	if req.Status != nil {
+		arg.Status = pgtype.Text{String: *req.Status, Valid: true}
+	}
+
+	scheduled, err := server.bank.ChangeScheduledTransfer(ctx, arg)
+	if err != nil {
+		scheduledTransferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (server *Server) deleteScheduledTransfer(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.authorizeScheduledTransfer(ctx, uri.ID) {
+		return
+	}
+
+	// Runs keep referring to the scheduled transfer, it is cancelled rather than deleted
+	arg := bank.ChangeScheduledTransferParams{
+		ID:     uri.ID,
+		Status: pgtype.Text{String: bank.ScheduledTransferStatusCancelled, Valid: true},
+	}
+
+	scheduled, err := server.bank.ChangeScheduledTransfer(ctx, arg)
+	if err != nil {
+		scheduledTransferErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduled)
+}
+
+func (server *Server) listScheduledTransferRuns(ctx *gin.Context) {
+	var uri scheduledTransferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	_, fromAccount, ok := server.getScheduledTransferAccount(ctx, uri.ID)
+	if !ok {
+		return
+	}
+
+	if !authorizeOwner(ctx, fromAccount.Owner, permReadAnyAccount) {
+		return
+	}
+
+	arg := db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: uri.ID,
+		Limit:               req.PageSize,
+		Offset:              (req.PageID - 1) * req.PageSize,
+	}
+
+	runs, err := server.bank.ListScheduledTransferRuns(ctx, arg)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, runs)
+}
+
+// authorizeScheduledTransfer responds with not found or forbidden unless the scheduled transfer is from an
+// account of the authenticated user. No role may change transfers of other users.
+func (server *Server) authorizeScheduledTransfer(ctx *gin.Context, id int64) bool {
+	_, fromAccount, ok := server.getScheduledTransferAccount(ctx, id)
+	if !ok {
+		return false
+	}
+
+	return authorizeSelf(ctx, fromAccount.Owner)
+}
+
+// scheduledTransferErrorResponse maps errors from the scheduled transfer operations to a response.
+func scheduledTransferErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, db.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+	case errors.Is(err, recurrence.ErrInvalidRule),
+		errors.Is(err, bank.ErrNoOccurrence),
+		errors.Is(err, bank.ErrInvalidAmount):
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	case errors.Is(err, bank.ErrScheduleFinished),
+		errors.Is(err, bank.ErrInvalidStatusTransition),
+		errors.Is(err, bank.ErrCurrencyMismatch),
+		errors.Is(err, bank.ErrSameAccount),
+		db.ErrorCode(err) == db.ForeignKeyViolation:
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
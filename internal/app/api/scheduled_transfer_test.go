@@ -0,0 +1,593 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/recurrence"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestScheduledTransferAPI(t *testing.T) {
+	user, _ := randomUser(t)
+	from := randomAccount(user.Username)
+	to := randomAccount(user.Username)
+	scheduled := randomScheduledTransfer(from, to)
+
+	other, _ := randomUser(t)
+
+	getFromAccount := func(store *mockdb.MockBank) {
+		store.EXPECT().
+			GetAccount(gomock.Any(), gomock.Eq(from.ID)).
+			Times(1).
+			Return(from, nil)
+	}
+	getScheduled := func(store *mockdb.MockBank) {
+		store.EXPECT().
+			GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+			Times(1).
+			Return(scheduled, nil)
+		getFromAccount(store)
+	}
+
+	testCases := []struct {
+		name          string
+		method        string
+		url           string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "CreateOK",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          scheduled.Amount,
+				"recurrence":      scheduled.Recurrence,
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				arg := bank.ScheduleTransferParams{
+					FromAccountID: from.ID,
+					ToAccountID:   to.ID,
+					Amount:        scheduled.Amount,
+					Recurrence:    scheduled.Recurrence,
+					StartAt:       scheduled.StartAt,
+				}
+
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(scheduled, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got db.ScheduledTransfer
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, scheduled, got)
+			},
+		},
+		{
+			name:   "CreateAboveThresholdMissingTOTPCode",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          1001,
+				"recurrence":      scheduled.Recurrence,
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateInvalidRecurrence",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          scheduled.Amount,
+				"recurrence":      "every now and then",
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, fmt.Errorf("%w: cron expression must have 5 fields", recurrence.ErrInvalidRule))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateSameAccount",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   from.ID,
+				"amount":          scheduled.Amount,
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateCurrencyMismatch",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          scheduled.Amount,
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.ScheduledTransfer{}, bank.ErrCurrencyMismatch)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateMissingStart",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          scheduled.Amount,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "GetNotFound",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetScheduledTransfer(gomock.Any(), gomock.Eq(scheduled.ID)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:   "ListOK",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers?account_id=%d&page_id=2&page_size=5", from.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				arg := db.ListScheduledTransfersParams{
+					FromAccountID: from.ID,
+					Limit:         5,
+					Offset:        5,
+				}
+
+				store.EXPECT().
+					ListScheduledTransfers(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ScheduledTransfer{scheduled}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "PauseOK",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			body: gin.H{
+				"status": bank.ScheduledTransferStatusPaused,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				arg := bank.ChangeScheduledTransferParams{
+					ID:     scheduled.ID,
+					Status: pgtype.Text{String: bank.ScheduledTransferStatusPaused, Valid: true},
+				}
+
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(scheduled, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateAboveThresholdMissingTOTPCode",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			body: gin.H{
+				"amount": 1001,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{Username: user.Username, IsEnabled: true}, nil)
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "UpdateInvalidStatus",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			body: gin.H{
+				"status": bank.ScheduledTransferStatusCompleted,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteFinished",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				arg := bank.ChangeScheduledTransferParams{
+					ID:     scheduled.ID,
+					Status: pgtype.Text{String: bank.ScheduledTransferStatusCancelled, Valid: true},
+				}
+
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(db.ScheduledTransfer{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrScheduleFinished))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ListRunsOK",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d/runs?page_id=1&page_size=10", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				arg := db.ListScheduledTransferRunsParams{
+					ScheduledTransferID: scheduled.ID,
+					Limit:               10,
+					Offset:              0,
+				}
+
+				store.EXPECT().
+					ListScheduledTransferRuns(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return([]db.ScheduledTransferRun{}, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateNoAuthorization",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          scheduled.Amount,
+				"recurrence":      scheduled.Recurrence,
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "CreateFromOtherUsersAccount",
+			method: http.MethodPost,
+			url:    "/scheduled_transfers",
+			body: gin.H{
+				"from_account_id": from.ID,
+				"to_account_id":   to.ID,
+				"amount":          scheduled.Amount,
+				"recurrence":      scheduled.Recurrence,
+				"start_at":        scheduled.StartAt,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				store.EXPECT().
+					ScheduleTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "GetNoAuthorization",
+			method:    http.MethodGet,
+			url:       fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "GetOtherUsers",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "GetOtherUsersBySupport",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleSupport, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:   "ListOtherUsers",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers?account_id=%d&page_id=1&page_size=5", from.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getFromAccount(store)
+				store.EXPECT().
+					ListScheduledTransfers(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "PauseNoAuthorization",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			body: gin.H{
+				"status": bank.ScheduledTransferStatusPaused,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:   "PauseOtherUsers",
+			method: http.MethodPatch,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			body: gin.H{
+				"status": bank.ScheduledTransferStatusPaused,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "DeleteOtherUsers",
+			method: http.MethodDelete,
+			url:    fmt.Sprintf("/scheduled_transfers/%d", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				store.EXPECT().
+					ChangeScheduledTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:   "ListRunsOtherUsers",
+			method: http.MethodGet,
+			url:    fmt.Sprintf("/scheduled_transfers/%d/runs?page_id=1&page_size=10", scheduled.ID),
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, other.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getScheduled(store)
+				store.EXPECT().
+					ListScheduledTransferRuns(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(tc.method, tc.url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomScheduledTransfer(from, to db.Account) db.ScheduledTransfer {
+	startAt := time.Date(2023, 11, 1, 8, 0, 0, 0, time.UTC)
+
+	return db.ScheduledTransfer{
+		ID:               random.Int(1000) + 1,
+		FromAccountID:    from.ID,
+		ToAccountID:      to.ID,
+		Amount:           random.Money() + 1,
+		Recurrence:       "0 8 1 * *",
+		Status:           bank.ScheduledTransferStatusActive,
+		StartAt:          startAt,
+		NextOccurrenceAt: startAt,
+		NextAttemptAt:    startAt,
+	}
+}
@@ -1,41 +1,260 @@
 package api
 
 import (
+	"crypto/tls"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/certs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/encrypt"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
 )
 
 // Server serves HTTP requests for our banking service.
 type Server struct {
-	config util.Config
-	bank   bank.Bank
-	router *gin.Engine
+	config     util.Config
+	bank       bank.Bank
+	tokenMaker token.Maker
+	// Encrypts the TOTP secrets of users
+	cipher *encrypt.Cipher
+	// Hashes the passwords of users, new passwords must comply with the policy
+	hasher         *password.Hasher
+	passwordPolicy password.Policy
+	// Hash of no user's password, checked for unknown usernames so their logins take as long as others
+	dummyHash string
+	// Limits requests per client, nil when off
+	rateLimiter *rateLimiter
+	// Serves TLS, reloading the certificates when changed, nil when serving plain HTTP
+	tlsConfig *tls.Config
+	// Internal callers authenticated by their client certificate, nil when there are none
+	internalCallers *internalCallers
+	balances        *balance.Hub
+	router          *gin.Engine
 }
 
-// NewServer creates a new HTTP server and set up routing.
-func NewServer(config util.Config, bank bank.Bank) (*Server, error) {
+// NewServer creates a new HTTP server and set up routing. Balance events are streamed from the hub.
+func NewServer(config util.Config, bank bank.Bank, balances *balance.Hub) (*Server, error) {
+	// Keys are never defaulted, the server refuses to start rather than sign tokens with a known key
+	if config.TokenSymmetricKey == "" {
+		return nil, errors.New("TOKEN_SYMMETRIC_KEY is required")
+	}
+
+	tokenMaker, err := token.NewHMACMaker(config.TokenSymmetricKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create token maker: %w", err)
+	}
+
+	if config.TOTPEncryptionKey == "" {
+		return nil, errors.New("TOTP_ENCRYPTION_KEY is required")
+	}
+
+	cipher, err := encrypt.NewCipher(config.TOTPEncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TOTP cipher: %w", err)
+	}
+
+	hasher, err := password.NewHasher(password.Params{
+		Algorithm:         config.PasswordHashAlgorithm,
+		Argon2Memory:      config.PasswordArgon2Memory,
+		Argon2Iterations:  config.PasswordArgon2Iterations,
+		Argon2Parallelism: config.PasswordArgon2Parallelism,
+		BcryptCost:        config.PasswordBcryptCost,
+		Pepper:            config.PasswordPepper,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot create password hasher: %w", err)
+	}
+
+	dummyHash, err := hasher.Hash(random.String(32))
+	if err != nil {
+		return nil, fmt.Errorf("cannot create dummy password hash: %w", err)
+	}
+
+	passwordPolicy := password.Policy{
+		MinLength: config.PasswordMinLength,
+		MaxLength: config.PasswordMaxLength,
+		MinScore:  config.PasswordMinScore,
+	}
+	if config.PasswordBreachedFile != "" {
+		passwordPolicy.Breached, err = password.LoadBreachedList(config.PasswordBreachedFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot load breached passwords: %w", err)
+		}
+	}
+
+	rateLimiter, err := newRateLimiter(config, bank)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create rate limiter: %w", err)
+	}
+
+	tlsConfig, err := newTLSConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create TLS config: %w", err)
+	}
+
+	internalCallers, err := newInternalCallers(config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create internal callers: %w", err)
+	}
+
 	server := &Server{
-		config: config,
-		bank:   bank,
+		config:          config,
+		bank:            bank,
+		tokenMaker:      tokenMaker,
+		cipher:          cipher,
+		hasher:          hasher,
+		dummyHash:       dummyHash,
+		passwordPolicy:  passwordPolicy,
+		rateLimiter:     rateLimiter,
+		tlsConfig:       tlsConfig,
+		internalCallers: internalCallers,
+		balances:        balances,
 	}
 
 	server.setupRouter()
+
+	// Gin trusts every proxy unless told otherwise, letting any client choose its IP for the per IP limits
+	if err := server.router.SetTrustedProxies(parseTrustedProxies(config.TrustedProxies)); err != nil {
+		return nil, fmt.Errorf("cannot set trusted proxies: %w", err)
+	}
+
+	if rateLimiter != nil {
+		if err := rateLimiter.checkRoutes(server.router.Routes()); err != nil {
+			return nil, err
+		}
+	}
+
 	return server, nil
 }
 
 func (server *Server) setupRouter() {
 	router := gin.Default()
+	// Handlers pass the gin context to the bank, which reads the audit actor from the request context
+	router.ContextWithFallback = true
+	router.Use(auditActor(), clientCertMiddleware(server.internalCallers))
+
+	// Public routes, rate limited per client IP
+	publicRoutes := router.Group("/").Use(server.rateLimit())
+	publicRoutes.POST("/users", server.createUser)
+	publicRoutes.POST("/users/login", server.loginUser)
+	publicRoutes.POST("/users/login/totp", server.loginUserTOTP)
+	publicRoutes.POST("/users/forgot_password", server.forgotPassword)
+	publicRoutes.POST("/users/reset_password", server.resetPassword)
+	publicRoutes.POST("/tokens/renew_access", server.renewAccessToken)
+	publicRoutes.GET("/verify_email", server.verifyEmail)
+	publicRoutes.GET("/account_products", server.listAccountProducts)
+
+	// Routes of authenticated users, acting on their own resources unless their role permits otherwise, and of
+	// internal callers, acting by their role only. Like all routes below they are rate limited per client IP
+	// before authentication, and per user, api key or internal caller after.
+	authRoutes := router.Group("/").Use(server.rateLimitPreAuth(), authMiddleware(server.tokenMaker, server.bank, false), server.rateLimit())
+	authRoutes.PATCH("/users/:username", server.updateUser)
+	authRoutes.POST("/users/:username/password", server.changePassword)
+	authRoutes.POST("/users/:username/totp", server.enrollTOTP)
+	authRoutes.POST("/users/:username/totp/enable", server.enableTOTP)
+	authRoutes.DELETE("/users/:username/totp", server.disableTOTP)
+	authRoutes.POST("/users/:username/api_keys", server.createAPIKey)
+	authRoutes.GET("/users/:username/api_keys", server.listAPIKeys)
+	authRoutes.DELETE("/users/:username/api_keys/:id", server.revokeAPIKey)
+	authRoutes.POST("/accounts", server.createAccount)
+	authRoutes.GET("/accounts/:id/events", server.streamAccountEvents)
+	authRoutes.POST("/transfers/:id/reversal", requirePermission(permReverseTransfer), server.reverseTransfer)
+	authRoutes.POST("/holds", server.placeHold)
+	authRoutes.POST("/holds/:id/capture", server.captureHold)
+	authRoutes.POST("/holds/:id/release", server.releaseHold)
+	authRoutes.POST("/scheduled_transfers", server.createScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers", server.listScheduledTransfers)
+	authRoutes.GET("/scheduled_transfers/:id", server.getScheduledTransfer)
+	authRoutes.PATCH("/scheduled_transfers/:id", server.updateScheduledTransfer)
+	authRoutes.DELETE("/scheduled_transfers/:id", server.deleteScheduledTransfer)
+	authRoutes.GET("/scheduled_transfers/:id/runs", server.listScheduledTransferRuns)
+	authRoutes.POST("/webhooks", server.createWebhookSubscription)
+	authRoutes.GET("/webhooks", server.listWebhookSubscriptions)
+	authRoutes.DELETE("/webhooks/:id", server.deleteWebhookSubscription)
+	authRoutes.GET("/webhooks/:id/deliveries", server.listWebhookDeliveries)
+	authRoutes.POST("/webhook_deliveries/:id/replay", server.replayWebhookDelivery)
 
-	router.POST("/users", server.createUser)
-	router.POST("/accounts", server.createAccount)
+	// Routes also open to api keys of machine clients, each requiring a scope of the key
+	keyRoutes := router.Group("/").Use(server.rateLimitPreAuth(), authMiddleware(server.tokenMaker, server.bank, true), server.rateLimit())
+	keyRoutes.GET("/accounts/:id", requireScope(scopeReadAccounts), server.getAccount)
+	keyRoutes.GET("/accounts/:id/limits", requireScope(scopeReadAccounts), server.getAccountLimits)
+	keyRoutes.GET("/accounts/:id/entries", requireScope(scopeReadAccounts), server.listAccountEntries)
+	keyRoutes.POST("/transfers", requireScope(scopeWriteTransfers), server.createTransfer)
+
+	// Routes of operations staff, each requiring a permission of the role of the authenticated user
+	adminRoutes := router.Group("/admin").Use(server.rateLimitPreAuth(), authMiddleware(server.tokenMaker, server.bank, false), server.rateLimit())
+	adminRoutes.POST("/accounts/:id/freeze", requirePermission(permFreezeAccount), server.freezeAccount)
+	adminRoutes.POST("/accounts/:id/unfreeze", requirePermission(permFreezeAccount), server.unfreezeAccount)
+	adminRoutes.POST("/accounts/:id/close", requirePermission(permCloseAccount), server.closeAccount)
+	adminRoutes.PUT("/users/:username/role", requirePermission(permSetUserRole), server.setUserRole)
+	adminRoutes.POST("/users/:username/unlock", requirePermission(permUnlockUser), server.unlockUser)
+	adminRoutes.GET("/login_throttles", requirePermission(permReadLogins), server.listLoginThrottles)
+	adminRoutes.GET("/audit_events", requirePermission(permReadAuditLog), server.listAuditEvents)
+	adminRoutes.GET("/jobs", requirePermission(permManageJobs), server.listJobs)
+	adminRoutes.POST("/jobs/:id/requeue", requirePermission(permManageJobs), server.requeueJob)
+	// Metrics, e.g. the outcome of the last ledger reconciliation, next to the command line and memory stats
+	adminRoutes.GET("/debug/vars", requirePermission(permReadMetrics), gin.WrapH(expvar.Handler()))
 
 	server.router = router
 }
 
-// Start runs the HTTP server on a specific address.
+// newTLSConfig creates the TLS config of the certificate files in config, nil when none are set.
+func newTLSConfig(config util.Config) (*tls.Config, error) {
+	if config.TLSCertFile == "" && config.TLSKeyFile == "" {
+		if config.TLSClientCAFile != "" {
+			return nil, errors.New("client CA requires a certificate and key")
+		}
+		return nil, nil
+	}
+
+	minVersion, err := certs.ParseVersion(config.TLSMinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	reloader, err := certs.NewReloader(config.TLSCertFile, config.TLSKeyFile, config.TLSClientCAFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return reloader.TLSConfig(minVersion), nil
+}
+
+// Start runs the HTTP server on a specific address, serving TLS when configured.
 func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+	if server.tlsConfig == nil {
+		return server.router.Run(address)
+	}
+
+	httpServer := &http.Server{
+		Addr:      address,
+		Handler:   server.router,
+		TLSConfig: server.tlsConfig,
+	}
+
+	// The certificate is of the TLS config, not of files given here
+	return httpServer.ListenAndServeTLS("", "")
+}
+
+// parseTrustedProxies parses comma separated IPs or CIDRs of proxies, none when empty.
+func parseTrustedProxies(s string) []string {
+	var proxies []string
+	for _, proxy := range strings.Split(s, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+
+	return proxies
 }
 
 // errorResponse formats the errors returned to the client.
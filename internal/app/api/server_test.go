@@ -0,0 +1,21 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewServerKeys(t *testing.T) {
+	config := newTestConfig()
+	config.TokenSymmetricKey = ""
+
+	_, err := NewServer(config, nil, nil)
+	require.EqualError(t, err, "TOKEN_SYMMETRIC_KEY is required")
+
+	config = newTestConfig()
+	config.TOTPEncryptionKey = ""
+
+	_, err = NewServer(config, nil, nil)
+	require.EqualError(t, err, "TOTP_ENCRYPTION_KEY is required")
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/audit"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/balance"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/util"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/certs/certstest"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// newTestTLSConfig returns the config of test servers serving TLS with a certificate of the CA, verifying
+// clients of the client CA
+func newTestTLSConfig(t *testing.T, ca *certstest.CA, clientCA *certstest.CA) util.Config {
+	dir := t.TempDir()
+	certPEM, keyPEM := ca.Issue(t, "bank")
+
+	config := newTestConfig()
+	config.TLSCertFile = certstest.WriteFile(t, dir, "bank.crt", certPEM)
+	config.TLSKeyFile = certstest.WriteFile(t, dir, "bank.key", keyPEM)
+	config.TLSMinVersion = "1.2"
+	config.TLSClientCAFile = certstest.WriteFile(t, dir, "client-ca.crt", clientCA.PEM)
+
+	return config
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	ca := certstest.NewCA(t, "ca")
+
+	// Plain HTTP
+	tlsConfig, err := newTLSConfig(newTestConfig())
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+
+	config := newTestTLSConfig(t, ca, ca)
+	tlsConfig, err = newTLSConfig(config)
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig)
+	require.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+
+	invalidConfig := config
+	invalidConfig.TLSMinVersion = "1.1"
+	_, err = newTLSConfig(invalidConfig)
+	require.Error(t, err)
+
+	invalidConfig = config
+	invalidConfig.TLSKeyFile = ""
+	_, err = newTLSConfig(invalidConfig)
+	require.Error(t, err)
+
+	// Clients can only be verified over TLS
+	invalidConfig = newTestConfig()
+	invalidConfig.TLSClientCAFile = config.TLSClientCAFile
+	_, err = newTLSConfig(invalidConfig)
+	require.Error(t, err)
+}
+
+func TestServerTLS(t *testing.T) {
+	ca := certstest.NewCA(t, "ca")
+	clientCA := certstest.NewCA(t, "client-ca")
+
+	testCases := []struct {
+		name        string
+		clientCerts []tls.Certificate
+		actor       string
+	}{
+		{
+			name:  "Anonymous",
+			actor: audit.Anonymous,
+		},
+		{
+			name:        "InternalCaller",
+			clientCerts: []tls.Certificate{clientCA.IssueTLS(t, "payments")},
+			actor:       "internal:payments",
+		},
+		{
+			name:        "ClientOfOtherCA",
+			clientCerts: []tls.Certificate{ca.IssueTLS(t, "payments")},
+			actor:       audit.Anonymous,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var actor audit.Actor
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				ListAccountProducts(gomock.Any()).
+				Times(1).
+				DoAndReturn(func(ctx context.Context) ([]db.AccountProduct, error) {
+					actor = audit.ActorFrom(ctx)
+					return []db.AccountProduct{}, nil
+				})
+
+			server, err := NewServer(newTestTLSConfig(t, ca, clientCA), store, nil)
+			require.NoError(t, err)
+
+			httpServer := httptest.NewUnstartedServer(server.router)
+			httpServer.TLS = server.tlsConfig
+			httpServer.StartTLS()
+			defer httpServer.Close()
+
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{RootCAs: ca.Pool(), Certificates: tc.clientCerts},
+				},
+			}
+
+			response, err := client.Get(httpServer.URL + "/account_products")
+			require.NoError(t, err)
+			defer response.Body.Close()
+
+			require.Equal(t, http.StatusOK, response.StatusCode)
+			require.Equal(t, "bank", response.TLS.PeerCertificates[0].Subject.CommonName)
+			require.Equal(t, tc.actor, actor.Name)
+		})
+	}
+}
+
+func TestNewInternalCallers(t *testing.T) {
+	config := newTestConfig()
+
+	internal, err := newInternalCallers(config)
+	require.NoError(t, err)
+	require.Nil(t, internal)
+
+	config.TLSInternalSubjects = "payments, reporting,"
+	config.TLSClientCAFile = "client-ca.crt"
+	config.TLSInternalRole = bank.RoleSupport
+	internal, err = newInternalCallers(config)
+	require.NoError(t, err)
+	require.Equal(t, map[string]bool{"payments": true, "reporting": true}, internal.subjects)
+	require.Equal(t, bank.RoleSupport, internal.role)
+
+	invalidConfig := config
+	invalidConfig.TLSInternalRole = ""
+	_, err = newInternalCallers(invalidConfig)
+	require.Error(t, err)
+
+	// Subjects are only verified with a client CA
+	invalidConfig = config
+	invalidConfig.TLSClientCAFile = ""
+	_, err = newInternalCallers(invalidConfig)
+	require.Error(t, err)
+}
+
+func TestServerTLSInternalCaller(t *testing.T) {
+	ca := certstest.NewCA(t, "ca")
+	clientCA := certstest.NewCA(t, "client-ca")
+
+	account := randomAccount("user")
+
+	testCases := []struct {
+		name          string
+		role          string
+		clientCerts   []tls.Certificate
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		getAccount    int
+		checkResponse func(t *testing.T, response *http.Response, actor audit.Actor)
+	}{
+		{
+			name:        "InternalCaller",
+			role:        bank.RoleSupport,
+			clientCerts: []tls.Certificate{clientCA.IssueTLS(t, "payments")},
+			setupAuth:   func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			getAccount:  1,
+			checkResponse: func(t *testing.T, response *http.Response, actor audit.Actor) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+				require.Equal(t, "internal:payments", actor.Name)
+			},
+		},
+		{
+			name:        "InternalCallerWithoutPermission",
+			role:        bank.RoleCustomer,
+			clientCerts: []tls.Certificate{clientCA.IssueTLS(t, "payments")},
+			setupAuth:   func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			getAccount:  1,
+			checkResponse: func(t *testing.T, response *http.Response, actor audit.Actor) {
+				require.Equal(t, http.StatusForbidden, response.StatusCode)
+			},
+		},
+		{
+			name:        "UnlistedSubject",
+			role:        bank.RoleSupport,
+			clientCerts: []tls.Certificate{clientCA.IssueTLS(t, "reporting")},
+			setupAuth:   func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			checkResponse: func(t *testing.T, response *http.Response, actor audit.Actor) {
+				require.Equal(t, http.StatusUnauthorized, response.StatusCode)
+			},
+		},
+		{
+			name:        "UserOfInternalCaller",
+			role:        bank.RoleSupport,
+			clientCerts: []tls.Certificate{clientCA.IssueTLS(t, "payments")},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, account.Owner, bank.RoleCustomer, time.Minute)
+			},
+			getAccount: 1,
+			checkResponse: func(t *testing.T, response *http.Response, actor audit.Actor) {
+				require.Equal(t, http.StatusOK, response.StatusCode)
+				require.Equal(t, account.Owner, actor.Name)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var actor audit.Actor
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				GetAccount(gomock.Any(), gomock.Eq(account.ID)).
+				Times(tc.getAccount).
+				DoAndReturn(func(ctx context.Context, id int64) (db.Account, error) {
+					actor = audit.ActorFrom(ctx)
+					return account, nil
+				})
+			store.EXPECT().GetHeldAmount(gomock.Any(), gomock.Eq(account.ID)).AnyTimes().Return(int64(0), nil)
+			acceptAccessTokens(store)
+
+			config := newTestTLSConfig(t, ca, clientCA)
+			config.TLSInternalSubjects = "payments"
+			config.TLSInternalRole = tc.role
+
+			server, err := NewServer(config, store, balance.NewHub(store, 2))
+			require.NoError(t, err)
+
+			httpServer := httptest.NewUnstartedServer(server.router)
+			httpServer.TLS = server.tlsConfig
+			httpServer.StartTLS()
+			defer httpServer.Close()
+
+			client := &http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{RootCAs: ca.Pool(), Certificates: tc.clientCerts},
+				},
+			}
+
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/accounts/%d", httpServer.URL, account.ID), nil)
+			require.NoError(t, err)
+			tc.setupAuth(t, request, server.tokenMaker)
+
+			response, err := client.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+
+			tc.checkResponse(t, response, actor)
+		})
+	}
+}
+
+func TestLoadConfigTLS(t *testing.T) {
+	ca := certstest.NewCA(t, "ca")
+	clientCA := certstest.NewCA(t, "client-ca")
+	tlsConfig := newTestTLSConfig(t, ca, clientCA)
+
+	// TLS and internal callers are turned on by the environment alone
+	t.Setenv("TLS_CERT_FILE", tlsConfig.TLSCertFile)
+	t.Setenv("TLS_KEY_FILE", tlsConfig.TLSKeyFile)
+	t.Setenv("TLS_CLIENT_CA_FILE", tlsConfig.TLSClientCAFile)
+	t.Setenv("TLS_INTERNAL_SUBJECTS", "payments")
+	t.Setenv("TLS_INTERNAL_ROLE", bank.RoleSupport)
+
+	config, err := util.LoadConfig(t.TempDir())
+	require.NoError(t, err)
+
+	serverTLS, err := newTLSConfig(config)
+	require.NoError(t, err)
+	require.NotNil(t, serverTLS)
+
+	internal, err := newInternalCallers(config)
+	require.NoError(t, err)
+	require.NotNil(t, internal)
+	require.True(t, internal.subjects["payments"])
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRenewAccessTokenAPI(t *testing.T) {
+	username := random.Owner()
+
+	testCases := []struct {
+		name          string
+		tokenType     token.TokenType
+		session       func(payload *token.Payload, refreshToken string) db.Session
+		checkResponse func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker)
+	}{
+		{
+			name:      "OK",
+			tokenType: token.TypeRefresh,
+			session: func(payload *token.Payload, refreshToken string) db.Session {
+				return db.Session{ID: payload.ID, Username: username, RefreshToken: refreshToken, ExpiresAt: payload.ExpiredAt}
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got renewAccessTokenResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+
+				payload, err := tokenMaker.VerifyToken(got.AccessToken, token.TypeAccess)
+				require.NoError(t, err)
+				require.Equal(t, username, payload.Username)
+				require.Equal(t, bank.RoleSupport, payload.Role)
+			},
+		},
+		{
+			name:      "BlockedSession",
+			tokenType: token.TypeRefresh,
+			session: func(payload *token.Payload, refreshToken string) db.Session {
+				return db.Session{ID: payload.ID, Username: username, RefreshToken: refreshToken, IsBlocked: true, ExpiresAt: payload.ExpiredAt}
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "OtherRefreshToken",
+			tokenType: token.TypeRefresh,
+			session: func(payload *token.Payload, refreshToken string) db.Session {
+				return db.Session{ID: payload.ID, Username: username, RefreshToken: "other", ExpiresAt: payload.ExpiredAt}
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "ExpiredSession",
+			tokenType: token.TypeRefresh,
+			session: func(payload *token.Payload, refreshToken string) db.Session {
+				return db.Session{ID: payload.ID, Username: username, RefreshToken: refreshToken, ExpiresAt: time.Now().Add(-time.Minute)}
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:      "NotFound",
+			tokenType: token.TypeRefresh,
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:      "AccessToken",
+			tokenType: token.TypeAccess,
+			checkResponse: func(recorder *httptest.ResponseRecorder, tokenMaker token.Maker) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			refreshToken, payload, err := server.tokenMaker.CreateToken(username, bank.RoleCustomer, tc.tokenType, time.Hour)
+			require.NoError(t, err)
+
+			switch {
+			case tc.tokenType != token.TypeRefresh:
+				store.EXPECT().GetSession(gomock.Any(), gomock.Any()).Times(0)
+			case tc.session == nil:
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(db.Session{}, db.ErrRecordNotFound)
+			default:
+				store.EXPECT().GetSession(gomock.Any(), gomock.Eq(payload.ID)).Times(1).Return(tc.session(payload, refreshToken), nil)
+				// The role changed since the session started
+				store.EXPECT().GetUser(gomock.Any(), gomock.Eq(username)).AnyTimes().Return(db.User{Username: username, Role: bank.RoleSupport}, nil)
+			}
+
+			data, err := json.Marshal(gin.H{"refresh_token": refreshToken})
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/tokens/renew_access", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder, server.tokenMaker)
+		})
+	}
+}
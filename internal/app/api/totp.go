@@ -0,0 +1,321 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/totp"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes given when TOTP is enabled
+	recoveryCodeCount = 10
+	// recoveryCodeLength is the length of a recovery code
+	recoveryCodeLength = 12
+)
+
+var (
+	errInvalidTOTPCode = errors.New("invalid or already used code")
+	errTOTPNotEnabled  = errors.New("two-factor authentication is not enabled")
+)
+
+// verifyTOTP accepts a recovery code of the user once, or else a code of the enabled TOTP of the user once,
+// by using up its time step.
+func (server *Server) verifyTOTP(ctx *gin.Context, userTOTP db.UserTotp, code string, recoveryCode string) error {
+	if !userTOTP.IsEnabled {
+		return errTOTPNotEnabled
+	}
+
+	if recoveryCode != "" {
+		used, err := server.bank.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			Username: userTOTP.Username,
+			CodeHash: password.HashToken(recoveryCode),
+		})
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return errInvalidTOTPCode
+		}
+		return nil
+	}
+
+	secret, err := server.cipher.Decrypt(userTOTP.EncryptedSecret, []byte(userTOTP.Username))
+	if err != nil {
+		return err
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now())
+	if !ok {
+		return errInvalidTOTPCode
+	}
+
+	used, err := server.bank.UseUserTOTPStep(ctx, db.UseUserTOTPStepParams{
+		Username:     userTOTP.Username,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return errInvalidTOTPCode
+	}
+
+	return nil
+}
+
+// totpErrorResponse maps errors from verifying a code to a response.
+func totpErrorResponse(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errInvalidTOTPCode), errors.Is(err, errTOTPNotEnabled):
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	default:
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
+
+type enrollTOTPResponse struct {
+	// Base32 encoded secret, for authenticator apps that can not read the URI
+	Secret string `json:"secret"`
+	// otpauth URI, usually shown as a QR code
+	URI string `json:"uri"`
+}
+
+func (server *Server) enrollTOTP(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeSelf(ctx, uri.Username) {
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	encryptedSecret, err := server.cipher.Encrypt([]byte(secret), []byte(uri.Username))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.bank.EnrollTOTP(ctx, bank.EnrollTOTPParams{
+		Username:        uri.Username,
+		EncryptedSecret: encryptedSecret,
+	})
+	if err != nil {
+		if errors.Is(err, bank.ErrTOTPEnabled) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enrollTOTPResponse{
+		Secret: secret,
+		URI:    totp.URI(server.config.TOTPIssuer, uri.Username, secret),
+	})
+}
+
+type enableTOTPRequest struct {
+	// First code of the enrolled secret
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type enableTOTPResponse struct {
+	// Single-use codes replacing a code at login, only shown this once
+	RecoveryCodes []string  `json:"recovery_codes"`
+	EnabledAt     time.Time `json:"enabled_at"`
+}
+
+func (server *Server) enableTOTP(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req enableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeSelf(ctx, uri.Username) {
+		return
+	}
+
+	userTOTP, err := server.bank.GetUserTOTP(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusForbidden, errorResponse(bank.ErrTOTPNotEnrolled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	secret, err := server.cipher.Decrypt(userTOTP.EncryptedSecret, []byte(userTOTP.Username))
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	step, ok := totp.Validate(string(secret), req.Code, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidTOTPCode))
+		return
+	}
+
+	recoveryCodes := make([]string, recoveryCodeCount)
+	recoveryCodeHashes := make([]string, recoveryCodeCount)
+	for i := range recoveryCodes {
+		recoveryCodes[i] = random.String(recoveryCodeLength)
+		recoveryCodeHashes[i] = password.HashToken(recoveryCodes[i])
+	}
+
+	userTOTP, err = server.bank.EnableTOTP(ctx, bank.EnableTOTPParams{
+		Username:           uri.Username,
+		EncryptedSecret:    userTOTP.EncryptedSecret,
+		Step:               step,
+		RecoveryCodeHashes: recoveryCodeHashes,
+	})
+	if err != nil {
+		if errors.Is(err, bank.ErrTOTPEnabled) || errors.Is(err, bank.ErrTOTPNotEnrolled) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, enableTOTPResponse{
+		RecoveryCodes: recoveryCodes,
+		EnabledAt:     userTOTP.EnabledAt.Time,
+	})
+}
+
+type disableTOTPRequest struct {
+	// A code or a recovery code is required unless an admin disables TOTP of another user
+	Code         string `json:"code" binding:"omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func (server *Server) disableTOTP(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req disableTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeOwner(ctx, uri.Username, permUpdateAnyUser) {
+		return
+	}
+
+	userTOTP, err := server.bank.GetUserTOTP(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// An enrolled secret that is not enabled yet protects nothing
+	if userTOTP.IsEnabled && uri.Username == authPayload(ctx).Username {
+		if err := server.verifyTOTP(ctx, userTOTP, req.Code, req.RecoveryCode); err != nil {
+			totpErrorResponse(ctx, err)
+			return
+		}
+	}
+
+	if err := server.bank.DisableTOTP(ctx, uri.Username); err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type loginUserTOTPRequest struct {
+	MFAToken     string `json:"mfa_token" binding:"required"`
+	Code         string `json:"code" binding:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+// loginUserTOTP completes the login of a user with TOTP enabled, who proved the password with the MFA token
+// of the login, with a code or a recovery code.
+func (server *Server) loginUserTOTP(ctx *gin.Context) {
+	var req loginUserTOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	payload, err := server.tokenMaker.VerifyToken(req.MFAToken, token.TypeMFA)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return
+	}
+
+	// Codes are guessed more easily than passwords, so they are throttled alike
+	if !server.checkLogin(ctx, payload.Username) {
+		return
+	}
+
+	user, err := server.bank.GetUser(ctx, payload.Username)
+	if err != nil {
+		// The user was deleted since the password was checked
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	userTOTP, err := server.bank.GetUserTOTP(ctx, user.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errTOTPNotEnabled))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.verifyTOTP(ctx, userTOTP, req.Code, req.RecoveryCode); err != nil {
+		if errors.Is(err, errInvalidTOTPCode) {
+			server.loginFailureResponse(ctx, user.Username, http.StatusUnauthorized, err)
+			return
+		}
+		totpErrorResponse(ctx, err)
+		return
+	}
+
+	server.startSession(ctx, user)
+}
@@ -0,0 +1,788 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/encrypt"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/totp"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// randomUserTOTP returns an enabled TOTP of the user with its secret, encrypted with the cipher
+func randomUserTOTP(t *testing.T, cipher *encrypt.Cipher, username string) (db.UserTotp, string) {
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+
+	encryptedSecret, err := cipher.Encrypt([]byte(secret), []byte(username))
+	require.NoError(t, err)
+
+	return db.UserTotp{
+		Username:        username,
+		EncryptedSecret: encryptedSecret,
+		IsEnabled:       true,
+		LastUsedStep:    totp.Step(time.Now()) - 10,
+	}, secret
+}
+
+// totpCode returns the code of the secret steps away from the current time step
+func totpCode(t *testing.T, secret string, steps int64) string {
+	code, err := totp.Code(secret, totp.Step(time.Now())+steps)
+	require.NoError(t, err)
+	return code
+}
+
+func newTestCipher(t *testing.T) *encrypt.Cipher {
+	cipher, err := encrypt.NewCipher(random.String(encrypt.KeySize))
+	require.NoError(t, err)
+	return cipher
+}
+
+func TestEnrollTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	// Secret stored by the bank
+	var encryptedSecret []byte
+
+	testCases := []struct {
+		name          string
+		username      string
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(t *testing.T, server *Server, recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:     "OK",
+			username: user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					EnrollTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.EnrollTOTPParams) (db.UserTotp, error) {
+						encryptedSecret = arg.EncryptedSecret
+						return db.UserTotp{Username: arg.Username, EncryptedSecret: arg.EncryptedSecret}, nil
+					})
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got enrollTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.NotEmpty(t, got.Secret)
+				require.True(t, strings.HasPrefix(got.URI, "otpauth://totp/"))
+				require.Contains(t, got.URI, "secret="+got.Secret)
+				require.Contains(t, got.URI, user.Username)
+
+				// Stored encrypted, authenticated with the username
+				secret, err := server.cipher.Decrypt(encryptedSecret, []byte(user.Username))
+				require.NoError(t, err)
+				require.Equal(t, got.Secret, string(secret))
+			},
+		},
+		{
+			name:     "AlreadyEnabled",
+			username: user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					EnrollTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, bank.ErrTOTPEnabled)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			// Not even admins enroll authenticators of other users
+			name:     "OtherUser",
+			username: user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					EnrollTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			username:  user.Username,
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					EnrollTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, server *Server, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			url := fmt.Sprintf("/users/%s/totp", tc.username)
+			request, err := http.NewRequest(http.MethodPost, url, nil)
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(t, server, recorder)
+		})
+	}
+}
+
+func TestEnableTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	cipher := newTestCipher(t)
+	enrolled, secret := randomUserTOTP(t, cipher, user.Username)
+	enrolled.IsEnabled = false
+	enrolled.LastUsedStep = 0
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"code": totpCode(t, secret, 0)},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(enrolled, nil)
+				store.EXPECT().
+					EnableTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.EnableTOTPParams) (db.UserTotp, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.Equal(t, enrolled.EncryptedSecret, arg.EncryptedSecret)
+						require.InDelta(t, totp.Step(time.Now()), arg.Step, totp.Skew)
+						require.Len(t, arg.RecoveryCodeHashes, recoveryCodeCount)
+
+						enabled := enrolled
+						enabled.IsEnabled = true
+						enabled.EnabledAt.Time, enabled.EnabledAt.Valid = time.Now(), true
+						return enabled, nil
+					})
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got enableTOTPResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Len(t, got.RecoveryCodes, recoveryCodeCount)
+				require.Len(t, got.RecoveryCodes[0], recoveryCodeLength)
+				require.False(t, got.EnabledAt.IsZero())
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: gin.H{"code": totpCode(t, secret, -5)},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(enrolled, nil)
+				store.EXPECT().
+					EnableTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "NotEnrolled",
+			body: gin.H{"code": totpCode(t, secret, 0)},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					EnableTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			// The secret was enrolled again after the code was validated
+			name: "EnrolledAgain",
+			body: gin.H{"code": totpCode(t, secret, 0)},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(enrolled, nil)
+				store.EXPECT().
+					EnableTOTP(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(db.UserTotp{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrTOTPNotEnrolled))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCodeFormat",
+			body: gin.H{"code": "12ab56"},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.cipher = cipher
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/users/%s/totp/enable", user.Username)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			addAuthorization(t, request, server.tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestDisableTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	cipher := newTestCipher(t)
+	userTOTP, secret := randomUserTOTP(t, cipher, user.Username)
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"code": totpCode(t, secret, 0)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg db.UseUserTOTPStepParams) (int64, error) {
+						require.Equal(t, user.Username, arg.Username)
+						require.InDelta(t, totp.Step(time.Now()), arg.LastUsedStep, totp.Skew)
+						return 1, nil
+					})
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "OKRecoveryCode",
+			body: gin.H{"recovery_code": "recoverycode"},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{Username: user.Username, CodeHash: password.HashToken("recoverycode")})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			body: gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "UsedCode",
+			body: gin.H{"code": totpCode(t, secret, 0)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// Admins disable TOTP of users who lost their authenticator and recovery codes
+			name: "AdminWithoutCode",
+			body: gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "admin", bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNoContent, recorder.Code)
+			},
+		},
+		{
+			name: "OtherCustomer",
+			body: gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, "other", bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "NotFound",
+			body: gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					DisableTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.cipher = cipher
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/users/%s/totp", user.Username)
+			request, err := http.NewRequest(http.MethodDelete, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestLoginUserTOTPAPI(t *testing.T) {
+	user, _ := randomUser(t)
+
+	cipher := newTestCipher(t)
+	userTOTP, secret := randomUserTOTP(t, cipher, user.Username)
+
+	createSession := func(_ context.Context, arg db.CreateSessionParams) (db.Session, error) {
+		return db.Session{ID: arg.ID, Username: arg.Username, RefreshToken: arg.RefreshToken, ExpiresAt: arg.ExpiresAt}, nil
+	}
+
+	testCases := []struct {
+		name          string
+		body          func(t *testing.T, tokenMaker token.Maker) gin.H
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute), "code": totpCode(t, secret, 0)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(createSession)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got loginUserResponse
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.NotEmpty(t, got.AccessToken)
+				require.NotEmpty(t, got.RefreshToken)
+				require.Equal(t, user.Username, got.User.Username)
+			},
+		},
+		{
+			name: "OKRecoveryCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute), "recovery_code": "recoverycode"}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Eq(db.UseRecoveryCodeParams{Username: user.Username, CodeHash: password.HashToken("recoverycode")})).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					ResetLoginThrottle(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(createSession)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute), "code": totpCode(t, secret, -5)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.RecordLoginFailureParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						return nil
+					})
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// The user was deleted since the password was checked
+			name: "UserNotFound",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute), "code": totpCode(t, secret, 0)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(db.User{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+				require.Contains(t, recorder.Body.String(), errInvalidCredentials.Error())
+			},
+		},
+		{
+			name: "UsedRecoveryCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute), "recovery_code": "recoverycode"}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(user, nil)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					UseRecoveryCode(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.RecordLoginFailureParams) error {
+						require.Equal(t, user.Username, arg.Username)
+						return nil
+					})
+				store.EXPECT().
+					CreateSession(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "Throttled",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute), "code": totpCode(t, secret, 0)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&bank.LoginThrottledError{Scope: bank.LoginScopeUsername, RetryAfter: 4 * time.Second})
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+				require.Equal(t, "4", recorder.Header().Get("Retry-After"))
+			},
+		},
+		{
+			// An access token does not prove the password
+			name: "AccessToken",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeAccess, time.Minute), "code": totpCode(t, secret, 0)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "ExpiredMFAToken",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, -time.Minute), "code": totpCode(t, secret, 0)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "MissingCode",
+			body: func(t *testing.T, tokenMaker token.Maker) gin.H {
+				return gin.H{"mfa_token": mfaToken(t, tokenMaker, user.Username, token.TypeMFA, time.Minute)}
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetUser(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.cipher = cipher
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body(t, server.tokenMaker))
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/users/login/totp", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func mfaToken(t *testing.T, tokenMaker token.Maker, username string, tokenType token.TokenType, duration time.Duration) string {
+	mfaToken, _, err := tokenMaker.CreateToken(username, bank.RoleCustomer, tokenType, duration)
+	require.NoError(t, err)
+	return mfaToken
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+type createTransferRequest struct {
+	FromAccountID int64 `json:"from_account_id" binding:"required,min=1"`
+	ToAccountID   int64 `json:"to_account_id" binding:"required,min=1,nefield=FromAccountID"`
+	Amount        int64 `json:"amount" binding:"required,gt=0"`
+	// Code of the TOTP of the user, required for amounts above the TOTP threshold
+	TOTPCode string `json:"totp_code" binding:"omitempty,len=6,numeric"`
+}
+
+// createTransfer moves money from an account of the authenticated user. Transfers of amounts above the
+// TOTP threshold require a code of the enabled TOTP of the user, each code is accepted once.
+func (server *Server) createTransfer(ctx *gin.Context) {
+	var req createTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	fromAccount, ok := server.getTransferAccount(ctx, req.FromAccountID)
+	if !ok {
+		return
+	}
+
+	// No role may move money of other users
+	if !authorizeSelf(ctx, fromAccount.Owner) {
+		return
+	}
+
+	toAccount, ok := server.getTransferAccount(ctx, req.ToAccountID)
+	if !ok {
+		return
+	}
+
+	if fromAccount.Currency != toAccount.Currency {
+		ctx.JSON(http.StatusForbidden, errorResponse(bank.ErrCurrencyMismatch))
+		return
+	}
+
+	if !server.checkTransferTOTP(ctx, fromAccount.Owner, req.Amount, req.TOTPCode) {
+		return
+	}
+
+	result, err := server.bank.Transfer(ctx, bank.TransferParams{
+		FromAccountID: req.FromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, bank.ErrInsufficientFunds),
+			errors.Is(err, bank.ErrAccountFrozen),
+			errors.Is(err, bank.ErrAccountClosed),
+			errors.Is(err, bank.ErrWithdrawalLimit):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		case errors.Is(err, bank.ErrLimitExceeded):
+			limitErrorResponse(ctx, err)
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// limitErrorResponse responds to a transfer breaking a transfer limit with forbidden, telling the broken limit,
+// its maximum and the amount remaining within it.
+func limitErrorResponse(ctx *gin.Context, err error) {
+	var limitErr *bank.LimitError
+	if !errors.As(err, &limitErr) {
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusForbidden, gin.H{
+		"error":     err.Error(),
+		"limit":     limitErr.Limit,
+		"max":       limitErr.Max,
+		"remaining": limitErr.Remaining,
+	})
+}
+
+// checkTransferTOTP responds with an error unless the amount is within the TOTP threshold or the code is
+// accepted by the enabled TOTP of the owner. Wrong codes count as failed logins, so guessing is throttled.
+func (server *Server) checkTransferTOTP(ctx *gin.Context, owner string, amount int64, code string) bool {
+	if amount <= server.config.TransferTOTPThreshold {
+		return true
+	}
+
+	userTOTP, err := server.bank.GetUserTOTP(ctx, owner)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	if !userTOTP.IsEnabled {
+		err := fmt.Errorf("transfers above %d require two-factor authentication: %w", server.config.TransferTOTPThreshold, errTOTPNotEnabled)
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+		return false
+	}
+
+	if code == "" {
+		err := fmt.Errorf("totp_code is required for transfers above %d", server.config.TransferTOTPThreshold)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+		return false
+	}
+
+	if !server.checkLogin(ctx, owner) {
+		return false
+	}
+
+	if err := server.verifyTOTP(ctx, userTOTP, code, ""); err != nil {
+		if errors.Is(err, errInvalidTOTPCode) {
+			server.loginFailureResponse(ctx, owner, http.StatusUnauthorized, err)
+			return false
+		}
+		totpErrorResponse(ctx, err)
+		return false
+	}
+
+	return true
+}
+
+// getTransferAccount returns an account of a transfer, responding with not found unless it exists.
+func (server *Server) getTransferAccount(ctx *gin.Context, accountID int64) (db.Account, bool) {
+	account, err := server.bank.GetAccount(ctx, accountID)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return account, false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return account, false
+	}
+
+	return account, true
+}
+
+type transferURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+type reverseTransferRequest struct {
+	// Amount to refund, zero or omitted refunds the remaining reversible amount
+	Amount int64  `json:"amount" binding:"min=0"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// reverseTransfer refunds a transfer, by staff of a role permitted to reverse transfers of any user.
+func (server *Server) reverseTransfer(ctx *gin.Context) {
+	var uri transferURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req reverseTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	arg := bank.ReverseTransferParams{
+		TransferID: uri.ID,
+		Amount:     req.Amount,
+		Reason:     req.Reason,
+	}
+
+	result, err := server.bank.ReverseTransfer(ctx, arg)
+	if err != nil {
+		switch {
+		case errors.Is(err, db.ErrRecordNotFound):
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+		case errors.Is(err, bank.ErrTransferReversed),
+			errors.Is(err, bank.ErrReverseReversal),
+			errors.Is(err, bank.ErrReversalExceedsRemaining),
+			errors.Is(err, bank.ErrAccountFrozen),
+			errors.Is(err, bank.ErrAccountClosed):
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+		default:
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
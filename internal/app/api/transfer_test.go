@@ -0,0 +1,664 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCreateTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.ID = account1.ID + 1
+	account2.Currency = account1.Currency
+
+	otherCurrency := randomAccount(user2.Username)
+	otherCurrency.ID = account1.ID + 2
+	for otherCurrency.Currency == account1.Currency {
+		otherCurrency.Currency = random.Currency()
+	}
+
+	cipher := newTestCipher(t)
+	userTOTP, secret := randomUserTOTP(t, cipher, user1.Username)
+
+	// Amounts up to the threshold of the test server need no code
+	const threshold = 1000
+
+	result := bank.TransferResult{
+		Transfer:    db.Transfer{ID: 1, FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: threshold},
+		FromAccount: account1,
+		ToAccount:   account2,
+	}
+
+	getAccounts := func(store *mockdb.MockBank) {
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+		store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(account2, nil)
+	}
+
+	testCases := []struct {
+		name          string
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(store *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name: "OK",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Eq(bank.TransferParams{FromAccountID: account1.ID, ToAccountID: account2.ID, Amount: threshold})).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+
+				var got bank.TransferResult
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Equal(t, result.Transfer, got.Transfer)
+			},
+		},
+		{
+			name: "OKAboveThreshold",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold + 1, "totp_code": totpCode(t, secret, 0)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(1), nil)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name: "MissingTOTPCode",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold + 1},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name: "InvalidTOTPCode",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold + 1, "totp_code": totpCode(t, secret, -5)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.RecordLoginFailureParams) error {
+						require.Equal(t, user1.Username, arg.Username)
+						return nil
+					})
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// The code was accepted before, e.g. by a replayed request
+			name: "UsedTOTPCode",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold + 1, "totp_code": totpCode(t, secret, 0)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(nil)
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(int64(0), nil)
+				store.EXPECT().
+					RecordLoginFailure(gomock.Any(), gomock.Any()).
+					Times(1).
+					DoAndReturn(func(_ context.Context, arg bank.RecordLoginFailureParams) error {
+						require.Equal(t, user1.Username, arg.Username)
+						return nil
+					})
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			// Wrong codes count as failed logins, guessing is locked out alike
+			name: "TOTPThrottled",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold + 1, "totp_code": totpCode(t, secret, 0)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(userTOTP, nil)
+				store.EXPECT().
+					CheckLogin(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(&bank.LoginThrottledError{Scope: bank.LoginScopeUsername, RetryAfter: 4 * time.Second})
+				store.EXPECT().
+					UseUserTOTPStep(gomock.Any(), gomock.Any()).
+					Times(0)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusTooManyRequests, recorder.Code)
+			},
+		},
+		{
+			name: "TOTPNotEnabled",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold + 1, "totp_code": totpCode(t, secret, 0)},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					GetUserTOTP(gomock.Any(), gomock.Eq(user1.Username)).
+					Times(1).
+					Return(db.UserTotp{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			// Not even admins move money of other users
+			name: "UnauthorizedUser",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user2.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "CurrencyMismatch",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": otherCurrency.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(otherCurrency.ID)).Times(1).Return(otherCurrency, nil)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "ToAccountNotFound",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account1.ID)).Times(1).Return(account1, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account2.ID)).Times(1).Return(db.Account{}, db.ErrRecordNotFound)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name: "InsufficientFunds",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.TransferResult{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrInsufficientFunds))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "LimitExceeded",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				getAccounts(store)
+				store.EXPECT().
+					Transfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.TransferResult{}, fmt.Errorf("exec tx:transaction err: %w", &bank.LimitError{Limit: bank.LimitUserMonthly, Max: 5000, Remaining: 400}))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+
+				// The client is told which limit was broken and how much is left
+				var got struct {
+					Error string `json:"error"`
+					bank.LimitError
+				}
+				require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &got))
+				require.Contains(t, got.Error, bank.ErrLimitExceeded.Error())
+				require.Equal(t, bank.LimitError{Limit: bank.LimitUserMonthly, Max: 5000, Remaining: 400}, got.LimitError)
+			},
+		},
+		{
+			name: "SameAccount",
+			body: gin.H{"from_account_id": account1.ID, "to_account_id": account1.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:      "NoAuthorization",
+			body:      gin.H{"from_account_id": account1.ID, "to_account_id": account2.ID, "amount": threshold},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			server.cipher = cipher
+			require.EqualValues(t, threshold, server.config.TransferTOTPThreshold)
+			recorder := httptest.NewRecorder()
+
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			request, err := http.NewRequest(http.MethodPost, "/transfers", bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func TestReverseTransferAPI(t *testing.T) {
+	user1, _ := randomUser(t)
+	user2, _ := randomUser(t)
+	staff, _ := randomUser(t)
+	account1 := randomAccount(user1.Username)
+	account2 := randomAccount(user2.Username)
+	account2.Currency = account1.Currency
+
+	original := randomTransfer(account1, account2)
+	reversal := db.Transfer{
+		ID:             original.ID + 1,
+		FromAccountID:  account2.ID,
+		ToAccountID:    account1.ID,
+		Amount:         original.Amount,
+		ReversalOf:     pgtype.Int8{Int64: original.ID, Valid: true},
+		ReversalReason: "wrong recipient",
+	}
+
+	result := bank.ReverseTransferResult{
+		TransferResult: bank.TransferResult{
+			Transfer:    reversal,
+			FromAccount: account2,
+			ToAccount:   account1,
+		},
+		OriginalTransfer: original,
+	}
+
+	testCases := []struct {
+		name          string
+		transferID    int64
+		body          gin.H
+		setupAuth     func(t *testing.T, request *http.Request, tokenMaker token.Maker)
+		buildStubs    func(bank *mockdb.MockBank)
+		checkResponse func(recoder *httptest.ResponseRecorder)
+	}{
+		{
+			name:       "OK",
+			transferID: original.ID,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := bank.ReverseTransferParams{
+					TransferID: original.ID,
+					Reason:     reversal.ReversalReason,
+				}
+
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+				requireBodyMatchReversal(t, recorder.Body, result)
+			},
+		},
+		{
+			name:       "PartialRefund",
+			transferID: original.ID,
+			body: gin.H{
+				"amount": 1,
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				arg := bank.ReverseTransferParams{
+					TransferID: original.ID,
+					Amount:     1,
+					Reason:     reversal.ReversalReason,
+				}
+
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Eq(arg)).
+					Times(1).
+					Return(result, nil)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusOK, recorder.Code)
+			},
+		},
+		{
+			name:       "NotFound",
+			transferID: original.ID,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.ReverseTransferResult{}, db.ErrRecordNotFound)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
+		{
+			name:       "AlreadyReversed",
+			transferID: original.ID,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(1).
+					Return(bank.ReverseTransferResult{}, fmt.Errorf("exec tx:transaction err: %w", bank.ErrTransferReversed))
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "MissingReason",
+			transferID: original.ID,
+			body:       gin.H{},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "InvalidID",
+			transferID: 0,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleAdmin, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusBadRequest, recorder.Code)
+			},
+		},
+		{
+			name:       "NoAuthorization",
+			transferID: original.ID,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusUnauthorized, recorder.Code)
+			},
+		},
+		{
+			name:       "Customer",
+			transferID: original.ID,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				// Customers may not reverse transfers, not even their own
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, user1.Username, bank.RoleCustomer, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name:       "Support",
+			transferID: original.ID,
+			body: gin.H{
+				"reason": reversal.ReversalReason,
+			},
+			setupAuth: func(t *testing.T, request *http.Request, tokenMaker token.Maker) {
+				addAuthorization(t, request, tokenMaker, authorizationTypeBearer, staff.Username, bank.RoleSupport, time.Minute)
+			},
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().
+					ReverseTransfer(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			tc.buildStubs(store)
+
+			server := newTestServer(t, store)
+			recorder := httptest.NewRecorder()
+
+			// Marshal body data to JSON
+			data, err := json.Marshal(tc.body)
+			require.NoError(t, err)
+
+			url := fmt.Sprintf("/transfers/%d/reversal", tc.transferID)
+			request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+			require.NoError(t, err)
+
+			tc.setupAuth(t, request, server.tokenMaker)
+			server.router.ServeHTTP(recorder, request)
+			tc.checkResponse(recorder)
+		})
+	}
+}
+
+func randomTransfer(from, to db.Account) db.Transfer {
+	return db.Transfer{
+		ID:            random.Int(1000) + 1,
+		FromAccountID: from.ID,
+		ToAccountID:   to.ID,
+		Amount:        random.Int(1000) + 1,
+	}
+}
+
+func requireBodyMatchReversal(t *testing.T, body *bytes.Buffer, result bank.ReverseTransferResult) {
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+
+	var gotResult bank.ReverseTransferResult
+	err = json.Unmarshal(data, &gotResult)
+	require.NoError(t, err)
+	require.Equal(t, result, gotResult)
+}
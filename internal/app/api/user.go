@@ -1,17 +1,30 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/mail"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/token"
 )
 
+// verifyEmailCodeLength is the length of the secret code of a verification email
+const verifyEmailCodeLength = 32
+
+// errInvalidCredentials answers failed logins alike, whether the username exists or not
+var errInvalidCredentials = errors.New("invalid username or password")
+
 type createUserRequest struct {
 	Username string `json:"username" binding:"required,alphanum"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"`
 	FullName string `json:"full_name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
 }
@@ -20,6 +33,8 @@ type userResponse struct {
 	Username          string    `json:"username"`
 	FullName          string    `json:"full_name"`
 	Email             string    `json:"email"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	Role              string    `json:"role"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
 }
@@ -29,11 +44,29 @@ func newUserResponse(user db.User) userResponse {
 		Username:          user.Username,
 		FullName:          user.FullName,
 		Email:             user.Email,
+		IsEmailVerified:   user.IsEmailVerified,
+		Role:              user.Role,
 		PasswordChangedAt: user.PasswordChangedAt,
 		CreatedAt:         user.CreatedAt,
 	}
 }
 
+// enqueueVerifyEmail creates a verification email for the email of the user within the transaction of q. The
+// email is sent by a job, which only runs once the transaction is committed.
+func enqueueVerifyEmail(ctx context.Context, q db.Querier, user db.User) error {
+	verifyEmail, err := q.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+		Username:   user.Username,
+		Email:      user.Email,
+		SecretCode: random.String(verifyEmailCodeLength),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = mail.VerifyEmailTask.Enqueue(ctx, q, mail.VerifyEmailPayload{VerifyEmailID: verifyEmail.ID}, jobs.EnqueueOptions{})
+	return err
+}
+
 func (server *Server) createUser(ctx *gin.Context) {
 	var req createUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -41,21 +74,255 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := password.HashPassword(req.Password)
+	hashedPassword, ok := server.hashPassword(ctx, req.Password, password.Identity{Username: req.Username, Email: req.Email})
+	if !ok {
+		return
+	}
+
+	arg := bank.AddUserParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       req.Username,
+			HashedPassword: hashedPassword,
+			FullName:       req.FullName,
+			Email:          req.Email,
+		},
+		AfterCreate: enqueueVerifyEmail,
+	}
+
+	result, err := server.bank.AddUser(ctx, arg)
 	if err != nil {
+		if db.ErrorCode(err) == db.UniqueViolation {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
-	arg := db.CreateUserParams{
-		Username:       req.Username,
-		HashedPassword: hashedPassword,
-		FullName:       req.FullName,
-		Email:          req.Email,
+	rsp := newUserResponse(result.User)
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type loginUserRequest struct {
+	Username string `json:"username" binding:"required,alphanum"`
+	Password string `json:"password" binding:"required,min=6"`
+}
+
+type loginUserResponse struct {
+	SessionID             string       `json:"session_id"`
+	AccessToken           string       `json:"access_token"`
+	AccessTokenExpiresAt  time.Time    `json:"access_token_expires_at"`
+	RefreshToken          string       `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time    `json:"refresh_token_expires_at"`
+	User                  userResponse `json:"user"`
+}
+
+// loginMFAResponse asks a user with TOTP enabled to complete the login with a code, see loginUserTOTP
+type loginMFAResponse struct {
+	MFARequired       bool      `json:"mfa_required"`
+	MFAToken          string    `json:"mfa_token"`
+	MFATokenExpiresAt time.Time `json:"mfa_token_expires_at"`
+}
+
+func (server *Server) loginUser(ctx *gin.Context) {
+	var req loginUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.checkLogin(ctx, req.Username) {
+		return
+	}
+
+	user, err := server.bank.GetUser(ctx, req.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			// Takes as long as a wrong password, so the response time does not tell the username is unknown
+			_ = server.hasher.Check(req.Password, server.dummyHash)
+			server.loginFailureResponse(ctx, req.Username, http.StatusUnauthorized, errInvalidCredentials)
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err := server.hasher.Check(req.Password, user.HashedPassword); err != nil {
+		server.loginFailureResponse(ctx, req.Username, http.StatusUnauthorized, errInvalidCredentials)
+		return
+	}
+
+	if err := server.rehashPassword(ctx, user, req.Password); err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	userTOTP, err := server.bank.GetUserTOTP(ctx, user.Username)
+	if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if err == nil && userTOTP.IsEnabled {
+		mfaToken, mfaPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TypeMFA, server.config.MFATokenDuration)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+
+		ctx.JSON(http.StatusOK, loginMFAResponse{
+			MFARequired:       true,
+			MFAToken:          mfaToken,
+			MFATokenExpiresAt: mfaPayload.ExpiredAt,
+		})
+		return
+	}
+
+	server.startSession(ctx, user)
+}
+
+// startSession responds to a completed login of the user with the access and refresh tokens of a new session,
+// and forgets failed logins of the user. Failed logins of the client IP are kept, else a client guessing
+// passwords of others could clear them by logging in to an account of its own between guesses.
+func (server *Server) startSession(ctx *gin.Context, user db.User) {
+	err := server.bank.ResetLoginThrottle(ctx, user.Username)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	accessToken, accessPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TypeAccess, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	refreshToken, refreshPayload, err := server.tokenMaker.CreateToken(user.Username, user.Role, token.TypeRefresh, server.config.RefreshTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	session, err := server.bank.CreateSession(ctx, db.CreateSessionParams{
+		ID:           refreshPayload.ID,
+		Username:     user.Username,
+		RefreshToken: refreshToken,
+		UserAgent:    ctx.Request.UserAgent(),
+		ClientIp:     ctx.ClientIP(),
+		ExpiresAt:    refreshPayload.ExpiredAt,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, loginUserResponse{
+		SessionID:             session.ID,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessPayload.ExpiredAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: refreshPayload.ExpiredAt,
+		User:                  newUserResponse(user),
+	})
+}
+
+// passwordPolicyResponse responds to a new password breaking the password policy with bad request, listing
+// each broken rule.
+func passwordPolicyResponse(ctx *gin.Context, err error) {
+	var policyErr *password.PolicyError
+	if !errors.As(err, &policyErr) {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusBadRequest, gin.H{
+		"error":      err.Error(),
+		"violations": policyErr.Violations,
+	})
+}
+
+// hashPassword returns the hash of a new password of the user, responding with bad request when it breaks
+// the password policy or can not be hashed with the configured algorithm.
+func (server *Server) hashPassword(ctx *gin.Context, pwd string, user password.Identity) (string, bool) {
+	if err := server.passwordPolicy.Validate(pwd, user); err != nil {
+		passwordPolicyResponse(ctx, err)
+		return "", false
+	}
+
+	hashedPassword, err := server.hasher.Hash(pwd)
+	if err != nil {
+		if errors.Is(err, password.ErrPasswordTooLong) {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return "", false
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return "", false
+	}
+
+	return hashedPassword, true
+}
+
+// rehashPassword replaces a hash of the checked password of the user that is not of the configured
+// parameters, unless the password changed meanwhile. Passwords too long for the configured algorithm keep
+// their hash.
+func (server *Server) rehashPassword(ctx *gin.Context, user db.User, pwd string) error {
+	if !server.hasher.NeedsRehash(user.HashedPassword) {
+		return nil
+	}
+
+	hashedPassword, err := server.hasher.Hash(pwd)
+	if err != nil {
+		if errors.Is(err, password.ErrPasswordTooLong) {
+			return nil
+		}
+		return err
+	}
+
+	_, err = server.bank.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewHashedPassword: hashedPassword,
+		Username:          user.Username,
+		HashedPassword:    user.HashedPassword,
+	})
+	return err
+}
+
+type userURI struct {
+	Username string `uri:"username" binding:"required,alphanum"`
+}
+
+type updateUserRequest struct {
+	FullName string `json:"full_name" binding:"required_without=Email"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+func (server *Server) updateUser(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
 	}
 
-	user, err := server.bank.CreateUser(ctx, arg)
+	var req updateUserRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeOwner(ctx, uri.Username, permUpdateAnyUser) {
+		return
+	}
+
+	user, err := server.bank.UpdateProfile(ctx, bank.UpdateProfileParams{
+		Username:         uri.Username,
+		FullName:         optionalText(req.FullName),
+		Email:            optionalText(req.Email),
+		AfterEmailChange: enqueueVerifyEmail,
+	})
 	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
 		if db.ErrorCode(err) == db.UniqueViolation {
 			ctx.JSON(http.StatusForbidden, errorResponse(err))
 			return
@@ -64,6 +331,139 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	rsp := newUserResponse(user)
-	ctx.JSON(http.StatusOK, rsp)
+	ctx.JSON(http.StatusOK, newUserResponse(user))
+}
+
+type changePasswordRequest struct {
+	// Required unless an admin changes the password of another user
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type changePasswordResponse struct {
+	User            userResponse `json:"user"`
+	RevokedSessions int64        `json:"revoked_sessions"`
+}
+
+func (server *Server) changePassword(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !authorizeOwner(ctx, uri.Username, permUpdateAnyUser) {
+		return
+	}
+
+	user, err := server.bank.GetUser(ctx, uri.Username)
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.Username == authPayload(ctx).Username {
+		if err := server.hasher.Check(req.CurrentPassword, user.HashedPassword); err != nil {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+	}
+
+	hashedPassword, ok := server.hashPassword(ctx, req.NewPassword, password.Identity{Username: user.Username, Email: user.Email})
+	if !ok {
+		return
+	}
+
+	result, err := server.bank.ChangePassword(ctx, bank.ChangePasswordParams{
+		Username:       uri.Username,
+		HashedPassword: hashedPassword,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, changePasswordResponse{
+		User:            newUserResponse(result.User),
+		RevokedSessions: result.RevokedSessions,
+	})
+}
+
+type verifyEmailRequest struct {
+	ID   int64  `form:"id" binding:"required,min=1"`
+	Code string `form:"code" binding:"required,len=32"`
+}
+
+type verifyEmailResponse struct {
+	IsVerified bool `json:"is_verified"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	result, err := server.bank.VerifyEmail(ctx, bank.VerifyEmailParams{
+		ID:         req.ID,
+		SecretCode: req.Code,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		if errors.Is(err, bank.ErrInvalidVerifyEmail) || errors.Is(err, bank.ErrVerifyEmailExpired) {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, verifyEmailResponse{IsVerified: result.User.IsEmailVerified})
+}
+
+type setUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=customer support admin"`
+}
+
+func (server *Server) setUserRole(ctx *gin.Context) {
+	var uri userURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req setUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.bank.SetUserRole(ctx, bank.SetUserRoleParams{
+		Username: uri.Username,
+		Role:     req.Role,
+	})
+	if err != nil {
+		if errors.Is(err, db.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newUserResponse(user))
 }
@@ -0,0 +1,217 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/webhook"
+)
+
+// webhookSubscriptionResponse leaves out the secret, it is only shown when the subscription is created
+type webhookSubscriptionResponse struct {
+	ID         int64     `json:"id"`
+	Owner      string    `json:"owner"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func newWebhookSubscriptionResponse(subscription db.WebhookSubscription) webhookSubscriptionResponse {
+	return webhookSubscriptionResponse{
+		ID:         subscription.ID,
+		Owner:      subscription.Owner,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+		CreatedAt:  subscription.CreatedAt,
+	}
+}
+
+type createWebhookSubscriptionResponse struct {
+	webhookSubscriptionResponse
+	// Key of the signature of deliveries
+	Secret string `json:"secret"`
+}
+
+// createWebhookSubscriptionRequest subscribes the authenticated user, no role may subscribe for other users
+type createWebhookSubscriptionRequest struct {
+	URL string `json:"url" binding:"required,url,startswith=https://"`
+	// Event types to deliver, all when empty
+	EventTypes []string `json:"event_types" binding:"dive,oneof=account.created transfer.created"`
+}
+
+func (server *Server) createWebhookSubscription(ctx *gin.Context) {
+	var req createWebhookSubscriptionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	arg := db.CreateWebhookSubscriptionParams{
+		Owner:      authPayload(ctx).Username,
+		Url:        req.URL,
+		EventTypes: req.EventTypes,
+		Secret:     secret,
+	}
+
+	if arg.EventTypes == nil {
+		arg.EventTypes = []string{}
+	}
+
+	subscription, err := server.bank.CreateWebhookSubscription(ctx, arg)
+	if err != nil {
+		if db.ErrorCode(err) == db.ForeignKeyViolation {
+			ctx.JSON(http.StatusForbidden, errorResponse(err))
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, createWebhookSubscriptionResponse{
+		webhookSubscriptionResponse: newWebhookSubscriptionResponse(subscription),
+		Secret:                      subscription.Secret,
+	})
+}
+
+// listWebhookSubscriptions lists the subscriptions of the authenticated user.
+func (server *Server) listWebhookSubscriptions(ctx *gin.Context) {
+	var req pageRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	subscriptions, err := server.bank.ListWebhookSubscriptions(ctx, db.ListWebhookSubscriptionsParams{
+		Owner:  authPayload(ctx).Username,
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	rsp := make([]webhookSubscriptionResponse, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		rsp = append(rsp, newWebhookSubscriptionResponse(subscription))
+	}
+
+	ctx.JSON(http.StatusOK, rsp)
+}
+
+type webhookURI struct {
+	ID int64 `uri:"id" binding:"required,min=1"`
+}
+
+func (server *Server) deleteWebhookSubscription(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.authorizeWebhookSubscription(ctx, uri.ID) {
+		return
+	}
+
+	// Deliveries of the subscription are deleted with it
+	if err := server.bank.DeleteWebhookSubscription(ctx, uri.ID); err != nil {
+		webhookErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type listWebhookDeliveriesRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	pageRequest
+}
+
+func (server *Server) listWebhookDeliveries(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	var req listWebhookDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if !server.authorizeWebhookSubscription(ctx, uri.ID) {
+		return
+	}
+
+	deliveries, err := server.bank.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: uri.ID,
+		Status:         optionalText(req.Status),
+		Limit:          req.PageSize,
+		Offset:         (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveries)
+}
+
+func (server *Server) replayWebhookDelivery(ctx *gin.Context) {
+	var uri webhookURI
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	delivery, err := server.bank.GetWebhookDelivery(ctx, uri.ID)
+	if err != nil {
+		webhookErrorResponse(ctx, err)
+		return
+	}
+
+	if !server.authorizeWebhookSubscription(ctx, delivery.SubscriptionID) {
+		return
+	}
+
+	delivery, err = server.bank.ReplayWebhookDelivery(ctx, uri.ID)
+	if err != nil {
+		webhookErrorResponse(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, delivery)
+}
+
+// authorizeWebhookSubscription responds with not found or forbidden unless the authenticated user owns the
+// subscription, or the role of the user may manage subscriptions of any user.
+func (server *Server) authorizeWebhookSubscription(ctx *gin.Context, id int64) bool {
+	subscription, err := server.bank.GetWebhookSubscription(ctx, id)
+	if err != nil {
+		webhookErrorResponse(ctx, err)
+		return false
+	}
+
+	return authorizeOwner(ctx, subscription.Owner, permManageAnyWebhook)
+}
+
+// webhookErrorResponse maps errors from the webhook operations to a response.
+func webhookErrorResponse(ctx *gin.Context, err error) {
+	if errors.Is(err, db.ErrRecordNotFound) {
+		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+}
@@ -0,0 +1,67 @@
+// Package audit tells who makes a change and links audit events into a hash chain, so that changing or
+// removing a recorded event can be detected.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Actors of changes not made by a known user
+const (
+	// Anonymous makes changes through unauthenticated requests
+	Anonymous = "anonymous"
+	// System makes changes outside of requests, e.g. background jobs
+	System = "system"
+)
+
+// Actor is who makes a change, and from where
+type Actor struct {
+	Name      string `json:"name"`
+	IP        string `json:"ip"`
+	RequestID string `json:"request_id"`
+}
+
+type actorKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the actor carried by ctx, the system when there is none.
+func ActorFrom(ctx context.Context) Actor {
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok || actor.Name == "" {
+		actor.Name = System
+	}
+
+	return actor
+}
+
+// Hash returns the hash of an event, chained to the hash of the previous event. The ID and the hash
+// of the event itself are not hashed.
+func Hash(event db.AuditEvent) string {
+	// Marshalling a list of strings is unambiguous, the fields can not bleed into each other
+	content, _ := json.Marshal([]string{
+		event.PrevHash,
+		event.OccurredAt.UTC().Format(time.RFC3339Nano),
+		event.Actor,
+		event.Ip,
+		event.RequestID,
+		event.Action,
+		event.EntityType,
+		event.EntityID,
+		string(event.Before),
+		string(event.After),
+	})
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
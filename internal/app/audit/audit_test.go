@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActorFrom(t *testing.T) {
+	require.Equal(t, System, ActorFrom(context.Background()).Name)
+
+	actor := Actor{Name: "alice", IP: "127.0.0.1", RequestID: "r1"}
+	require.Equal(t, actor, ActorFrom(WithActor(context.Background(), actor)))
+}
+
+// chain links events the way the bank appends them
+func chain(events ...db.AuditEvent) []db.AuditEvent {
+	prevHash := ""
+	for i := range events {
+		events[i].ID = int64(i + 1)
+		events[i].PrevHash = prevHash
+		events[i].Hash = Hash(events[i])
+		prevHash = events[i].Hash
+	}
+
+	return events
+}
+
+func TestHash(t *testing.T) {
+	event := db.AuditEvent{
+		OccurredAt: time.Date(2023, 10, 27, 14, 9, 55, 123456000, time.UTC),
+		Actor:      Anonymous,
+		Action:     "account.create",
+		EntityType: "account",
+		EntityID:   "1",
+		After:      []byte(`{"id":1}`),
+	}
+
+	hash := Hash(event)
+	require.Len(t, hash, 64)
+
+	// The time zone of the event time does not matter
+	event.OccurredAt = event.OccurredAt.In(time.FixedZone("CET", 3600))
+	require.Equal(t, hash, Hash(event))
+
+	// Fields can not bleed into each other
+	event.EntityType, event.EntityID = "accoun", "t1"
+	require.NotEqual(t, hash, Hash(event))
+}
+
+// chainQuerier serves the events of a chain, the bank mock can not be used as the bank imports this package
+type chainQuerier struct {
+	db.Querier
+	events []db.AuditEvent
+}
+
+func (q chainQuerier) ListAuditEventsAfter(_ context.Context, arg db.ListAuditEventsAfterParams) ([]db.AuditEvent, error) {
+	var batch []db.AuditEvent
+	for _, event := range q.events {
+		if event.ID > arg.ID && len(batch) < int(arg.Limit) {
+			batch = append(batch, event)
+		}
+	}
+
+	return batch, nil
+}
+
+func TestVerify(t *testing.T) {
+	newEvents := func() []db.AuditEvent {
+		return chain(
+			db.AuditEvent{Action: "user.create", EntityType: "user", EntityID: "alice", After: []byte(`{}`)},
+			db.AuditEvent{Action: "account.create", EntityType: "account", EntityID: "1", After: []byte(`{}`)},
+			db.AuditEvent{Action: "account.freeze", EntityType: "account", EntityID: "1", After: []byte(`{}`)},
+		)
+	}
+
+	testCases := []struct {
+		name   string
+		tamper func(events []db.AuditEvent)
+		check  func(result Verification)
+	}{
+		{
+			name:   "Valid",
+			tamper: func(events []db.AuditEvent) {},
+			check: func(result Verification) {
+				require.Equal(t, Verification{Events: 3, Valid: true}, result)
+			},
+		},
+		{
+			name: "ChangedContent",
+			tamper: func(events []db.AuditEvent) {
+				events[1].After = []byte(`{"balance":100}`)
+			},
+			check: func(result Verification) {
+				require.False(t, result.Valid)
+				require.Equal(t, int64(2), result.BrokenEventID)
+				require.Equal(t, int64(2), result.Events)
+			},
+		},
+		{
+			name: "RemovedEvent",
+			tamper: func(events []db.AuditEvent) {
+				events[1] = events[2]
+			},
+			check: func(result Verification) {
+				require.False(t, result.Valid)
+				require.Equal(t, int64(3), result.BrokenEventID)
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			events := newEvents()
+			tc.tamper(events)
+
+			result, err := Verify(context.Background(), chainQuerier{events: events}, 2)
+			require.NoError(t, err)
+			tc.check(result)
+		})
+	}
+}
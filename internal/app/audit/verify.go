@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Verification is the result of verifying the hash chain
+type Verification struct {
+	// Events verified, up to and including the first broken event
+	Events int64 `json:"events"`
+	Valid  bool  `json:"valid"`
+	// First event breaking the chain, zero when valid
+	BrokenEventID int64  `json:"broken_event_id,omitempty"`
+	Reason        string `json:"reason,omitempty"`
+}
+
+// Verify walks the hash chain in order, reading batches of events, and stops at the first event that does
+// not link to the previous event or whose content does not match its hash.
+func Verify(ctx context.Context, q db.Querier, batchSize int32) (Verification, error) {
+	var (
+		result   Verification
+		prevHash string
+		afterID  int64
+	)
+
+	for {
+		events, err := q.ListAuditEventsAfter(ctx, db.ListAuditEventsAfterParams{
+			ID:    afterID,
+			Limit: batchSize,
+		})
+		if err != nil {
+			return result, err
+		}
+
+		if len(events) == 0 {
+			result.Valid = true
+			return result, nil
+		}
+
+		for _, event := range events {
+			result.Events++
+
+			reason := ""
+			switch {
+			case event.PrevHash != prevHash:
+				reason = fmt.Sprintf("previous hash %q does not match %q", event.PrevHash, prevHash)
+			case Hash(event) != event.Hash:
+				reason = "content does not match the hash"
+			}
+
+			if reason != "" {
+				result.BrokenEventID = event.ID
+				result.Reason = reason
+				return result, nil
+			}
+
+			prevHash = event.Hash
+		}
+
+		afterID = events[len(events)-1].ID
+	}
+}
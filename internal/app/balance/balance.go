@@ -0,0 +1,193 @@
+// Package balance streams balance changes of accounts to subscribers. Postgres notifies the channel of
+// every committed balance change, and a hub listening on one dedicated connection fans the changes out to
+// the subscribers of each account.
+package balance
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/jackc/pgx/v5"
+)
+
+// Channel is notified with the account ID when the balance of an account changes
+const Channel = "account_balance"
+
+// Event is the balance of an account
+type Event struct {
+	AccountID int64  `json:"account_id"`
+	Balance   int64  `json:"balance"`
+	Currency  string `json:"currency"`
+	// Latest entry of the account, the balance includes it
+	EntryID int64 `json:"entry_id"`
+}
+
+// Subscription receives the balance events of an account
+type Subscription struct {
+	AccountID int64
+	events    chan Event
+	hub       *Hub
+}
+
+// Events returns the events of the subscription. The channel is closed when the subscription is closed, or
+// dropped by the hub because the subscriber does not keep up.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the subscription.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	s.hub.drop(s)
+}
+
+// Hub fans balance events out to subscriptions
+type Hub struct {
+	bank bank.Bank
+	// Events buffered per subscription, a subscription with a full buffer is dropped
+	bufferSize int
+
+	mu            sync.Mutex
+	subscriptions map[int64]map[*Subscription]struct{}
+}
+
+// NewHub creates a hub reading balances from the bank, buffering bufferSize events per subscription.
+func NewHub(b bank.Bank, bufferSize int) *Hub {
+	return &Hub{
+		bank:          b,
+		bufferSize:    bufferSize,
+		subscriptions: make(map[int64]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe subscribes to the balance events of an account.
+func (h *Hub) Subscribe(accountID int64) *Subscription {
+	s := &Subscription{
+		AccountID: accountID,
+		events:    make(chan Event, h.bufferSize),
+		hub:       h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscriptions[accountID] == nil {
+		h.subscriptions[accountID] = make(map[*Subscription]struct{})
+	}
+	h.subscriptions[accountID][s] = struct{}{}
+
+	return s
+}
+
+// Subscribers returns the number of subscriptions to an account.
+func (h *Hub) Subscribers(accountID int64) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.subscriptions[accountID])
+}
+
+// drop removes a subscription and closes its events, h.mu must be held.
+func (h *Hub) drop(s *Subscription) {
+	subscriptions, ok := h.subscriptions[s.AccountID]
+	if !ok {
+		return
+	}
+
+	if _, ok := subscriptions[s]; !ok {
+		return
+	}
+
+	delete(subscriptions, s)
+	if len(subscriptions) == 0 {
+		delete(h.subscriptions, s.AccountID)
+	}
+
+	close(s.events)
+}
+
+// Publish sends the current balance of an account to its subscriptions. Subscriptions that do not keep up
+// are dropped rather than blocking the hub, their subscribers catch up by subscribing again.
+func (h *Hub) Publish(ctx context.Context, accountID int64) error {
+	if h.Subscribers(accountID) == 0 {
+		return nil
+	}
+
+	row, err := h.bank.GetAccountBalanceEvent(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	event := Event{
+		AccountID: row.ID,
+		Balance:   row.Balance,
+		Currency:  row.Currency,
+		EntryID:   row.EntryID,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subscriptions[accountID] {
+		select {
+		case s.events <- event:
+		default:
+			h.drop(s)
+		}
+	}
+
+	return nil
+}
+
+// Resync publishes the current balance of every subscribed account, e.g. after notifications may have
+// been missed while not listening.
+func (h *Hub) Resync(ctx context.Context) error {
+	h.mu.Lock()
+	accountIDs := make([]int64, 0, len(h.subscriptions))
+	for accountID := range h.subscriptions {
+		accountIDs = append(accountIDs, accountID)
+	}
+	h.mu.Unlock()
+
+	for _, accountID := range accountIDs {
+		if err := h.Publish(ctx, accountID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Listen listens to Channel on the connection and publishes the notified balances, until ctx is done or
+// the connection fails. Changes made before listening are not notified, so the current balances are
+// published once listening.
+func (h *Hub) Listen(ctx context.Context, conn *pgx.Conn) error {
+	if _, err := conn.Exec(ctx, "LISTEN "+Channel); err != nil {
+		return err
+	}
+
+	if err := h.Resync(ctx); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		// Anyone can notify the channel, payloads other than account IDs are ignored
+		accountID, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if err := h.Publish(ctx, accountID); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package balance
+
+import (
+	"context"
+	"testing"
+
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHub(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	row := db.GetAccountBalanceEventRow{ID: 1, Owner: "alice", Balance: 100, Currency: "SEK", EntryID: 7}
+
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().GetAccountBalanceEvent(gomock.Any(), gomock.Eq(int64(1))).Return(row, nil).Times(3)
+
+	hub := NewHub(store, 2)
+
+	// Accounts without subscribers are not read
+	require.NoError(t, hub.Publish(context.Background(), 2))
+
+	fast := hub.Subscribe(1)
+	slow := hub.Subscribe(1)
+	require.Equal(t, 2, hub.Subscribers(1))
+
+	require.NoError(t, hub.Publish(context.Background(), 1))
+	require.NoError(t, hub.Publish(context.Background(), 1))
+
+	want := Event{AccountID: 1, Balance: 100, Currency: "SEK", EntryID: 7}
+	require.Equal(t, want, <-fast.Events())
+	require.Equal(t, want, <-fast.Events())
+
+	// The buffer of the slow subscription is full, it is dropped
+	require.NoError(t, hub.Publish(context.Background(), 1))
+	require.Equal(t, want, <-fast.Events())
+	require.Equal(t, 1, hub.Subscribers(1))
+
+	<-slow.Events()
+	<-slow.Events()
+	_, ok := <-slow.Events()
+	require.False(t, ok)
+
+	// Closing twice is harmless
+	slow.Close()
+	fast.Close()
+	fast.Close()
+	require.Equal(t, 0, hub.Subscribers(1))
+
+	_, ok = <-fast.Events()
+	require.False(t, ok)
+}
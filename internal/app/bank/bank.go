@@ -2,16 +2,50 @@ package bank
 
 import (
 	"context"
+	"time"
 
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/ratelimit"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Bank defines all functions to execute db queries and transactions
 type Bank interface {
 	db.Querier
+	OpenAccount(ctx context.Context, arg OpenAccountParams) (db.Account, error)
+	FreezeAccount(ctx context.Context, accountID int64) (db.Account, error)
+	UnfreezeAccount(ctx context.Context, accountID int64) (db.Account, error)
+	CloseAccount(ctx context.Context, arg CloseAccountParams) (CloseAccountResult, error)
 	Transfer(ctx context.Context, arg TransferParams) (TransferResult, error)
+	GetTransferLimitUsage(ctx context.Context, accountID int64) (TransferLimitUsage, error)
+	ReverseTransfer(ctx context.Context, arg ReverseTransferParams) (ReverseTransferResult, error)
+	PlaceHold(ctx context.Context, arg PlaceHoldParams) (db.Hold, error)
+	CaptureHold(ctx context.Context, arg CaptureHoldParams) (CaptureHoldResult, error)
+	ReleaseHold(ctx context.Context, holdID int64) (db.Hold, error)
+	ScheduleTransfer(ctx context.Context, arg ScheduleTransferParams) (db.ScheduledTransfer, error)
+	ChangeScheduledTransfer(ctx context.Context, arg ChangeScheduledTransferParams) (db.ScheduledTransfer, error)
+	RunScheduledTransfer(ctx context.Context, policy RetryPolicy) (RunScheduledTransferResult, error)
+	Accrue(ctx context.Context, arg AccrueParams) (AccrueResult, error)
 	AddUser(ctx context.Context, arg AddUserParams) (AddUserResult, error)
+	RepairAccountBalance(ctx context.Context, accountID int64) (db.Account, error)
+	RelayOutboxEvent(ctx context.Context, retryDelay time.Duration, publish func(ctx context.Context, event db.Outbox) error) (db.Outbox, error)
+	DeliverWebhook(ctx context.Context, policy RetryPolicy, deliver func(ctx context.Context, delivery db.ClaimWebhookDeliveryRow) (int32, error)) (db.WebhookDelivery, error)
+	VerifyEmail(ctx context.Context, arg VerifyEmailParams) (VerifyEmailResult, error)
+	UpdateProfile(ctx context.Context, arg UpdateProfileParams) (db.User, error)
+	ChangePassword(ctx context.Context, arg ChangePasswordParams) (ChangePasswordResult, error)
+	SetUserRole(ctx context.Context, arg SetUserRoleParams) (db.User, error)
+	RequestPasswordReset(ctx context.Context, arg RequestPasswordResetParams) error
+	ResetPassword(ctx context.Context, arg ResetPasswordParams) (ChangePasswordResult, error)
+	EnrollTOTP(ctx context.Context, arg EnrollTOTPParams) (db.UserTotp, error)
+	EnableTOTP(ctx context.Context, arg EnableTOTPParams) (db.UserTotp, error)
+	DisableTOTP(ctx context.Context, username string) error
+	CheckLogin(ctx context.Context, arg CheckLoginParams) error
+	RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) error
+	UnlockUser(ctx context.Context, username string) (db.User, error)
+	IssueAPIKey(ctx context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (db.ApiKey, error)
+	TakeRateLimit(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+	RunJob(ctx context.Context, kinds []string, retryDelay time.Duration, run func(ctx context.Context, q db.Querier, job db.Job) error) (db.Job, error)
 }
 
 // SQLBank a composition that provides transactions over multiple database queries.
@@ -3,11 +3,101 @@
 package bank
 
 import (
+	"errors"
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/recurrence"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestSomething(t *testing.T) {
 	assert.Equal(t, 1, 1)
 }
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, Delay: time.Minute}
+
+	assert.Equal(t, time.Minute, policy.backoff(1))
+	assert.Equal(t, 2*time.Minute, policy.backoff(2))
+	assert.Equal(t, 4*time.Minute, policy.backoff(3))
+	assert.Equal(t, 24*time.Hour, policy.backoff(100))
+}
+
+func TestParseSchedule(t *testing.T) {
+	// Scheduled at 07:30 in UTC+2, read back from the database in UTC
+	start := time.Date(2023, 11, 1, 7, 30, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+
+	scheduled, err := parseSchedule("0 8 * * *", start)
+	assert.NoError(t, err)
+	first := recurrence.First(scheduled, start)
+
+	readBack, err := parseSchedule("0 8 * * *", start.UTC())
+	assert.NoError(t, err)
+	second := readBack.Next(first)
+
+	assert.Equal(t, time.Date(2023, 11, 1, 8, 0, 0, 0, time.UTC), first)
+	assert.Equal(t, 24*time.Hour, second.Sub(first))
+}
+
+func TestRateOfDay(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 11, d, 0, 0, 0, 0, time.UTC) }
+
+	rates := []db.AccountProductRate{
+		{AnnualRateBps: 150, ValidFrom: day(1)},
+		{AnnualRateBps: 200, ValidFrom: day(10).Add(9 * time.Hour)},
+		{AnnualRateBps: 0, ValidFrom: day(20)},
+	}
+
+	// Days before a change keep the old rate, the day of a change has the new rate
+	assert.Equal(t, int32(0), rateOfDay(rates, day(1).AddDate(0, 0, -1)))
+	assert.Equal(t, int32(150), rateOfDay(rates, day(1)))
+	assert.Equal(t, int32(150), rateOfDay(rates, day(9)))
+	assert.Equal(t, int32(200), rateOfDay(rates, day(10)))
+	assert.Equal(t, int32(200), rateOfDay(rates, day(19)))
+	assert.Equal(t, int32(0), rateOfDay(rates, day(20)))
+}
+
+func TestLimitError(t *testing.T) {
+	var err error = &LimitError{Limit: LimitAccountDaily, Max: 1000, Remaining: 250}
+
+	assert.ErrorIs(t, err, ErrLimitExceeded)
+	assert.NotErrorIs(t, err, ErrWithdrawalLimit)
+	assert.EqualError(t, err, "transfer exceeds a transfer limit: account_daily limit is 1000, remaining allowance 250")
+
+	var limitErr *LimitError
+	assert.True(t, errors.As(fmt.Errorf("exec tx:transaction err: %w", err), &limitErr))
+	assert.Equal(t, int64(250), limitErr.Remaining)
+}
+
+func TestRemaining(t *testing.T) {
+	assert.Equal(t, int64(600), remaining(1000, 400))
+	assert.Equal(t, int64(0), remaining(1000, 1000))
+	assert.Equal(t, int64(0), remaining(1000, 1200))
+}
+
+func TestLoginPolicyDelay(t *testing.T) {
+	policy := LoginPolicy{DelayAt: 3, Delay: time.Second, MaxDelay: 30 * time.Second}
+
+	assert.Equal(t, time.Duration(0), policy.delay(2))
+	assert.Equal(t, time.Second, policy.delay(3))
+	assert.Equal(t, 2*time.Second, policy.delay(4))
+	assert.Equal(t, 16*time.Second, policy.delay(7))
+	assert.Equal(t, 30*time.Second, policy.delay(8))
+	assert.Equal(t, 30*time.Second, policy.delay(1000))
+
+	assert.Equal(t, time.Duration(0), LoginPolicy{Delay: time.Second, MaxDelay: time.Minute}.delay(1000))
+}
+
+func TestLoginThrottledError(t *testing.T) {
+	var err error = &LoginThrottledError{Scope: LoginScopeUsername, Locked: true, RetryAfter: 90 * time.Second}
+
+	assert.ErrorIs(t, err, ErrLoginThrottled)
+	assert.EqualError(t, err, "too many failed logins: username is locked out, retry after 1m30s")
+
+	var throttled *LoginThrottledError
+	assert.True(t, errors.As(fmt.Errorf("check login: %w", err), &throttled))
+	assert.True(t, throttled.Locked)
+}
@@ -0,0 +1,80 @@
+package bank
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned by the bank when a request breaks a business rule.
+var (
+	ErrInvalidAmount            = errors.New("amount must be positive")
+	ErrTransferReversed         = errors.New("transfer is already fully reversed")
+	ErrReverseReversal          = errors.New("a reversal can not be reversed")
+	ErrReversalExceedsRemaining = errors.New("amount exceeds the remaining reversible amount")
+	ErrInsufficientFunds        = errors.New("insufficient available balance")
+	ErrHoldNotActive            = errors.New("hold is not active")
+	ErrHoldExpired              = errors.New("hold has expired")
+	ErrCaptureExceedsHold       = errors.New("amount exceeds the held amount")
+	ErrNoOccurrence             = errors.New("recurrence has no upcoming occurrence")
+	ErrScheduleFinished         = errors.New("scheduled transfer is completed or cancelled")
+	ErrInvalidStatusTransition  = errors.New("invalid status transition")
+	ErrDuplicateAccount         = errors.New("owner already has an account of the product in the currency")
+	ErrWithdrawalLimit          = errors.New("withdrawal exceeds the limits of the account product")
+	ErrAccountFrozen            = errors.New("account is frozen")
+	ErrAccountClosed            = errors.New("account is closed")
+	ErrAccountHasHolds          = errors.New("account has active holds")
+	ErrNonZeroBalance           = errors.New("account balance must be zero or swept to another account")
+	ErrCurrencyMismatch         = errors.New("accounts have different currencies")
+	ErrSameAccount              = errors.New("from and to account must differ")
+	ErrLimitExceeded            = errors.New("transfer exceeds a transfer limit")
+	ErrEmailNotVerified         = errors.New("email must be verified to open more accounts")
+	ErrInvalidVerifyEmail       = errors.New("invalid or already used email verification")
+	ErrVerifyEmailExpired       = errors.New("email verification has expired")
+	ErrTooManyPasswordResets    = errors.New("too many password resets requested, try again later")
+	ErrInvalidPasswordReset     = errors.New("invalid or already used password reset")
+	ErrPasswordResetExpired     = errors.New("password reset has expired")
+	ErrTOTPEnabled              = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled          = errors.New("two-factor authentication is not enrolled, or was enrolled again")
+	ErrLoginThrottled           = errors.New("too many failed logins")
+	ErrAPIKeyRevoked            = errors.New("api key is already revoked")
+)
+
+// LimitError is returned when a transfer breaks a transfer limit, it matches ErrLimitExceeded.
+type LimitError struct {
+	// Name of the broken limit, e.g. account_daily
+	Limit string `json:"limit"`
+	// Configured limit
+	Max int64 `json:"max"`
+	// Amount that could still have been transferred within the limit
+	Remaining int64 `json:"remaining"`
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v: %s limit is %d, remaining allowance %d", ErrLimitExceeded, e.Limit, e.Max, e.Remaining)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// LoginThrottledError is returned when failed logins refuse a login, it matches ErrLoginThrottled.
+type LoginThrottledError struct {
+	// Scope of the failed logins refusing the login, username or client_ip
+	Scope string `json:"scope"`
+	// Whether the scope is locked out, or else the login is delayed
+	Locked bool `json:"locked"`
+	// Time until a login may be attempted again
+	RetryAfter time.Duration `json:"retry_after"`
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("%v: %s is locked out, retry after %v", ErrLoginThrottled, e.Scope, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%v: %s is delayed, retry after %v", ErrLoginThrottled, e.Scope, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottledError) Is(target error) bool {
+	return target == ErrLoginThrottled
+}
@@ -4,23 +4,43 @@ package integration
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/audit"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/mail"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/outbox"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/webhook"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/currency"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/interest"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/ratelimit"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/require"
 )
 
 func createRandomAccount(t *testing.T, user db.User, currency string) db.Account {
 	ctx := context.Background()
 
+	// Transfers are checked against the available balance, make sure there is enough to move around
 	accParams := db.CreateAccountParams{
 		Owner:    user.Username,
-		Balance:  random.Money(),
+		Balance:  1000 + random.Money(),
 		Currency: currency,
+		Product:  bank.ProductChecking,
 	}
 
 	account, err := testee.CreateAccount(ctx, accParams)
@@ -30,6 +50,7 @@ func createRandomAccount(t *testing.T, user db.User, currency string) db.Account
 	require.Equal(t, accParams.Owner, account.Owner)
 	require.Equal(t, accParams.Balance, account.Balance)
 	require.Equal(t, accParams.Currency, account.Currency)
+	require.Equal(t, accParams.Product, account.Product)
 
 	require.NotZero(t, account.ID)
 	require.NotZero(t, account.CreatedAt)
@@ -58,6 +79,15 @@ func createRandomUser(t *testing.T) db.User {
 
 	require.NotEmpty(t, user.Username)
 	require.NotZero(t, user.CreatedAt)
+	require.False(t, user.IsEmailVerified)
+
+	// Verified so the user can open any number of accounts
+	user, err = testee.UpdateUser(ctx, db.UpdateUserParams{
+		IsEmailVerified: pgtype.Bool{Bool: true, Valid: true},
+		Username:        user.Username,
+	})
+	require.NoError(t, err)
+	require.True(t, user.IsEmailVerified)
 
 	return user
 }
@@ -172,3 +202,1758 @@ func TestAccountTransfer(t *testing.T) {
 	require.Equal(t, account1.Balance-int64(n)*amount, updatedAccount1.Balance)
 	require.Equal(t, account2.Balance+int64(n)*amount, updatedAccount2.Balance)
 }
+
+func TestReverseTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	amount := int64(10)
+
+	transferred, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        amount,
+	})
+	require.NoError(t, err)
+
+	// Partial refund
+	partial, err := testee.ReverseTransfer(ctx, bank.ReverseTransferParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     4,
+		Reason:     "partial refund",
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, account2.ID, partial.Transfer.FromAccountID)
+	require.Equal(t, account1.ID, partial.Transfer.ToAccountID)
+	require.Equal(t, int64(4), partial.Transfer.Amount)
+	require.True(t, partial.Transfer.ReversalOf.Valid)
+	require.Equal(t, transferred.Transfer.ID, partial.Transfer.ReversalOf.Int64)
+	require.Equal(t, "partial refund", partial.Transfer.ReversalReason)
+	require.Equal(t, int64(-4), partial.FromEntry.Amount)
+	require.Equal(t, int64(4), partial.ToEntry.Amount)
+	require.Equal(t, amount-4, partial.Remaining)
+
+	// Refund can not exceed what is left of the original transfer
+	_, err = testee.ReverseTransfer(ctx, bank.ReverseTransferParams{
+		TransferID: transferred.Transfer.ID,
+		Amount:     amount,
+		Reason:     "too much",
+	})
+	require.ErrorIs(t, err, bank.ErrReversalExceedsRemaining)
+
+	// A reversal can not be reversed
+	_, err = testee.ReverseTransfer(ctx, bank.ReverseTransferParams{
+		TransferID: partial.Transfer.ID,
+		Reason:     "reverse the reversal",
+	})
+	require.ErrorIs(t, err, bank.ErrReverseReversal)
+
+	// Zero amount refunds the remaining amount
+	full, err := testee.ReverseTransfer(ctx, bank.ReverseTransferParams{
+		TransferID: transferred.Transfer.ID,
+		Reason:     "full refund",
+	})
+	require.NoError(t, err)
+	require.Equal(t, amount-4, full.Transfer.Amount)
+	require.Zero(t, full.Remaining)
+
+	// Refuse to reverse twice
+	_, err = testee.ReverseTransfer(ctx, bank.ReverseTransferParams{
+		TransferID: transferred.Transfer.ID,
+		Reason:     "again",
+	})
+	require.ErrorIs(t, err, bank.ErrTransferReversed)
+
+	updatedAccount1, err := testee.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance, updatedAccount1.Balance)
+
+	updatedAccount2, err := testee.GetAccount(ctx, account2.ID)
+	require.NoError(t, err)
+	require.Equal(t, account2.Balance, updatedAccount2.Balance)
+}
+
+func TestHold(t *testing.T) {
+	ctx := context.Background()
+
+	account := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	merchant := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	hold, err := testee.PlaceHold(ctx, bank.PlaceHoldParams{
+		AccountID: account.ID,
+		Amount:    account.Balance - 10,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.HoldStatusActive, hold.Status)
+
+	held, err := testee.GetHeldAmount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, hold.Amount, held)
+
+	// Only 10 is available, the rest is reserved by the hold
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account.ID,
+		ToAccountID:   merchant.ID,
+		Amount:        11,
+	})
+	require.ErrorIs(t, err, bank.ErrInsufficientFunds)
+
+	_, err = testee.PlaceHold(ctx, bank.PlaceHoldParams{
+		AccountID: account.ID,
+		Amount:    11,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.ErrorIs(t, err, bank.ErrInsufficientFunds)
+
+	// Partial capture settles the hold and releases the rest
+	captured, err := testee.CaptureHold(ctx, bank.CaptureHoldParams{
+		HoldID:      hold.ID,
+		ToAccountID: merchant.ID,
+		Amount:      100,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.HoldStatusCaptured, captured.Hold.Status)
+	require.Equal(t, int64(100), captured.Hold.CapturedAmount)
+	require.Equal(t, captured.Transfer.ID, captured.Hold.TransferID.Int64)
+	require.Equal(t, account.Balance-100, captured.FromAccount.Balance)
+	require.Equal(t, merchant.Balance+100, captured.ToAccount.Balance)
+
+	events, err := testee.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		EntityType: pgtype.Text{String: bank.AuditEntityHold, Valid: true},
+		EntityID:   pgtype.Text{String: strconv.FormatInt(hold.ID, 10), Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, bank.AuditActionCaptureHold, events[0].Action)
+
+	held, err = testee.GetHeldAmount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = testee.CaptureHold(ctx, bank.CaptureHoldParams{
+		HoldID:      hold.ID,
+		ToAccountID: merchant.ID,
+	})
+	require.ErrorIs(t, err, bank.ErrHoldNotActive)
+
+	// Release
+	hold, err = testee.PlaceHold(ctx, bank.PlaceHoldParams{
+		AccountID: account.ID,
+		Amount:    10,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	// Holds are not captured to accounts of another currency, the failed capture leaves the hold active
+	otherCurrency := createRandomAccount(t, createRandomUser(t), currency.EUR)
+	_, err = testee.CaptureHold(ctx, bank.CaptureHoldParams{
+		HoldID:      hold.ID,
+		ToAccountID: otherCurrency.ID,
+	})
+	require.ErrorIs(t, err, bank.ErrCurrencyMismatch)
+
+	hold, err = testee.GetHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.HoldStatusActive, hold.Status)
+
+	unchanged, err := testee.GetAccount(ctx, otherCurrency.ID)
+	require.NoError(t, err)
+	require.Equal(t, otherCurrency.Balance, unchanged.Balance)
+
+	released, err := testee.ReleaseHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.HoldStatusReleased, released.Status)
+
+	_, err = testee.ReleaseHold(ctx, hold.ID)
+	require.ErrorIs(t, err, bank.ErrHoldNotActive)
+
+	// Expired holds no longer reserve money and can not be captured
+	hold, err = testee.PlaceHold(ctx, bank.PlaceHoldParams{
+		AccountID: account.ID,
+		Amount:    10,
+		ExpiresAt: time.Now().Add(-time.Second),
+	})
+	require.NoError(t, err)
+
+	held, err = testee.GetHeldAmount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Zero(t, held)
+
+	_, err = testee.CaptureHold(ctx, bank.CaptureHoldParams{
+		HoldID:      hold.ID,
+		ToAccountID: merchant.ID,
+	})
+	require.ErrorIs(t, err, bank.ErrHoldExpired)
+
+	expired, err := testee.ExpireHolds(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, expired, int64(1))
+
+	hold, err = testee.GetHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.HoldStatusExpired, hold.Status)
+}
+
+func TestScheduledTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	policy := bank.RetryPolicy{MaxAttempts: 2, Delay: time.Hour}
+
+	// Daily transfer that started two days ago, the first occurrence is due
+	scheduled, err := testee.ScheduleTransfer(ctx, bank.ScheduleTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+		Recurrence:    "FREQ=DAILY",
+		StartAt:       time.Now().Truncate(time.Second).Add(-48 * time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.ScheduledTransferStatusActive, scheduled.Status)
+
+	// Concurrent runners never execute an occurrence twice
+	n := 3
+	results := make(chan bank.RunScheduledTransferResult, n)
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			result, err := testee.RunScheduledTransfer(ctx, policy)
+			errs <- err
+			results <- result
+		}()
+	}
+
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		err := <-errs
+		result := <-results
+		if errors.Is(err, db.ErrRecordNotFound) {
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, bank.RunStatusSucceeded, result.Run.Status)
+		require.Equal(t, result.Transfer.Transfer.ID, result.Run.TransferID.Int64)
+		succeeded++
+	}
+	require.GreaterOrEqual(t, succeeded, 1)
+
+	// Catch up with the due occurrences, the next one is tomorrow
+	for {
+		_, err := testee.RunScheduledTransfer(ctx, policy)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			break
+		}
+		require.NoError(t, err)
+	}
+
+	scheduled, err = testee.GetScheduledTransfer(ctx, scheduled.ID)
+	require.NoError(t, err)
+	require.True(t, scheduled.NextOccurrenceAt.After(time.Now()))
+
+	runs, err := testee.ListScheduledTransferRuns(ctx, db.ListScheduledTransferRunsParams{
+		ScheduledTransferID: scheduled.ID,
+		Limit:               10,
+	})
+	require.NoError(t, err)
+	require.Len(t, runs, 3)
+
+	updatedAccount1, err := testee.GetAccount(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, account1.Balance-30, updatedAccount1.Balance)
+
+	// A failing transfer is retried according to the policy
+	failing, err := testee.ScheduleTransfer(ctx, bank.ScheduleTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        updatedAccount1.Balance + 1,
+		StartAt:       time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	result, err := testee.RunScheduledTransfer(ctx, policy)
+	require.NoError(t, err)
+	require.Equal(t, failing.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, bank.RunStatusFailed, result.Run.Status)
+	require.NotEmpty(t, result.Run.Error)
+	require.Equal(t, int32(1), result.ScheduledTransfer.Attempts)
+	require.True(t, result.ScheduledTransfer.NextAttemptAt.After(time.Now()))
+
+	// A resumed scheduled transfer skips the occurrences missed while paused and starts over without failed attempts
+	resumed, err := testee.ScheduleTransfer(ctx, bank.ScheduleTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        updatedAccount1.Balance + 1,
+		Recurrence:    "FREQ=DAILY",
+		StartAt:       time.Now().Truncate(time.Second).Add(-48 * time.Hour),
+	})
+	require.NoError(t, err)
+
+	result, err = testee.RunScheduledTransfer(ctx, policy)
+	require.NoError(t, err)
+	require.Equal(t, resumed.ID, result.ScheduledTransfer.ID)
+	require.Equal(t, int32(1), result.ScheduledTransfer.Attempts)
+
+	_, err = testee.ChangeScheduledTransfer(ctx, bank.ChangeScheduledTransferParams{
+		ID:     resumed.ID,
+		Status: pgtype.Text{String: bank.ScheduledTransferStatusPaused, Valid: true},
+	})
+	require.NoError(t, err)
+
+	resumed, err = testee.ChangeScheduledTransfer(ctx, bank.ChangeScheduledTransferParams{
+		ID:     resumed.ID,
+		Status: pgtype.Text{String: bank.ScheduledTransferStatusActive, Valid: true},
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.ScheduledTransferStatusActive, resumed.Status)
+	require.True(t, resumed.NextOccurrenceAt.After(time.Now()))
+	require.WithinDuration(t, time.Now(), resumed.NextOccurrenceAt, 24*time.Hour)
+	require.Equal(t, resumed.NextOccurrenceAt, resumed.NextAttemptAt)
+	require.Zero(t, resumed.Attempts)
+
+	// Finished scheduled transfers can not be changed
+	_, err = testee.ChangeScheduledTransfer(ctx, bank.ChangeScheduledTransferParams{
+		ID:     scheduled.ID,
+		Status: pgtype.Text{String: bank.ScheduledTransferStatusCancelled, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = testee.ChangeScheduledTransfer(ctx, bank.ChangeScheduledTransferParams{
+		ID:     scheduled.ID,
+		Status: pgtype.Text{String: bank.ScheduledTransferStatusActive, Valid: true},
+	})
+	require.ErrorIs(t, err, bank.ErrScheduleFinished)
+
+	// Transfers to the from account itself, or to an account of another currency, are never scheduled
+	_, err = testee.ScheduleTransfer(ctx, bank.ScheduleTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+		StartAt:       time.Now(),
+	})
+	require.ErrorIs(t, err, bank.ErrSameAccount)
+
+	otherCurrency := createRandomAccount(t, createRandomUser(t), currency.EUR)
+	_, err = testee.ScheduleTransfer(ctx, bank.ScheduleTransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   otherCurrency.ID,
+		Amount:        10,
+		StartAt:       time.Now(),
+	})
+	require.ErrorIs(t, err, bank.ErrCurrencyMismatch)
+}
+
+func TestAccrue(t *testing.T) {
+	ctx := context.Background()
+
+	// A product of its own keeps other tests out of the way
+	product := "savings-" + random.String(8)
+
+	savings, err := testee.CreateAccountProduct(ctx, db.CreateAccountProductParams{
+		Name:                product,
+		InterestRateBps:     3650,
+		MultiplePerCurrency: true,
+	})
+	require.NoError(t, err)
+
+	fee, err := testee.CreateFeeSchedule(ctx, db.CreateFeeScheduleParams{
+		Product: product,
+		Name:    "maintenance",
+		Amount:  100,
+	})
+	require.NoError(t, err)
+
+	account, err := testee.CreateAccount(ctx, db.CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Balance:  1000 + random.Money(),
+		Currency: currency.SEK,
+		Product:  product,
+	})
+	require.NoError(t, err)
+
+	policy := bank.AccrualPolicy{Basis: interest.Actual365, Rounding: interest.HalfEven}
+	asOf := time.Now().AddDate(0, 2, 0)
+
+	result, err := testee.Accrue(ctx, bank.AccrueParams{
+		AccountID: account.ID,
+		AsOf:      asOf,
+		Policy:    policy,
+	})
+	require.NoError(t, err)
+
+	// One accrual per day from the day the account was opened until the day before as of
+	opened := utcDay(account.CreatedAt)
+	today := utcDay(asOf)
+	require.Len(t, result.Accruals, int(today.Sub(opened).Hours()/24))
+
+	// The interest of every completed month is posted, the balance is the same every day
+	expected := account.Balance
+	postings := 0
+
+	first := time.Date(opened.Year(), opened.Month(), 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for month := first; month.Before(last); month = month.AddDate(0, 1, 0) {
+		var micros int64
+		for day := month; day.Before(month.AddDate(0, 1, 0)); day = day.AddDate(0, 0, 1) {
+			if !day.Before(opened) {
+				micros += interest.DailyMicros(account.Balance, savings.InterestRateBps, day, policy.Basis)
+			}
+		}
+		expected += policy.Rounding.Round(micros)
+		postings++
+
+		// The fee of every completed month is charged
+		expected -= fee.Amount
+		postings++
+	}
+
+	require.Len(t, result.Postings, postings)
+	require.Equal(t, expected, result.Account.Balance)
+
+	for _, posting := range result.Postings {
+		require.True(t, posting.TransferID.Valid)
+	}
+
+	// Running it again posts nothing new
+	result, err = testee.Accrue(ctx, bank.AccrueParams{
+		AccountID: account.ID,
+		AsOf:      asOf,
+		Policy:    policy,
+	})
+	require.NoError(t, err)
+	require.Empty(t, result.Accruals)
+	require.Empty(t, result.Postings)
+	require.Equal(t, expected, result.Account.Balance)
+
+	// A run skipping a month catches up, the fees of both months are charged
+	result, err = testee.Accrue(ctx, bank.AccrueParams{
+		AccountID: account.ID,
+		AsOf:      last.AddDate(0, 2, 0),
+		Policy:    policy,
+	})
+	require.NoError(t, err)
+
+	var charged []string
+	for _, posting := range result.Postings {
+		if posting.Kind == bank.PostingKindFee {
+			require.Equal(t, fee.ID, posting.FeeScheduleID.Int64)
+			charged = append(charged, posting.PeriodStart.Time.Format("2006-01"))
+		}
+	}
+	require.Equal(t, []string{last.Format("2006-01"), last.AddDate(0, 1, 0).Format("2006-01")}, charged)
+}
+
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCreateInternalAccount(t *testing.T) {
+	ctx := context.Background()
+
+	owner := createRandomUser(t).Username
+
+	// Concurrent runs open one account
+	n := 5
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		go func() {
+			errs <- testee.CreateInternalAccount(ctx, db.CreateInternalAccountParams{
+				Owner:    owner,
+				Currency: currency.SEK,
+			})
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+	}
+
+	count, err := testee.CountOwnerAccounts(ctx, db.CountOwnerAccountsParams{
+		Owner:    owner,
+		Currency: currency.SEK,
+		Product:  bank.ProductInternal,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), count)
+}
+
+func TestAccountProductRates(t *testing.T) {
+	ctx := context.Background()
+
+	product := "savings-" + random.String(8)
+
+	_, err := testee.CreateAccountProduct(ctx, db.CreateAccountProductParams{
+		Name:            product,
+		InterestRateBps: 150,
+	})
+	require.NoError(t, err)
+
+	// Changes of other columns keep the rate
+	_, err = testee.UpdateAccountProduct(ctx, db.UpdateAccountProductParams{
+		Description: pgtype.Text{String: "savings", Valid: true},
+		Name:        product,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.UpdateAccountProduct(ctx, db.UpdateAccountProductParams{
+		InterestRateBps: pgtype.Int4{Int32: 200, Valid: true},
+		Name:            product,
+	})
+	require.NoError(t, err)
+
+	rates, err := testee.ListAccountProductRates(ctx, product)
+	require.NoError(t, err)
+	require.Len(t, rates, 2)
+	require.Equal(t, int32(150), rates[0].AnnualRateBps)
+	require.Equal(t, int32(200), rates[1].AnnualRateBps)
+	require.True(t, rates[0].ValidFrom.Before(rates[1].ValidFrom))
+}
+
+func TestAccountProduct(t *testing.T) {
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+
+	// Only one checking account per currency
+	_, err := testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.SEK,
+		Product:  bank.ProductChecking,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.SEK,
+		Product:  bank.ProductChecking,
+	})
+	require.ErrorIs(t, err, bank.ErrDuplicateAccount)
+
+	product, err := testee.CreateAccountProduct(ctx, db.CreateAccountProductParams{
+		Name:                "limited-" + random.String(8),
+		OverdraftLimit:      100,
+		MaxWithdrawal:       150,
+		MonthlyWithdrawals:  1,
+		MultiplePerCurrency: true,
+	})
+	require.NoError(t, err)
+
+	limited, err := testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.SEK,
+		Product:  product.Name,
+	})
+	require.NoError(t, err)
+	require.Zero(t, limited.Balance)
+
+	// Several accounts of the product in the same currency
+	other, err := testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.SEK,
+		Product:  product.Name,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: limited.ID,
+		ToAccountID:   other.ID,
+		Amount:        product.MaxWithdrawal + 1,
+	})
+	require.ErrorIs(t, err, bank.ErrWithdrawalLimit)
+
+	// The account may be overdrawn down to the overdraft limit
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: limited.ID,
+		ToAccountID:   other.ID,
+		Amount:        product.OverdraftLimit + 1,
+	})
+	require.ErrorIs(t, err, bank.ErrInsufficientFunds)
+
+	result, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: limited.ID,
+		ToAccountID:   other.ID,
+		Amount:        product.OverdraftLimit,
+	})
+	require.NoError(t, err)
+	require.Equal(t, -product.OverdraftLimit, result.FromAccount.Balance)
+
+	// One withdrawal per month
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: other.ID,
+		ToAccountID:   limited.ID,
+		Amount:        product.OverdraftLimit,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: limited.ID,
+		ToAccountID:   other.ID,
+		Amount:        1,
+	})
+	require.ErrorIs(t, err, bank.ErrWithdrawalLimit)
+}
+
+func TestAccountLifecycle(t *testing.T) {
+	ctx := context.Background()
+
+	account := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	other := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	frozen, err := testee.FreezeAccount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.AccountStatusFrozen, frozen.Status)
+	require.True(t, frozen.FrozenAt.Valid)
+
+	// Money moves neither out of nor in to a frozen account
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account.ID,
+		ToAccountID:   other.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, bank.ErrAccountFrozen)
+
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, bank.ErrAccountFrozen)
+
+	_, err = testee.FreezeAccount(ctx, account.ID)
+	require.ErrorIs(t, err, bank.ErrInvalidStatusTransition)
+
+	// Only active accounts are closed
+	_, err = testee.CloseAccount(ctx, bank.CloseAccountParams{AccountID: account.ID, SweepToAccountID: other.ID})
+	require.ErrorIs(t, err, bank.ErrInvalidStatusTransition)
+
+	active, err := testee.UnfreezeAccount(ctx, account.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.AccountStatusActive, active.Status)
+	require.False(t, active.FrozenAt.Valid)
+
+	// The balance must be swept
+	_, err = testee.CloseAccount(ctx, bank.CloseAccountParams{AccountID: account.ID})
+	require.ErrorIs(t, err, bank.ErrNonZeroBalance)
+
+	result, err := testee.CloseAccount(ctx, bank.CloseAccountParams{AccountID: account.ID, SweepToAccountID: other.ID})
+	require.NoError(t, err)
+	require.Equal(t, bank.AccountStatusClosed, result.Account.Status)
+	require.True(t, result.Account.ClosedAt.Valid)
+	require.Zero(t, result.Account.Balance)
+	require.Equal(t, account.Balance, result.Sweep.Transfer.Amount)
+	require.Equal(t, other.Balance+account.Balance, result.Sweep.ToAccount.Balance)
+
+	_, err = testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: other.ID,
+		ToAccountID:   account.ID,
+		Amount:        10,
+	})
+	require.ErrorIs(t, err, bank.ErrAccountClosed)
+
+	_, err = testee.UnfreezeAccount(ctx, account.ID)
+	require.ErrorIs(t, err, bank.ErrInvalidStatusTransition)
+}
+
+func TestTransferLimits(t *testing.T) {
+	ctx := context.Background()
+
+	product, err := testee.CreateAccountProduct(ctx, db.CreateAccountProductParams{
+		Name:                "limits-" + random.String(8),
+		OverdraftLimit:      10000,
+		MultiplePerCurrency: true,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.SetProductTransferLimit(ctx, db.SetProductTransferLimitParams{
+		Product:      product.Name,
+		MaxAmount:    500,
+		AccountDaily: 800,
+		UserDaily:    900,
+	})
+	require.NoError(t, err)
+
+	user := createRandomUser(t)
+	receiver := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	var accounts []db.Account
+	for i := 0; i < 2; i++ {
+		account, err := testee.OpenAccount(ctx, bank.OpenAccountParams{
+			Owner:    user.Username,
+			Currency: currency.SEK,
+			Product:  product.Name,
+		})
+		require.NoError(t, err)
+		accounts = append(accounts, account)
+	}
+
+	transfer := func(from db.Account, amount int64) error {
+		_, err := testee.Transfer(ctx, bank.TransferParams{
+			FromAccountID: from.ID,
+			ToAccountID:   receiver.ID,
+			Amount:        amount,
+		})
+		return err
+	}
+
+	requireLimitError := func(err error, limit string, remaining int64) {
+		require.ErrorIs(t, err, bank.ErrLimitExceeded)
+
+		var limitErr *bank.LimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Equal(t, limit, limitErr.Limit)
+		require.Equal(t, remaining, limitErr.Remaining)
+	}
+
+	requireLimitError(transfer(accounts[0], 600), bank.LimitMaxAmount, 500)
+
+	require.NoError(t, transfer(accounts[0], 500))
+	requireLimitError(transfer(accounts[0], 400), bank.LimitAccountDaily, 300)
+
+	// The daily limit of the user sums all accounts of the user
+	requireLimitError(transfer(accounts[1], 500), bank.LimitUserDaily, 400)
+	require.NoError(t, transfer(accounts[1], 400))
+
+	usage, err := testee.GetTransferLimitUsage(ctx, accounts[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(500), usage.MaxAmount)
+	require.Len(t, usage.Windows, 2)
+	require.Equal(t, bank.LimitAccountDaily, usage.Windows[0].Limit)
+	require.Equal(t, int64(500), usage.Windows[0].Used)
+	require.Equal(t, int64(300), usage.Windows[0].Remaining)
+	require.Equal(t, bank.LimitUserDaily, usage.Windows[1].Limit)
+	require.Equal(t, int64(900), usage.Windows[1].Used)
+	require.Zero(t, usage.Windows[1].Remaining)
+
+	// A user override replaces the limits of the product
+	_, err = testee.SetUserTransferLimit(ctx, db.SetUserTransferLimitParams{Owner: user.Username})
+	require.NoError(t, err)
+	require.NoError(t, transfer(accounts[0], 600))
+
+	require.NoError(t, testee.DeleteUserTransferLimit(ctx, user.Username))
+	requireLimitError(transfer(accounts[0], 10), bank.LimitAccountDaily, 0)
+
+	// Capturing a hold is limited like a transfer, a refused capture leaves the hold active
+	hold, err := testee.PlaceHold(ctx, bank.PlaceHoldParams{
+		AccountID: accounts[1].ID,
+		Amount:    900,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	_, err = testee.CaptureHold(ctx, bank.CaptureHoldParams{
+		HoldID:      hold.ID,
+		ToAccountID: receiver.ID,
+	})
+	requireLimitError(err, bank.LimitMaxAmount, 500)
+
+	_, err = testee.CaptureHold(ctx, bank.CaptureHoldParams{
+		HoldID:      hold.ID,
+		ToAccountID: receiver.ID,
+		Amount:      500,
+	})
+	requireLimitError(err, bank.LimitAccountDaily, 400)
+
+	hold, err = testee.GetHold(ctx, hold.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.HoldStatusActive, hold.Status)
+
+	// Reversing an incoming transfer takes money out of the account, but is not a transfer of the customer
+	merchant, err := testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: currency.SEK,
+		Product:  product.Name,
+	})
+	require.NoError(t, err)
+
+	incoming, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: receiver.ID,
+		ToAccountID:   merchant.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.ReverseTransfer(ctx, bank.ReverseTransferParams{
+		TransferID: incoming.Transfer.ID,
+		Reason:     "refund",
+	})
+	require.NoError(t, err)
+
+	usage, err = testee.GetTransferLimitUsage(ctx, merchant.ID)
+	require.NoError(t, err)
+	require.Zero(t, usage.Windows[0].Used)
+	require.Zero(t, usage.Windows[1].Used)
+}
+
+func TestRepairAccountBalance(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	result, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// Entries are linked to the transfer booking them
+	require.Equal(t, result.Transfer.ID, result.FromEntry.TransferID.Int64)
+	require.Equal(t, result.Transfer.ID, result.ToEntry.TransferID.Int64)
+
+	// The opening balance has no entry, the balance is set to the sum of the entries
+	repaired, err := testee.RepairAccountBalance(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-10), repaired.Balance)
+
+	// Repairing a consistent account changes nothing
+	repaired, err = testee.RepairAccountBalance(ctx, account1.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(-10), repaired.Balance)
+
+	_, err = testee.RepairAccountBalance(ctx, 0)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func TestJournal(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	result, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	journal, err := testee.GetJournal(ctx, result.Transfer.JournalID)
+	require.NoError(t, err)
+	require.Equal(t, bank.JournalKindTransfer, journal.Kind)
+	require.Equal(t, journal.ID, result.FromEntry.JournalID)
+	require.Equal(t, journal.ID, result.ToEntry.JournalID)
+
+	reversal, err := testee.ReverseTransfer(ctx, bank.ReverseTransferParams{TransferID: result.Transfer.ID})
+	require.NoError(t, err)
+	require.NotEqual(t, journal.ID, reversal.Transfer.JournalID)
+
+	// History shows the other account of each transfer
+	history, err := testee.ListAccountHistory(ctx, db.ListAccountHistoryParams{
+		AccountID: account1.ID,
+		Limit:     10,
+	})
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+
+	require.Equal(t, int64(-10), history[0].Amount)
+	require.Equal(t, bank.JournalKindTransfer, history[0].JournalKind)
+	require.Equal(t, account2.ID, history[0].CounterpartyAccountID.Int64)
+	require.Equal(t, account2.Owner, history[0].CounterpartyOwner.String)
+
+	require.Equal(t, int64(10), history[1].Amount)
+	require.Equal(t, bank.JournalKindReversal, history[1].JournalKind)
+	require.Equal(t, account2.ID, history[1].CounterpartyAccountID.Int64)
+}
+
+func TestAuditLog(t *testing.T) {
+	actor := audit.Actor{Name: "alice", IP: "127.0.0.1", RequestID: "audit-test"}
+	ctx := audit.WithActor(context.Background(), actor)
+
+	account, err := testee.CreateAccount(ctx, db.CreateAccountParams{
+		Owner:    createRandomUser(t).Username,
+		Currency: currency.SEK,
+		Product:  bank.ProductChecking,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.FreezeAccount(ctx, account.ID)
+	require.NoError(t, err)
+
+	events, err := testee.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		EntityType: pgtype.Text{String: bank.AuditEntityAccount, Valid: true},
+		EntityID:   pgtype.Text{String: strconv.FormatInt(account.ID, 10), Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+
+	// Newest first
+	require.Equal(t, bank.AuditActionFreezeAccount, events[0].Action)
+	require.Equal(t, actor.Name, events[0].Actor)
+	require.Equal(t, actor.RequestID, events[0].RequestID)
+	require.Equal(t, events[1].Hash, events[0].PrevHash)
+	require.Equal(t, audit.Hash(events[0]), events[0].Hash)
+	require.NotEmpty(t, events[0].Before)
+
+	require.Equal(t, bank.AuditActionCreateAccount, events[1].Action)
+	require.Empty(t, events[1].Before)
+
+	result, err := audit.Verify(ctx, testee, 100)
+	require.NoError(t, err)
+	require.True(t, result.Valid, result.Reason)
+}
+
+func TestAuditLogConcurrentTransfers(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account3 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	// Transfers between unrelated accounts lock no rows in common, only the audit chain
+	n := 10
+	errs := make(chan error, n)
+	transfers := make(chan db.Transfer, n)
+
+	for i := 0; i < n; i++ {
+		from, to := account1, account2
+		if i%2 == 1 {
+			from, to = account3, account1
+		}
+
+		go func() {
+			result, err := testee.Transfer(ctx, bank.TransferParams{
+				FromAccountID: from.ID,
+				ToAccountID:   to.ID,
+				Amount:        10,
+			})
+			errs <- err
+			transfers <- result.Transfer
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		require.NoError(t, <-errs)
+		transfer := <-transfers
+
+		events, err := testee.ListAuditEvents(ctx, db.ListAuditEventsParams{
+			EntityType: pgtype.Text{String: bank.AuditEntityTransfer, Valid: true},
+			EntityID:   pgtype.Text{String: strconv.FormatInt(transfer.ID, 10), Valid: true},
+			Limit:      10,
+		})
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, bank.AuditActionTransfer, events[0].Action)
+	}
+
+	// Every append linked to the event committed before it
+	result, err := audit.Verify(ctx, testee, 100)
+	require.NoError(t, err)
+	require.True(t, result.Valid, result.Reason)
+}
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+
+	account1 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	result, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account1.ID,
+		ToAccountID:   account2.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	// Failed events are retried later, other aggregates are published meanwhile
+	failing := func(ctx context.Context, event db.Outbox) error {
+		return errors.New("unavailable")
+	}
+
+	failed, err := testee.RelayOutboxEvent(ctx, time.Hour, failing)
+	require.NoError(t, err)
+	require.False(t, failed.PublishedAt.Valid)
+	require.Equal(t, int32(1), failed.Attempts)
+	require.Equal(t, "unavailable", failed.LastError)
+	require.True(t, failed.NextAttemptAt.After(time.Now()))
+
+	publisher := outbox.NewMemoryPublisher()
+
+	_, err = outbox.Relay(ctx, testee, publisher, time.Hour)
+	require.NoError(t, err)
+
+	var transferEvent *outbox.Event
+	events := publisher.Events()
+	for i := range events {
+		require.NotEqual(t, failed.ID, events[i].ID)
+
+		if events[i].Type == bank.EventTransferCreated && events[i].AggregateID == strconv.FormatInt(result.Transfer.ID, 10) {
+			transferEvent = &events[i]
+		}
+	}
+
+	// The failed event can have been the transfer event
+	if transferEvent == nil {
+		require.Equal(t, bank.EventTransferCreated, failed.EventType)
+		return
+	}
+
+	var payload bank.TransferCreatedEvent
+	require.NoError(t, json.Unmarshal(transferEvent.Payload, &payload))
+	require.Equal(t, bank.TransferCreatedVersion, transferEvent.Version)
+	require.Equal(t, account2.Owner, payload.ToOwner)
+	require.Equal(t, int64(10), payload.Amount)
+}
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+
+	user := createRandomUser(t)
+
+	var received []outbox.Event
+	receiverStatus := http.StatusOK
+
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		err = webhook.Verify("secret", r.Header.Get(webhook.TimestampHeader), r.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now())
+		require.NoError(t, err)
+
+		var event outbox.Event
+		require.NoError(t, json.Unmarshal(body, &event))
+		received = append(received, event)
+
+		w.WriteHeader(receiverStatus)
+	}))
+	defer receiver.Close()
+
+	subscription, err := testee.CreateWebhookSubscription(ctx, db.CreateWebhookSubscriptionParams{
+		Owner:      user.Username,
+		Url:        receiver.URL,
+		EventTypes: []string{bank.EventTransferCreated},
+		Secret:     "secret",
+	})
+	require.NoError(t, err)
+
+	// Account events are filtered out
+	account1 := createRandomAccount(t, user, currency.SEK)
+	account2 := createRandomAccount(t, createRandomUser(t), currency.SEK)
+
+	transfer, err := testee.Transfer(ctx, bank.TransferParams{
+		FromAccountID: account2.ID,
+		ToAccountID:   account1.ID,
+		Amount:        10,
+	})
+	require.NoError(t, err)
+
+	deliveries, err := testee.ListWebhookDeliveries(ctx, db.ListWebhookDeliveriesParams{
+		SubscriptionID: subscription.ID,
+		Limit:          10,
+	})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	// Deliveries of other tests can be due as well, a policy of one attempt has failures dead at once
+	policy := bank.RetryPolicy{MaxAttempts: 1, Delay: time.Minute}
+	// The receiver listens on the host itself, its client dials it
+	sender := webhook.NewSenderClient(receiver.Client())
+
+	receiverStatus = http.StatusServiceUnavailable
+	_, err = webhook.Run(ctx, testee, sender, policy)
+	require.NoError(t, err)
+
+	delivery, err := testee.GetWebhookDelivery(ctx, deliveries[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.WebhookDeliveryDead, delivery.Status)
+	require.Equal(t, int32(http.StatusServiceUnavailable), delivery.ResponseStatus)
+
+	// Replayed once the receiver is fixed
+	receiverStatus = http.StatusOK
+	_, err = testee.ReplayWebhookDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+
+	_, err = webhook.Run(ctx, testee, sender, policy)
+	require.NoError(t, err)
+
+	delivery, err = testee.GetWebhookDelivery(ctx, delivery.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.WebhookDeliverySucceeded, delivery.Status)
+	require.True(t, delivery.DeliveredAt.Valid)
+
+	require.Len(t, received, 2)
+	require.Equal(t, bank.EventTransferCreated, received[1].Type)
+	require.Equal(t, strconv.FormatInt(transfer.Transfer.ID, 10), received[1].AggregateID)
+}
+
+// recordingMailer keeps sent messages
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+
+	var verifyEmail db.VerifyEmail
+	result, err := testee.AddUser(ctx, bank.AddUserParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       random.Owner(),
+			HashedPassword: random.String(10),
+			FullName:       random.Owner(),
+			Email:          random.Email(),
+		},
+		AfterCreate: func(ctx context.Context, q db.Querier, user db.User) error {
+			var err error
+			verifyEmail, err = q.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+				Username:   user.Username,
+				Email:      user.Email,
+				SecretCode: random.String(32),
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = mail.VerifyEmailTask.Enqueue(ctx, q, mail.VerifyEmailPayload{VerifyEmailID: verifyEmail.ID}, jobs.EnqueueOptions{})
+			return err
+		},
+	})
+	require.NoError(t, err)
+	user := result.User
+	require.False(t, user.IsEmailVerified)
+	require.Equal(t, user.Email, verifyEmail.Email)
+	require.True(t, verifyEmail.ExpiredAt.After(verifyEmail.CreatedAt))
+
+	// Unverified users are limited to one account
+	_, err = testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.SEK,
+		Product:  bank.ProductChecking,
+	})
+	require.NoError(t, err)
+
+	_, err = testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.EUR,
+		Product:  bank.ProductChecking,
+	})
+	require.ErrorIs(t, err, bank.ErrEmailNotVerified)
+
+	// Verification emails of other tests can be due as well
+	mailer := &recordingMailer{}
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, mail.VerifyEmailTask, mail.SendVerifyEmail(mailer, "bank@example.com", "http://localhost/verify_email"))
+
+	_, err = jobs.Run(ctx, testee, registry, time.Minute)
+	require.NoError(t, err)
+
+	var found bool
+	for _, msg := range mailer.sent {
+		if msg.To[0] == user.Email {
+			found = true
+			require.Contains(t, msg.Body, verifyEmail.SecretCode)
+		}
+	}
+	require.True(t, found)
+
+	_, err = testee.VerifyEmail(ctx, bank.VerifyEmailParams{ID: verifyEmail.ID, SecretCode: random.String(32)})
+	require.ErrorIs(t, err, bank.ErrInvalidVerifyEmail)
+
+	verified, err := testee.VerifyEmail(ctx, bank.VerifyEmailParams{ID: verifyEmail.ID, SecretCode: verifyEmail.SecretCode})
+	require.NoError(t, err)
+	require.True(t, verified.User.IsEmailVerified)
+	require.True(t, verified.VerifyEmail.IsUsed)
+	require.True(t, verified.VerifyEmail.SentAt.Valid)
+
+	// A code is used once
+	_, err = testee.VerifyEmail(ctx, bank.VerifyEmailParams{ID: verifyEmail.ID, SecretCode: verifyEmail.SecretCode})
+	require.ErrorIs(t, err, bank.ErrInvalidVerifyEmail)
+
+	_, err = testee.OpenAccount(ctx, bank.OpenAccountParams{
+		Owner:    user.Username,
+		Currency: currency.EUR,
+		Product:  bank.ProductChecking,
+	})
+	require.NoError(t, err)
+}
+
+func TestJobs(t *testing.T) {
+	ctx := context.Background()
+
+	// Kinds of their own, jobs of other tests are not run
+	type payload struct {
+		Name string `json:"name"`
+	}
+	task := jobs.Task[payload]{Kind: "test:" + random.String(8), MaxAttempts: 2}
+
+	// Jobs enqueued by a failing transaction are rolled back with it
+	_, err := testee.AddUser(ctx, bank.AddUserParams{
+		CreateUserParams: db.CreateUserParams{
+			Username:       random.Owner(),
+			HashedPassword: random.String(10),
+			FullName:       random.Owner(),
+			Email:          random.Email(),
+		},
+		AfterCreate: func(ctx context.Context, q db.Querier, user db.User) error {
+			if _, err := task.Enqueue(ctx, q, payload{Name: "rolled back"}, jobs.EnqueueOptions{}); err != nil {
+				return err
+			}
+			return errors.New("enqueue failed")
+		},
+	})
+	require.Error(t, err)
+
+	low, err := task.Enqueue(ctx, testee, payload{Name: "low"}, jobs.EnqueueOptions{UniqueKey: "low"})
+	require.NoError(t, err)
+
+	_, err = task.Enqueue(ctx, testee, payload{Name: "low"}, jobs.EnqueueOptions{UniqueKey: "low"})
+	require.ErrorIs(t, err, jobs.ErrDuplicateJob)
+
+	high, err := task.Enqueue(ctx, testee, payload{Name: "high"}, jobs.EnqueueOptions{Priority: 10})
+	require.NoError(t, err)
+
+	failing, err := task.Enqueue(ctx, testee, payload{Name: "failing"}, jobs.EnqueueOptions{})
+	require.NoError(t, err)
+
+	delayed, err := task.Enqueue(ctx, testee, payload{Name: "delayed"}, jobs.EnqueueOptions{RunAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+
+	var ran []string
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, task, func(ctx context.Context, q db.Querier, p payload) error {
+		ran = append(ran, p.Name)
+		if p.Name == "failing" {
+			return errors.New("failing job")
+		}
+		return nil
+	})
+
+	// Retries are due at once with a zero delay, the failing job is dead after two attempts
+	result, err := jobs.Run(ctx, testee, registry, 0)
+	require.NoError(t, err)
+	require.Equal(t, jobs.RunResult{Succeeded: 2, Failed: 1, Dead: 1}, result)
+	require.Equal(t, []string{"high", "low", "failing", "failing"}, ran)
+
+	for _, id := range []int64{high.ID, low.ID} {
+		job, err := testee.GetJob(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, bank.JobSucceeded, job.Status)
+		require.True(t, job.FinishedAt.Valid)
+	}
+
+	job, err := testee.GetJob(ctx, failing.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.JobDead, job.Status)
+	require.Equal(t, int32(2), job.Attempts)
+	require.Equal(t, "failing job", job.LastError)
+
+	job, err = testee.GetJob(ctx, delayed.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.JobPending, job.Status)
+	require.Zero(t, job.Attempts)
+
+	// A unique key is free again once its job is done
+	_, err = task.Enqueue(ctx, testee, payload{Name: "low"}, jobs.EnqueueOptions{UniqueKey: "low"})
+	require.NoError(t, err)
+
+	// Dead jobs are requeued from the first attempt
+	job, err = testee.RequeueJob(ctx, failing.ID)
+	require.NoError(t, err)
+	require.Equal(t, bank.JobPending, job.Status)
+	require.Zero(t, job.Attempts)
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+	require.True(t, user.IsEmailVerified)
+
+	fullName := random.Owner()
+	updated, err := testee.UpdateProfile(ctx, bank.UpdateProfileParams{
+		Username: user.Username,
+		FullName: pgtype.Text{String: fullName, Valid: true},
+		AfterEmailChange: func(ctx context.Context, q db.Querier, user db.User) error {
+			t.Fatal("email has not changed")
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, fullName, updated.FullName)
+	require.Equal(t, user.Email, updated.Email)
+	require.True(t, updated.IsEmailVerified)
+
+	// A changed email must be verified again
+	var verifyEmail db.VerifyEmail
+	email := random.Email()
+	updated, err = testee.UpdateProfile(ctx, bank.UpdateProfileParams{
+		Username: user.Username,
+		Email:    pgtype.Text{String: email, Valid: true},
+		AfterEmailChange: func(ctx context.Context, q db.Querier, user db.User) error {
+			verifyEmail, err = q.CreateVerifyEmail(ctx, db.CreateVerifyEmailParams{
+				Username:   user.Username,
+				Email:      user.Email,
+				SecretCode: random.String(32),
+			})
+			return err
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, email, updated.Email)
+	require.False(t, updated.IsEmailVerified)
+	require.Equal(t, email, verifyEmail.Email)
+
+	// The profile is unchanged when the hook fails
+	errHook := errors.New("hook failed")
+	_, err = testee.UpdateProfile(ctx, bank.UpdateProfileParams{
+		Username: user.Username,
+		Email:    pgtype.Text{String: random.Email(), Valid: true},
+		AfterEmailChange: func(ctx context.Context, q db.Querier, user db.User) error {
+			return errHook
+		},
+	})
+	require.ErrorIs(t, err, errHook)
+
+	got, err := testee.GetUser(ctx, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, email, got.Email)
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+
+	session, err := testee.CreateSession(ctx, db.CreateSessionParams{
+		ID:           random.String(32),
+		Username:     user.Username,
+		RefreshToken: random.String(32),
+		UserAgent:    "test",
+		ClientIp:     "127.0.0.1",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.False(t, session.IsBlocked)
+
+	hashedPassword := random.String(10)
+	result, err := testee.ChangePassword(ctx, bank.ChangePasswordParams{
+		Username:       user.Username,
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.True(t, result.User.PasswordChangedAt.After(user.PasswordChangedAt))
+	require.Equal(t, int64(1), result.RevokedSessions)
+
+	session, err = testee.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	_, err = testee.ChangePassword(ctx, bank.ChangePasswordParams{
+		Username:       random.Owner(),
+		HashedPassword: hashedPassword,
+	})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+}
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+	clientIP := fmt.Sprintf("10.%d.%d.%d", random.Int(256), random.Int(256), random.Int(256))
+	limits := bank.PasswordResetLimits{Window: time.Hour, MaxPerEmail: 2, MaxPerClientIP: 3}
+
+	session, err := testee.CreateSession(ctx, db.CreateSessionParams{
+		ID:           random.String(32),
+		Username:     user.Username,
+		RefreshToken: random.String(32),
+		UserAgent:    "test",
+		ClientIp:     clientIP,
+		ExpiresAt:    time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	var reset db.PasswordReset
+	err = testee.RequestPasswordReset(ctx, bank.RequestPasswordResetParams{
+		Email:    user.Email,
+		ClientIP: clientIP,
+		Limits:   limits,
+		AfterCreate: func(ctx context.Context, q db.Querier, created db.PasswordReset) error {
+			reset = created
+			_, err := mail.PasswordResetTask.Enqueue(ctx, q, mail.PasswordResetPayload{PasswordResetID: created.ID}, jobs.EnqueueOptions{})
+			return err
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, reset.Username)
+	require.False(t, reset.TokenHash.Valid)
+
+	// Unknown emails count towards the limits, but create no reset
+	err = testee.RequestPasswordReset(ctx, bank.RequestPasswordResetParams{
+		Email:    random.Email(),
+		ClientIP: clientIP,
+		Limits:   limits,
+		AfterCreate: func(ctx context.Context, q db.Querier, created db.PasswordReset) error {
+			t.Fatal("reset of unknown email")
+			return nil
+		},
+	})
+	require.NoError(t, err)
+
+	err = testee.RequestPasswordReset(ctx, bank.RequestPasswordResetParams{Email: user.Email, ClientIP: clientIP, Limits: limits})
+	require.NoError(t, err)
+
+	err = testee.RequestPasswordReset(ctx, bank.RequestPasswordResetParams{Email: user.Email, ClientIP: random.String(8), Limits: limits})
+	require.ErrorIs(t, err, bank.ErrTooManyPasswordResets)
+
+	err = testee.RequestPasswordReset(ctx, bank.RequestPasswordResetParams{Email: random.Email(), ClientIP: clientIP, Limits: limits})
+	require.ErrorIs(t, err, bank.ErrTooManyPasswordResets)
+
+	// Password resets of other tests can be due as well
+	mailer := &recordingMailer{}
+	registry := jobs.NewRegistry()
+	jobs.Register(registry, mail.PasswordResetTask, mail.SendPasswordReset(mailer, "bank@example.com", "http://localhost/reset_password"))
+
+	_, err = jobs.Run(ctx, testee, registry, time.Minute)
+	require.NoError(t, err)
+
+	var token string
+	for _, msg := range mailer.sent {
+		if msg.To[0] != user.Email {
+			continue
+		}
+		for _, line := range strings.Split(msg.Body, "\n") {
+			if link, err := url.Parse(line); err == nil && link.Query().Has("token") {
+				token = link.Query().Get("token")
+			}
+		}
+	}
+	require.Len(t, token, mail.PasswordResetTokenLength)
+
+	_, err = testee.ResetPassword(ctx, bank.ResetPasswordParams{
+		TokenHash:      password.HashToken(random.String(mail.PasswordResetTokenLength)),
+		HashedPassword: random.String(10),
+	})
+	require.ErrorIs(t, err, bank.ErrInvalidPasswordReset)
+
+	hashedPassword := random.String(10)
+	result, err := testee.ResetPassword(ctx, bank.ResetPasswordParams{
+		TokenHash:      password.HashToken(token),
+		HashedPassword: hashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, hashedPassword, result.User.HashedPassword)
+	require.Equal(t, int64(1), result.RevokedSessions)
+
+	session, err = testee.GetSession(ctx, session.ID)
+	require.NoError(t, err)
+	require.True(t, session.IsBlocked)
+
+	// The token is single use
+	_, err = testee.ResetPassword(ctx, bank.ResetPasswordParams{
+		TokenHash:      password.HashToken(token),
+		HashedPassword: random.String(10),
+	})
+	require.ErrorIs(t, err, bank.ErrInvalidPasswordReset)
+}
+
+func TestSetUserRole(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+	require.Equal(t, bank.RoleCustomer, user.Role)
+
+	support, err := testee.SetUserRole(ctx, bank.SetUserRoleParams{Username: user.Username, Role: bank.RoleSupport})
+	require.NoError(t, err)
+	require.Equal(t, bank.RoleSupport, support.Role)
+	require.True(t, support.RoleChangedAt.After(user.RoleChangedAt))
+
+	// Access tokens issued before the role changed are refused
+	changedAt, err := testee.GetUserAuthChangedAt(ctx, user.Username)
+	require.NoError(t, err)
+	require.WithinDuration(t, support.RoleChangedAt, changedAt, time.Microsecond)
+
+	// Setting the same role again keeps the access tokens
+	same, err := testee.SetUserRole(ctx, bank.SetUserRoleParams{Username: user.Username, Role: bank.RoleSupport})
+	require.NoError(t, err)
+	require.Equal(t, support.RoleChangedAt, same.RoleChangedAt)
+
+	// Roles are checked by the database
+	_, err = testee.SetUserRole(ctx, bank.SetUserRoleParams{Username: user.Username, Role: "root"})
+	require.Error(t, err)
+
+	_, err = testee.SetUserRole(ctx, bank.SetUserRoleParams{Username: random.Owner(), Role: bank.RoleAdmin})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	events, err := testee.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		EntityType: pgtype.Text{String: bank.AuditEntityUser, Valid: true},
+		EntityID:   pgtype.Text{String: user.Username, Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.AuditActionSetUserRole, events[0].Action)
+}
+
+func TestTOTP(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+
+	enrolled, err := testee.EnrollTOTP(ctx, bank.EnrollTOTPParams{Username: user.Username, EncryptedSecret: []byte("first")})
+	require.NoError(t, err)
+	require.False(t, enrolled.IsEnabled)
+
+	// Enrolling again replaces the secret until it is enabled
+	enrolled, err = testee.EnrollTOTP(ctx, bank.EnrollTOTPParams{Username: user.Username, EncryptedSecret: []byte("second")})
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), enrolled.EncryptedSecret)
+
+	_, err = testee.EnableTOTP(ctx, bank.EnableTOTPParams{Username: user.Username, EncryptedSecret: []byte("first"), Step: 100})
+	require.ErrorIs(t, err, bank.ErrTOTPNotEnrolled)
+
+	enabled, err := testee.EnableTOTP(ctx, bank.EnableTOTPParams{
+		Username:           user.Username,
+		EncryptedSecret:    []byte("second"),
+		Step:               100,
+		RecoveryCodeHashes: []string{"hash1", "hash2"},
+	})
+	require.NoError(t, err)
+	require.True(t, enabled.IsEnabled)
+	require.True(t, enabled.EnabledAt.Valid)
+	require.Equal(t, int64(100), enabled.LastUsedStep)
+
+	_, err = testee.EnrollTOTP(ctx, bank.EnrollTOTPParams{Username: user.Username, EncryptedSecret: []byte("third")})
+	require.ErrorIs(t, err, bank.ErrTOTPEnabled)
+
+	// Each time step is accepted once, and only after the last used
+	for _, tc := range []struct {
+		step int64
+		used int64
+	}{{100, 0}, {99, 0}, {101, 1}, {101, 0}} {
+		used, err := testee.UseUserTOTPStep(ctx, db.UseUserTOTPStepParams{Username: user.Username, LastUsedStep: tc.step})
+		require.NoError(t, err)
+		require.Equal(t, tc.used, used, tc.step)
+	}
+
+	// Each recovery code is accepted once
+	for _, tc := range []struct {
+		hash string
+		used int64
+	}{{"hash1", 1}, {"hash1", 0}, {"unknown", 0}, {"hash2", 1}} {
+		used, err := testee.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{Username: user.Username, CodeHash: tc.hash})
+		require.NoError(t, err)
+		require.Equal(t, tc.used, used, tc.hash)
+	}
+
+	require.NoError(t, testee.DisableTOTP(ctx, user.Username))
+
+	_, err = testee.GetUserTOTP(ctx, user.Username)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	err = testee.DisableTOTP(ctx, user.Username)
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	events, err := testee.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		EntityType: pgtype.Text{String: bank.AuditEntityUser, Valid: true},
+		EntityID:   pgtype.Text{String: user.Username, Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.AuditActionDisableTOTP, events[0].Action)
+	require.Equal(t, bank.AuditActionEnableTOTP, events[1].Action)
+	require.NotContains(t, string(events[1].After), "second")
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+	clientIP := fmt.Sprintf("10.%d.%d.%d", random.Int(255), random.Int(255), random.Int(255))
+
+	policy := bank.LoginPolicy{
+		Window:         time.Hour,
+		DelayAt:        2,
+		Delay:          time.Minute,
+		MaxDelay:       time.Hour,
+		LockAt:         3,
+		LockAtClientIP: 100,
+		LockDuration:   15 * time.Minute,
+	}
+
+	var lockedOut []time.Time
+	afterLock := func(_ context.Context, _ db.Querier, locked db.User, lockedUntil time.Time) error {
+		require.Equal(t, user.Username, locked.Username)
+		lockedOut = append(lockedOut, lockedUntil)
+		return nil
+	}
+
+	check := bank.CheckLoginParams{Username: user.Username, ClientIP: clientIP, Policy: policy}
+	failure := bank.RecordLoginFailureParams{Username: user.Username, ClientIP: clientIP, Policy: policy, AfterLock: afterLock}
+
+	require.NoError(t, testee.RecordLoginFailure(ctx, failure))
+	require.NoError(t, testee.CheckLogin(ctx, check))
+
+	// From the second failure on logins are delayed
+	require.NoError(t, testee.RecordLoginFailure(ctx, failure))
+	var throttled *bank.LoginThrottledError
+	require.ErrorAs(t, testee.CheckLogin(ctx, check), &throttled)
+	require.False(t, throttled.Locked)
+	require.Equal(t, bank.LoginScopeUsername, throttled.Scope)
+	require.InDelta(t, time.Minute, throttled.RetryAfter, float64(10*time.Second))
+
+	// The third failure locks the user out, once
+	require.NoError(t, testee.RecordLoginFailure(ctx, failure))
+	require.NoError(t, testee.RecordLoginFailure(ctx, failure))
+	require.Len(t, lockedOut, 1)
+
+	require.ErrorAs(t, testee.CheckLogin(ctx, check), &throttled)
+	require.True(t, throttled.Locked)
+	require.InDelta(t, 15*time.Minute, throttled.RetryAfter, float64(10*time.Second))
+
+	locked, err := testee.ListLoginThrottles(ctx, db.ListLoginThrottlesParams{Locked: true, Limit: 100})
+	require.NoError(t, err)
+	var listed bool
+	for _, throttle := range locked {
+		listed = listed || throttle.Scope == bank.LoginScopeUsername && throttle.Key == user.Username
+	}
+	require.True(t, listed)
+
+	// Unlocking forgets the failures of the user, but not those of the client IP
+	_, err = testee.UnlockUser(ctx, user.Username)
+	require.NoError(t, err)
+	require.NoError(t, testee.CheckLogin(ctx, bank.CheckLoginParams{Username: user.Username, ClientIP: "10.255.255.255", Policy: policy}))
+	require.ErrorAs(t, testee.CheckLogin(ctx, check), &throttled)
+	require.Equal(t, bank.LoginScopeClientIP, throttled.Scope)
+
+	// A successful login forgets the failures of the user, but not those of the client IP, else logging in to
+	// an account of its own would clear the failures of a client guessing passwords
+	for _, ip := range []string{"10.255.255.1", "10.255.255.2"} {
+		otherIP := bank.RecordLoginFailureParams{Username: user.Username, ClientIP: ip, Policy: policy}
+		require.NoError(t, testee.RecordLoginFailure(ctx, otherIP))
+	}
+	require.ErrorAs(t, testee.CheckLogin(ctx, bank.CheckLoginParams{Username: user.Username, ClientIP: "10.255.255.255", Policy: policy}), &throttled)
+	require.Equal(t, bank.LoginScopeUsername, throttled.Scope)
+
+	err = testee.ResetLoginThrottle(ctx, user.Username)
+	require.NoError(t, err)
+	require.NoError(t, testee.CheckLogin(ctx, bank.CheckLoginParams{Username: user.Username, ClientIP: "10.255.255.255", Policy: policy}))
+	require.ErrorAs(t, testee.CheckLogin(ctx, check), &throttled)
+	require.Equal(t, bank.LoginScopeClientIP, throttled.Scope)
+
+	_, err = testee.UnlockUser(ctx, random.Owner())
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	events, err := testee.ListAuditEvents(ctx, db.ListAuditEventsParams{
+		EntityType: pgtype.Text{String: bank.AuditEntityUser, Valid: true},
+		EntityID:   pgtype.Text{String: user.Username, Valid: true},
+		Limit:      10,
+	})
+	require.NoError(t, err)
+	require.Equal(t, bank.AuditActionUnlockUser, events[0].Action)
+	require.Equal(t, bank.AuditActionLockUser, events[1].Action)
+}
+
+func TestRehashUserPassword(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+
+	// A hash replaced meanwhile is kept
+	rehashed, err := testee.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewHashedPassword: "rehashed",
+		Username:          user.Username,
+		HashedPassword:    "changed",
+	})
+	require.NoError(t, err)
+	require.Zero(t, rehashed)
+
+	rehashed, err = testee.RehashUserPassword(ctx, db.RehashUserPasswordParams{
+		NewHashedPassword: "rehashed",
+		Username:          user.Username,
+		HashedPassword:    user.HashedPassword,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), rehashed)
+
+	// The password did not change, so neither does the time it changed
+	got, err := testee.GetUser(ctx, user.Username)
+	require.NoError(t, err)
+	require.Equal(t, "rehashed", got.HashedPassword)
+	require.Equal(t, user.PasswordChangedAt, got.PasswordChangedAt)
+}
+
+func TestAPIKeys(t *testing.T) {
+	ctx := context.Background()
+	user := createRandomUser(t)
+	other := createRandomUser(t)
+	keyHash := password.HashToken(random.String(32))
+
+	key, err := testee.IssueAPIKey(ctx, db.CreateAPIKeyParams{
+		Username:  user.Username,
+		Name:      "batch",
+		Prefix:    random.String(8),
+		KeyHash:   keyHash,
+		Scopes:    []string{"read:accounts"},
+		ExpiredAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.Equal(t, user.Username, key.Username)
+	require.False(t, key.RevokedAt.Valid)
+
+	// Keys are found by their hash, with the role of their user
+	found, err := testee.GetAPIKeyByHash(ctx, keyHash)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, found.ID)
+	require.Equal(t, []string{"read:accounts"}, found.Scopes)
+	require.Equal(t, user.Role, found.Role)
+
+	keys, err := testee.ListAPIKeys(ctx, db.ListAPIKeysParams{Username: user.Username, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.Equal(t, key.ID, keys[0].ID)
+
+	// Other users can not revoke the key
+	_, err = testee.RevokeAPIKey(ctx, bank.RevokeAPIKeyParams{ID: key.ID, Username: other.Username})
+	require.ErrorIs(t, err, db.ErrRecordNotFound)
+
+	revoked, err := testee.RevokeAPIKey(ctx, bank.RevokeAPIKeyParams{ID: key.ID, Username: user.Username})
+	require.NoError(t, err)
+	require.True(t, revoked.RevokedAt.Valid)
+
+	found, err = testee.GetAPIKeyByHash(ctx, keyHash)
+	require.NoError(t, err)
+	require.True(t, found.RevokedAt.Valid)
+
+	_, err = testee.RevokeAPIKey(ctx, bank.RevokeAPIKeyParams{ID: key.ID, Username: user.Username})
+	require.ErrorIs(t, err, bank.ErrAPIKeyRevoked)
+}
+
+func TestTakeRateLimit(t *testing.T) {
+	ctx := context.Background()
+	key := "ip:" + random.String(12)
+	limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+
+	for remaining := 1; remaining >= 0; remaining-- {
+		result, err := testee.TakeRateLimit(ctx, key, limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, remaining, result.Remaining)
+	}
+
+	result, err := testee.TakeRateLimit(ctx, key, limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.InDelta(t, 30*time.Second, result.RetryAfter, float64(time.Second))
+
+	// Buckets are deleted once full again
+	short := "ip:" + random.String(12)
+	_, err = testee.TakeRateLimit(ctx, short, ratelimit.Limit{Requests: 100, Per: time.Second})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+
+	deleted, err := testee.DeleteFullRateLimitBuckets(ctx)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, deleted, int64(1))
+
+	result, err = testee.TakeRateLimit(ctx, key, limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+}
@@ -11,9 +11,12 @@ package mockdb
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	bank "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
 	db "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	ratelimit "github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/ratelimit"
+	pgtype "github.com/jackc/pgx/v5/pgtype"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -40,228 +43,2540 @@ func (m *MockBank) EXPECT() *MockBankMockRecorder {
 	return m.recorder
 }
 
+// Accrue mocks base method.
+func (m *MockBank) Accrue(ctx context.Context, arg bank.AccrueParams) (bank.AccrueResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Accrue", ctx, arg)
+	ret0, _ := ret[0].(bank.AccrueResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Accrue indicates an expected call of Accrue.
+func (mr *MockBankMockRecorder) Accrue(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Accrue", reflect.TypeOf((*MockBank)(nil).Accrue), ctx, arg)
+}
+
 // AddAccountBalance mocks base method.
 func (m *MockBank) AddAccountBalance(ctx context.Context, arg db.AddAccountBalanceParams) (db.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddAccountBalance", ctx, arg)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "AddAccountBalance", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddAccountBalance indicates an expected call of AddAccountBalance.
+func (mr *MockBankMockRecorder) AddAccountBalance(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAccountBalance", reflect.TypeOf((*MockBank)(nil).AddAccountBalance), ctx, arg)
+}
+
+// AddUser mocks base method.
+func (m *MockBank) AddUser(ctx context.Context, arg bank.AddUserParams) (bank.AddUserResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddUser", ctx, arg)
+	ret0, _ := ret[0].(bank.AddUserResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddUser indicates an expected call of AddUser.
+func (mr *MockBankMockRecorder) AddUser(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockBank)(nil).AddUser), ctx, arg)
+}
+
+// AdvanceScheduledTransfer mocks base method.
+func (m *MockBank) AdvanceScheduledTransfer(ctx context.Context, arg db.AdvanceScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceScheduledTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceScheduledTransfer indicates an expected call of AdvanceScheduledTransfer.
+func (mr *MockBankMockRecorder) AdvanceScheduledTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceScheduledTransfer", reflect.TypeOf((*MockBank)(nil).AdvanceScheduledTransfer), ctx, arg)
+}
+
+// BlockUserSessions mocks base method.
+func (m *MockBank) BlockUserSessions(ctx context.Context, username string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BlockUserSessions", ctx, username)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BlockUserSessions indicates an expected call of BlockUserSessions.
+func (mr *MockBankMockRecorder) BlockUserSessions(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockUserSessions", reflect.TypeOf((*MockBank)(nil).BlockUserSessions), ctx, username)
+}
+
+// CancelAccountScheduledTransfers mocks base method.
+func (m *MockBank) CancelAccountScheduledTransfers(ctx context.Context, accountID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CancelAccountScheduledTransfers", ctx, accountID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CancelAccountScheduledTransfers indicates an expected call of CancelAccountScheduledTransfers.
+func (mr *MockBankMockRecorder) CancelAccountScheduledTransfers(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CancelAccountScheduledTransfers", reflect.TypeOf((*MockBank)(nil).CancelAccountScheduledTransfers), ctx, accountID)
+}
+
+// CaptureHold mocks base method.
+func (m *MockBank) CaptureHold(ctx context.Context, arg bank.CaptureHoldParams) (bank.CaptureHoldResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CaptureHold", ctx, arg)
+	ret0, _ := ret[0].(bank.CaptureHoldResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CaptureHold indicates an expected call of CaptureHold.
+func (mr *MockBankMockRecorder) CaptureHold(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CaptureHold", reflect.TypeOf((*MockBank)(nil).CaptureHold), ctx, arg)
+}
+
+// ChangePassword mocks base method.
+func (m *MockBank) ChangePassword(ctx context.Context, arg bank.ChangePasswordParams) (bank.ChangePasswordResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangePassword", ctx, arg)
+	ret0, _ := ret[0].(bank.ChangePasswordResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangePassword indicates an expected call of ChangePassword.
+func (mr *MockBankMockRecorder) ChangePassword(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangePassword", reflect.TypeOf((*MockBank)(nil).ChangePassword), ctx, arg)
+}
+
+// ChangeScheduledTransfer mocks base method.
+func (m *MockBank) ChangeScheduledTransfer(ctx context.Context, arg bank.ChangeScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ChangeScheduledTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ChangeScheduledTransfer indicates an expected call of ChangeScheduledTransfer.
+func (mr *MockBankMockRecorder) ChangeScheduledTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ChangeScheduledTransfer", reflect.TypeOf((*MockBank)(nil).ChangeScheduledTransfer), ctx, arg)
+}
+
+// CheckLogin mocks base method.
+func (m *MockBank) CheckLogin(ctx context.Context, arg bank.CheckLoginParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckLogin", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckLogin indicates an expected call of CheckLogin.
+func (mr *MockBankMockRecorder) CheckLogin(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckLogin", reflect.TypeOf((*MockBank)(nil).CheckLogin), ctx, arg)
+}
+
+// ClaimDueScheduledTransfer mocks base method.
+func (m *MockBank) ClaimDueScheduledTransfer(ctx context.Context) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimDueScheduledTransfer", ctx)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimDueScheduledTransfer indicates an expected call of ClaimDueScheduledTransfer.
+func (mr *MockBankMockRecorder) ClaimDueScheduledTransfer(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimDueScheduledTransfer", reflect.TypeOf((*MockBank)(nil).ClaimDueScheduledTransfer), ctx)
+}
+
+// ClaimJob mocks base method.
+func (m *MockBank) ClaimJob(ctx context.Context, kinds []string) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimJob", ctx, kinds)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimJob indicates an expected call of ClaimJob.
+func (mr *MockBankMockRecorder) ClaimJob(ctx, kinds any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimJob", reflect.TypeOf((*MockBank)(nil).ClaimJob), ctx, kinds)
+}
+
+// ClaimOutboxEvent mocks base method.
+func (m *MockBank) ClaimOutboxEvent(ctx context.Context) (db.Outbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimOutboxEvent", ctx)
+	ret0, _ := ret[0].(db.Outbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimOutboxEvent indicates an expected call of ClaimOutboxEvent.
+func (mr *MockBankMockRecorder) ClaimOutboxEvent(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimOutboxEvent", reflect.TypeOf((*MockBank)(nil).ClaimOutboxEvent), ctx)
+}
+
+// ClaimWebhookDelivery mocks base method.
+func (m *MockBank) ClaimWebhookDelivery(ctx context.Context) (db.ClaimWebhookDeliveryRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimWebhookDelivery", ctx)
+	ret0, _ := ret[0].(db.ClaimWebhookDeliveryRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimWebhookDelivery indicates an expected call of ClaimWebhookDelivery.
+func (mr *MockBankMockRecorder) ClaimWebhookDelivery(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimWebhookDelivery", reflect.TypeOf((*MockBank)(nil).ClaimWebhookDelivery), ctx)
+}
+
+// CloseAccount mocks base method.
+func (m *MockBank) CloseAccount(ctx context.Context, arg bank.CloseAccountParams) (bank.CloseAccountResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CloseAccount", ctx, arg)
+	ret0, _ := ret[0].(bank.CloseAccountResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CloseAccount indicates an expected call of CloseAccount.
+func (mr *MockBankMockRecorder) CloseAccount(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CloseAccount", reflect.TypeOf((*MockBank)(nil).CloseAccount), ctx, arg)
+}
+
+// CountLoginFailure mocks base method.
+func (m *MockBank) CountLoginFailure(ctx context.Context, arg db.CountLoginFailureParams) (db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountLoginFailure", ctx, arg)
+	ret0, _ := ret[0].(db.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountLoginFailure indicates an expected call of CountLoginFailure.
+func (mr *MockBankMockRecorder) CountLoginFailure(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountLoginFailure", reflect.TypeOf((*MockBank)(nil).CountLoginFailure), ctx, arg)
+}
+
+// CountOwnerAccounts mocks base method.
+func (m *MockBank) CountOwnerAccounts(ctx context.Context, arg db.CountOwnerAccountsParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountOwnerAccounts", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountOwnerAccounts indicates an expected call of CountOwnerAccounts.
+func (mr *MockBankMockRecorder) CountOwnerAccounts(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountOwnerAccounts", reflect.TypeOf((*MockBank)(nil).CountOwnerAccounts), ctx, arg)
+}
+
+// CountPasswordResetRequestsByClientIP mocks base method.
+func (m *MockBank) CountPasswordResetRequestsByClientIP(ctx context.Context, arg db.CountPasswordResetRequestsByClientIPParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPasswordResetRequestsByClientIP", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPasswordResetRequestsByClientIP indicates an expected call of CountPasswordResetRequestsByClientIP.
+func (mr *MockBankMockRecorder) CountPasswordResetRequestsByClientIP(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPasswordResetRequestsByClientIP", reflect.TypeOf((*MockBank)(nil).CountPasswordResetRequestsByClientIP), ctx, arg)
+}
+
+// CountPasswordResetRequestsByEmail mocks base method.
+func (m *MockBank) CountPasswordResetRequestsByEmail(ctx context.Context, arg db.CountPasswordResetRequestsByEmailParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPasswordResetRequestsByEmail", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPasswordResetRequestsByEmail indicates an expected call of CountPasswordResetRequestsByEmail.
+func (mr *MockBankMockRecorder) CountPasswordResetRequestsByEmail(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPasswordResetRequestsByEmail", reflect.TypeOf((*MockBank)(nil).CountPasswordResetRequestsByEmail), ctx, arg)
+}
+
+// CountPendingOutboxEvents mocks base method.
+func (m *MockBank) CountPendingOutboxEvents(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountPendingOutboxEvents", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountPendingOutboxEvents indicates an expected call of CountPendingOutboxEvents.
+func (mr *MockBankMockRecorder) CountPendingOutboxEvents(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountPendingOutboxEvents", reflect.TypeOf((*MockBank)(nil).CountPendingOutboxEvents), ctx)
+}
+
+// CountUnlinkedEntries mocks base method.
+func (m *MockBank) CountUnlinkedEntries(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUnlinkedEntries", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUnlinkedEntries indicates an expected call of CountUnlinkedEntries.
+func (mr *MockBankMockRecorder) CountUnlinkedEntries(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUnlinkedEntries", reflect.TypeOf((*MockBank)(nil).CountUnlinkedEntries), ctx)
+}
+
+// CountUserAccounts mocks base method.
+func (m *MockBank) CountUserAccounts(ctx context.Context, owner string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUserAccounts", ctx, owner)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUserAccounts indicates an expected call of CountUserAccounts.
+func (mr *MockBankMockRecorder) CountUserAccounts(ctx, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUserAccounts", reflect.TypeOf((*MockBank)(nil).CountUserAccounts), ctx, owner)
+}
+
+// CountWithdrawalsSince mocks base method.
+func (m *MockBank) CountWithdrawalsSince(ctx context.Context, arg db.CountWithdrawalsSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountWithdrawalsSince", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountWithdrawalsSince indicates an expected call of CountWithdrawalsSince.
+func (mr *MockBankMockRecorder) CountWithdrawalsSince(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountWithdrawalsSince", reflect.TypeOf((*MockBank)(nil).CountWithdrawalsSince), ctx, arg)
+}
+
+// CreateAPIKey mocks base method.
+func (m *MockBank) CreateAPIKey(ctx context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAPIKey", ctx, arg)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAPIKey indicates an expected call of CreateAPIKey.
+func (mr *MockBankMockRecorder) CreateAPIKey(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAPIKey", reflect.TypeOf((*MockBank)(nil).CreateAPIKey), ctx, arg)
+}
+
+// CreateAccount mocks base method.
+func (m *MockBank) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccount", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccount indicates an expected call of CreateAccount.
+func (mr *MockBankMockRecorder) CreateAccount(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockBank)(nil).CreateAccount), ctx, arg)
+}
+
+// CreateAccountProduct mocks base method.
+func (m *MockBank) CreateAccountProduct(ctx context.Context, arg db.CreateAccountProductParams) (db.AccountProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccountProduct", ctx, arg)
+	ret0, _ := ret[0].(db.AccountProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccountProduct indicates an expected call of CreateAccountProduct.
+func (mr *MockBankMockRecorder) CreateAccountProduct(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccountProduct", reflect.TypeOf((*MockBank)(nil).CreateAccountProduct), ctx, arg)
+}
+
+// CreateAccrualPosting mocks base method.
+func (m *MockBank) CreateAccrualPosting(ctx context.Context, arg db.CreateAccrualPostingParams) (db.AccrualPosting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAccrualPosting", ctx, arg)
+	ret0, _ := ret[0].(db.AccrualPosting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAccrualPosting indicates an expected call of CreateAccrualPosting.
+func (mr *MockBankMockRecorder) CreateAccrualPosting(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccrualPosting", reflect.TypeOf((*MockBank)(nil).CreateAccrualPosting), ctx, arg)
+}
+
+// CreateAuditEvent mocks base method.
+func (m *MockBank) CreateAuditEvent(ctx context.Context, arg db.CreateAuditEventParams) (db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateAuditEvent", ctx, arg)
+	ret0, _ := ret[0].(db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateAuditEvent indicates an expected call of CreateAuditEvent.
+func (mr *MockBankMockRecorder) CreateAuditEvent(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAuditEvent", reflect.TypeOf((*MockBank)(nil).CreateAuditEvent), ctx, arg)
+}
+
+// CreateEntry mocks base method.
+func (m *MockBank) CreateEntry(ctx context.Context, arg db.CreateEntryParams) (db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateEntry", ctx, arg)
+	ret0, _ := ret[0].(db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateEntry indicates an expected call of CreateEntry.
+func (mr *MockBankMockRecorder) CreateEntry(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntry", reflect.TypeOf((*MockBank)(nil).CreateEntry), ctx, arg)
+}
+
+// CreateFeeSchedule mocks base method.
+func (m *MockBank) CreateFeeSchedule(ctx context.Context, arg db.CreateFeeScheduleParams) (db.FeeSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFeeSchedule", ctx, arg)
+	ret0, _ := ret[0].(db.FeeSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFeeSchedule indicates an expected call of CreateFeeSchedule.
+func (mr *MockBankMockRecorder) CreateFeeSchedule(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFeeSchedule", reflect.TypeOf((*MockBank)(nil).CreateFeeSchedule), ctx, arg)
+}
+
+// CreateHold mocks base method.
+func (m *MockBank) CreateHold(ctx context.Context, arg db.CreateHoldParams) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHold", ctx, arg)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHold indicates an expected call of CreateHold.
+func (mr *MockBankMockRecorder) CreateHold(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHold", reflect.TypeOf((*MockBank)(nil).CreateHold), ctx, arg)
+}
+
+// CreateInterestAccrual mocks base method.
+func (m *MockBank) CreateInterestAccrual(ctx context.Context, arg db.CreateInterestAccrualParams) (db.InterestAccrual, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInterestAccrual", ctx, arg)
+	ret0, _ := ret[0].(db.InterestAccrual)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateInterestAccrual indicates an expected call of CreateInterestAccrual.
+func (mr *MockBankMockRecorder) CreateInterestAccrual(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInterestAccrual", reflect.TypeOf((*MockBank)(nil).CreateInterestAccrual), ctx, arg)
+}
+
+// CreateInternalAccount mocks base method.
+func (m *MockBank) CreateInternalAccount(ctx context.Context, arg db.CreateInternalAccountParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateInternalAccount", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateInternalAccount indicates an expected call of CreateInternalAccount.
+func (mr *MockBankMockRecorder) CreateInternalAccount(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateInternalAccount", reflect.TypeOf((*MockBank)(nil).CreateInternalAccount), ctx, arg)
+}
+
+// CreateJob mocks base method.
+func (m *MockBank) CreateJob(ctx context.Context, arg db.CreateJobParams) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJob", ctx, arg)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateJob indicates an expected call of CreateJob.
+func (mr *MockBankMockRecorder) CreateJob(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJob", reflect.TypeOf((*MockBank)(nil).CreateJob), ctx, arg)
+}
+
+// CreateJournal mocks base method.
+func (m *MockBank) CreateJournal(ctx context.Context, kind string) (db.Journal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateJournal", ctx, kind)
+	ret0, _ := ret[0].(db.Journal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateJournal indicates an expected call of CreateJournal.
+func (mr *MockBankMockRecorder) CreateJournal(ctx, kind any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateJournal", reflect.TypeOf((*MockBank)(nil).CreateJournal), ctx, kind)
+}
+
+// CreateOutboxEvent mocks base method.
+func (m *MockBank) CreateOutboxEvent(ctx context.Context, arg db.CreateOutboxEventParams) (db.Outbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOutboxEvent", ctx, arg)
+	ret0, _ := ret[0].(db.Outbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateOutboxEvent indicates an expected call of CreateOutboxEvent.
+func (mr *MockBankMockRecorder) CreateOutboxEvent(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOutboxEvent", reflect.TypeOf((*MockBank)(nil).CreateOutboxEvent), ctx, arg)
+}
+
+// CreatePasswordReset mocks base method.
+func (m *MockBank) CreatePasswordReset(ctx context.Context, arg db.CreatePasswordResetParams) (db.PasswordReset, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordReset", ctx, arg)
+	ret0, _ := ret[0].(db.PasswordReset)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePasswordReset indicates an expected call of CreatePasswordReset.
+func (mr *MockBankMockRecorder) CreatePasswordReset(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordReset", reflect.TypeOf((*MockBank)(nil).CreatePasswordReset), ctx, arg)
+}
+
+// CreatePasswordResetRequest mocks base method.
+func (m *MockBank) CreatePasswordResetRequest(ctx context.Context, arg db.CreatePasswordResetRequestParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordResetRequest", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordResetRequest indicates an expected call of CreatePasswordResetRequest.
+func (mr *MockBankMockRecorder) CreatePasswordResetRequest(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordResetRequest", reflect.TypeOf((*MockBank)(nil).CreatePasswordResetRequest), ctx, arg)
+}
+
+// CreateRecoveryCode mocks base method.
+func (m *MockBank) CreateRecoveryCode(ctx context.Context, arg db.CreateRecoveryCodeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecoveryCode", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRecoveryCode indicates an expected call of CreateRecoveryCode.
+func (mr *MockBankMockRecorder) CreateRecoveryCode(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecoveryCode", reflect.TypeOf((*MockBank)(nil).CreateRecoveryCode), ctx, arg)
+}
+
+// CreateReversal mocks base method.
+func (m *MockBank) CreateReversal(ctx context.Context, arg db.CreateReversalParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateReversal", ctx, arg)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateReversal indicates an expected call of CreateReversal.
+func (mr *MockBankMockRecorder) CreateReversal(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateReversal", reflect.TypeOf((*MockBank)(nil).CreateReversal), ctx, arg)
+}
+
+// CreateScheduledTransfer mocks base method.
+func (m *MockBank) CreateScheduledTransfer(ctx context.Context, arg db.CreateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateScheduledTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledTransfer indicates an expected call of CreateScheduledTransfer.
+func (mr *MockBankMockRecorder) CreateScheduledTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledTransfer", reflect.TypeOf((*MockBank)(nil).CreateScheduledTransfer), ctx, arg)
+}
+
+// CreateScheduledTransferRun mocks base method.
+func (m *MockBank) CreateScheduledTransferRun(ctx context.Context, arg db.CreateScheduledTransferRunParams) (db.ScheduledTransferRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateScheduledTransferRun", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransferRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateScheduledTransferRun indicates an expected call of CreateScheduledTransferRun.
+func (mr *MockBankMockRecorder) CreateScheduledTransferRun(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateScheduledTransferRun", reflect.TypeOf((*MockBank)(nil).CreateScheduledTransferRun), ctx, arg)
+}
+
+// CreateSession mocks base method.
+func (m *MockBank) CreateSession(ctx context.Context, arg db.CreateSessionParams) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSession", ctx, arg)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSession indicates an expected call of CreateSession.
+func (mr *MockBankMockRecorder) CreateSession(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockBank)(nil).CreateSession), ctx, arg)
+}
+
+// CreateTransfer mocks base method.
+func (m *MockBank) CreateTransfer(ctx context.Context, arg db.CreateTransferParams) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateTransfer indicates an expected call of CreateTransfer.
+func (mr *MockBankMockRecorder) CreateTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransfer", reflect.TypeOf((*MockBank)(nil).CreateTransfer), ctx, arg)
+}
+
+// CreateUser mocks base method.
+func (m *MockBank) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUser", ctx, arg)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUser indicates an expected call of CreateUser.
+func (mr *MockBankMockRecorder) CreateUser(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockBank)(nil).CreateUser), ctx, arg)
+}
+
+// CreateUserTOTP mocks base method.
+func (m *MockBank) CreateUserTOTP(ctx context.Context, arg db.CreateUserTOTPParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateUserTOTP", ctx, arg)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateUserTOTP indicates an expected call of CreateUserTOTP.
+func (mr *MockBankMockRecorder) CreateUserTOTP(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUserTOTP", reflect.TypeOf((*MockBank)(nil).CreateUserTOTP), ctx, arg)
+}
+
+// CreateVerifyEmail mocks base method.
+func (m *MockBank) CreateVerifyEmail(ctx context.Context, arg db.CreateVerifyEmailParams) (db.VerifyEmail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVerifyEmail", ctx, arg)
+	ret0, _ := ret[0].(db.VerifyEmail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateVerifyEmail indicates an expected call of CreateVerifyEmail.
+func (mr *MockBankMockRecorder) CreateVerifyEmail(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVerifyEmail", reflect.TypeOf((*MockBank)(nil).CreateVerifyEmail), ctx, arg)
+}
+
+// CreateWebhookDeliveries mocks base method.
+func (m *MockBank) CreateWebhookDeliveries(ctx context.Context, arg db.CreateWebhookDeliveriesParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookDeliveries", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateWebhookDeliveries indicates an expected call of CreateWebhookDeliveries.
+func (mr *MockBankMockRecorder) CreateWebhookDeliveries(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookDeliveries", reflect.TypeOf((*MockBank)(nil).CreateWebhookDeliveries), ctx, arg)
+}
+
+// CreateWebhookSubscription mocks base method.
+func (m *MockBank) CreateWebhookSubscription(ctx context.Context, arg db.CreateWebhookSubscriptionParams) (db.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWebhookSubscription", ctx, arg)
+	ret0, _ := ret[0].(db.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateWebhookSubscription indicates an expected call of CreateWebhookSubscription.
+func (mr *MockBankMockRecorder) CreateWebhookSubscription(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWebhookSubscription", reflect.TypeOf((*MockBank)(nil).CreateWebhookSubscription), ctx, arg)
+}
+
+// DeleteFullRateLimitBuckets mocks base method.
+func (m *MockBank) DeleteFullRateLimitBuckets(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFullRateLimitBuckets", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFullRateLimitBuckets indicates an expected call of DeleteFullRateLimitBuckets.
+func (mr *MockBankMockRecorder) DeleteFullRateLimitBuckets(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFullRateLimitBuckets", reflect.TypeOf((*MockBank)(nil).DeleteFullRateLimitBuckets), ctx)
+}
+
+// DeleteLoginThrottle mocks base method.
+func (m *MockBank) DeleteLoginThrottle(ctx context.Context, arg db.DeleteLoginThrottleParams) (db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginThrottle", ctx, arg)
+	ret0, _ := ret[0].(db.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLoginThrottle indicates an expected call of DeleteLoginThrottle.
+func (mr *MockBankMockRecorder) DeleteLoginThrottle(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginThrottle", reflect.TypeOf((*MockBank)(nil).DeleteLoginThrottle), ctx, arg)
+}
+
+// DeleteUserRecoveryCodes mocks base method.
+func (m *MockBank) DeleteUserRecoveryCodes(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserRecoveryCodes", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserRecoveryCodes indicates an expected call of DeleteUserRecoveryCodes.
+func (mr *MockBankMockRecorder) DeleteUserRecoveryCodes(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserRecoveryCodes", reflect.TypeOf((*MockBank)(nil).DeleteUserRecoveryCodes), ctx, username)
+}
+
+// DeleteUserTOTP mocks base method.
+func (m *MockBank) DeleteUserTOTP(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserTOTP", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserTOTP indicates an expected call of DeleteUserTOTP.
+func (mr *MockBankMockRecorder) DeleteUserTOTP(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTOTP", reflect.TypeOf((*MockBank)(nil).DeleteUserTOTP), ctx, username)
+}
+
+// DeleteUserTransferLimit mocks base method.
+func (m *MockBank) DeleteUserTransferLimit(ctx context.Context, owner string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserTransferLimit", ctx, owner)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserTransferLimit indicates an expected call of DeleteUserTransferLimit.
+func (mr *MockBankMockRecorder) DeleteUserTransferLimit(ctx, owner any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserTransferLimit", reflect.TypeOf((*MockBank)(nil).DeleteUserTransferLimit), ctx, owner)
+}
+
+// DeleteWebhookSubscription mocks base method.
+func (m *MockBank) DeleteWebhookSubscription(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteWebhookSubscription", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteWebhookSubscription indicates an expected call of DeleteWebhookSubscription.
+func (mr *MockBankMockRecorder) DeleteWebhookSubscription(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWebhookSubscription", reflect.TypeOf((*MockBank)(nil).DeleteWebhookSubscription), ctx, id)
+}
+
+// DeliverWebhook mocks base method.
+func (m *MockBank) DeliverWebhook(ctx context.Context, policy bank.RetryPolicy, deliver func(context.Context, db.ClaimWebhookDeliveryRow) (int32, error)) (db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeliverWebhook", ctx, policy, deliver)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeliverWebhook indicates an expected call of DeliverWebhook.
+func (mr *MockBankMockRecorder) DeliverWebhook(ctx, policy, deliver any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeliverWebhook", reflect.TypeOf((*MockBank)(nil).DeliverWebhook), ctx, policy, deliver)
+}
+
+// DisableTOTP mocks base method.
+func (m *MockBank) DisableTOTP(ctx context.Context, username string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTOTP", ctx, username)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTOTP indicates an expected call of DisableTOTP.
+func (mr *MockBankMockRecorder) DisableTOTP(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTOTP", reflect.TypeOf((*MockBank)(nil).DisableTOTP), ctx, username)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockBank) EnableTOTP(ctx context.Context, arg bank.EnableTOTPParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, arg)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockBankMockRecorder) EnableTOTP(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockBank)(nil).EnableTOTP), ctx, arg)
+}
+
+// EnableUserTOTP mocks base method.
+func (m *MockBank) EnableUserTOTP(ctx context.Context, arg db.EnableUserTOTPParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableUserTOTP", ctx, arg)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableUserTOTP indicates an expected call of EnableUserTOTP.
+func (mr *MockBankMockRecorder) EnableUserTOTP(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableUserTOTP", reflect.TypeOf((*MockBank)(nil).EnableUserTOTP), ctx, arg)
+}
+
+// EnrollTOTP mocks base method.
+func (m *MockBank) EnrollTOTP(ctx context.Context, arg bank.EnrollTOTPParams) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollTOTP", ctx, arg)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollTOTP indicates an expected call of EnrollTOTP.
+func (mr *MockBankMockRecorder) EnrollTOTP(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollTOTP", reflect.TypeOf((*MockBank)(nil).EnrollTOTP), ctx, arg)
+}
+
+// ExpireHolds mocks base method.
+func (m *MockBank) ExpireHolds(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExpireHolds", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ExpireHolds indicates an expected call of ExpireHolds.
+func (mr *MockBankMockRecorder) ExpireHolds(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExpireHolds", reflect.TypeOf((*MockBank)(nil).ExpireHolds), ctx)
+}
+
+// FreezeAccount mocks base method.
+func (m *MockBank) FreezeAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FreezeAccount", ctx, accountID)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FreezeAccount indicates an expected call of FreezeAccount.
+func (mr *MockBankMockRecorder) FreezeAccount(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FreezeAccount", reflect.TypeOf((*MockBank)(nil).FreezeAccount), ctx, accountID)
+}
+
+// GetAPIKeyByHash mocks base method.
+func (m *MockBank) GetAPIKeyByHash(ctx context.Context, keyHash string) (db.GetAPIKeyByHashRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyByHash", ctx, keyHash)
+	ret0, _ := ret[0].(db.GetAPIKeyByHashRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyByHash indicates an expected call of GetAPIKeyByHash.
+func (mr *MockBankMockRecorder) GetAPIKeyByHash(ctx, keyHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyByHash", reflect.TypeOf((*MockBank)(nil).GetAPIKeyByHash), ctx, keyHash)
+}
+
+// GetAPIKeyForUpdate mocks base method.
+func (m *MockBank) GetAPIKeyForUpdate(ctx context.Context, id int64) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAPIKeyForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAPIKeyForUpdate indicates an expected call of GetAPIKeyForUpdate.
+func (mr *MockBankMockRecorder) GetAPIKeyForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAPIKeyForUpdate", reflect.TypeOf((*MockBank)(nil).GetAPIKeyForUpdate), ctx, id)
+}
+
+// GetAccount mocks base method.
+func (m *MockBank) GetAccount(ctx context.Context, id int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccount", ctx, id)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccount indicates an expected call of GetAccount.
+func (mr *MockBankMockRecorder) GetAccount(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockBank)(nil).GetAccount), ctx, id)
+}
+
+// GetAccountBalanceEvent mocks base method.
+func (m *MockBank) GetAccountBalanceEvent(ctx context.Context, id int64) (db.GetAccountBalanceEventRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountBalanceEvent", ctx, id)
+	ret0, _ := ret[0].(db.GetAccountBalanceEventRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountBalanceEvent indicates an expected call of GetAccountBalanceEvent.
+func (mr *MockBankMockRecorder) GetAccountBalanceEvent(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountBalanceEvent", reflect.TypeOf((*MockBank)(nil).GetAccountBalanceEvent), ctx, id)
+}
+
+// GetAccountForUpdate mocks base method.
+func (m *MockBank) GetAccountForUpdate(ctx context.Context, id int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountForUpdate indicates an expected call of GetAccountForUpdate.
+func (mr *MockBankMockRecorder) GetAccountForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountForUpdate", reflect.TypeOf((*MockBank)(nil).GetAccountForUpdate), ctx, id)
+}
+
+// GetAccountProduct mocks base method.
+func (m *MockBank) GetAccountProduct(ctx context.Context, name string) (db.AccountProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAccountProduct", ctx, name)
+	ret0, _ := ret[0].(db.AccountProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAccountProduct indicates an expected call of GetAccountProduct.
+func (mr *MockBankMockRecorder) GetAccountProduct(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountProduct", reflect.TypeOf((*MockBank)(nil).GetAccountProduct), ctx, name)
+}
+
+// GetEntriesAmountSince mocks base method.
+func (m *MockBank) GetEntriesAmountSince(ctx context.Context, arg db.GetEntriesAmountSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEntriesAmountSince", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEntriesAmountSince indicates an expected call of GetEntriesAmountSince.
+func (mr *MockBankMockRecorder) GetEntriesAmountSince(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntriesAmountSince", reflect.TypeOf((*MockBank)(nil).GetEntriesAmountSince), ctx, arg)
+}
+
+// GetEntry mocks base method.
+func (m *MockBank) GetEntry(ctx context.Context, id int64) (db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetEntry", ctx, id)
+	ret0, _ := ret[0].(db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetEntry indicates an expected call of GetEntry.
+func (mr *MockBankMockRecorder) GetEntry(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntry", reflect.TypeOf((*MockBank)(nil).GetEntry), ctx, id)
+}
+
+// GetHeldAmount mocks base method.
+func (m *MockBank) GetHeldAmount(ctx context.Context, accountID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHeldAmount", ctx, accountID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHeldAmount indicates an expected call of GetHeldAmount.
+func (mr *MockBankMockRecorder) GetHeldAmount(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHeldAmount", reflect.TypeOf((*MockBank)(nil).GetHeldAmount), ctx, accountID)
+}
+
+// GetHold mocks base method.
+func (m *MockBank) GetHold(ctx context.Context, id int64) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHold", ctx, id)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHold indicates an expected call of GetHold.
+func (mr *MockBankMockRecorder) GetHold(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHold", reflect.TypeOf((*MockBank)(nil).GetHold), ctx, id)
+}
+
+// GetHoldForUpdate mocks base method.
+func (m *MockBank) GetHoldForUpdate(ctx context.Context, id int64) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetHoldForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetHoldForUpdate indicates an expected call of GetHoldForUpdate.
+func (mr *MockBankMockRecorder) GetHoldForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetHoldForUpdate", reflect.TypeOf((*MockBank)(nil).GetHoldForUpdate), ctx, id)
+}
+
+// GetJob mocks base method.
+func (m *MockBank) GetJob(ctx context.Context, id int64) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJob", ctx, id)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJob indicates an expected call of GetJob.
+func (mr *MockBankMockRecorder) GetJob(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJob", reflect.TypeOf((*MockBank)(nil).GetJob), ctx, id)
+}
+
+// GetJournal mocks base method.
+func (m *MockBank) GetJournal(ctx context.Context, id int64) (db.Journal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetJournal", ctx, id)
+	ret0, _ := ret[0].(db.Journal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetJournal indicates an expected call of GetJournal.
+func (mr *MockBankMockRecorder) GetJournal(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetJournal", reflect.TypeOf((*MockBank)(nil).GetJournal), ctx, id)
+}
+
+// GetLastAuditEvent mocks base method.
+func (m *MockBank) GetLastAuditEvent(ctx context.Context) (db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastAuditEvent", ctx)
+	ret0, _ := ret[0].(db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastAuditEvent indicates an expected call of GetLastAuditEvent.
+func (mr *MockBankMockRecorder) GetLastAuditEvent(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastAuditEvent", reflect.TypeOf((*MockBank)(nil).GetLastAuditEvent), ctx)
+}
+
+// GetLastInterestAccrual mocks base method.
+func (m *MockBank) GetLastInterestAccrual(ctx context.Context, accountID int64) (db.InterestAccrual, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetLastInterestAccrual", ctx, accountID)
+	ret0, _ := ret[0].(db.InterestAccrual)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetLastInterestAccrual indicates an expected call of GetLastInterestAccrual.
+func (mr *MockBankMockRecorder) GetLastInterestAccrual(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLastInterestAccrual", reflect.TypeOf((*MockBank)(nil).GetLastInterestAccrual), ctx, accountID)
+}
+
+// GetOutgoingAmountSince mocks base method.
+func (m *MockBank) GetOutgoingAmountSince(ctx context.Context, arg db.GetOutgoingAmountSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOutgoingAmountSince", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOutgoingAmountSince indicates an expected call of GetOutgoingAmountSince.
+func (mr *MockBankMockRecorder) GetOutgoingAmountSince(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOutgoingAmountSince", reflect.TypeOf((*MockBank)(nil).GetOutgoingAmountSince), ctx, arg)
+}
+
+// GetOwnerAccount mocks base method.
+func (m *MockBank) GetOwnerAccount(ctx context.Context, arg db.GetOwnerAccountParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnerAccount", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnerAccount indicates an expected call of GetOwnerAccount.
+func (mr *MockBankMockRecorder) GetOwnerAccount(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnerAccount", reflect.TypeOf((*MockBank)(nil).GetOwnerAccount), ctx, arg)
+}
+
+// GetOwnerOutgoingAmountSince mocks base method.
+func (m *MockBank) GetOwnerOutgoingAmountSince(ctx context.Context, arg db.GetOwnerOutgoingAmountSinceParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOwnerOutgoingAmountSince", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetOwnerOutgoingAmountSince indicates an expected call of GetOwnerOutgoingAmountSince.
+func (mr *MockBankMockRecorder) GetOwnerOutgoingAmountSince(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOwnerOutgoingAmountSince", reflect.TypeOf((*MockBank)(nil).GetOwnerOutgoingAmountSince), ctx, arg)
+}
+
+// GetPasswordResetByTokenForUpdate mocks base method.
+func (m *MockBank) GetPasswordResetByTokenForUpdate(ctx context.Context, tokenHash pgtype.Text) (db.PasswordReset, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordResetByTokenForUpdate", ctx, tokenHash)
+	ret0, _ := ret[0].(db.PasswordReset)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordResetByTokenForUpdate indicates an expected call of GetPasswordResetByTokenForUpdate.
+func (mr *MockBankMockRecorder) GetPasswordResetByTokenForUpdate(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordResetByTokenForUpdate", reflect.TypeOf((*MockBank)(nil).GetPasswordResetByTokenForUpdate), ctx, tokenHash)
+}
+
+// GetPasswordResetToSend mocks base method.
+func (m *MockBank) GetPasswordResetToSend(ctx context.Context, id int64) (db.GetPasswordResetToSendRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPasswordResetToSend", ctx, id)
+	ret0, _ := ret[0].(db.GetPasswordResetToSendRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPasswordResetToSend indicates an expected call of GetPasswordResetToSend.
+func (mr *MockBankMockRecorder) GetPasswordResetToSend(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPasswordResetToSend", reflect.TypeOf((*MockBank)(nil).GetPasswordResetToSend), ctx, id)
+}
+
+// GetReversedAmount mocks base method.
+func (m *MockBank) GetReversedAmount(ctx context.Context, transferID int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetReversedAmount", ctx, transferID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetReversedAmount indicates an expected call of GetReversedAmount.
+func (mr *MockBankMockRecorder) GetReversedAmount(ctx, transferID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetReversedAmount", reflect.TypeOf((*MockBank)(nil).GetReversedAmount), ctx, transferID)
+}
+
+// GetScheduledTransfer mocks base method.
+func (m *MockBank) GetScheduledTransfer(ctx context.Context, id int64) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledTransfer", ctx, id)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledTransfer indicates an expected call of GetScheduledTransfer.
+func (mr *MockBankMockRecorder) GetScheduledTransfer(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledTransfer", reflect.TypeOf((*MockBank)(nil).GetScheduledTransfer), ctx, id)
+}
+
+// GetScheduledTransferForUpdate mocks base method.
+func (m *MockBank) GetScheduledTransferForUpdate(ctx context.Context, id int64) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetScheduledTransferForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetScheduledTransferForUpdate indicates an expected call of GetScheduledTransferForUpdate.
+func (mr *MockBankMockRecorder) GetScheduledTransferForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetScheduledTransferForUpdate", reflect.TypeOf((*MockBank)(nil).GetScheduledTransferForUpdate), ctx, id)
+}
+
+// GetSession mocks base method.
+func (m *MockBank) GetSession(ctx context.Context, id string) (db.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSession", ctx, id)
+	ret0, _ := ret[0].(db.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSession indicates an expected call of GetSession.
+func (mr *MockBankMockRecorder) GetSession(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockBank)(nil).GetSession), ctx, id)
+}
+
+// GetTransfer mocks base method.
+func (m *MockBank) GetTransfer(ctx context.Context, id int64) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransfer", ctx, id)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransfer indicates an expected call of GetTransfer.
+func (mr *MockBankMockRecorder) GetTransfer(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfer", reflect.TypeOf((*MockBank)(nil).GetTransfer), ctx, id)
+}
+
+// GetTransferForUpdate mocks base method.
+func (m *MockBank) GetTransferForUpdate(ctx context.Context, id int64) (db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferForUpdate indicates an expected call of GetTransferForUpdate.
+func (mr *MockBankMockRecorder) GetTransferForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferForUpdate", reflect.TypeOf((*MockBank)(nil).GetTransferForUpdate), ctx, id)
+}
+
+// GetTransferLimit mocks base method.
+func (m *MockBank) GetTransferLimit(ctx context.Context, arg db.GetTransferLimitParams) (db.TransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferLimit", ctx, arg)
+	ret0, _ := ret[0].(db.TransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferLimit indicates an expected call of GetTransferLimit.
+func (mr *MockBankMockRecorder) GetTransferLimit(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferLimit", reflect.TypeOf((*MockBank)(nil).GetTransferLimit), ctx, arg)
+}
+
+// GetTransferLimitUsage mocks base method.
+func (m *MockBank) GetTransferLimitUsage(ctx context.Context, accountID int64) (bank.TransferLimitUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransferLimitUsage", ctx, accountID)
+	ret0, _ := ret[0].(bank.TransferLimitUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransferLimitUsage indicates an expected call of GetTransferLimitUsage.
+func (mr *MockBankMockRecorder) GetTransferLimitUsage(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransferLimitUsage", reflect.TypeOf((*MockBank)(nil).GetTransferLimitUsage), ctx, accountID)
+}
+
+// GetUser mocks base method.
+func (m *MockBank) GetUser(ctx context.Context, username string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", ctx, username)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockBankMockRecorder) GetUser(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockBank)(nil).GetUser), ctx, username)
+}
+
+// GetUserAuthChangedAt mocks base method.
+func (m *MockBank) GetUserAuthChangedAt(ctx context.Context, username string) (time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserAuthChangedAt", ctx, username)
+	ret0, _ := ret[0].(time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserAuthChangedAt indicates an expected call of GetUserAuthChangedAt.
+func (mr *MockBankMockRecorder) GetUserAuthChangedAt(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserAuthChangedAt", reflect.TypeOf((*MockBank)(nil).GetUserAuthChangedAt), ctx, username)
+}
+
+// GetUserByEmail mocks base method.
+func (m *MockBank) GetUserByEmail(ctx context.Context, email string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserByEmail", ctx, email)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserByEmail indicates an expected call of GetUserByEmail.
+func (mr *MockBankMockRecorder) GetUserByEmail(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserByEmail", reflect.TypeOf((*MockBank)(nil).GetUserByEmail), ctx, email)
+}
+
+// GetUserForUpdate mocks base method.
+func (m *MockBank) GetUserForUpdate(ctx context.Context, username string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserForUpdate", ctx, username)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserForUpdate indicates an expected call of GetUserForUpdate.
+func (mr *MockBankMockRecorder) GetUserForUpdate(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserForUpdate", reflect.TypeOf((*MockBank)(nil).GetUserForUpdate), ctx, username)
+}
+
+// GetUserTOTP mocks base method.
+func (m *MockBank) GetUserTOTP(ctx context.Context, username string) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTOTP", ctx, username)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTOTP indicates an expected call of GetUserTOTP.
+func (mr *MockBankMockRecorder) GetUserTOTP(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTOTP", reflect.TypeOf((*MockBank)(nil).GetUserTOTP), ctx, username)
+}
+
+// GetUserTOTPForUpdate mocks base method.
+func (m *MockBank) GetUserTOTPForUpdate(ctx context.Context, username string) (db.UserTotp, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUserTOTPForUpdate", ctx, username)
+	ret0, _ := ret[0].(db.UserTotp)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUserTOTPForUpdate indicates an expected call of GetUserTOTPForUpdate.
+func (mr *MockBankMockRecorder) GetUserTOTPForUpdate(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUserTOTPForUpdate", reflect.TypeOf((*MockBank)(nil).GetUserTOTPForUpdate), ctx, username)
+}
+
+// GetVerifyEmailForUpdate mocks base method.
+func (m *MockBank) GetVerifyEmailForUpdate(ctx context.Context, id int64) (db.VerifyEmail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVerifyEmailForUpdate", ctx, id)
+	ret0, _ := ret[0].(db.VerifyEmail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVerifyEmailForUpdate indicates an expected call of GetVerifyEmailForUpdate.
+func (mr *MockBankMockRecorder) GetVerifyEmailForUpdate(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVerifyEmailForUpdate", reflect.TypeOf((*MockBank)(nil).GetVerifyEmailForUpdate), ctx, id)
+}
+
+// GetVerifyEmailToSend mocks base method.
+func (m *MockBank) GetVerifyEmailToSend(ctx context.Context, id int64) (db.GetVerifyEmailToSendRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVerifyEmailToSend", ctx, id)
+	ret0, _ := ret[0].(db.GetVerifyEmailToSendRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVerifyEmailToSend indicates an expected call of GetVerifyEmailToSend.
+func (mr *MockBankMockRecorder) GetVerifyEmailToSend(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVerifyEmailToSend", reflect.TypeOf((*MockBank)(nil).GetVerifyEmailToSend), ctx, id)
+}
+
+// GetWebhookDelivery mocks base method.
+func (m *MockBank) GetWebhookDelivery(ctx context.Context, id int64) (db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookDelivery", ctx, id)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookDelivery indicates an expected call of GetWebhookDelivery.
+func (mr *MockBankMockRecorder) GetWebhookDelivery(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookDelivery", reflect.TypeOf((*MockBank)(nil).GetWebhookDelivery), ctx, id)
+}
+
+// GetWebhookSubscription mocks base method.
+func (m *MockBank) GetWebhookSubscription(ctx context.Context, id int64) (db.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetWebhookSubscription", ctx, id)
+	ret0, _ := ret[0].(db.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetWebhookSubscription indicates an expected call of GetWebhookSubscription.
+func (mr *MockBankMockRecorder) GetWebhookSubscription(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetWebhookSubscription", reflect.TypeOf((*MockBank)(nil).GetWebhookSubscription), ctx, id)
+}
+
+// InvalidateUserPasswordResets mocks base method.
+func (m *MockBank) InvalidateUserPasswordResets(ctx context.Context, username string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InvalidateUserPasswordResets", ctx, username)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// InvalidateUserPasswordResets indicates an expected call of InvalidateUserPasswordResets.
+func (mr *MockBankMockRecorder) InvalidateUserPasswordResets(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InvalidateUserPasswordResets", reflect.TypeOf((*MockBank)(nil).InvalidateUserPasswordResets), ctx, username)
+}
+
+// IssueAPIKey mocks base method.
+func (m *MockBank) IssueAPIKey(ctx context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IssueAPIKey", ctx, arg)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IssueAPIKey indicates an expected call of IssueAPIKey.
+func (mr *MockBankMockRecorder) IssueAPIKey(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueAPIKey", reflect.TypeOf((*MockBank)(nil).IssueAPIKey), ctx, arg)
+}
+
+// ListAPIKeys mocks base method.
+func (m *MockBank) ListAPIKeys(ctx context.Context, arg db.ListAPIKeysParams) ([]db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAPIKeys", ctx, arg)
+	ret0, _ := ret[0].([]db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAPIKeys indicates an expected call of ListAPIKeys.
+func (mr *MockBankMockRecorder) ListAPIKeys(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAPIKeys", reflect.TypeOf((*MockBank)(nil).ListAPIKeys), ctx, arg)
+}
+
+// ListAccountBalancesAfter mocks base method.
+func (m *MockBank) ListAccountBalancesAfter(ctx context.Context, arg db.ListAccountBalancesAfterParams) ([]db.ListAccountBalancesAfterRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountBalancesAfter", ctx, arg)
+	ret0, _ := ret[0].([]db.ListAccountBalancesAfterRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountBalancesAfter indicates an expected call of ListAccountBalancesAfter.
+func (mr *MockBankMockRecorder) ListAccountBalancesAfter(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountBalancesAfter", reflect.TypeOf((*MockBank)(nil).ListAccountBalancesAfter), ctx, arg)
+}
+
+// ListAccountHistory mocks base method.
+func (m *MockBank) ListAccountHistory(ctx context.Context, arg db.ListAccountHistoryParams) ([]db.ListAccountHistoryRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountHistory", ctx, arg)
+	ret0, _ := ret[0].([]db.ListAccountHistoryRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountHistory indicates an expected call of ListAccountHistory.
+func (mr *MockBankMockRecorder) ListAccountHistory(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountHistory", reflect.TypeOf((*MockBank)(nil).ListAccountHistory), ctx, arg)
+}
+
+// ListAccountProductRates mocks base method.
+func (m *MockBank) ListAccountProductRates(ctx context.Context, product string) ([]db.AccountProductRate, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountProductRates", ctx, product)
+	ret0, _ := ret[0].([]db.AccountProductRate)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountProductRates indicates an expected call of ListAccountProductRates.
+func (mr *MockBankMockRecorder) ListAccountProductRates(ctx, product any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountProductRates", reflect.TypeOf((*MockBank)(nil).ListAccountProductRates), ctx, product)
+}
+
+// ListAccountProducts mocks base method.
+func (m *MockBank) ListAccountProducts(ctx context.Context) ([]db.AccountProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountProducts", ctx)
+	ret0, _ := ret[0].([]db.AccountProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountProducts indicates an expected call of ListAccountProducts.
+func (mr *MockBankMockRecorder) ListAccountProducts(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountProducts", reflect.TypeOf((*MockBank)(nil).ListAccountProducts), ctx)
+}
+
+// ListAccounts mocks base method.
+func (m *MockBank) ListAccounts(ctx context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccounts", ctx, arg)
+	ret0, _ := ret[0].([]db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccounts indicates an expected call of ListAccounts.
+func (mr *MockBankMockRecorder) ListAccounts(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockBank)(nil).ListAccounts), ctx, arg)
+}
+
+// ListAccountsAfter mocks base method.
+func (m *MockBank) ListAccountsAfter(ctx context.Context, arg db.ListAccountsAfterParams) ([]db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountsAfter", ctx, arg)
+	ret0, _ := ret[0].([]db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountsAfter indicates an expected call of ListAccountsAfter.
+func (mr *MockBankMockRecorder) ListAccountsAfter(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountsAfter", reflect.TypeOf((*MockBank)(nil).ListAccountsAfter), ctx, arg)
+}
+
+// ListAccrualPostings mocks base method.
+func (m *MockBank) ListAccrualPostings(ctx context.Context, arg db.ListAccrualPostingsParams) ([]db.AccrualPosting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccrualPostings", ctx, arg)
+	ret0, _ := ret[0].([]db.AccrualPosting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccrualPostings indicates an expected call of ListAccrualPostings.
+func (mr *MockBankMockRecorder) ListAccrualPostings(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccrualPostings", reflect.TypeOf((*MockBank)(nil).ListAccrualPostings), ctx, arg)
+}
+
+// ListAuditEvents mocks base method.
+func (m *MockBank) ListAuditEvents(ctx context.Context, arg db.ListAuditEventsParams) ([]db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEvents", ctx, arg)
+	ret0, _ := ret[0].([]db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEvents indicates an expected call of ListAuditEvents.
+func (mr *MockBankMockRecorder) ListAuditEvents(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEvents", reflect.TypeOf((*MockBank)(nil).ListAuditEvents), ctx, arg)
+}
+
+// ListAuditEventsAfter mocks base method.
+func (m *MockBank) ListAuditEventsAfter(ctx context.Context, arg db.ListAuditEventsAfterParams) ([]db.AuditEvent, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAuditEventsAfter", ctx, arg)
+	ret0, _ := ret[0].([]db.AuditEvent)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAuditEventsAfter indicates an expected call of ListAuditEventsAfter.
+func (mr *MockBankMockRecorder) ListAuditEventsAfter(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAuditEventsAfter", reflect.TypeOf((*MockBank)(nil).ListAuditEventsAfter), ctx, arg)
+}
+
+// ListCurrencyEntryTotals mocks base method.
+func (m *MockBank) ListCurrencyEntryTotals(ctx context.Context) ([]db.ListCurrencyEntryTotalsRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListCurrencyEntryTotals", ctx)
+	ret0, _ := ret[0].([]db.ListCurrencyEntryTotalsRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListCurrencyEntryTotals indicates an expected call of ListCurrencyEntryTotals.
+func (mr *MockBankMockRecorder) ListCurrencyEntryTotals(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListCurrencyEntryTotals", reflect.TypeOf((*MockBank)(nil).ListCurrencyEntryTotals), ctx)
+}
+
+// ListEntries mocks base method.
+func (m *MockBank) ListEntries(ctx context.Context, arg db.ListEntriesParams) ([]db.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEntries", ctx, arg)
+	ret0, _ := ret[0].([]db.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEntries indicates an expected call of ListEntries.
+func (mr *MockBankMockRecorder) ListEntries(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockBank)(nil).ListEntries), ctx, arg)
+}
+
+// ListFeeAccrualPostings mocks base method.
+func (m *MockBank) ListFeeAccrualPostings(ctx context.Context, accountID int64) ([]db.AccrualPosting, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFeeAccrualPostings", ctx, accountID)
+	ret0, _ := ret[0].([]db.AccrualPosting)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFeeAccrualPostings indicates an expected call of ListFeeAccrualPostings.
+func (mr *MockBankMockRecorder) ListFeeAccrualPostings(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFeeAccrualPostings", reflect.TypeOf((*MockBank)(nil).ListFeeAccrualPostings), ctx, accountID)
+}
+
+// ListFeeSchedules mocks base method.
+func (m *MockBank) ListFeeSchedules(ctx context.Context, product string) ([]db.FeeSchedule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListFeeSchedules", ctx, product)
+	ret0, _ := ret[0].([]db.FeeSchedule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListFeeSchedules indicates an expected call of ListFeeSchedules.
+func (mr *MockBankMockRecorder) ListFeeSchedules(ctx, product any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListFeeSchedules", reflect.TypeOf((*MockBank)(nil).ListFeeSchedules), ctx, product)
+}
+
+// ListHolds mocks base method.
+func (m *MockBank) ListHolds(ctx context.Context, arg db.ListHoldsParams) ([]db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHolds", ctx, arg)
+	ret0, _ := ret[0].([]db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHolds indicates an expected call of ListHolds.
+func (mr *MockBankMockRecorder) ListHolds(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHolds", reflect.TypeOf((*MockBank)(nil).ListHolds), ctx, arg)
+}
+
+// ListJobs mocks base method.
+func (m *MockBank) ListJobs(ctx context.Context, arg db.ListJobsParams) ([]db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListJobs", ctx, arg)
+	ret0, _ := ret[0].([]db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListJobs indicates an expected call of ListJobs.
+func (mr *MockBankMockRecorder) ListJobs(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListJobs", reflect.TypeOf((*MockBank)(nil).ListJobs), ctx, arg)
+}
+
+// ListLoginThrottles mocks base method.
+func (m *MockBank) ListLoginThrottles(ctx context.Context, arg db.ListLoginThrottlesParams) ([]db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoginThrottles", ctx, arg)
+	ret0, _ := ret[0].([]db.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoginThrottles indicates an expected call of ListLoginThrottles.
+func (mr *MockBankMockRecorder) ListLoginThrottles(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoginThrottles", reflect.TypeOf((*MockBank)(nil).ListLoginThrottles), ctx, arg)
+}
+
+// ListLoginThrottlesByKeys mocks base method.
+func (m *MockBank) ListLoginThrottlesByKeys(ctx context.Context, arg db.ListLoginThrottlesByKeysParams) ([]db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLoginThrottlesByKeys", ctx, arg)
+	ret0, _ := ret[0].([]db.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLoginThrottlesByKeys indicates an expected call of ListLoginThrottlesByKeys.
+func (mr *MockBankMockRecorder) ListLoginThrottlesByKeys(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLoginThrottlesByKeys", reflect.TypeOf((*MockBank)(nil).ListLoginThrottlesByKeys), ctx, arg)
+}
+
+// ListScheduledTransferRuns mocks base method.
+func (m *MockBank) ListScheduledTransferRuns(ctx context.Context, arg db.ListScheduledTransferRunsParams) ([]db.ScheduledTransferRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScheduledTransferRuns", ctx, arg)
+	ret0, _ := ret[0].([]db.ScheduledTransferRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScheduledTransferRuns indicates an expected call of ListScheduledTransferRuns.
+func (mr *MockBankMockRecorder) ListScheduledTransferRuns(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduledTransferRuns", reflect.TypeOf((*MockBank)(nil).ListScheduledTransferRuns), ctx, arg)
+}
+
+// ListScheduledTransfers mocks base method.
+func (m *MockBank) ListScheduledTransfers(ctx context.Context, arg db.ListScheduledTransfersParams) ([]db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListScheduledTransfers", ctx, arg)
+	ret0, _ := ret[0].([]db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListScheduledTransfers indicates an expected call of ListScheduledTransfers.
+func (mr *MockBankMockRecorder) ListScheduledTransfers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListScheduledTransfers", reflect.TypeOf((*MockBank)(nil).ListScheduledTransfers), ctx, arg)
+}
+
+// ListTransferEntryCountsAfter mocks base method.
+func (m *MockBank) ListTransferEntryCountsAfter(ctx context.Context, arg db.ListTransferEntryCountsAfterParams) ([]db.ListTransferEntryCountsAfterRow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransferEntryCountsAfter", ctx, arg)
+	ret0, _ := ret[0].([]db.ListTransferEntryCountsAfterRow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransferEntryCountsAfter indicates an expected call of ListTransferEntryCountsAfter.
+func (mr *MockBankMockRecorder) ListTransferEntryCountsAfter(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransferEntryCountsAfter", reflect.TypeOf((*MockBank)(nil).ListTransferEntryCountsAfter), ctx, arg)
+}
+
+// ListTransferLimits mocks base method.
+func (m *MockBank) ListTransferLimits(ctx context.Context) ([]db.TransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransferLimits", ctx)
+	ret0, _ := ret[0].([]db.TransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransferLimits indicates an expected call of ListTransferLimits.
+func (mr *MockBankMockRecorder) ListTransferLimits(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransferLimits", reflect.TypeOf((*MockBank)(nil).ListTransferLimits), ctx)
+}
+
+// ListTransfers mocks base method.
+func (m *MockBank) ListTransfers(ctx context.Context, arg db.ListTransfersParams) ([]db.Transfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransfers", ctx, arg)
+	ret0, _ := ret[0].([]db.Transfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransfers indicates an expected call of ListTransfers.
+func (mr *MockBankMockRecorder) ListTransfers(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransfers", reflect.TypeOf((*MockBank)(nil).ListTransfers), ctx, arg)
+}
+
+// ListUnpostedInterestPeriods mocks base method.
+func (m *MockBank) ListUnpostedInterestPeriods(ctx context.Context, arg db.ListUnpostedInterestPeriodsParams) ([]pgtype.Date, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUnpostedInterestPeriods", ctx, arg)
+	ret0, _ := ret[0].([]pgtype.Date)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUnpostedInterestPeriods indicates an expected call of ListUnpostedInterestPeriods.
+func (mr *MockBankMockRecorder) ListUnpostedInterestPeriods(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUnpostedInterestPeriods", reflect.TypeOf((*MockBank)(nil).ListUnpostedInterestPeriods), ctx, arg)
+}
+
+// ListWebhookDeliveries mocks base method.
+func (m *MockBank) ListWebhookDeliveries(ctx context.Context, arg db.ListWebhookDeliveriesParams) ([]db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookDeliveries", ctx, arg)
+	ret0, _ := ret[0].([]db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookDeliveries indicates an expected call of ListWebhookDeliveries.
+func (mr *MockBankMockRecorder) ListWebhookDeliveries(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookDeliveries", reflect.TypeOf((*MockBank)(nil).ListWebhookDeliveries), ctx, arg)
+}
+
+// ListWebhookSubscriptions mocks base method.
+func (m *MockBank) ListWebhookSubscriptions(ctx context.Context, arg db.ListWebhookSubscriptionsParams) ([]db.WebhookSubscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWebhookSubscriptions", ctx, arg)
+	ret0, _ := ret[0].([]db.WebhookSubscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWebhookSubscriptions indicates an expected call of ListWebhookSubscriptions.
+func (mr *MockBankMockRecorder) ListWebhookSubscriptions(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWebhookSubscriptions", reflect.TypeOf((*MockBank)(nil).ListWebhookSubscriptions), ctx, arg)
+}
+
+// LockAuditChain mocks base method.
+func (m *MockBank) LockAuditChain(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockAuditChain", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockAuditChain indicates an expected call of LockAuditChain.
+func (mr *MockBankMockRecorder) LockAuditChain(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockAuditChain", reflect.TypeOf((*MockBank)(nil).LockAuditChain), ctx)
+}
+
+// LockLoginThrottle mocks base method.
+func (m *MockBank) LockLoginThrottle(ctx context.Context, arg db.LockLoginThrottleParams) (db.LoginThrottle, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLoginThrottle", ctx, arg)
+	ret0, _ := ret[0].(db.LoginThrottle)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockLoginThrottle indicates an expected call of LockLoginThrottle.
+func (mr *MockBankMockRecorder) LockLoginThrottle(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLoginThrottle", reflect.TypeOf((*MockBank)(nil).LockLoginThrottle), ctx, arg)
+}
+
+// LockRateLimitBucket mocks base method.
+func (m *MockBank) LockRateLimitBucket(ctx context.Context, arg db.LockRateLimitBucketParams) (db.RateLimitBucket, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockRateLimitBucket", ctx, arg)
+	ret0, _ := ret[0].(db.RateLimitBucket)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LockRateLimitBucket indicates an expected call of LockRateLimitBucket.
+func (mr *MockBankMockRecorder) LockRateLimitBucket(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockRateLimitBucket", reflect.TypeOf((*MockBank)(nil).LockRateLimitBucket), ctx, arg)
+}
+
+// MarkAPIKeyRevoked mocks base method.
+func (m *MockBank) MarkAPIKeyRevoked(ctx context.Context, id int64) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAPIKeyRevoked", ctx, id)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAPIKeyRevoked indicates an expected call of MarkAPIKeyRevoked.
+func (mr *MockBankMockRecorder) MarkAPIKeyRevoked(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAPIKeyRevoked", reflect.TypeOf((*MockBank)(nil).MarkAPIKeyRevoked), ctx, id)
+}
+
+// MarkAccountActive mocks base method.
+func (m *MockBank) MarkAccountActive(ctx context.Context, id int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAccountActive", ctx, id)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAccountActive indicates an expected call of MarkAccountActive.
+func (mr *MockBankMockRecorder) MarkAccountActive(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAccountActive", reflect.TypeOf((*MockBank)(nil).MarkAccountActive), ctx, id)
+}
+
+// MarkAccountClosed mocks base method.
+func (m *MockBank) MarkAccountClosed(ctx context.Context, id int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAccountClosed", ctx, id)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAccountClosed indicates an expected call of MarkAccountClosed.
+func (mr *MockBankMockRecorder) MarkAccountClosed(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAccountClosed", reflect.TypeOf((*MockBank)(nil).MarkAccountClosed), ctx, id)
+}
+
+// MarkAccountFrozen mocks base method.
+func (m *MockBank) MarkAccountFrozen(ctx context.Context, id int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkAccountFrozen", ctx, id)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkAccountFrozen indicates an expected call of MarkAccountFrozen.
+func (mr *MockBankMockRecorder) MarkAccountFrozen(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAccountFrozen", reflect.TypeOf((*MockBank)(nil).MarkAccountFrozen), ctx, id)
+}
+
+// MarkHoldCaptured mocks base method.
+func (m *MockBank) MarkHoldCaptured(ctx context.Context, arg db.MarkHoldCapturedParams) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkHoldCaptured", ctx, arg)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkHoldCaptured indicates an expected call of MarkHoldCaptured.
+func (mr *MockBankMockRecorder) MarkHoldCaptured(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkHoldCaptured", reflect.TypeOf((*MockBank)(nil).MarkHoldCaptured), ctx, arg)
+}
+
+// MarkHoldReleased mocks base method.
+func (m *MockBank) MarkHoldReleased(ctx context.Context, id int64) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkHoldReleased", ctx, id)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkHoldReleased indicates an expected call of MarkHoldReleased.
+func (mr *MockBankMockRecorder) MarkHoldReleased(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkHoldReleased", reflect.TypeOf((*MockBank)(nil).MarkHoldReleased), ctx, id)
+}
+
+// MarkJobSucceeded mocks base method.
+func (m *MockBank) MarkJobSucceeded(ctx context.Context, id int64) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkJobSucceeded", ctx, id)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkJobSucceeded indicates an expected call of MarkJobSucceeded.
+func (mr *MockBankMockRecorder) MarkJobSucceeded(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkJobSucceeded", reflect.TypeOf((*MockBank)(nil).MarkJobSucceeded), ctx, id)
+}
+
+// MarkOutboxEventPublished mocks base method.
+func (m *MockBank) MarkOutboxEventPublished(ctx context.Context, id int64) (db.Outbox, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkOutboxEventPublished", ctx, id)
+	ret0, _ := ret[0].(db.Outbox)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkOutboxEventPublished indicates an expected call of MarkOutboxEventPublished.
+func (mr *MockBankMockRecorder) MarkOutboxEventPublished(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkOutboxEventPublished", reflect.TypeOf((*MockBank)(nil).MarkOutboxEventPublished), ctx, id)
+}
+
+// MarkVerifyEmailSent mocks base method.
+func (m *MockBank) MarkVerifyEmailSent(ctx context.Context, id int64) (db.VerifyEmail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkVerifyEmailSent", ctx, id)
+	ret0, _ := ret[0].(db.VerifyEmail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkVerifyEmailSent indicates an expected call of MarkVerifyEmailSent.
+func (mr *MockBankMockRecorder) MarkVerifyEmailSent(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkVerifyEmailSent", reflect.TypeOf((*MockBank)(nil).MarkVerifyEmailSent), ctx, id)
+}
+
+// MarkVerifyEmailUsed mocks base method.
+func (m *MockBank) MarkVerifyEmailUsed(ctx context.Context, id int64) (db.VerifyEmail, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkVerifyEmailUsed", ctx, id)
+	ret0, _ := ret[0].(db.VerifyEmail)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkVerifyEmailUsed indicates an expected call of MarkVerifyEmailUsed.
+func (mr *MockBankMockRecorder) MarkVerifyEmailUsed(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkVerifyEmailUsed", reflect.TypeOf((*MockBank)(nil).MarkVerifyEmailUsed), ctx, id)
+}
+
+// MarkWebhookDeliverySucceeded mocks base method.
+func (m *MockBank) MarkWebhookDeliverySucceeded(ctx context.Context, arg db.MarkWebhookDeliverySucceededParams) (db.WebhookDelivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkWebhookDeliverySucceeded", ctx, arg)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MarkWebhookDeliverySucceeded indicates an expected call of MarkWebhookDeliverySucceeded.
+func (mr *MockBankMockRecorder) MarkWebhookDeliverySucceeded(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkWebhookDeliverySucceeded", reflect.TypeOf((*MockBank)(nil).MarkWebhookDeliverySucceeded), ctx, arg)
+}
+
+// OpenAccount mocks base method.
+func (m *MockBank) OpenAccount(ctx context.Context, arg bank.OpenAccountParams) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OpenAccount", ctx, arg)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OpenAccount indicates an expected call of OpenAccount.
+func (mr *MockBankMockRecorder) OpenAccount(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OpenAccount", reflect.TypeOf((*MockBank)(nil).OpenAccount), ctx, arg)
+}
+
+// PlaceHold mocks base method.
+func (m *MockBank) PlaceHold(ctx context.Context, arg bank.PlaceHoldParams) (db.Hold, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PlaceHold", ctx, arg)
+	ret0, _ := ret[0].(db.Hold)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PlaceHold indicates an expected call of PlaceHold.
+func (mr *MockBankMockRecorder) PlaceHold(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PlaceHold", reflect.TypeOf((*MockBank)(nil).PlaceHold), ctx, arg)
+}
+
+// RecordLoginFailure mocks base method.
+func (m *MockBank) RecordLoginFailure(ctx context.Context, arg bank.RecordLoginFailureParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLoginFailure", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RecordLoginFailure indicates an expected call of RecordLoginFailure.
+func (mr *MockBankMockRecorder) RecordLoginFailure(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginFailure", reflect.TypeOf((*MockBank)(nil).RecordLoginFailure), ctx, arg)
+}
+
+// RehashUserPassword mocks base method.
+func (m *MockBank) RehashUserPassword(ctx context.Context, arg db.RehashUserPasswordParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RehashUserPassword", ctx, arg)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddAccountBalance indicates an expected call of AddAccountBalance.
-func (mr *MockBankMockRecorder) AddAccountBalance(ctx, arg any) *gomock.Call {
+// RehashUserPassword indicates an expected call of RehashUserPassword.
+func (mr *MockBankMockRecorder) RehashUserPassword(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddAccountBalance", reflect.TypeOf((*MockBank)(nil).AddAccountBalance), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashUserPassword", reflect.TypeOf((*MockBank)(nil).RehashUserPassword), ctx, arg)
 }
 
-// AddUser mocks base method.
-func (m *MockBank) AddUser(ctx context.Context, arg bank.AddUserParams) (bank.AddUserResult, error) {
+// RelayOutboxEvent mocks base method.
+func (m *MockBank) RelayOutboxEvent(ctx context.Context, retryDelay time.Duration, publish func(context.Context, db.Outbox) error) (db.Outbox, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddUser", ctx, arg)
-	ret0, _ := ret[0].(bank.AddUserResult)
+	ret := m.ctrl.Call(m, "RelayOutboxEvent", ctx, retryDelay, publish)
+	ret0, _ := ret[0].(db.Outbox)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// AddUser indicates an expected call of AddUser.
-func (mr *MockBankMockRecorder) AddUser(ctx, arg any) *gomock.Call {
+// RelayOutboxEvent indicates an expected call of RelayOutboxEvent.
+func (mr *MockBankMockRecorder) RelayOutboxEvent(ctx, retryDelay, publish any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddUser", reflect.TypeOf((*MockBank)(nil).AddUser), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RelayOutboxEvent", reflect.TypeOf((*MockBank)(nil).RelayOutboxEvent), ctx, retryDelay, publish)
 }
 
-// CreateAccount mocks base method.
-func (m *MockBank) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+// ReleaseHold mocks base method.
+func (m *MockBank) ReleaseHold(ctx context.Context, holdID int64) (db.Hold, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateAccount", ctx, arg)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "ReleaseHold", ctx, holdID)
+	ret0, _ := ret[0].(db.Hold)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateAccount indicates an expected call of CreateAccount.
-func (mr *MockBankMockRecorder) CreateAccount(ctx, arg any) *gomock.Call {
+// ReleaseHold indicates an expected call of ReleaseHold.
+func (mr *MockBankMockRecorder) ReleaseHold(ctx, holdID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateAccount", reflect.TypeOf((*MockBank)(nil).CreateAccount), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseHold", reflect.TypeOf((*MockBank)(nil).ReleaseHold), ctx, holdID)
 }
 
-// CreateEntry mocks base method.
-func (m *MockBank) CreateEntry(ctx context.Context, arg db.CreateEntryParams) (db.Entry, error) {
+// RepairAccountBalance mocks base method.
+func (m *MockBank) RepairAccountBalance(ctx context.Context, accountID int64) (db.Account, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateEntry", ctx, arg)
-	ret0, _ := ret[0].(db.Entry)
+	ret := m.ctrl.Call(m, "RepairAccountBalance", ctx, accountID)
+	ret0, _ := ret[0].(db.Account)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateEntry indicates an expected call of CreateEntry.
-func (mr *MockBankMockRecorder) CreateEntry(ctx, arg any) *gomock.Call {
+// RepairAccountBalance indicates an expected call of RepairAccountBalance.
+func (mr *MockBankMockRecorder) RepairAccountBalance(ctx, accountID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEntry", reflect.TypeOf((*MockBank)(nil).CreateEntry), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RepairAccountBalance", reflect.TypeOf((*MockBank)(nil).RepairAccountBalance), ctx, accountID)
 }
 
-// CreateTransfer mocks base method.
-func (m *MockBank) CreateTransfer(ctx context.Context, arg db.CreateTransferParams) (db.Transfer, error) {
+// ReplayWebhookDelivery mocks base method.
+func (m *MockBank) ReplayWebhookDelivery(ctx context.Context, id int64) (db.WebhookDelivery, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateTransfer", ctx, arg)
-	ret0, _ := ret[0].(db.Transfer)
+	ret := m.ctrl.Call(m, "ReplayWebhookDelivery", ctx, id)
+	ret0, _ := ret[0].(db.WebhookDelivery)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateTransfer indicates an expected call of CreateTransfer.
-func (mr *MockBankMockRecorder) CreateTransfer(ctx, arg any) *gomock.Call {
+// ReplayWebhookDelivery indicates an expected call of ReplayWebhookDelivery.
+func (mr *MockBankMockRecorder) ReplayWebhookDelivery(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateTransfer", reflect.TypeOf((*MockBank)(nil).CreateTransfer), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplayWebhookDelivery", reflect.TypeOf((*MockBank)(nil).ReplayWebhookDelivery), ctx, id)
 }
 
-// CreateUser mocks base method.
-func (m *MockBank) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+// RequestPasswordReset mocks base method.
+func (m *MockBank) RequestPasswordReset(ctx context.Context, arg bank.RequestPasswordResetParams) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateUser", ctx, arg)
-	ret0, _ := ret[0].(db.User)
+	ret := m.ctrl.Call(m, "RequestPasswordReset", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RequestPasswordReset indicates an expected call of RequestPasswordReset.
+func (mr *MockBankMockRecorder) RequestPasswordReset(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestPasswordReset", reflect.TypeOf((*MockBank)(nil).RequestPasswordReset), ctx, arg)
+}
+
+// RequeueJob mocks base method.
+func (m *MockBank) RequeueJob(ctx context.Context, id int64) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueJob", ctx, id)
+	ret0, _ := ret[0].(db.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// CreateUser indicates an expected call of CreateUser.
-func (mr *MockBankMockRecorder) CreateUser(ctx, arg any) *gomock.Call {
+// RequeueJob indicates an expected call of RequeueJob.
+func (mr *MockBankMockRecorder) RequeueJob(ctx, id any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockBank)(nil).CreateUser), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueJob", reflect.TypeOf((*MockBank)(nil).RequeueJob), ctx, id)
 }
 
-// DeleteAccount mocks base method.
-func (m *MockBank) DeleteAccount(ctx context.Context, id int64) error {
+// ResetLoginThrottle mocks base method.
+func (m *MockBank) ResetLoginThrottle(ctx context.Context, username string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "DeleteAccount", ctx, id)
+	ret := m.ctrl.Call(m, "ResetLoginThrottle", ctx, username)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// DeleteAccount indicates an expected call of DeleteAccount.
-func (mr *MockBankMockRecorder) DeleteAccount(ctx, id any) *gomock.Call {
+// ResetLoginThrottle indicates an expected call of ResetLoginThrottle.
+func (mr *MockBankMockRecorder) ResetLoginThrottle(ctx, username any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAccount", reflect.TypeOf((*MockBank)(nil).DeleteAccount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetLoginThrottle", reflect.TypeOf((*MockBank)(nil).ResetLoginThrottle), ctx, username)
 }
 
-// GetAccount mocks base method.
-func (m *MockBank) GetAccount(ctx context.Context, id int64) (db.Account, error) {
+// ResetPassword mocks base method.
+func (m *MockBank) ResetPassword(ctx context.Context, arg bank.ResetPasswordParams) (bank.ChangePasswordResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccount", ctx, id)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "ResetPassword", ctx, arg)
+	ret0, _ := ret[0].(bank.ChangePasswordResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccount indicates an expected call of GetAccount.
-func (mr *MockBankMockRecorder) GetAccount(ctx, id any) *gomock.Call {
+// ResetPassword indicates an expected call of ResetPassword.
+func (mr *MockBankMockRecorder) ResetPassword(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccount", reflect.TypeOf((*MockBank)(nil).GetAccount), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockBank)(nil).ResetPassword), ctx, arg)
 }
 
-// GetAccountForUpdate mocks base method.
-func (m *MockBank) GetAccountForUpdate(ctx context.Context, id int64) (db.Account, error) {
+// RetryJob mocks base method.
+func (m *MockBank) RetryJob(ctx context.Context, arg db.RetryJobParams) (db.Job, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAccountForUpdate", ctx, id)
-	ret0, _ := ret[0].(db.Account)
+	ret := m.ctrl.Call(m, "RetryJob", ctx, arg)
+	ret0, _ := ret[0].(db.Job)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetAccountForUpdate indicates an expected call of GetAccountForUpdate.
-func (mr *MockBankMockRecorder) GetAccountForUpdate(ctx, id any) *gomock.Call {
+// RetryJob indicates an expected call of RetryJob.
+func (mr *MockBankMockRecorder) RetryJob(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAccountForUpdate", reflect.TypeOf((*MockBank)(nil).GetAccountForUpdate), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryJob", reflect.TypeOf((*MockBank)(nil).RetryJob), ctx, arg)
 }
 
-// GetEntry mocks base method.
-func (m *MockBank) GetEntry(ctx context.Context, id int64) (db.Entry, error) {
+// RetryOutboxEvent mocks base method.
+func (m *MockBank) RetryOutboxEvent(ctx context.Context, arg db.RetryOutboxEventParams) (db.Outbox, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetEntry", ctx, id)
-	ret0, _ := ret[0].(db.Entry)
+	ret := m.ctrl.Call(m, "RetryOutboxEvent", ctx, arg)
+	ret0, _ := ret[0].(db.Outbox)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetEntry indicates an expected call of GetEntry.
-func (mr *MockBankMockRecorder) GetEntry(ctx, id any) *gomock.Call {
+// RetryOutboxEvent indicates an expected call of RetryOutboxEvent.
+func (mr *MockBankMockRecorder) RetryOutboxEvent(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetEntry", reflect.TypeOf((*MockBank)(nil).GetEntry), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryOutboxEvent", reflect.TypeOf((*MockBank)(nil).RetryOutboxEvent), ctx, arg)
 }
 
-// GetTransfer mocks base method.
-func (m *MockBank) GetTransfer(ctx context.Context, id int64) (db.Transfer, error) {
+// RetryScheduledTransfer mocks base method.
+func (m *MockBank) RetryScheduledTransfer(ctx context.Context, arg db.RetryScheduledTransferParams) (db.ScheduledTransfer, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetTransfer", ctx, id)
-	ret0, _ := ret[0].(db.Transfer)
+	ret := m.ctrl.Call(m, "RetryScheduledTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetTransfer indicates an expected call of GetTransfer.
-func (mr *MockBankMockRecorder) GetTransfer(ctx, id any) *gomock.Call {
+// RetryScheduledTransfer indicates an expected call of RetryScheduledTransfer.
+func (mr *MockBankMockRecorder) RetryScheduledTransfer(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransfer", reflect.TypeOf((*MockBank)(nil).GetTransfer), ctx, id)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryScheduledTransfer", reflect.TypeOf((*MockBank)(nil).RetryScheduledTransfer), ctx, arg)
 }
 
-// GetUser mocks base method.
-func (m *MockBank) GetUser(ctx context.Context, username string) (db.User, error) {
+// RetryWebhookDelivery mocks base method.
+func (m *MockBank) RetryWebhookDelivery(ctx context.Context, arg db.RetryWebhookDeliveryParams) (db.WebhookDelivery, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetUser", ctx, username)
+	ret := m.ctrl.Call(m, "RetryWebhookDelivery", ctx, arg)
+	ret0, _ := ret[0].(db.WebhookDelivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetryWebhookDelivery indicates an expected call of RetryWebhookDelivery.
+func (mr *MockBankMockRecorder) RetryWebhookDelivery(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryWebhookDelivery", reflect.TypeOf((*MockBank)(nil).RetryWebhookDelivery), ctx, arg)
+}
+
+// ReverseTransfer mocks base method.
+func (m *MockBank) ReverseTransfer(ctx context.Context, arg bank.ReverseTransferParams) (bank.ReverseTransferResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReverseTransfer", ctx, arg)
+	ret0, _ := ret[0].(bank.ReverseTransferResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReverseTransfer indicates an expected call of ReverseTransfer.
+func (mr *MockBankMockRecorder) ReverseTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReverseTransfer", reflect.TypeOf((*MockBank)(nil).ReverseTransfer), ctx, arg)
+}
+
+// RevokeAPIKey mocks base method.
+func (m *MockBank) RevokeAPIKey(ctx context.Context, arg bank.RevokeAPIKeyParams) (db.ApiKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeAPIKey", ctx, arg)
+	ret0, _ := ret[0].(db.ApiKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RevokeAPIKey indicates an expected call of RevokeAPIKey.
+func (mr *MockBankMockRecorder) RevokeAPIKey(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAPIKey", reflect.TypeOf((*MockBank)(nil).RevokeAPIKey), ctx, arg)
+}
+
+// RunJob mocks base method.
+func (m *MockBank) RunJob(ctx context.Context, kinds []string, retryDelay time.Duration, run func(context.Context, db.Querier, db.Job) error) (db.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunJob", ctx, kinds, retryDelay, run)
+	ret0, _ := ret[0].(db.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunJob indicates an expected call of RunJob.
+func (mr *MockBankMockRecorder) RunJob(ctx, kinds, retryDelay, run any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunJob", reflect.TypeOf((*MockBank)(nil).RunJob), ctx, kinds, retryDelay, run)
+}
+
+// RunScheduledTransfer mocks base method.
+func (m *MockBank) RunScheduledTransfer(ctx context.Context, policy bank.RetryPolicy) (bank.RunScheduledTransferResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RunScheduledTransfer", ctx, policy)
+	ret0, _ := ret[0].(bank.RunScheduledTransferResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RunScheduledTransfer indicates an expected call of RunScheduledTransfer.
+func (mr *MockBankMockRecorder) RunScheduledTransfer(ctx, policy any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RunScheduledTransfer", reflect.TypeOf((*MockBank)(nil).RunScheduledTransfer), ctx, policy)
+}
+
+// ScheduleTransfer mocks base method.
+func (m *MockBank) ScheduleTransfer(ctx context.Context, arg bank.ScheduleTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ScheduleTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ScheduleTransfer indicates an expected call of ScheduleTransfer.
+func (mr *MockBankMockRecorder) ScheduleTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ScheduleTransfer", reflect.TypeOf((*MockBank)(nil).ScheduleTransfer), ctx, arg)
+}
+
+// SetPasswordResetToken mocks base method.
+func (m *MockBank) SetPasswordResetToken(ctx context.Context, arg db.SetPasswordResetTokenParams) (db.PasswordReset, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetPasswordResetToken", ctx, arg)
+	ret0, _ := ret[0].(db.PasswordReset)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetPasswordResetToken indicates an expected call of SetPasswordResetToken.
+func (mr *MockBankMockRecorder) SetPasswordResetToken(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetPasswordResetToken", reflect.TypeOf((*MockBank)(nil).SetPasswordResetToken), ctx, arg)
+}
+
+// SetProductTransferLimit mocks base method.
+func (m *MockBank) SetProductTransferLimit(ctx context.Context, arg db.SetProductTransferLimitParams) (db.TransferLimit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetProductTransferLimit", ctx, arg)
+	ret0, _ := ret[0].(db.TransferLimit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetProductTransferLimit indicates an expected call of SetProductTransferLimit.
+func (mr *MockBankMockRecorder) SetProductTransferLimit(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetProductTransferLimit", reflect.TypeOf((*MockBank)(nil).SetProductTransferLimit), ctx, arg)
+}
+
+// SetUserRole mocks base method.
+func (m *MockBank) SetUserRole(ctx context.Context, arg bank.SetUserRoleParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserRole", ctx, arg)
 	ret0, _ := ret[0].(db.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetUser indicates an expected call of GetUser.
-func (mr *MockBankMockRecorder) GetUser(ctx, username any) *gomock.Call {
+// SetUserRole indicates an expected call of SetUserRole.
+func (mr *MockBankMockRecorder) SetUserRole(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockBank)(nil).GetUser), ctx, username)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserRole", reflect.TypeOf((*MockBank)(nil).SetUserRole), ctx, arg)
 }
 
-// ListAccounts mocks base method.
-func (m *MockBank) ListAccounts(ctx context.Context, arg db.ListAccountsParams) ([]db.Account, error) {
+// SetUserTransferLimit mocks base method.
+func (m *MockBank) SetUserTransferLimit(ctx context.Context, arg db.SetUserTransferLimitParams) (db.TransferLimit, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListAccounts", ctx, arg)
-	ret0, _ := ret[0].([]db.Account)
+	ret := m.ctrl.Call(m, "SetUserTransferLimit", ctx, arg)
+	ret0, _ := ret[0].(db.TransferLimit)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListAccounts indicates an expected call of ListAccounts.
-func (mr *MockBankMockRecorder) ListAccounts(ctx, arg any) *gomock.Call {
+// SetUserTransferLimit indicates an expected call of SetUserTransferLimit.
+func (mr *MockBankMockRecorder) SetUserTransferLimit(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockBank)(nil).ListAccounts), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserTransferLimit", reflect.TypeOf((*MockBank)(nil).SetUserTransferLimit), ctx, arg)
 }
 
-// ListEntries mocks base method.
-func (m *MockBank) ListEntries(ctx context.Context, arg db.ListEntriesParams) ([]db.Entry, error) {
+// SumAccountEntries mocks base method.
+func (m *MockBank) SumAccountEntries(ctx context.Context, accountID int64) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListEntries", ctx, arg)
-	ret0, _ := ret[0].([]db.Entry)
+	ret := m.ctrl.Call(m, "SumAccountEntries", ctx, accountID)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListEntries indicates an expected call of ListEntries.
-func (mr *MockBankMockRecorder) ListEntries(ctx, arg any) *gomock.Call {
+// SumAccountEntries indicates an expected call of SumAccountEntries.
+func (mr *MockBankMockRecorder) SumAccountEntries(ctx, accountID any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEntries", reflect.TypeOf((*MockBank)(nil).ListEntries), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumAccountEntries", reflect.TypeOf((*MockBank)(nil).SumAccountEntries), ctx, accountID)
 }
 
-// ListTransfers mocks base method.
-func (m *MockBank) ListTransfers(ctx context.Context, arg db.ListTransfersParams) ([]db.Transfer, error) {
+// SumInterestAccruals mocks base method.
+func (m *MockBank) SumInterestAccruals(ctx context.Context, arg db.SumInterestAccrualsParams) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "ListTransfers", ctx, arg)
-	ret0, _ := ret[0].([]db.Transfer)
+	ret := m.ctrl.Call(m, "SumInterestAccruals", ctx, arg)
+	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// ListTransfers indicates an expected call of ListTransfers.
-func (mr *MockBankMockRecorder) ListTransfers(ctx, arg any) *gomock.Call {
+// SumInterestAccruals indicates an expected call of SumInterestAccruals.
+func (mr *MockBankMockRecorder) SumInterestAccruals(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransfers", reflect.TypeOf((*MockBank)(nil).ListTransfers), ctx, arg)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SumInterestAccruals", reflect.TypeOf((*MockBank)(nil).SumInterestAccruals), ctx, arg)
+}
+
+// TakeRateLimit mocks base method.
+func (m *MockBank) TakeRateLimit(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TakeRateLimit", ctx, key, limit)
+	ret0, _ := ret[0].(ratelimit.Result)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TakeRateLimit indicates an expected call of TakeRateLimit.
+func (mr *MockBankMockRecorder) TakeRateLimit(ctx, key, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TakeRateLimit", reflect.TypeOf((*MockBank)(nil).TakeRateLimit), ctx, key, limit)
 }
 
 // Transfer mocks base method.
@@ -279,6 +2594,36 @@ func (mr *MockBankMockRecorder) Transfer(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Transfer", reflect.TypeOf((*MockBank)(nil).Transfer), ctx, arg)
 }
 
+// UnfreezeAccount mocks base method.
+func (m *MockBank) UnfreezeAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnfreezeAccount", ctx, accountID)
+	ret0, _ := ret[0].(db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnfreezeAccount indicates an expected call of UnfreezeAccount.
+func (mr *MockBankMockRecorder) UnfreezeAccount(ctx, accountID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnfreezeAccount", reflect.TypeOf((*MockBank)(nil).UnfreezeAccount), ctx, accountID)
+}
+
+// UnlockUser mocks base method.
+func (m *MockBank) UnlockUser(ctx context.Context, username string) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UnlockUser", ctx, username)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UnlockUser indicates an expected call of UnlockUser.
+func (mr *MockBankMockRecorder) UnlockUser(ctx, username any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UnlockUser", reflect.TypeOf((*MockBank)(nil).UnlockUser), ctx, username)
+}
+
 // UpdateAccount mocks base method.
 func (m *MockBank) UpdateAccount(ctx context.Context, arg db.UpdateAccountParams) (db.Account, error) {
 	m.ctrl.T.Helper()
@@ -294,6 +2639,65 @@ func (mr *MockBankMockRecorder) UpdateAccount(ctx, arg any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccount", reflect.TypeOf((*MockBank)(nil).UpdateAccount), ctx, arg)
 }
 
+// UpdateAccountProduct mocks base method.
+func (m *MockBank) UpdateAccountProduct(ctx context.Context, arg db.UpdateAccountProductParams) (db.AccountProduct, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAccountProduct", ctx, arg)
+	ret0, _ := ret[0].(db.AccountProduct)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateAccountProduct indicates an expected call of UpdateAccountProduct.
+func (mr *MockBankMockRecorder) UpdateAccountProduct(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAccountProduct", reflect.TypeOf((*MockBank)(nil).UpdateAccountProduct), ctx, arg)
+}
+
+// UpdateProfile mocks base method.
+func (m *MockBank) UpdateProfile(ctx context.Context, arg bank.UpdateProfileParams) (db.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateProfile", ctx, arg)
+	ret0, _ := ret[0].(db.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateProfile indicates an expected call of UpdateProfile.
+func (mr *MockBankMockRecorder) UpdateProfile(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateProfile", reflect.TypeOf((*MockBank)(nil).UpdateProfile), ctx, arg)
+}
+
+// UpdateRateLimitBucket mocks base method.
+func (m *MockBank) UpdateRateLimitBucket(ctx context.Context, arg db.UpdateRateLimitBucketParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRateLimitBucket", ctx, arg)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRateLimitBucket indicates an expected call of UpdateRateLimitBucket.
+func (mr *MockBankMockRecorder) UpdateRateLimitBucket(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRateLimitBucket", reflect.TypeOf((*MockBank)(nil).UpdateRateLimitBucket), ctx, arg)
+}
+
+// UpdateScheduledTransfer mocks base method.
+func (m *MockBank) UpdateScheduledTransfer(ctx context.Context, arg db.UpdateScheduledTransferParams) (db.ScheduledTransfer, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateScheduledTransfer", ctx, arg)
+	ret0, _ := ret[0].(db.ScheduledTransfer)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateScheduledTransfer indicates an expected call of UpdateScheduledTransfer.
+func (mr *MockBankMockRecorder) UpdateScheduledTransfer(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateScheduledTransfer", reflect.TypeOf((*MockBank)(nil).UpdateScheduledTransfer), ctx, arg)
+}
+
 // UpdateUser mocks base method.
 func (m *MockBank) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
 	m.ctrl.T.Helper()
@@ -308,3 +2712,48 @@ func (mr *MockBankMockRecorder) UpdateUser(ctx, arg any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUser", reflect.TypeOf((*MockBank)(nil).UpdateUser), ctx, arg)
 }
+
+// UseRecoveryCode mocks base method.
+func (m *MockBank) UseRecoveryCode(ctx context.Context, arg db.UseRecoveryCodeParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockBankMockRecorder) UseRecoveryCode(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockBank)(nil).UseRecoveryCode), ctx, arg)
+}
+
+// UseUserTOTPStep mocks base method.
+func (m *MockBank) UseUserTOTPStep(ctx context.Context, arg db.UseUserTOTPStepParams) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseUserTOTPStep", ctx, arg)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseUserTOTPStep indicates an expected call of UseUserTOTPStep.
+func (mr *MockBankMockRecorder) UseUserTOTPStep(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseUserTOTPStep", reflect.TypeOf((*MockBank)(nil).UseUserTOTPStep), ctx, arg)
+}
+
+// VerifyEmail mocks base method.
+func (m *MockBank) VerifyEmail(ctx context.Context, arg bank.VerifyEmailParams) (bank.VerifyEmailResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyEmail", ctx, arg)
+	ret0, _ := ret[0].(bank.VerifyEmailResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// VerifyEmail indicates an expected call of VerifyEmail.
+func (mr *MockBankMockRecorder) VerifyEmail(ctx, arg any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyEmail", reflect.TypeOf((*MockBank)(nil).VerifyEmail), ctx, arg)
+}
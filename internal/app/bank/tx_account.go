@@ -0,0 +1,278 @@
+package bank
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Account products, the product of an account decides the rules of the account
+const (
+	ProductChecking = "checking"
+	ProductSavings  = "savings"
+	ProductEscrow   = "escrow"
+	// Accounts owned by the bank itself
+	ProductInternal = "internal"
+)
+
+// Account statuses, money only moves in and out of active accounts
+const (
+	AccountStatusActive = "active"
+	AccountStatusFrozen = "frozen"
+	AccountStatusClosed = "closed"
+)
+
+// OpenAccountParams contains the input parameters of the open account transaction
+type OpenAccountParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+	Product  string `json:"product"`
+}
+
+// OpenAccount opens an account of a product with a zero balance. Unless the product allows multiple
+// accounts per currency, an owner can only have one account of the product in each currency. An owner
+// with an unverified email can only have MaxUnverifiedAccounts open accounts.
+func (bank *SQLBank) OpenAccount(ctx context.Context, arg OpenAccountParams) (db.Account, error) {
+	var account db.Account
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		// Lock the owner so concurrent requests can not open more accounts than allowed
+		owner, err := q.GetUserForUpdate(ctx, arg.Owner)
+		if err != nil {
+			return err
+		}
+
+		if err := checkEmailVerified(ctx, q, owner); err != nil {
+			return err
+		}
+
+		product, err := q.GetAccountProduct(ctx, arg.Product)
+		if err != nil {
+			return err
+		}
+
+		if !product.MultiplePerCurrency {
+			count, err := q.CountOwnerAccounts(ctx, db.CountOwnerAccountsParams{
+				Owner:    arg.Owner,
+				Currency: arg.Currency,
+				Product:  arg.Product,
+			})
+			if err != nil {
+				return err
+			}
+
+			if count > 0 {
+				return ErrDuplicateAccount
+			}
+		}
+
+		account, err = q.CreateAccount(ctx, db.CreateAccountParams{
+			Owner:    arg.Owner,
+			Balance:  0,
+			Currency: arg.Currency,
+			Product:  arg.Product,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := recordAccountCreated(ctx, q, account); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, accountEvent(AuditActionCreateAccount, nil, account))
+	})
+
+	return account, err
+}
+
+// checkWithdrawalRules checks an outgoing transfer against the withdrawal rules of the product of the
+// from account. The transfer must be booked, its balance update locks the account so concurrent
+// withdrawals are counted.
+func checkWithdrawalRules(ctx context.Context, q *db.Queries, account db.Account, amount int64) error {
+	product, err := q.GetAccountProduct(ctx, account.Product)
+	if err != nil {
+		return err
+	}
+
+	if product.MaxWithdrawal > 0 && amount > product.MaxWithdrawal {
+		return ErrWithdrawalLimit
+	}
+
+	if product.MonthlyWithdrawals > 0 {
+		// Includes the transfer being checked
+		count, err := q.CountWithdrawalsSince(ctx, db.CountWithdrawalsSinceParams{
+			FromAccountID: account.ID,
+			Since:         firstOfMonth(utcDay(time.Now())),
+		})
+		if err != nil {
+			return err
+		}
+
+		if count > int64(product.MonthlyWithdrawals) {
+			return ErrWithdrawalLimit
+		}
+	}
+
+	return nil
+}
+
+// CloseAccountParams contains the input parameters of the close account transaction
+type CloseAccountParams struct {
+	AccountID int64 `json:"account_id"`
+	// Account receiving the remaining balance, zero when the balance already is zero
+	SweepToAccountID int64 `json:"sweep_to_account_id"`
+}
+
+// CloseAccountResult is the result of the close account transaction
+type CloseAccountResult struct {
+	// Closed account
+	Account db.Account `json:"account"`
+	// Transfer sweeping the remaining balance, empty when there was nothing to sweep
+	Sweep TransferResult `json:"sweep"`
+}
+
+// FreezeAccount freezes an active account, no money moves in or out of it until it is unfrozen.
+func (bank *SQLBank) FreezeAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	return bank.changeAccountStatus(ctx, accountID, AccountStatusActive, AuditActionFreezeAccount, func(q *db.Queries) (db.Account, error) {
+		return q.MarkAccountFrozen(ctx, accountID)
+	})
+}
+
+// UnfreezeAccount makes a frozen account active again.
+func (bank *SQLBank) UnfreezeAccount(ctx context.Context, accountID int64) (db.Account, error) {
+	return bank.changeAccountStatus(ctx, accountID, AccountStatusFrozen, AuditActionUnfreezeAccount, func(q *db.Queries) (db.Account, error) {
+		return q.MarkAccountActive(ctx, accountID)
+	})
+}
+
+// changeAccountStatus locks an account and changes its status with mark, when the account has the from status.
+// The change is recorded in the audit log as the action.
+func (bank *SQLBank) changeAccountStatus(
+	ctx context.Context,
+	accountID int64,
+	from string,
+	action string,
+	mark func(q *db.Queries) (db.Account, error),
+) (db.Account, error) {
+	var account db.Account
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		account, err = q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status != from {
+			return ErrInvalidStatusTransition
+		}
+
+		before := account
+
+		account, err = mark(q)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, accountEvent(action, &before, account))
+	})
+
+	return account, err
+}
+
+// CloseAccount closes an active account. The account must have a zero balance, or its balance is swept to
+// another active account in the same currency. Accounts with active holds can not be closed. Scheduled
+// transfers from or to the account are cancelled. A closed account keeps its entries and transfers.
+func (bank *SQLBank) CloseAccount(ctx context.Context, arg CloseAccountParams) (CloseAccountResult, error) {
+	var result CloseAccountResult
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if account.Status != AccountStatusActive {
+			return ErrInvalidStatusTransition
+		}
+
+		held, err := q.GetHeldAmount(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		if held > 0 {
+			return ErrAccountHasHolds
+		}
+
+		if account.Balance != 0 {
+			result.Sweep, err = sweepAccount(ctx, q, account, arg.SweepToAccountID)
+			if err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.CancelAccountScheduledTransfers(ctx, account.ID); err != nil {
+			return err
+		}
+
+		result.Account, err = q.MarkAccountClosed(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, accountEvent(AuditActionCloseAccount, &account, result.Account))
+	})
+
+	return result, err
+}
+
+// sweepAccount moves the whole positive balance of an account to another account in the same currency.
+// Withdrawal rules of the product do not apply, the account is about to be closed.
+func sweepAccount(ctx context.Context, q *db.Queries, account db.Account, toAccountID int64) (TransferResult, error) {
+	if account.Balance < 0 || toAccountID == 0 || toAccountID == account.ID {
+		return TransferResult{}, ErrNonZeroBalance
+	}
+
+	to, err := q.GetAccount(ctx, toAccountID)
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	if to.Currency != account.Currency {
+		return TransferResult{}, ErrCurrencyMismatch
+	}
+
+	journal, err := q.CreateJournal(ctx, JournalKindAccountSweep)
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	transfer, err := q.CreateTransfer(ctx, db.CreateTransferParams{
+		FromAccountID: account.ID,
+		ToAccountID:   to.ID,
+		Amount:        account.Balance,
+		JournalID:     journal.ID,
+	})
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	return moveMoney(ctx, q, transfer)
+}
+
+// checkAccountActive makes sure money can move in or out of the account.
+func checkAccountActive(account db.Account) error {
+	switch account.Status {
+	case AccountStatusFrozen:
+		return fmt.Errorf("account %d: %w", account.ID, ErrAccountFrozen)
+	case AccountStatusClosed:
+		return fmt.Errorf("account %d: %w", account.ID, ErrAccountClosed)
+	default:
+		return nil
+	}
+}
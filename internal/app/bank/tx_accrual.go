@@ -0,0 +1,410 @@
+package bank
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/interest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Owners of the bank's own accounts, interest is paid from and fees are paid to their accounts
+const (
+	InterestOwner = "bank_interest"
+	FeeOwner      = "bank_fees"
+)
+
+// Accrual posting kinds
+const (
+	PostingKindInterest = "interest"
+	PostingKindFee      = "fee"
+)
+
+// AccrualPolicy decides how interest is accrued and posted
+type AccrualPolicy struct {
+	// Day count basis of daily accruals
+	Basis interest.Basis `json:"basis"`
+	// Rounding of the interest of a month to whole minor units when posted
+	Rounding interest.Rounding `json:"rounding"`
+}
+
+// AccrueParams contains the input parameters of an accrual run for an account
+type AccrueParams struct {
+	AccountID int64 `json:"account_id"`
+	// Days before the day of AsOf are accrued and months before its month are posted
+	AsOf   time.Time     `json:"as_of"`
+	Policy AccrualPolicy `json:"policy"`
+}
+
+// AccrueResult is the result of an accrual run for an account
+type AccrueResult struct {
+	// Account after interest and fees are posted
+	Account db.Account `json:"account"`
+	// Interest accrued by the run, one accrual per day
+	Accruals []db.InterestAccrual `json:"accruals"`
+	// Interest and fees posted by the run
+	Postings []db.AccrualPosting `json:"postings"`
+}
+
+// Accrue accrues daily interest, posts the interest of completed months and charges the monthly fees
+// of completed months of an account within a database transaction. Days, months and fees handled
+// by an earlier run are skipped, running it twice for the same period posts nothing new.
+//
+// Interest accrues on the end of day balance, the balance minus the entries booked after the day, at the
+// interest rate the account product had on the day. Days and months are UTC. Accounts owned by the bank
+// and closed accounts are skipped. Postings moving money are recorded in the audit log.
+func (bank *SQLBank) Accrue(ctx context.Context, arg AccrueParams) (AccrueResult, error) {
+	var result AccrueResult
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		// Locking the account serializes runs for the account and keeps its balance still
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		result.Account = account
+
+		if account.Owner == InterestOwner || account.Owner == FeeOwner || account.Status == AccountStatusClosed {
+			return nil
+		}
+
+		today := utcDay(arg.AsOf)
+
+		// The interest and fees posted by the run share a journal, created with the first posting moving money
+		var journal db.Journal
+
+		result.Accruals, err = accrueInterest(ctx, q, account, today, arg.Policy.Basis)
+		if err != nil {
+			return err
+		}
+
+		postings, err := postInterest(ctx, q, &journal, account, today, arg.Policy.Rounding)
+		if err != nil {
+			return err
+		}
+
+		fees, err := chargeFees(ctx, q, &journal, account, today)
+		if err != nil {
+			return err
+		}
+
+		result.Postings = append(postings, fees...)
+
+		if len(result.Postings) == 0 {
+			return nil
+		}
+
+		result.Account, err = q.GetAccount(ctx, account.ID)
+		if err != nil {
+			return err
+		}
+
+		// Postings that moved money are audited, zero amounts only mark the period as handled
+		var events []auditEvent
+		for _, posting := range result.Postings {
+			if posting.TransferID.Valid {
+				events = append(events, auditEvent{
+					action:     AuditActionPostAccrual,
+					entityType: AuditEntityAccount,
+					entityID:   strconv.FormatInt(account.ID, 10),
+					after:      posting,
+				})
+			}
+		}
+
+		return recordAudit(ctx, q, events...)
+	})
+
+	return result, err
+}
+
+// accrueInterest accrues interest for the days before today that have not been accrued yet, starting
+// at the day the account was opened. Each day accrues at the rate of the account product in force at the
+// end of the day, days of a zero rate accrue nothing. Accrual never starts before the first known rate
+// of the product, the interest rate of earlier days is not known.
+func accrueInterest(
+	ctx context.Context,
+	q *db.Queries,
+	account db.Account,
+	today time.Time,
+	basis interest.Basis,
+) ([]db.InterestAccrual, error) {
+	rates, err := q.ListAccountProductRates(ctx, account.Product)
+	if err != nil || len(rates) == 0 {
+		return nil, err
+	}
+
+	day := utcDay(account.CreatedAt)
+
+	last, err := q.GetLastInterestAccrual(ctx, account.ID)
+	switch {
+	case err == nil:
+		day = last.AccrualDate.Time.AddDate(0, 0, 1)
+	case !errors.Is(err, db.ErrRecordNotFound):
+		return nil, err
+	}
+
+	if rateSet := utcDay(rates[0].ValidFrom); day.Before(rateSet) {
+		day = rateSet
+	}
+
+	var accruals []db.InterestAccrual
+
+	for ; day.Before(today); day = day.AddDate(0, 0, 1) {
+		rateBps := rateOfDay(rates, day)
+		if rateBps == 0 {
+			continue
+		}
+
+		later, err := q.GetEntriesAmountSince(ctx, db.GetEntriesAmountSinceParams{
+			AccountID: account.ID,
+			Since:     day.AddDate(0, 0, 1),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// End of day balance
+		balance := account.Balance - later
+
+		accrual, err := q.CreateInterestAccrual(ctx, db.CreateInterestAccrualParams{
+			AccountID:     account.ID,
+			AccrualDate:   pgDate(day),
+			Balance:       balance,
+			AnnualRateBps: rateBps,
+			AmountMicros:  interest.DailyMicros(balance, rateBps, day, basis),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		accruals = append(accruals, accrual)
+	}
+
+	return accruals, nil
+}
+
+// rateOfDay returns the rate in force at the end of the UTC day, the last rate set before the next day.
+// The rates must be ordered by when they were set.
+func rateOfDay(rates []db.AccountProductRate, day time.Time) int32 {
+	var rateBps int32
+
+	next := day.AddDate(0, 0, 1)
+	for _, rate := range rates {
+		if !rate.ValidFrom.Before(next) {
+			break
+		}
+		rateBps = rate.AnnualRateBps
+	}
+
+	return rateBps
+}
+
+// postInterest posts the accrued interest of the completed months not posted yet, paid from the
+// interest account of the bank. A month with interest rounding to zero is posted without a transfer.
+func postInterest(
+	ctx context.Context,
+	q *db.Queries,
+	journal *db.Journal,
+	account db.Account,
+	today time.Time,
+	rounding interest.Rounding,
+) ([]db.AccrualPosting, error) {
+	periods, err := q.ListUnpostedInterestPeriods(ctx, db.ListUnpostedInterestPeriodsParams{
+		AccountID: account.ID,
+		Before:    pgDate(firstOfMonth(today)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var postings []db.AccrualPosting
+
+	for _, period := range periods {
+		micros, err := q.SumInterestAccruals(ctx, db.SumInterestAccrualsParams{
+			AccountID:   account.ID,
+			PeriodStart: period,
+			PeriodEnd:   pgDate(period.Time.AddDate(0, 1, 0)),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		posting, err := postAccrual(ctx, q, journal, account, db.CreateAccrualPostingParams{
+			AccountID:   account.ID,
+			Kind:        PostingKindInterest,
+			PeriodStart: period,
+			Amount:      rounding.Round(micros),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		postings = append(postings, posting)
+	}
+
+	return postings, nil
+}
+
+// chargeFees charges the fee schedules of the account product for the completed months not charged yet,
+// paid to the fee account of the bank. Each fee schedule is charged for every month from the month the
+// account was opened, or the fee schedule was created when later, so a run catches up with months missed
+// by earlier runs. Fees are charged even when they overdraw the account.
+func chargeFees(
+	ctx context.Context,
+	q *db.Queries,
+	journal *db.Journal,
+	account db.Account,
+	today time.Time,
+) ([]db.AccrualPosting, error) {
+	periodEnd := firstOfMonth(today)
+
+	if !account.CreatedAt.Before(periodEnd) {
+		return nil, nil
+	}
+
+	schedules, err := q.ListFeeSchedules(ctx, account.Product)
+	if err != nil || len(schedules) == 0 {
+		return nil, err
+	}
+
+	posted, err := q.ListFeeAccrualPostings(ctx, account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Months charged by fee schedule, by the Unix time of the start of the month
+	type feePeriod struct {
+		scheduleID  int64
+		periodStart int64
+	}
+
+	charged := make(map[feePeriod]bool)
+	for _, posting := range posted {
+		charged[feePeriod{posting.FeeScheduleID.Int64, posting.PeriodStart.Time.Unix()}] = true
+	}
+
+	var postings []db.AccrualPosting
+
+	for period := firstOfMonth(utcDay(account.CreatedAt)); period.Before(periodEnd); period = period.AddDate(0, 1, 0) {
+		next := period.AddDate(0, 1, 0)
+
+		for _, schedule := range schedules {
+			if charged[feePeriod{schedule.ID, period.Unix()}] || !schedule.CreatedAt.Before(next) {
+				continue
+			}
+
+			posting, err := postAccrual(ctx, q, journal, account, db.CreateAccrualPostingParams{
+				AccountID:     account.ID,
+				Kind:          PostingKindFee,
+				FeeScheduleID: pgtype.Int8{Int64: schedule.ID, Valid: true},
+				PeriodStart:   pgDate(period),
+				Amount:        schedule.Amount,
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			postings = append(postings, posting)
+		}
+	}
+
+	return postings, nil
+}
+
+// postAccrual moves the amount of a posting between the account and the interest or fee account of
+// the bank in the currency of the account, and records the posting. The money moves in the journal of
+// the run, it is created when the journal has not been created yet.
+func postAccrual(
+	ctx context.Context,
+	q *db.Queries,
+	journal *db.Journal,
+	account db.Account,
+	arg db.CreateAccrualPostingParams,
+) (db.AccrualPosting, error) {
+	if arg.Amount > 0 {
+		owner := InterestOwner
+		if arg.Kind == PostingKindFee {
+			owner = FeeOwner
+		}
+
+		bankAccount, err := internalAccount(ctx, q, owner, account.Currency)
+		if err != nil {
+			return db.AccrualPosting{}, err
+		}
+
+		if journal.ID == 0 {
+			*journal, err = q.CreateJournal(ctx, JournalKindAccrual)
+			if err != nil {
+				return db.AccrualPosting{}, err
+			}
+		}
+
+		// Interest moves from the bank, fees move to the bank
+		transferArg := db.CreateTransferParams{
+			FromAccountID: bankAccount.ID,
+			ToAccountID:   account.ID,
+			Amount:        arg.Amount,
+			JournalID:     journal.ID,
+		}
+		if arg.Kind == PostingKindFee {
+			transferArg.FromAccountID, transferArg.ToAccountID = account.ID, bankAccount.ID
+		}
+
+		transfer, err := q.CreateTransfer(ctx, transferArg)
+		if err != nil {
+			return db.AccrualPosting{}, err
+		}
+
+		if _, err := bookMoney(ctx, q, transfer); err != nil {
+			return db.AccrualPosting{}, err
+		}
+
+		arg.TransferID = pgtype.Int8{Int64: transfer.ID, Valid: true}
+	}
+
+	return q.CreateAccrualPosting(ctx, arg)
+}
+
+// internalAccount returns the account of a bank owner in a currency, the account is opened on first use.
+// Runs opening the account at once wait for each other, only one of them opens it.
+func internalAccount(ctx context.Context, q *db.Queries, owner string, currency string) (db.Account, error) {
+	arg := db.GetOwnerAccountParams{
+		Owner:    owner,
+		Currency: currency,
+	}
+
+	account, err := q.GetOwnerAccount(ctx, arg)
+	if !errors.Is(err, db.ErrRecordNotFound) {
+		return account, err
+	}
+
+	err = q.CreateInternalAccount(ctx, db.CreateInternalAccountParams{
+		Owner:    owner,
+		Currency: currency,
+	})
+	if err != nil {
+		return account, err
+	}
+
+	return q.GetOwnerAccount(ctx, arg)
+}
+
+// utcDay returns the start of the UTC day of t.
+func utcDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// firstOfMonth returns the first day of the month of day.
+func firstOfMonth(day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+}
+
+func pgDate(day time.Time) pgtype.Date {
+	return pgtype.Date{Time: day, Valid: true}
+}
@@ -6,27 +6,37 @@ import (
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
 )
 
+// AddUserParams contains the input parameters of the add user transaction
 type AddUserParams struct {
 	db.CreateUserParams
-	AfterCreate func(user db.User) error
+	// AfterCreate runs within the transaction with its querier, the user is not created when it fails
+	AfterCreate func(ctx context.Context, q db.Querier, user db.User) error
 }
 
 type AddUserResult struct {
 	User db.User `json:"user"`
 }
 
+// AddUser creates a user, runs the AfterCreate hook and records the user in the audit log within a database transaction, e.g. to enqueue
+// work that must only happen once the user exists.
 func (store *SQLBank) AddUser(ctx context.Context, arg AddUserParams) (AddUserResult, error) {
 	var result AddUserResult
 
 	err := store.execTx(ctx, func(q *db.Queries) error {
 		var err error
 
-		result.User, err = q.CreateUser(ctx, arg.CreateUserParams)
+		result.User, err = createUser(ctx, q, arg.CreateUserParams)
 		if err != nil {
 			return err
 		}
 
-		return arg.AfterCreate(result.User)
+		if arg.AfterCreate != nil {
+			if err := arg.AfterCreate(ctx, q, result.User); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, q, userCreatedEvent(result.User))
 	})
 
 	return result, err
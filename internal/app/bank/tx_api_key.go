@@ -0,0 +1,72 @@
+package bank
+
+import (
+	"context"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// IssueAPIKey stores the hash of a new api key of a user within a database transaction.
+func (bank *SQLBank) IssueAPIKey(ctx context.Context, arg db.CreateAPIKeyParams) (db.ApiKey, error) {
+	var key db.ApiKey
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		key, err = q.CreateAPIKey(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionIssueAPIKey,
+			entityType: AuditEntityUser,
+			entityID:   key.Username,
+			after:      newAuditedAPIKey(key),
+		})
+	})
+
+	return key, err
+}
+
+// RevokeAPIKeyParams contains the input parameters of the revoke api key transaction
+type RevokeAPIKeyParams struct {
+	ID int64 `json:"id"`
+	// Owner of the key, keys of other users are not found
+	Username string `json:"username"`
+}
+
+// RevokeAPIKey revokes an api key of a user within a database transaction, the key is refused from then on.
+func (bank *SQLBank) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (db.ApiKey, error) {
+	var key db.ApiKey
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetAPIKeyForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		if before.Username != arg.Username {
+			return db.ErrRecordNotFound
+		}
+
+		if before.RevokedAt.Valid {
+			return ErrAPIKeyRevoked
+		}
+
+		key, err = q.MarkAPIKeyRevoked(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionRevokeAPIKey,
+			entityType: AuditEntityUser,
+			entityID:   key.Username,
+			before:     newAuditedAPIKey(before),
+			after:      newAuditedAPIKey(key),
+		})
+	})
+
+	return key, err
+}
@@ -0,0 +1,315 @@
+package bank
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/audit"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Audited entity types
+const (
+	AuditEntityUser     = "user"
+	AuditEntityAccount  = "account"
+	AuditEntityTransfer = "transfer"
+	AuditEntityHold     = "hold"
+)
+
+// Audited actions
+const (
+	AuditActionCreateUser      = "user.create"
+	AuditActionUpdateUser      = "user.update"
+	AuditActionVerifyEmail     = "user.verify_email"
+	AuditActionChangePassword  = "user.change_password"
+	AuditActionResetPassword   = "user.reset_password"
+	AuditActionSetUserRole     = "user.set_role"
+	AuditActionEnableTOTP      = "user.enable_totp"
+	AuditActionDisableTOTP     = "user.disable_totp"
+	AuditActionLockUser        = "user.lock"
+	AuditActionUnlockUser      = "user.unlock"
+	AuditActionIssueAPIKey     = "user.issue_api_key"
+	AuditActionRevokeAPIKey    = "user.revoke_api_key"
+	AuditActionCreateAccount   = "account.create"
+	AuditActionFreezeAccount   = "account.freeze"
+	AuditActionUnfreezeAccount = "account.unfreeze"
+	AuditActionCloseAccount    = "account.close"
+	AuditActionPostAccrual     = "account.post_accrual"
+	AuditActionTransfer        = "transfer.create"
+	AuditActionReverseTransfer = "transfer.reverse"
+	AuditActionRunScheduled    = "transfer.run_scheduled"
+	AuditActionCaptureHold     = "hold.capture"
+)
+
+// auditEvent is a state change to record in the audit log
+type auditEvent struct {
+	action     string
+	entityType string
+	entityID   string
+	// State before and after the change, nil when there is none
+	before any
+	after  any
+}
+
+// auditedUser is the audited state of a user, the password hash is left out
+type auditedUser struct {
+	Username          string    `json:"username"`
+	FullName          string    `json:"full_name"`
+	Email             string    `json:"email"`
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	Role              string    `json:"role"`
+}
+
+func newAuditedUser(user db.User) auditedUser {
+	return auditedUser{
+		Username:          user.Username,
+		FullName:          user.FullName,
+		Email:             user.Email,
+		PasswordChangedAt: user.PasswordChangedAt,
+		CreatedAt:         user.CreatedAt,
+		IsEmailVerified:   user.IsEmailVerified,
+		Role:              user.Role,
+	}
+}
+
+// auditedTOTP is the audited state of the TOTP of a user, the secret is left out
+type auditedTOTP struct {
+	Username  string     `json:"username"`
+	IsEnabled bool       `json:"is_enabled"`
+	CreatedAt time.Time  `json:"created_at"`
+	EnabledAt *time.Time `json:"enabled_at"`
+}
+
+func newAuditedTOTP(userTOTP db.UserTotp) auditedTOTP {
+	audited := auditedTOTP{
+		Username:  userTOTP.Username,
+		IsEnabled: userTOTP.IsEnabled,
+		CreatedAt: userTOTP.CreatedAt,
+	}
+
+	if userTOTP.EnabledAt.Valid {
+		audited.EnabledAt = &userTOTP.EnabledAt.Time
+	}
+
+	return audited
+}
+
+// auditedAPIKey is the audited state of an api key of a user, the key hash is left out
+type auditedAPIKey struct {
+	ID        int64      `json:"id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	ExpiredAt time.Time  `json:"expired_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+func newAuditedAPIKey(key db.ApiKey) auditedAPIKey {
+	audited := auditedAPIKey{
+		ID:        key.ID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		ExpiredAt: key.ExpiredAt,
+	}
+
+	if key.RevokedAt.Valid {
+		audited.RevokedAt = &key.RevokedAt.Time
+	}
+
+	return audited
+}
+
+func accountEvent(action string, before *db.Account, after db.Account) auditEvent {
+	event := auditEvent{
+		action:     action,
+		entityType: AuditEntityAccount,
+		entityID:   strconv.FormatInt(after.ID, 10),
+		after:      after,
+	}
+
+	if before != nil {
+		event.before = *before
+	}
+
+	return event
+}
+
+// recordAudit appends events to the audit log within the transaction of q, made by the actor of ctx.
+// Appends are serialized until the transaction ends, so each event links to the event committed before it.
+// The chain lock is shared by every audited transaction, money movement included, so record the events
+// as the last step of the transaction: the lock is then only held over the append and the commit.
+func recordAudit(ctx context.Context, q *db.Queries, events ...auditEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	actor := audit.ActorFrom(ctx)
+	// The database keeps microseconds, the hashed time must survive the round trip
+	occurredAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	records := make([]db.AuditEvent, 0, len(events))
+	for _, event := range events {
+		before, err := marshalAuditState(event.before)
+		if err != nil {
+			return err
+		}
+
+		after, err := marshalAuditState(event.after)
+		if err != nil {
+			return err
+		}
+
+		records = append(records, db.AuditEvent{
+			OccurredAt: occurredAt,
+			Actor:      actor.Name,
+			Ip:         actor.IP,
+			RequestID:  actor.RequestID,
+			Action:     event.action,
+			EntityType: event.entityType,
+			EntityID:   event.entityID,
+			Before:     before,
+			After:      after,
+		})
+	}
+
+	if err := q.LockAuditChain(ctx); err != nil {
+		return err
+	}
+
+	var prevHash string
+
+	last, err := q.GetLastAuditEvent(ctx)
+	switch {
+	case err == nil:
+		prevHash = last.Hash
+	case !errors.Is(err, db.ErrRecordNotFound):
+		return err
+	}
+
+	for _, record := range records {
+		record.PrevHash = prevHash
+
+		created, err := q.CreateAuditEvent(ctx, db.CreateAuditEventParams{
+			OccurredAt: record.OccurredAt,
+			Actor:      record.Actor,
+			Ip:         record.Ip,
+			RequestID:  record.RequestID,
+			Action:     record.Action,
+			EntityType: record.EntityType,
+			EntityID:   record.EntityID,
+			Before:     record.Before,
+			After:      record.After,
+			PrevHash:   record.PrevHash,
+			Hash:       audit.Hash(record),
+		})
+		if err != nil {
+			return err
+		}
+
+		prevHash = created.Hash
+	}
+
+	return nil
+}
+
+func marshalAuditState(state any) ([]byte, error) {
+	if state == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(state)
+}
+
+// CreateUser creates a user and records it in the audit log and the outbox within a database transaction.
+func (bank *SQLBank) CreateUser(ctx context.Context, arg db.CreateUserParams) (db.User, error) {
+	var user db.User
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		user, err = createUser(ctx, q, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, userCreatedEvent(user))
+	})
+
+	return user, err
+}
+
+// createUser creates a user and records it in the outbox within the transaction of q. The caller records
+// userCreatedEvent in the audit log, as the last step of the transaction.
+func createUser(ctx context.Context, q *db.Queries, arg db.CreateUserParams) (db.User, error) {
+	user, err := q.CreateUser(ctx, arg)
+	if err != nil {
+		return user, err
+	}
+
+	return user, recordUserCreated(ctx, q, user)
+}
+
+func userCreatedEvent(user db.User) auditEvent {
+	return auditEvent{
+		action:     AuditActionCreateUser,
+		entityType: AuditEntityUser,
+		entityID:   user.Username,
+		after:      newAuditedUser(user),
+	}
+}
+
+// UpdateUser changes a user and records the change in the audit log within a database transaction.
+func (bank *SQLBank) UpdateUser(ctx context.Context, arg db.UpdateUserParams) (db.User, error) {
+	var user db.User
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUser(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionUpdateUser,
+			entityType: AuditEntityUser,
+			entityID:   user.Username,
+			before:     newAuditedUser(before),
+			after:      newAuditedUser(user),
+		})
+	})
+
+	return user, err
+}
+
+// CreateAccount creates an account and records it in the audit log and the outbox within a database transaction. Unlike
+// OpenAccount the rules of the account product are not checked.
+func (bank *SQLBank) CreateAccount(ctx context.Context, arg db.CreateAccountParams) (db.Account, error) {
+	var account db.Account
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		account, err = q.CreateAccount(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		if err := recordAccountCreated(ctx, q, account); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, accountEvent(AuditActionCreateAccount, nil, account))
+	})
+
+	return account, err
+}
@@ -5,27 +5,51 @@ import (
 	"fmt"
 
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5"
 )
 
 // execTx executes a callback function within a database transaction, finally it commits or rollbacks the transaction.
 func (bank *SQLBank) execTx(ctx context.Context, fn func(*db.Queries) error) error {
+	return bank.execPgxTx(ctx, func(tx pgx.Tx) error {
+		return fn(db.New(tx))
+	})
+}
+
+// execPgxTx is execTx for callbacks that need the transaction itself, e.g. to create savepoints.
+func (bank *SQLBank) execPgxTx(ctx context.Context, fn func(pgx.Tx) error) error {
 	// Begin transaction
 	tx, err := bank.connPool.Begin(ctx)
 	if err != nil {
 		return fmt.Errorf("exec tx:create transaction: %v", err)
 	}
 
-	q := db.New(tx)
-
 	// Execute transaction
-	if err := fn(q); err != nil {
+	if err := fn(tx); err != nil {
 		// Rollback transaction
 		if rbErr := tx.Rollback(ctx); rbErr != nil {
-			return fmt.Errorf("exec tx:transaction err: %v, rollback err: %v", err, rbErr)
+			return fmt.Errorf("exec tx:transaction err: %w, rollback err: %v", err, rbErr)
 		}
-		return fmt.Errorf("exec tx:transaction err: %v", err)
+		return fmt.Errorf("exec tx:transaction err: %w", err)
 	}
 
 	// Commit transaction
 	return tx.Commit(ctx)
 }
+
+// execSavepoint executes a callback function within a savepoint of the transaction. When the callback fails
+// only the work done within the savepoint is rolled back, and the transaction can continue.
+func execSavepoint(ctx context.Context, tx pgx.Tx, fn func(*db.Queries) error) error {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("exec savepoint:create savepoint: %v", err)
+	}
+
+	if err := fn(db.New(savepoint)); err != nil {
+		if rbErr := savepoint.Rollback(ctx); rbErr != nil {
+			return fmt.Errorf("exec savepoint:err: %w, rollback err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return savepoint.Commit(ctx)
+}
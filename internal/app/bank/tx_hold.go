@@ -0,0 +1,214 @@
+package bank
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Hold statuses, only active holds reserve money on the account
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+// PlaceHoldParams contains the input parameters of the place hold transaction
+type PlaceHoldParams struct {
+	// Account to reserve money on
+	AccountID int64 `json:"account_id"`
+	// Amount to reserve
+	Amount int64 `json:"amount"`
+	// When the hold expires and no longer reserves money
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// CaptureHoldParams contains the input parameters of the capture hold transaction
+type CaptureHoldParams struct {
+	// Hold to capture
+	HoldID int64 `json:"hold_id"`
+	// Account receiving the captured money
+	ToAccountID int64 `json:"to_account_id"`
+	// Amount to capture, zero captures the full amount of the hold
+	Amount int64 `json:"amount"`
+}
+
+// CaptureHoldResult is the result of the capture hold transaction
+type CaptureHoldResult struct {
+	// Captured hold
+	Hold db.Hold `json:"hold"`
+	// Transfer settling the hold, its entries and updated accounts
+	TransferResult
+}
+
+// PlaceHold reserves money on an account. The reserved amount is not available for transfers until
+// the hold is captured, released or has expired.
+func (bank *SQLBank) PlaceHold(ctx context.Context, arg PlaceHoldParams) (db.Hold, error) {
+	var hold db.Hold
+
+	if arg.Amount <= 0 {
+		return hold, ErrInvalidAmount
+	}
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		// Lock the account so concurrent holds and transfers see the same available balance
+		account, err := q.GetAccountForUpdate(ctx, arg.AccountID)
+		if err != nil {
+			return err
+		}
+
+		if err := checkAccountActive(account); err != nil {
+			return err
+		}
+
+		if err := checkAvailableBalance(ctx, q, account, arg.Amount); err != nil {
+			return err
+		}
+
+		hold, err = q.CreateHold(ctx, db.CreateHoldParams{
+			AccountID: arg.AccountID,
+			Amount:    arg.Amount,
+			ExpiresAt: arg.ExpiresAt,
+		})
+		return err
+	})
+
+	return hold, err
+}
+
+// CaptureHold settles an active hold, fully or partially, with a transfer to the receiving account.
+// Any part of the hold that is not captured is released. The capture is refused like a transfer when it
+// breaks the withdrawal rules or the transfer limits of the account. The capture is recorded in the audit log.
+func (bank *SQLBank) CaptureHold(ctx context.Context, arg CaptureHoldParams) (CaptureHoldResult, error) {
+	var result CaptureHoldResult
+
+	if arg.Amount < 0 {
+		return result, ErrInvalidAmount
+	}
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		hold, err := activeHoldForUpdate(ctx, q, arg.HoldID)
+		if err != nil {
+			return err
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = hold.Amount
+		}
+
+		if amount > hold.Amount {
+			return ErrCaptureExceedsHold
+		}
+
+		journal, err := q.CreateJournal(ctx, JournalKindHoldCapture)
+		if err != nil {
+			return err
+		}
+
+		transfer, err := q.CreateTransfer(ctx, db.CreateTransferParams{
+			FromAccountID: hold.AccountID,
+			ToAccountID:   arg.ToAccountID,
+			Amount:        amount,
+			JournalID:     journal.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The hold must stop reserving money before the transfer checks the available balance
+		result.Hold, err = q.MarkHoldCaptured(ctx, db.MarkHoldCapturedParams{
+			CapturedAmount: amount,
+			TransferID:     pgtype.Int8{Int64: transfer.ID, Valid: true},
+			ID:             hold.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.TransferResult, err = moveMoney(ctx, q, transfer)
+		if err != nil {
+			return err
+		}
+
+		// A capture moves money out like any transfer, the hold only reserved it
+		if err := checkWithdrawal(ctx, q, result.TransferResult); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionCaptureHold,
+			entityType: AuditEntityHold,
+			entityID:   strconv.FormatInt(hold.ID, 10),
+			before:     hold,
+			after:      result.Hold,
+		})
+	})
+
+	return result, err
+}
+
+// ReleaseHold releases an active hold, making the reserved money available again.
+func (bank *SQLBank) ReleaseHold(ctx context.Context, holdID int64) (db.Hold, error) {
+	var hold db.Hold
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		hold, err = q.GetHoldForUpdate(ctx, holdID)
+		if err != nil {
+			return err
+		}
+
+		if hold.Status != HoldStatusActive {
+			return ErrHoldNotActive
+		}
+
+		hold, err = q.MarkHoldReleased(ctx, hold.ID)
+		return err
+	})
+
+	return hold, err
+}
+
+// activeHoldForUpdate locks a hold and makes sure it still reserves money.
+func activeHoldForUpdate(ctx context.Context, q *db.Queries, holdID int64) (db.Hold, error) {
+	hold, err := q.GetHoldForUpdate(ctx, holdID)
+	if err != nil {
+		return hold, err
+	}
+
+	if hold.Status != HoldStatusActive {
+		return hold, ErrHoldNotActive
+	}
+
+	if !hold.ExpiresAt.After(time.Now()) {
+		return hold, ErrHoldExpired
+	}
+
+	return hold, nil
+}
+
+// checkAvailableBalance makes sure the account can cover amount with money not reserved by active holds,
+// overdrawing the account down to the overdraft limit of its product.
+func checkAvailableBalance(ctx context.Context, q *db.Queries, account db.Account, amount int64) error {
+	held, err := q.GetHeldAmount(ctx, account.ID)
+	if err != nil {
+		return err
+	}
+
+	product, err := q.GetAccountProduct(ctx, account.Product)
+	if err != nil {
+		return err
+	}
+
+	if account.Balance-held+product.OverdraftLimit < amount {
+		return ErrInsufficientFunds
+	}
+
+	return nil
+}
@@ -0,0 +1,67 @@
+package bank
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5"
+)
+
+// Job statuses, failed jobs stay pending until they are out of attempts
+const (
+	JobPending   = "pending"
+	JobSucceeded = "succeeded"
+	JobDead      = "dead"
+)
+
+// RunJob claims the due job of the highest priority among kinds and runs it. It returns db.ErrRecordNotFound
+// when no job of the kinds is due.
+//
+// The job runs within the database transaction that claimed it, with the querier of a savepoint, so the
+// database work of a successful job commits together with its completion, and the work of a failed job is
+// rolled back. A failed job is retried with exponential backoff starting at retryDelay, and is dead once
+// out of attempts. A job interrupted by a crash is rolled back and runs again.
+func (bank *SQLBank) RunJob(
+	ctx context.Context,
+	kinds []string,
+	retryDelay time.Duration,
+	run func(ctx context.Context, q db.Querier, job db.Job) error,
+) (db.Job, error) {
+	var result db.Job
+
+	err := bank.execPgxTx(ctx, func(tx pgx.Tx) error {
+		q := db.New(tx)
+
+		job, err := q.ClaimJob(ctx, kinds)
+		if err != nil {
+			return err
+		}
+
+		runErr := execSavepoint(ctx, tx, func(q *db.Queries) error {
+			return run(ctx, q, job)
+		})
+		if runErr == nil {
+			result, err = q.MarkJobSucceeded(ctx, job.ID)
+			return err
+		}
+
+		attempt := job.Attempts + 1
+
+		arg := db.RetryJobParams{
+			Status:    JobPending,
+			LastError: runErr.Error(),
+			RunAt:     time.Now().Add(RetryPolicy{Delay: retryDelay}.backoff(attempt)),
+			ID:        job.ID,
+		}
+
+		if attempt >= job.MaxAttempts {
+			arg.Status = JobDead
+		}
+
+		result, err = q.RetryJob(ctx, arg)
+		return err
+	})
+
+	return result, err
+}
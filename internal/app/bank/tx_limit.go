@@ -0,0 +1,179 @@
+package bank
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Transfer limit names
+const (
+	LimitMaxAmount      = "max_amount"
+	LimitAccountDaily   = "account_daily"
+	LimitAccountMonthly = "account_monthly"
+	LimitUserDaily      = "user_daily"
+	LimitUserMonthly    = "user_monthly"
+)
+
+// LimitUsage is the usage of a transfer limit over its window
+type LimitUsage struct {
+	// Name of the limit
+	Limit string `json:"limit"`
+	// Configured limit, 0 for no limit
+	Max int64 `json:"max"`
+	// Outgoing amount of transfers, scheduled transfers and hold captures within the window
+	Used int64 `json:"used"`
+	// Amount that can still be transferred within the window, 0 when there is no limit
+	Remaining int64 `json:"remaining"`
+	// Start of the window
+	Since time.Time `json:"since"`
+}
+
+// TransferLimitUsage is the usage of the transfer limits of an account
+type TransferLimitUsage struct {
+	AccountID int64 `json:"account_id"`
+	// Largest outgoing transfer, 0 for no limit
+	MaxAmount int64 `json:"max_amount"`
+	// Usage of the daily and monthly limits of the account and its owner
+	Windows []LimitUsage `json:"windows"`
+}
+
+// GetTransferLimitUsage returns how much of the transfer limits of an account is used.
+func (bank *SQLBank) GetTransferLimitUsage(ctx context.Context, accountID int64) (TransferLimitUsage, error) {
+	account, err := bank.GetAccount(ctx, accountID)
+	if err != nil {
+		return TransferLimitUsage{}, err
+	}
+
+	limit, err := accountTransferLimit(ctx, bank.Queries, account)
+	if err != nil {
+		return TransferLimitUsage{}, err
+	}
+
+	return transferLimitUsage(ctx, bank.Queries, account, limit, time.Now())
+}
+
+// accountTransferLimit returns the transfer limits of an account, the user override of the owner or else the
+// limits of the account product. An account without limits gets the zero value.
+func accountTransferLimit(ctx context.Context, q *db.Queries, account db.Account) (db.TransferLimit, error) {
+	limit, err := q.GetTransferLimit(ctx, db.GetTransferLimitParams{
+		Owner:   account.Owner,
+		Product: account.Product,
+	})
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return db.TransferLimit{}, nil
+	}
+
+	return limit, err
+}
+
+// transferLimitUsage returns the usage of the limits of the account at now. Outgoing amounts of the owner sum
+// all accounts of the owner in the currency of the account, limits are in the minor units of the currency.
+func transferLimitUsage(
+	ctx context.Context,
+	q *db.Queries,
+	account db.Account,
+	limit db.TransferLimit,
+	now time.Time,
+) (TransferLimitUsage, error) {
+	usage := TransferLimitUsage{
+		AccountID: account.ID,
+		MaxAmount: limit.MaxAmount,
+	}
+
+	day := utcDay(now)
+	month := firstOfMonth(day)
+
+	windows := []struct {
+		limit string
+		max   int64
+		since time.Time
+		owner bool
+	}{
+		{LimitAccountDaily, limit.AccountDaily, day, false},
+		{LimitAccountMonthly, limit.AccountMonthly, month, false},
+		{LimitUserDaily, limit.UserDaily, day, true},
+		{LimitUserMonthly, limit.UserMonthly, month, true},
+	}
+
+	for _, window := range windows {
+		if window.max == 0 {
+			continue
+		}
+
+		var (
+			used int64
+			err  error
+		)
+		if window.owner {
+			used, err = q.GetOwnerOutgoingAmountSince(ctx, db.GetOwnerOutgoingAmountSinceParams{
+				Owner:    account.Owner,
+				Currency: account.Currency,
+				Since:    window.since,
+			})
+		} else {
+			used, err = q.GetOutgoingAmountSince(ctx, db.GetOutgoingAmountSinceParams{
+				AccountID: account.ID,
+				Since:     window.since,
+			})
+		}
+		if err != nil {
+			return usage, err
+		}
+
+		usage.Windows = append(usage.Windows, LimitUsage{
+			Limit:     window.limit,
+			Max:       window.max,
+			Used:      used,
+			Remaining: remaining(window.max, used),
+			Since:     window.since,
+		})
+	}
+
+	return usage, nil
+}
+
+// checkTransferLimits checks a booked outgoing transfer against the transfer limits of the from account.
+// The balance update of the transfer locks the account. With limits on the owner the owner is locked
+// before its usage is read, so concurrent transfers from other accounts of the owner are counted.
+func checkTransferLimits(ctx context.Context, q *db.Queries, account db.Account, amount int64) error {
+	limit, err := accountTransferLimit(ctx, q, account)
+	if err != nil {
+		return err
+	}
+
+	if limit.MaxAmount > 0 && amount > limit.MaxAmount {
+		return &LimitError{Limit: LimitMaxAmount, Max: limit.MaxAmount, Remaining: limit.MaxAmount}
+	}
+
+	if limit.UserDaily > 0 || limit.UserMonthly > 0 {
+		if _, err := q.GetUserForUpdate(ctx, account.Owner); err != nil {
+			return err
+		}
+	}
+
+	usage, err := transferLimitUsage(ctx, q, account, limit, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, window := range usage.Windows {
+		// Usage includes the transfer being checked
+		if window.Used > window.Max {
+			return &LimitError{Limit: window.Limit, Max: window.Max, Remaining: remaining(window.Max, window.Used-amount)}
+		}
+	}
+
+	return nil
+}
+
+// remaining returns what is left of a limit, never below zero.
+func remaining(limit int64, used int64) int64 {
+	if used >= limit {
+		return 0
+	}
+
+	return limit - used
+}
@@ -0,0 +1,224 @@
+package bank
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scopes of login throttles, failed logins are counted per username and per client IP
+const (
+	LoginScopeUsername = "username"
+	LoginScopeClientIP = "client_ip"
+)
+
+// LoginPolicy throttles failed logins. Failures count until a login succeeds, or again from one after a window
+// without failures. From the delay threshold on, each login waits for the delay after the last failure,
+// doubled for every further failure up to the max delay. From the lockout threshold on, logins are refused
+// for the lockout duration. Zero thresholds disable the delay or lockout.
+type LoginPolicy struct {
+	Window   time.Duration `json:"window"`
+	DelayAt  int32         `json:"delay_at"`
+	Delay    time.Duration `json:"delay"`
+	MaxDelay time.Duration `json:"max_delay"`
+	// Lockout thresholds per username and per client IP, a client IP is shared by many users
+	LockAt         int32         `json:"lock_at"`
+	LockAtClientIP int32         `json:"lock_at_client_ip"`
+	LockDuration   time.Duration `json:"lock_duration"`
+}
+
+// delay returns the delay of a login after the failures.
+func (p LoginPolicy) delay(failures int32) time.Duration {
+	if p.DelayAt <= 0 || failures < p.DelayAt {
+		return 0
+	}
+
+	delay := p.Delay
+	for i := p.DelayAt; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// check returns a *LoginThrottledError when the throttle refuses a login at now.
+func (p LoginPolicy) check(throttle db.LoginThrottle, now time.Time) error {
+	if isLocked(throttle, now) {
+		return &LoginThrottledError{Scope: throttle.Scope, Locked: true, RetryAfter: throttle.LockedUntil.Time.Sub(now)}
+	}
+
+	if throttle.LastFailedAt.Before(now.Add(-p.Window)) {
+		return nil
+	}
+
+	if next := throttle.LastFailedAt.Add(p.delay(throttle.Failures)); now.Before(next) {
+		return &LoginThrottledError{Scope: throttle.Scope, RetryAfter: next.Sub(now)}
+	}
+
+	return nil
+}
+
+func isLocked(throttle db.LoginThrottle, now time.Time) bool {
+	return throttle.LockedUntil.Valid && now.Before(throttle.LockedUntil.Time)
+}
+
+// CheckLoginParams contains the input parameters of checking a login
+type CheckLoginParams struct {
+	Username string      `json:"username"`
+	ClientIP string      `json:"client_ip"`
+	Policy   LoginPolicy `json:"policy"`
+}
+
+// CheckLogin returns a *LoginThrottledError, matching ErrLoginThrottled, when the failed logins of the
+// username or the client IP refuse a login now. Of both it returns the one refusing for longest.
+func (bank *SQLBank) CheckLogin(ctx context.Context, arg CheckLoginParams) error {
+	throttles, err := bank.ListLoginThrottlesByKeys(ctx, db.ListLoginThrottlesByKeysParams{
+		Username: arg.Username,
+		ClientIp: arg.ClientIP,
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	var refused *LoginThrottledError
+	for _, throttle := range throttles {
+		var throttled *LoginThrottledError
+		if errors.As(arg.Policy.check(throttle, now), &throttled) {
+			if refused == nil || throttled.RetryAfter > refused.RetryAfter {
+				refused = throttled
+			}
+		}
+	}
+
+	if refused == nil {
+		return nil
+	}
+	return refused
+}
+
+// RecordLoginFailureParams contains the input parameters of the record login failure transaction
+type RecordLoginFailureParams struct {
+	Username string      `json:"username"`
+	ClientIP string      `json:"client_ip"`
+	Policy   LoginPolicy `json:"policy"`
+	// AfterLock runs within the transaction with its querier once an existing user is locked out, e.g. to
+	// enqueue a notification of the user. The failure is not recorded when it fails.
+	AfterLock func(ctx context.Context, q db.Querier, user db.User, lockedUntil time.Time) error
+}
+
+// RecordLoginFailure counts a failed login of the username from the client IP within a database transaction,
+// and locks out the username or the client IP reaching its lockout threshold. Lockouts of users are audited.
+func (bank *SQLBank) RecordLoginFailure(ctx context.Context, arg RecordLoginFailureParams) error {
+	return bank.execTx(ctx, func(q *db.Queries) error {
+		now := time.Now()
+		since := now.Add(-arg.Policy.Window)
+		lockedUntil := pgtype.Timestamptz{Time: now.Add(arg.Policy.LockDuration), Valid: true}
+
+		byClientIP, err := q.CountLoginFailure(ctx, db.CountLoginFailureParams{
+			Scope: LoginScopeClientIP,
+			Key:   arg.ClientIP,
+			Since: since,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.Policy.LockAtClientIP > 0 && byClientIP.Failures >= arg.Policy.LockAtClientIP && !isLocked(byClientIP, now) {
+			_, err := q.LockLoginThrottle(ctx, db.LockLoginThrottleParams{
+				Scope:       LoginScopeClientIP,
+				Key:         arg.ClientIP,
+				LockedUntil: lockedUntil,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		byUsername, err := q.CountLoginFailure(ctx, db.CountLoginFailureParams{
+			Scope: LoginScopeUsername,
+			Key:   arg.Username,
+			Since: since,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.Policy.LockAt <= 0 || byUsername.Failures < arg.Policy.LockAt || isLocked(byUsername, now) {
+			return nil
+		}
+
+		locked, err := q.LockLoginThrottle(ctx, db.LockLoginThrottleParams{
+			Scope:       LoginScopeUsername,
+			Key:         arg.Username,
+			LockedUntil: lockedUntil,
+		})
+		if err != nil {
+			return err
+		}
+
+		// Unknown usernames are locked out too, but there is no one to tell
+		user, err := q.GetUser(ctx, arg.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		if arg.AfterLock != nil {
+			if err := arg.AfterLock(ctx, q, user, lockedUntil.Time); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionLockUser,
+			entityType: AuditEntityUser,
+			entityID:   user.Username,
+			after:      locked,
+		})
+	})
+}
+
+// UnlockUser removes the failed logins and the lockout of a user within a database transaction. Unlocking is
+// audited when there was anything to remove.
+func (bank *SQLBank) UnlockUser(ctx context.Context, username string) (db.User, error) {
+	var user db.User
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		user, err = q.GetUserForUpdate(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		before, err := q.DeleteLoginThrottle(ctx, db.DeleteLoginThrottleParams{
+			Scope: LoginScopeUsername,
+			Key:   username,
+		})
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionUnlockUser,
+			entityType: AuditEntityUser,
+			entityID:   username,
+			before:     before,
+		})
+	})
+
+	return user, err
+}
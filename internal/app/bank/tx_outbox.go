@@ -0,0 +1,192 @@
+package bank
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Aggregates of domain events, events of an aggregate are published in order
+const (
+	AggregateUser     = "user"
+	AggregateAccount  = "account"
+	AggregateTransfer = "transfer"
+)
+
+// Domain event types
+const (
+	EventUserCreated     = "user.created"
+	EventAccountCreated  = "account.created"
+	EventTransferCreated = "transfer.created"
+)
+
+// Payload versions of the domain event types, bumped when a payload changes incompatibly
+const (
+	UserCreatedVersion     int32 = 1
+	AccountCreatedVersion  int32 = 1
+	TransferCreatedVersion int32 = 1
+)
+
+// UserCreatedEvent is the payload of EventUserCreated
+type UserCreatedEvent struct {
+	Username  string    `json:"username"`
+	FullName  string    `json:"full_name"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountCreatedEvent is the payload of EventAccountCreated
+type AccountCreatedEvent struct {
+	AccountID int64     `json:"account_id"`
+	Owner     string    `json:"owner"`
+	Currency  string    `json:"currency"`
+	Product   string    `json:"product"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TransferCreatedEvent is the payload of EventTransferCreated, published for every money movement between
+// customer accounts, e.g. transfers, reversals and captured holds.
+type TransferCreatedEvent struct {
+	TransferID    int64  `json:"transfer_id"`
+	JournalID     int64  `json:"journal_id"`
+	FromAccountID int64  `json:"from_account_id"`
+	FromOwner     string `json:"from_owner"`
+	ToAccountID   int64  `json:"to_account_id"`
+	ToOwner       string `json:"to_owner"`
+	Amount        int64  `json:"amount"`
+	Currency      string `json:"currency"`
+	// Reversed transfer, empty unless a reversal
+	ReversalOf *int64    `json:"reversal_of,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// recordEvent writes a domain event to the outbox within the transaction of q, it is published once the
+// transaction commits. The event is delivered to the matching webhook subscriptions of the owners.
+func recordEvent(
+	ctx context.Context,
+	q *db.Queries,
+	aggregateType string,
+	aggregateID string,
+	eventType string,
+	version int32,
+	payload any,
+	owners ...string,
+) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	event, err := q.CreateOutboxEvent(ctx, db.CreateOutboxEventParams{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		EventVersion:  version,
+		Payload:       data,
+	})
+	if err != nil || len(owners) == 0 {
+		return err
+	}
+
+	return q.CreateWebhookDeliveries(ctx, db.CreateWebhookDeliveriesParams{
+		EventID:   event.ID,
+		Owners:    owners,
+		EventType: eventType,
+	})
+}
+
+func recordUserCreated(ctx context.Context, q *db.Queries, user db.User) error {
+	return recordEvent(ctx, q, AggregateUser, user.Username, EventUserCreated, UserCreatedVersion, UserCreatedEvent{
+		Username:  user.Username,
+		FullName:  user.FullName,
+		Email:     user.Email,
+		CreatedAt: user.CreatedAt,
+	})
+}
+
+func recordAccountCreated(ctx context.Context, q *db.Queries, account db.Account) error {
+	aggregateID := strconv.FormatInt(account.ID, 10)
+
+	return recordEvent(ctx, q, AggregateAccount, aggregateID, EventAccountCreated, AccountCreatedVersion, AccountCreatedEvent{
+		AccountID: account.ID,
+		Owner:     account.Owner,
+		Currency:  account.Currency,
+		Product:   account.Product,
+		CreatedAt: account.CreatedAt,
+	}, account.Owner)
+}
+
+func recordTransferCreated(ctx context.Context, q *db.Queries, result TransferResult) error {
+	transfer := result.Transfer
+
+	event := TransferCreatedEvent{
+		TransferID:    transfer.ID,
+		JournalID:     transfer.JournalID,
+		FromAccountID: transfer.FromAccountID,
+		FromOwner:     result.FromAccount.Owner,
+		ToAccountID:   transfer.ToAccountID,
+		ToOwner:       result.ToAccount.Owner,
+		Amount:        transfer.Amount,
+		Currency:      result.FromAccount.Currency,
+		CreatedAt:     transfer.CreatedAt,
+	}
+
+	if transfer.ReversalOf.Valid {
+		event.ReversalOf = &transfer.ReversalOf.Int64
+	}
+
+	aggregateID := strconv.FormatInt(transfer.ID, 10)
+
+	return recordEvent(
+		ctx,
+		q,
+		AggregateTransfer,
+		aggregateID,
+		EventTransferCreated,
+		TransferCreatedVersion,
+		event,
+		event.FromOwner,
+		event.ToOwner,
+	)
+}
+
+// RelayOutboxEvent claims the oldest pending outbox event that is due and publishes it, within a database
+// transaction. It returns db.ErrRecordNotFound when no event is due.
+//
+// Published events are marked as published. When publish fails the event is retried after retryDelay,
+// doubled for every following attempt, and events of the same aggregate wait until it is published. Events
+// are never given up on. The transaction may fail to commit after publishing, so an event is published at
+// least once, and consumers should deduplicate on the event ID.
+func (bank *SQLBank) RelayOutboxEvent(
+	ctx context.Context,
+	retryDelay time.Duration,
+	publish func(ctx context.Context, event db.Outbox) error,
+) (db.Outbox, error) {
+	var event db.Outbox
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		event, err = q.ClaimOutboxEvent(ctx)
+		if err != nil {
+			return err
+		}
+
+		if publishErr := publish(ctx, event); publishErr != nil {
+			event, err = q.RetryOutboxEvent(ctx, db.RetryOutboxEventParams{
+				LastError:     publishErr.Error(),
+				NextAttemptAt: time.Now().Add(RetryPolicy{Delay: retryDelay}.backoff(event.Attempts + 1)),
+				ID:            event.ID,
+			})
+			return err
+		}
+
+		event, err = q.MarkOutboxEventPublished(ctx, event.ID)
+		return err
+	})
+
+	return event, err
+}
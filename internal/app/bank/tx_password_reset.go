@@ -0,0 +1,145 @@
+package bank
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PasswordResetLimits limits the password resets requested within the window, per email and per client IP
+type PasswordResetLimits struct {
+	Window         time.Duration `json:"window"`
+	MaxPerEmail    int64         `json:"max_per_email"`
+	MaxPerClientIP int64         `json:"max_per_client_ip"`
+}
+
+// RequestPasswordResetParams contains the input parameters of the request password reset transaction
+type RequestPasswordResetParams struct {
+	Email    string              `json:"email"`
+	ClientIP string              `json:"client_ip"`
+	Limits   PasswordResetLimits `json:"limits"`
+	// AfterCreate runs within the transaction with its querier once a password reset is created for the user
+	// of the email, e.g. to enqueue sending the reset token. No reset is created when it fails.
+	AfterCreate func(ctx context.Context, q db.Querier, reset db.PasswordReset) error
+}
+
+// RequestPasswordReset creates a password reset for the user of the email within a database transaction,
+// unless the requests within the window exceed the limits. Requests of unknown emails count towards the
+// limits but create no reset, and are otherwise indistinguishable to the caller.
+func (bank *SQLBank) RequestPasswordReset(ctx context.Context, arg RequestPasswordResetParams) error {
+	return bank.execTx(ctx, func(q *db.Queries) error {
+		since := time.Now().Add(-arg.Limits.Window)
+
+		byClientIP, err := q.CountPasswordResetRequestsByClientIP(ctx, db.CountPasswordResetRequestsByClientIPParams{
+			ClientIp: arg.ClientIP,
+			Since:    since,
+		})
+		if err != nil {
+			return err
+		}
+
+		byEmail, err := q.CountPasswordResetRequestsByEmail(ctx, db.CountPasswordResetRequestsByEmailParams{
+			Email: arg.Email,
+			Since: since,
+		})
+		if err != nil {
+			return err
+		}
+
+		if byClientIP >= arg.Limits.MaxPerClientIP || byEmail >= arg.Limits.MaxPerEmail {
+			return ErrTooManyPasswordResets
+		}
+
+		err = q.CreatePasswordResetRequest(ctx, db.CreatePasswordResetRequestParams{
+			Email:    arg.Email,
+			ClientIp: arg.ClientIP,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err := q.GetUserByEmail(ctx, arg.Email)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+
+		reset, err := q.CreatePasswordReset(ctx, db.CreatePasswordResetParams{
+			Username: user.Username,
+			Email:    user.Email,
+		})
+		if err != nil {
+			return err
+		}
+
+		if arg.AfterCreate == nil {
+			return nil
+		}
+
+		return arg.AfterCreate(ctx, q, reset)
+	})
+}
+
+// ResetPasswordParams contains the input parameters of the reset password transaction
+type ResetPasswordParams struct {
+	// Hash of the reset token, see password.HashToken
+	TokenHash      string `json:"-"`
+	HashedPassword string `json:"-"`
+	// ValidatePassword runs within the transaction with the user of the reset before the password is set, e.g.
+	// to check the password policy against the username and email. The password is not reset when it fails.
+	ValidatePassword func(user db.User) error `json:"-"`
+}
+
+// ResetPassword sets the hashed password of a user within a database transaction when the token hash
+// matches an unused and unexpired password reset sent to the current email of the user. Like a changed
+// password it blocks the sessions of the user, and it invalidates the other resets of the user.
+func (bank *SQLBank) ResetPassword(ctx context.Context, arg ResetPasswordParams) (ChangePasswordResult, error) {
+	var result ChangePasswordResult
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		reset, err := q.GetPasswordResetByTokenForUpdate(ctx, pgtype.Text{String: arg.TokenHash, Valid: true})
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return ErrInvalidPasswordReset
+			}
+			return err
+		}
+
+		if reset.IsUsed {
+			return ErrInvalidPasswordReset
+		}
+
+		if !time.Now().Before(reset.ExpiredAt) {
+			return ErrPasswordResetExpired
+		}
+
+		before, err := q.GetUserForUpdate(ctx, reset.Username)
+		if err != nil {
+			return err
+		}
+
+		if before.Email != reset.Email {
+			return ErrInvalidPasswordReset
+		}
+
+		if arg.ValidatePassword != nil {
+			if err := arg.ValidatePassword(before); err != nil {
+				return err
+			}
+		}
+
+		if _, err := q.InvalidateUserPasswordResets(ctx, before.Username); err != nil {
+			return err
+		}
+
+		result, err = setPassword(ctx, q, before, arg.HashedPassword, AuditActionResetPassword)
+		return err
+	})
+
+	return result, err
+}
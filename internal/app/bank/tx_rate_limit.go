@@ -0,0 +1,40 @@
+package bank
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/ratelimit"
+)
+
+// TakeRateLimit takes a token from the bucket of the key with the limit within a database transaction, so
+// the limit holds across the replicas of the api. See ratelimit.Store.
+func (bank *SQLBank) TakeRateLimit(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	var result ratelimit.Result
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		now := time.Now()
+
+		before, err := q.LockRateLimitBucket(ctx, db.LockRateLimitBucketParams{
+			Key:    key,
+			Tokens: float64(limit.Requests),
+			Now:    now,
+		})
+		if err != nil {
+			return err
+		}
+
+		var bucket ratelimit.Bucket
+		bucket, result = limit.Take(ratelimit.Bucket{Tokens: before.Tokens, UpdatedAt: before.UpdatedAt}, now)
+
+		return q.UpdateRateLimitBucket(ctx, db.UpdateRateLimitBucketParams{
+			Key:       key,
+			Tokens:    bucket.Tokens,
+			UpdatedAt: bucket.UpdatedAt,
+			FullAt:    now.Add(result.Reset),
+		})
+	})
+
+	return result, err
+}
@@ -0,0 +1,36 @@
+package bank
+
+import (
+	"context"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// RepairAccountBalance sets the balance of an account to the sum of its entries within a database
+// transaction. Entries are the ledger of the account, the balance is derived from them.
+func (bank *SQLBank) RepairAccountBalance(ctx context.Context, accountID int64) (db.Account, error) {
+	var account db.Account
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		// Locking the account keeps transfers from adding entries meanwhile
+		account, err = q.GetAccountForUpdate(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		balance, err := q.SumAccountEntries(ctx, accountID)
+		if err != nil || balance == account.Balance {
+			return err
+		}
+
+		account, err = q.UpdateAccount(ctx, db.UpdateAccountParams{
+			ID:      accountID,
+			Balance: balance,
+		})
+		return err
+	})
+
+	return account, err
+}
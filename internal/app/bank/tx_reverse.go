@@ -0,0 +1,108 @@
+package bank
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReverseTransferParams contains the input parameters of the reverse transfer transaction
+type ReverseTransferParams struct {
+	// Transfer to reverse
+	TransferID int64 `json:"transfer_id"`
+	// Amount to refund, zero refunds the remaining reversible amount
+	Amount int64 `json:"amount"`
+	// Reason for the reversal, recorded on the reversal transfer
+	Reason string `json:"reason"`
+}
+
+// ReverseTransferResult is the result of the reverse transfer transaction
+type ReverseTransferResult struct {
+	// Created reversal transfer, its entries and updated accounts
+	TransferResult
+	// Original transfer that was reversed
+	OriginalTransfer db.Transfer `json:"original_transfer"`
+	// Amount of the original transfer that can still be reversed
+	Remaining int64 `json:"remaining"`
+}
+
+// ReverseTransfer refunds a transfer, fully or partially, by moving money back from the receiving account.
+// It creates a new transfer that references the original one, adds compensating account entries and
+// updates accounts' balance within a database transaction. The total refunded amount can never exceed
+// the amount of the original transfer, and a reversal can not be reversed itself.
+func (bank *SQLBank) ReverseTransfer(ctx context.Context, arg ReverseTransferParams) (ReverseTransferResult, error) {
+	var result ReverseTransferResult
+
+	if arg.Amount < 0 {
+		return result, ErrInvalidAmount
+	}
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		// Lock the original transfer so concurrent reversals are serialized
+		result.OriginalTransfer, err = q.GetTransferForUpdate(ctx, arg.TransferID)
+		if err != nil {
+			return err
+		}
+
+		if result.OriginalTransfer.ReversalOf.Valid {
+			return ErrReverseReversal
+		}
+
+		reversed, err := q.GetReversedAmount(ctx, result.OriginalTransfer.ID)
+		if err != nil {
+			return err
+		}
+
+		remaining := result.OriginalTransfer.Amount - reversed
+		if remaining <= 0 {
+			return ErrTransferReversed
+		}
+
+		amount := arg.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+
+		if amount > remaining {
+			return ErrReversalExceedsRemaining
+		}
+
+		journal, err := q.CreateJournal(ctx, JournalKindReversal)
+		if err != nil {
+			return err
+		}
+
+		// Money moves back the opposite way of the original transfer
+		reversal, err := q.CreateReversal(ctx, db.CreateReversalParams{
+			FromAccountID:  result.OriginalTransfer.ToAccountID,
+			ToAccountID:    result.OriginalTransfer.FromAccountID,
+			Amount:         amount,
+			ReversalOf:     pgtype.Int8{Int64: result.OriginalTransfer.ID, Valid: true},
+			ReversalReason: arg.Reason,
+			JournalID:      journal.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		result.TransferResult, err = moveMoney(ctx, q, reversal)
+		if err != nil {
+			return err
+		}
+
+		result.Remaining = remaining - amount
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionReverseTransfer,
+			entityType: AuditEntityTransfer,
+			entityID:   strconv.FormatInt(reversal.ID, 10),
+			after:      reversal,
+		})
+	})
+
+	return result, err
+}
@@ -0,0 +1,298 @@
+package bank
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/recurrence"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Scheduled transfer statuses, only active scheduled transfers are executed
+const (
+	ScheduledTransferStatusActive    = "active"
+	ScheduledTransferStatusPaused    = "paused"
+	ScheduledTransferStatusCompleted = "completed"
+	ScheduledTransferStatusCancelled = "cancelled"
+)
+
+// Scheduled transfer run statuses
+const (
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+)
+
+// ScheduleTransferParams contains the input parameters of a scheduled transfer
+type ScheduleTransferParams struct {
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// Cron expression or RRULE, empty schedules a single transfer at StartAt. Occurrences are computed in UTC,
+	// whatever the offset of StartAt.
+	Recurrence string `json:"recurrence"`
+	// No transfer is executed before StartAt
+	StartAt time.Time `json:"start_at"`
+}
+
+// ChangeScheduledTransferParams contains the changes to a scheduled transfer, only valid fields are changed
+type ChangeScheduledTransferParams struct {
+	ID         int64       `json:"id"`
+	Amount     pgtype.Int8 `json:"amount"`
+	Recurrence pgtype.Text `json:"recurrence"`
+	// Status to move to, active, paused or cancelled
+	Status pgtype.Text `json:"status"`
+}
+
+// RetryPolicy decides how failed occurrences of scheduled transfers are retried
+type RetryPolicy struct {
+	// Attempts of an occurrence before it is skipped
+	MaxAttempts int32 `json:"max_attempts"`
+	// Delay before the first retry, doubled for every following retry
+	Delay time.Duration `json:"delay"`
+}
+
+// backoff returns the delay before the attempt following the given failed attempt.
+func (p RetryPolicy) backoff(attempt int32) time.Duration {
+	// Avoid overflow, a day is long enough to wait anyway
+	const maxBackoff = 24 * time.Hour
+
+	delay := p.Delay
+	for i := int32(1); i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// RunScheduledTransferResult is the result of executing an occurrence of a scheduled transfer
+type RunScheduledTransferResult struct {
+	// Scheduled transfer after it has been advanced to its next occurrence, or set up for a retry
+	ScheduledTransfer db.ScheduledTransfer `json:"scheduled_transfer"`
+	// Outcome of the run
+	Run db.ScheduledTransferRun `json:"run"`
+	// Executed transfer, empty when the run failed
+	Transfer TransferResult `json:"transfer"`
+}
+
+// ScheduleTransfer schedules a single or recurring transfer, executed by RunScheduledTransfer.
+func (bank *SQLBank) ScheduleTransfer(ctx context.Context, arg ScheduleTransferParams) (db.ScheduledTransfer, error) {
+	if arg.Amount <= 0 {
+		return db.ScheduledTransfer{}, ErrInvalidAmount
+	}
+
+	if arg.FromAccountID == arg.ToAccountID {
+		return db.ScheduledTransfer{}, ErrSameAccount
+	}
+
+	// Runs would fail on every occurrence, refuse the schedule up front
+	fromAccount, err := bank.GetAccount(ctx, arg.FromAccountID)
+	if err != nil {
+		return db.ScheduledTransfer{}, err
+	}
+
+	toAccount, err := bank.GetAccount(ctx, arg.ToAccountID)
+	if err != nil {
+		return db.ScheduledTransfer{}, err
+	}
+
+	if fromAccount.Currency != toAccount.Currency {
+		return db.ScheduledTransfer{}, ErrCurrencyMismatch
+	}
+
+	schedule, err := parseSchedule(arg.Recurrence, arg.StartAt)
+	if err != nil {
+		return db.ScheduledTransfer{}, err
+	}
+
+	first := recurrence.First(schedule, arg.StartAt)
+	if first.IsZero() {
+		return db.ScheduledTransfer{}, ErrNoOccurrence
+	}
+
+	return bank.CreateScheduledTransfer(ctx, db.CreateScheduledTransferParams{
+		FromAccountID:    arg.FromAccountID,
+		ToAccountID:      arg.ToAccountID,
+		Amount:           arg.Amount,
+		Recurrence:       arg.Recurrence,
+		StartAt:          arg.StartAt.UTC(),
+		NextOccurrenceAt: first,
+	})
+}
+
+// ChangeScheduledTransfer changes the amount, recurrence or status of a scheduled transfer. A changed recurrence
+// or a resumed scheduled transfer continues with its first occurrence from now, with no failed attempts.
+// Completed or cancelled scheduled transfers can not be changed.
+func (bank *SQLBank) ChangeScheduledTransfer(ctx context.Context, arg ChangeScheduledTransferParams) (db.ScheduledTransfer, error) {
+	var scheduled db.ScheduledTransfer
+
+	if arg.Amount.Valid && arg.Amount.Int64 <= 0 {
+		return scheduled, ErrInvalidAmount
+	}
+
+	if arg.Status.Valid {
+		switch arg.Status.String {
+		case ScheduledTransferStatusActive, ScheduledTransferStatusPaused, ScheduledTransferStatusCancelled:
+		default:
+			return scheduled, ErrInvalidStatusTransition
+		}
+	}
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		var err error
+
+		scheduled, err = q.GetScheduledTransferForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		if scheduled.Status == ScheduledTransferStatusCompleted || scheduled.Status == ScheduledTransferStatusCancelled {
+			return ErrScheduleFinished
+		}
+
+		update := db.UpdateScheduledTransferParams{
+			ID:         arg.ID,
+			Amount:     arg.Amount,
+			Recurrence: arg.Recurrence,
+			Status:     arg.Status,
+		}
+
+		// Occurrences missed while paused are skipped, a resumed transfer starts over with the next one
+		resumed := arg.Status.String == ScheduledTransferStatusActive && scheduled.Status != ScheduledTransferStatusActive
+
+		if arg.Recurrence.Valid || resumed {
+			rule := scheduled.Recurrence
+			if arg.Recurrence.Valid {
+				rule = arg.Recurrence.String
+			}
+
+			schedule, err := parseSchedule(rule, scheduled.StartAt)
+			if err != nil {
+				return err
+			}
+
+			next := schedule.Next(time.Now())
+			if next.IsZero() {
+				return ErrNoOccurrence
+			}
+
+			update.NextOccurrenceAt = pgtype.Timestamptz{Time: next, Valid: true}
+		}
+
+		scheduled, err = q.UpdateScheduledTransfer(ctx, update)
+		return err
+	})
+
+	return scheduled, err
+}
+
+// RunScheduledTransfer claims one due scheduled transfer and executes its next occurrence.
+// It returns db.ErrRecordNotFound when no scheduled transfer is due.
+//
+// Claiming, executing the transfer, recording the run and advancing to the next occurrence happen
+// within one database transaction. Claimed rows are skipped by other callers, so several replicas can
+// run scheduled transfers side by side, and an occurrence is never executed twice. A failed transfer
+// is recorded and retried according to policy, once out of attempts the occurrence is skipped. Executed
+// transfers are recorded in the audit log.
+func (bank *SQLBank) RunScheduledTransfer(ctx context.Context, policy RetryPolicy) (RunScheduledTransferResult, error) {
+	var result RunScheduledTransferResult
+
+	err := bank.execPgxTx(ctx, func(tx pgx.Tx) error {
+		q := db.New(tx)
+
+		scheduled, err := q.ClaimDueScheduledTransfer(ctx)
+		if err != nil {
+			return err
+		}
+
+		run := db.CreateScheduledTransferRunParams{
+			ScheduledTransferID: scheduled.ID,
+			OccurrenceAt:        scheduled.NextOccurrenceAt,
+			Attempt:             scheduled.Attempts + 1,
+			Status:              RunStatusSucceeded,
+		}
+
+		// A failed transfer only rolls back its savepoint, the outcome is recorded either way
+		transferErr := execSavepoint(ctx, tx, func(q *db.Queries) error {
+			var err error
+			result.Transfer, err = createTransfer(ctx, q, JournalKindScheduledTransfer, TransferParams{
+				FromAccountID: scheduled.FromAccountID,
+				ToAccountID:   scheduled.ToAccountID,
+				Amount:        scheduled.Amount,
+			})
+			return err
+		})
+
+		switch {
+		case transferErr == nil:
+			run.TransferID = pgtype.Int8{Int64: result.Transfer.Transfer.ID, Valid: true}
+			result.ScheduledTransfer, err = advanceScheduledTransfer(ctx, q, scheduled)
+		case run.Attempt < policy.MaxAttempts:
+			result.Transfer = TransferResult{}
+			run.Status = RunStatusFailed
+			run.Error = transferErr.Error()
+			result.ScheduledTransfer, err = q.RetryScheduledTransfer(ctx, db.RetryScheduledTransferParams{
+				NextAttemptAt: time.Now().Add(policy.backoff(run.Attempt)),
+				ID:            scheduled.ID,
+			})
+		default:
+			// Out of attempts, skip the occurrence
+			result.Transfer = TransferResult{}
+			run.Status = RunStatusFailed
+			run.Error = transferErr.Error()
+			result.ScheduledTransfer, err = advanceScheduledTransfer(ctx, q, scheduled)
+		}
+		if err != nil {
+			return err
+		}
+
+		result.Run, err = q.CreateScheduledTransferRun(ctx, run)
+		if err != nil || transferErr != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionRunScheduled,
+			entityType: AuditEntityTransfer,
+			entityID:   strconv.FormatInt(result.Transfer.Transfer.ID, 10),
+			after:      result.Transfer.Transfer,
+		})
+	})
+
+	return result, err
+}
+
+// parseSchedule parses the recurrence of a scheduled transfer in UTC. Occurrences are computed in the location
+// of the start, which is the offset of the request when scheduled but the location of the driver when read back,
+// computing every occurrence in UTC keeps them from drifting between the two.
+func parseSchedule(rule string, startAt time.Time) (recurrence.Schedule, error) {
+	return recurrence.Parse(rule, startAt.UTC())
+}
+
+// advanceScheduledTransfer moves a scheduled transfer to its next occurrence, or completes it
+// when there are no more occurrences.
+func advanceScheduledTransfer(ctx context.Context, q *db.Queries, scheduled db.ScheduledTransfer) (db.ScheduledTransfer, error) {
+	schedule, err := parseSchedule(scheduled.Recurrence, scheduled.StartAt)
+	if err != nil {
+		return scheduled, err
+	}
+
+	status := ScheduledTransferStatusActive
+
+	next := schedule.Next(scheduled.NextOccurrenceAt)
+	if next.IsZero() {
+		status = ScheduledTransferStatusCompleted
+		next = scheduled.NextOccurrenceAt
+	}
+
+	return q.AdvanceScheduledTransfer(ctx, db.AdvanceScheduledTransferParams{
+		Status:           status,
+		NextOccurrenceAt: next,
+		ID:               scheduled.ID,
+	})
+}
@@ -0,0 +1,133 @@
+package bank
+
+import (
+	"bytes"
+	"context"
+	"errors"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// EnrollTOTPParams contains the input parameters of the enroll TOTP transaction
+type EnrollTOTPParams struct {
+	Username string `json:"username"`
+	// Secret encrypted with the key from config, see encrypt.Cipher
+	EncryptedSecret []byte `json:"-"`
+}
+
+// EnrollTOTP stores a new TOTP secret of a user within a database transaction, replacing a secret that is
+// not yet enabled. The secret takes effect once enabled with a first code, see EnableTOTP.
+func (bank *SQLBank) EnrollTOTP(ctx context.Context, arg EnrollTOTPParams) (db.UserTotp, error) {
+	var userTOTP db.UserTotp
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserTOTPForUpdate(ctx, arg.Username)
+		if err != nil && !errors.Is(err, db.ErrRecordNotFound) {
+			return err
+		}
+
+		if err == nil && before.IsEnabled {
+			return ErrTOTPEnabled
+		}
+
+		userTOTP, err = q.CreateUserTOTP(ctx, db.CreateUserTOTPParams{
+			Username:        arg.Username,
+			EncryptedSecret: arg.EncryptedSecret,
+		})
+		return err
+	})
+
+	return userTOTP, err
+}
+
+// EnableTOTPParams contains the input parameters of the enable TOTP transaction
+type EnableTOTPParams struct {
+	Username string `json:"username"`
+	// Enrolled secret the first code was validated against, it is not enabled once enrolled again
+	EncryptedSecret []byte `json:"-"`
+	// Time step of the first code, codes of earlier or the same steps are rejected from then on
+	Step int64 `json:"step"`
+	// Hashes of the recovery codes, see password.HashToken
+	RecoveryCodeHashes []string `json:"-"`
+}
+
+// EnableTOTP enables the enrolled TOTP secret of a user within a database transaction, replacing the recovery
+// codes of the user. Logins and transfers above the threshold then require a code.
+func (bank *SQLBank) EnableTOTP(ctx context.Context, arg EnableTOTPParams) (db.UserTotp, error) {
+	var userTOTP db.UserTotp
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserTOTPForUpdate(ctx, arg.Username)
+		if err != nil {
+			if errors.Is(err, db.ErrRecordNotFound) {
+				return ErrTOTPNotEnrolled
+			}
+			return err
+		}
+
+		if before.IsEnabled {
+			return ErrTOTPEnabled
+		}
+
+		if !bytes.Equal(before.EncryptedSecret, arg.EncryptedSecret) {
+			return ErrTOTPNotEnrolled
+		}
+
+		userTOTP, err = q.EnableUserTOTP(ctx, db.EnableUserTOTPParams{
+			Username:     arg.Username,
+			LastUsedStep: arg.Step,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := q.DeleteUserRecoveryCodes(ctx, arg.Username); err != nil {
+			return err
+		}
+
+		for _, hash := range arg.RecoveryCodeHashes {
+			err := q.CreateRecoveryCode(ctx, db.CreateRecoveryCodeParams{
+				Username: arg.Username,
+				CodeHash: hash,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionEnableTOTP,
+			entityType: AuditEntityUser,
+			entityID:   arg.Username,
+			before:     newAuditedTOTP(before),
+			after:      newAuditedTOTP(userTOTP),
+		})
+	})
+
+	return userTOTP, err
+}
+
+// DisableTOTP removes the TOTP secret and the recovery codes of a user within a database transaction.
+func (bank *SQLBank) DisableTOTP(ctx context.Context, username string) error {
+	return bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserTOTPForUpdate(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		if err := q.DeleteUserRecoveryCodes(ctx, username); err != nil {
+			return err
+		}
+
+		if err := q.DeleteUserTOTP(ctx, username); err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionDisableTOTP,
+			entityType: AuditEntityUser,
+			entityID:   username,
+			before:     newAuditedTOTP(before),
+		})
+	})
+}
@@ -2,8 +2,21 @@ package bank
 
 import (
 	"context"
+	"strconv"
 
 	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Journal kinds, the business operations moving money. Every operation books its transfers and entries
+// in a journal of its own.
+const (
+	JournalKindTransfer          = "transfer"
+	JournalKindScheduledTransfer = "scheduled_transfer"
+	JournalKindReversal          = "reversal"
+	JournalKindHoldCapture       = "hold_capture"
+	JournalKindAccountSweep      = "account_sweep"
+	JournalKindAccrual           = "accrual"
 )
 
 // TransferParams contains the input parameters of the transfer transaction
@@ -29,57 +42,143 @@ type TransferResult struct {
 
 // Transfer performs a money transfer from one account to the other.
 // It creates the transfer, add account entries, and update accounts' balance within a database transaction.
-func (bank *SQLBank) Transfer(ctx context.Context, transfer TransferParams) (TransferResult, error) {
+// The transfer is refused when either account is frozen or closed, when the available balance (balance
+// minus active holds, plus the overdraft limit of the product) of the from account can not cover the amount,
+// or when it breaks the withdrawal rules of the product of the from account. Transfers breaking the transfer
+// limits of the from account are refused with a *LimitError telling the remaining allowance.
+func (bank *SQLBank) Transfer(ctx context.Context, arg TransferParams) (TransferResult, error) {
 	var result TransferResult
 
 	// Create a transaction with the callback function
 	err := bank.execTx(ctx, func(q *db.Queries) error {
 		var err error
 
-		result.Transfer, err = q.CreateTransfer(ctx, db.CreateTransferParams{
-			FromAccountID: transfer.FromAccountID,
-			ToAccountID:   transfer.ToAccountID,
-			Amount:        transfer.Amount, // Amount to transfer
-		})
+		result, err = createTransfer(ctx, q, JournalKindTransfer, arg)
 		if err != nil {
 			return err
 		}
 
-		result.FromEntry, err = q.CreateEntry(ctx, db.CreateEntryParams{
-			AccountID: transfer.FromAccountID,
-			Amount:    -transfer.Amount, // Money moves out from account
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionTransfer,
+			entityType: AuditEntityTransfer,
+			entityID:   strconv.FormatInt(result.Transfer.ID, 10),
+			after:      result.Transfer,
 		})
-		if err != nil {
-			return err
-		}
+	})
 
-		result.ToEntry, err = q.CreateEntry(ctx, db.CreateEntryParams{
-			AccountID: transfer.ToAccountID,
-			Amount:    transfer.Amount, // Money moves in to account
-		})
-		if err != nil {
-			return err
-		}
+	return result, err
+}
 
-		// Some notes about database locks.
-		// Its always good to be consistent in the way database locks should be handled.
-		// To handle dead locks we make sure to apply database locks in a consistent order, in
-		// our case we always update accounts with smaller ids first.
-		if transfer.FromAccountID < transfer.ToAccountID {
-			result.FromAccount, result.ToAccount, err = addMoney(
-				ctx,
-				q,
-				transfer.FromAccountID,
-				-transfer.Amount,
-				transfer.ToAccountID,
-				transfer.Amount,
-			)
-		} else {
-			result.ToAccount, result.FromAccount, err = addMoney(ctx, q, transfer.ToAccountID, transfer.Amount, transfer.FromAccountID, -transfer.Amount)
-		}
+// createTransfer creates a journal of the kind with the transfer and moves the money within the transaction of q.
+func createTransfer(ctx context.Context, q *db.Queries, kind string, arg TransferParams) (TransferResult, error) {
+	journal, err := q.CreateJournal(ctx, kind)
+	if err != nil {
+		return TransferResult{}, err
+	}
+
+	transfer, err := q.CreateTransfer(ctx, db.CreateTransferParams{
+		FromAccountID: arg.FromAccountID,
+		ToAccountID:   arg.ToAccountID,
+		Amount:        arg.Amount, // Amount to transfer
+		JournalID:     journal.ID,
+	})
+	if err != nil {
+		return TransferResult{}, err
+	}
 
+	result, err := moveMoney(ctx, q, transfer)
+	if err != nil {
+		return result, err
+	}
+
+	return result, checkWithdrawal(ctx, q, result)
+}
+
+// checkWithdrawal checks a booked transfer moving money out of a customer account against the withdrawal
+// rules of the product and the transfer limits of the from account.
+func checkWithdrawal(ctx context.Context, q *db.Queries, result TransferResult) error {
+	if err := checkWithdrawalRules(ctx, q, result.FromAccount, result.Transfer.Amount); err != nil {
 		return err
+	}
+
+	return checkTransferLimits(ctx, q, result.FromAccount, result.Transfer.Amount)
+}
+
+// moveMoney adds the account entries and updates the accounts' balance for a created transfer, and records
+// the transfer in the outbox.
+func moveMoney(ctx context.Context, q *db.Queries, transfer db.Transfer) (TransferResult, error) {
+	result, err := bookMoney(ctx, q, transfer)
+	if err != nil {
+		return result, err
+	}
+
+	// Checked here rather than by each caller, so that no path, e.g. capturing a hold or a scheduled
+	// transfer, moves money between currencies
+	if result.FromAccount.Currency != result.ToAccount.Currency {
+		return result, ErrCurrencyMismatch
+	}
+
+	// The accounts are locked, frozen or closed accounts can not have changed status meanwhile
+	if err := checkAccountActive(result.FromAccount); err != nil {
+		return result, err
+	}
+
+	if err := checkAccountActive(result.ToAccount); err != nil {
+		return result, err
+	}
+
+	// Balances are updated and the accounts locked, the from account must still
+	// cover money reserved by its active holds, within its overdraft limit.
+	if err := checkAvailableBalance(ctx, q, result.FromAccount, 0); err != nil {
+		return result, err
+	}
+
+	return result, recordTransferCreated(ctx, q, result)
+}
+
+// bookMoney is moveMoney without checking the available balance of the from account, for money
+// the bank moves regardless, e.g. interest paid from a bank account or fees charged from a customer.
+func bookMoney(ctx context.Context, q *db.Queries, transfer db.Transfer) (TransferResult, error) {
+	result := TransferResult{Transfer: transfer}
+
+	var err error
+
+	result.FromEntry, err = q.CreateEntry(ctx, db.CreateEntryParams{
+		AccountID:  transfer.FromAccountID,
+		Amount:     -transfer.Amount, // Money moves out from account
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+		JournalID:  transfer.JournalID,
 	})
+	if err != nil {
+		return result, err
+	}
+
+	result.ToEntry, err = q.CreateEntry(ctx, db.CreateEntryParams{
+		AccountID:  transfer.ToAccountID,
+		Amount:     transfer.Amount, // Money moves in to account
+		TransferID: pgtype.Int8{Int64: transfer.ID, Valid: true},
+		JournalID:  transfer.JournalID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	// Some notes about database locks.
+	// Its always good to be consistent in the way database locks should be handled.
+	// To handle dead locks we make sure to apply database locks in a consistent order, in
+	// our case we always update accounts with smaller ids first.
+	if transfer.FromAccountID < transfer.ToAccountID {
+		result.FromAccount, result.ToAccount, err = addMoney(
+			ctx,
+			q,
+			transfer.FromAccountID,
+			-transfer.Amount,
+			transfer.ToAccountID,
+			transfer.Amount,
+		)
+	} else {
+		result.ToAccount, result.FromAccount, err = addMoney(ctx, q, transfer.ToAccountID, transfer.Amount, transfer.FromAccountID, -transfer.Amount)
+	}
 
 	return result, err
 }
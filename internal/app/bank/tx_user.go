@@ -0,0 +1,173 @@
+package bank
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// User roles, customers act on their own resources, support and admins also on resources of other users
+const (
+	RoleCustomer = "customer"
+	RoleSupport  = "support"
+	RoleAdmin    = "admin"
+)
+
+// UpdateProfileParams contains the input parameters of the update profile transaction
+type UpdateProfileParams struct {
+	Username string `json:"username"`
+	// Unchanged when not valid
+	FullName pgtype.Text `json:"full_name"`
+	Email    pgtype.Text `json:"email"`
+	// AfterEmailChange runs within the transaction with its querier once the email has changed, e.g. to
+	// enqueue the verification of the new email. The profile is not changed when it fails.
+	AfterEmailChange func(ctx context.Context, q db.Querier, user db.User) error
+}
+
+// UpdateProfile changes the full name and email of a user within a database transaction. A changed email
+// is no longer verified, and the codes sent to the previous email can not verify it.
+func (bank *SQLBank) UpdateProfile(ctx context.Context, arg UpdateProfileParams) (db.User, error) {
+	var user db.User
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		update := db.UpdateUserParams{
+			FullName: arg.FullName,
+			Email:    arg.Email,
+			Username: arg.Username,
+		}
+
+		emailChanged := arg.Email.Valid && arg.Email.String != before.Email
+		if emailChanged {
+			update.IsEmailVerified = pgtype.Bool{Bool: false, Valid: true}
+		}
+
+		user, err = q.UpdateUser(ctx, update)
+		if err != nil {
+			return err
+		}
+
+		if emailChanged && arg.AfterEmailChange != nil {
+			if err := arg.AfterEmailChange(ctx, q, user); err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionUpdateUser,
+			entityType: AuditEntityUser,
+			entityID:   user.Username,
+			before:     newAuditedUser(before),
+			after:      newAuditedUser(user),
+		})
+	})
+
+	return user, err
+}
+
+// ChangePasswordParams contains the input parameters of the change password transaction
+type ChangePasswordParams struct {
+	Username       string `json:"username"`
+	HashedPassword string `json:"-"`
+}
+
+// ChangePasswordResult is the result of the change password transaction
+type ChangePasswordResult struct {
+	User db.User `json:"user"`
+	// Number of sessions blocked
+	RevokedSessions int64 `json:"revoked_sessions"`
+}
+
+// ChangePassword sets the hashed password of a user and blocks the sessions of the user within a database
+// transaction, so refresh tokens issued with the previous password can not renew access tokens.
+func (bank *SQLBank) ChangePassword(ctx context.Context, arg ChangePasswordParams) (ChangePasswordResult, error) {
+	var result ChangePasswordResult
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		result, err = setPassword(ctx, q, before, arg.HashedPassword, AuditActionChangePassword)
+		return err
+	})
+
+	return result, err
+}
+
+// setPassword sets the hashed password of the locked user and blocks the sessions of the user, recording
+// the change in the audit log as the action.
+func setPassword(ctx context.Context, q *db.Queries, before db.User, hashedPassword string, action string) (ChangePasswordResult, error) {
+	var result ChangePasswordResult
+
+	user, err := q.UpdateUser(ctx, db.UpdateUserParams{
+		HashedPassword:    pgtype.Text{String: hashedPassword, Valid: true},
+		PasswordChangedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Username:          before.Username,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	revoked, err := q.BlockUserSessions(ctx, before.Username)
+	if err != nil {
+		return result, err
+	}
+
+	err = recordAudit(ctx, q, auditEvent{
+		action:     action,
+		entityType: AuditEntityUser,
+		entityID:   user.Username,
+		before:     newAuditedUser(before),
+		after:      newAuditedUser(user),
+	})
+	if err != nil {
+		return result, err
+	}
+
+	return ChangePasswordResult{User: user, RevokedSessions: revoked}, nil
+}
+
+// SetUserRoleParams contains the input parameters of the set user role transaction
+type SetUserRoleParams struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// SetUserRole changes the role of a user within a database transaction. Access tokens issued before are refused,
+// renewed access tokens get the new role.
+func (bank *SQLBank) SetUserRole(ctx context.Context, arg SetUserRoleParams) (db.User, error) {
+	var user db.User
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		before, err := q.GetUserForUpdate(ctx, arg.Username)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.UpdateUser(ctx, db.UpdateUserParams{
+			Role:     pgtype.Text{String: arg.Role, Valid: true},
+			Username: arg.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionSetUserRole,
+			entityType: AuditEntityUser,
+			entityID:   user.Username,
+			before:     newAuditedUser(before),
+			after:      newAuditedUser(user),
+		})
+	})
+
+	return user, err
+}
@@ -0,0 +1,96 @@
+package bank
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// MaxUnverifiedAccounts is the number of open accounts of a user until the email of the user is verified
+const MaxUnverifiedAccounts = 1
+
+// VerifyEmailParams contains the input parameters of the verify email transaction
+type VerifyEmailParams struct {
+	ID         int64  `json:"id"`
+	SecretCode string `json:"secret_code"`
+}
+
+// VerifyEmailResult is the result of the verify email transaction
+type VerifyEmailResult struct {
+	User        db.User        `json:"user"`
+	VerifyEmail db.VerifyEmail `json:"verify_email"`
+}
+
+// VerifyEmail marks the email of a user verified when the secret code matches an unused and unexpired
+// verification email sent to the current email of the user.
+func (bank *SQLBank) VerifyEmail(ctx context.Context, arg VerifyEmailParams) (VerifyEmailResult, error) {
+	var result VerifyEmailResult
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		verifyEmail, err := q.GetVerifyEmailForUpdate(ctx, arg.ID)
+		if err != nil {
+			return err
+		}
+
+		if verifyEmail.IsUsed || subtle.ConstantTimeCompare([]byte(verifyEmail.SecretCode), []byte(arg.SecretCode)) != 1 {
+			return ErrInvalidVerifyEmail
+		}
+
+		if !time.Now().Before(verifyEmail.ExpiredAt) {
+			return ErrVerifyEmailExpired
+		}
+
+		before, err := q.GetUserForUpdate(ctx, verifyEmail.Username)
+		if err != nil {
+			return err
+		}
+
+		if before.Email != verifyEmail.Email {
+			return ErrInvalidVerifyEmail
+		}
+
+		result.VerifyEmail, err = q.MarkVerifyEmailUsed(ctx, verifyEmail.ID)
+		if err != nil {
+			return err
+		}
+
+		result.User, err = q.UpdateUser(ctx, db.UpdateUserParams{
+			IsEmailVerified: pgtype.Bool{Bool: true, Valid: true},
+			Username:        before.Username,
+		})
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(ctx, q, auditEvent{
+			action:     AuditActionVerifyEmail,
+			entityType: AuditEntityUser,
+			entityID:   result.User.Username,
+			before:     newAuditedUser(before),
+			after:      newAuditedUser(result.User),
+		})
+	})
+
+	return result, err
+}
+
+// checkEmailVerified limits the number of open accounts of a user with an unverified email
+func checkEmailVerified(ctx context.Context, q *db.Queries, user db.User) error {
+	if user.IsEmailVerified {
+		return nil
+	}
+
+	count, err := q.CountUserAccounts(ctx, user.Username)
+	if err != nil {
+		return err
+	}
+
+	if count >= MaxUnverifiedAccounts {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package bank
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Statuses of webhook deliveries
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	// Out of attempts, delivered again only when replayed
+	WebhookDeliveryDead = "dead"
+)
+
+// WebhookEventTypes are the domain event types delivered to webhook subscriptions
+var WebhookEventTypes = []string{EventAccountCreated, EventTransferCreated}
+
+// DeliverWebhook claims the webhook delivery that is due first and delivers it, within a database transaction.
+// deliver returns the HTTP status of the receiver, 0 when there was no response. It returns
+// db.ErrRecordNotFound when no delivery is due.
+//
+// A failed delivery is retried according to policy, once out of attempts it is dead. Claimed rows are skipped
+// by other callers, so several replicas can deliver side by side. The transaction may fail to commit after
+// delivering, so an event is delivered at least once.
+func (bank *SQLBank) DeliverWebhook(
+	ctx context.Context,
+	policy RetryPolicy,
+	deliver func(ctx context.Context, delivery db.ClaimWebhookDeliveryRow) (int32, error),
+) (db.WebhookDelivery, error) {
+	var result db.WebhookDelivery
+
+	err := bank.execTx(ctx, func(q *db.Queries) error {
+		delivery, err := q.ClaimWebhookDelivery(ctx)
+		if err != nil {
+			return err
+		}
+
+		status, deliverErr := deliver(ctx, delivery)
+		if deliverErr == nil {
+			result, err = q.MarkWebhookDeliverySucceeded(ctx, db.MarkWebhookDeliverySucceededParams{
+				ResponseStatus: status,
+				ID:             delivery.ID,
+			})
+			return err
+		}
+
+		attempt := delivery.Attempts + 1
+
+		arg := db.RetryWebhookDeliveryParams{
+			Status:         WebhookDeliveryPending,
+			LastError:      deliverErr.Error(),
+			ResponseStatus: status,
+			NextAttemptAt:  time.Now().Add(policy.backoff(attempt)),
+			ID:             delivery.ID,
+		}
+
+		if attempt >= policy.MaxAttempts {
+			arg.Status = WebhookDeliveryDead
+		}
+
+		result, err = q.RetryWebhookDelivery(ctx, arg)
+		return err
+	})
+
+	return result, err
+}
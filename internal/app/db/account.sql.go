@@ -13,7 +13,7 @@ const addAccountBalance = `-- name: AddAccountBalance :one
 UPDATE accounts
 SET balance = balance + $1
 WHERE id = $2
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, product, status, frozen_at, closed_at
 `
 
 type AddAccountBalanceParams struct {
@@ -30,28 +30,69 @@ func (q *Queries) AddAccountBalance(ctx context.Context, arg AddAccountBalancePa
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
+const countOwnerAccounts = `-- name: CountOwnerAccounts :one
+SELECT COUNT(*) FROM accounts
+WHERE owner = $1 AND currency = $2 AND product = $3 AND status <> 'closed'
+`
+
+type CountOwnerAccountsParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+	Product  string `json:"product"`
+}
+
+func (q *Queries) CountOwnerAccounts(ctx context.Context, arg CountOwnerAccountsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countOwnerAccounts, arg.Owner, arg.Currency, arg.Product)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countUserAccounts = `-- name: CountUserAccounts :one
+SELECT COUNT(*) FROM accounts
+WHERE owner = $1 AND status <> 'closed'
+`
+
+func (q *Queries) CountUserAccounts(ctx context.Context, owner string) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserAccounts, owner)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createAccount = `-- name: CreateAccount :one
 INSERT INTO accounts (
   owner,
   balance,
-  currency
+  currency,
+  product
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, owner, balance, currency, created_at
+  $1, $2, $3, $4
+) RETURNING id, owner, balance, currency, created_at, product, status, frozen_at, closed_at
 `
 
 type CreateAccountParams struct {
 	Owner    string `json:"owner"`
 	Balance  int64  `json:"balance"`
 	Currency string `json:"currency"`
+	Product  string `json:"product"`
 }
 
 func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error) {
-	row := q.db.QueryRow(ctx, createAccount, arg.Owner, arg.Balance, arg.Currency)
+	row := q.db.QueryRow(ctx, createAccount,
+		arg.Owner,
+		arg.Balance,
+		arg.Currency,
+		arg.Product,
+	)
 	var i Account
 	err := row.Scan(
 		&i.ID,
@@ -59,22 +100,38 @@ func (q *Queries) CreateAccount(ctx context.Context, arg CreateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
-const deleteAccount = `-- name: DeleteAccount :exec
-DELETE FROM accounts
-WHERE id = $1
+const createInternalAccount = `-- name: CreateInternalAccount :exec
+INSERT INTO accounts (
+  owner,
+  balance,
+  currency,
+  product
+) VALUES (
+  $1, 0, $2, 'internal'
+) ON CONFLICT (owner, currency) WHERE product = 'internal' DO NOTHING
 `
 
-func (q *Queries) DeleteAccount(ctx context.Context, id int64) error {
-	_, err := q.db.Exec(ctx, deleteAccount, id)
+type CreateInternalAccountParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+}
+
+// Opens the internal account of a bank owner in a currency unless it exists, waiting for concurrent openings
+func (q *Queries) CreateInternalAccount(ctx context.Context, arg CreateInternalAccountParams) error {
+	_, err := q.db.Exec(ctx, createInternalAccount, arg.Owner, arg.Currency)
 	return err
 }
 
 const getAccount = `-- name: GetAccount :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, product, status, frozen_at, closed_at FROM accounts
 WHERE id = $1 LIMIT 1
 `
 
@@ -87,12 +144,51 @@ func (q *Queries) GetAccount(ctx context.Context, id int64) (Account, error) {
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const getAccountBalanceEvent = `-- name: GetAccountBalanceEvent :one
+SELECT
+  a.id,
+  a.owner,
+  a.balance,
+  a.currency,
+  COALESCE(MAX(e.id), 0)::bigint AS entry_id
+FROM accounts a
+LEFT JOIN entries e ON e.account_id = a.id
+WHERE a.id = $1
+GROUP BY a.id
+`
+
+type GetAccountBalanceEventRow struct {
+	ID       int64  `json:"id"`
+	Owner    string `json:"owner"`
+	Balance  int64  `json:"balance"`
+	Currency string `json:"currency"`
+	EntryID  int64  `json:"entry_id"`
+}
+
+// Balance of an account with its latest entry, read together so the balance includes the entry
+func (q *Queries) GetAccountBalanceEvent(ctx context.Context, id int64) (GetAccountBalanceEventRow, error) {
+	row := q.db.QueryRow(ctx, getAccountBalanceEvent, id)
+	var i GetAccountBalanceEventRow
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.EntryID,
 	)
 	return i, err
 }
 
 const getAccountForUpdate = `-- name: GetAccountForUpdate :one
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, product, status, frozen_at, closed_at FROM accounts
 WHERE id = $1 LIMIT 1
 FOR NO KEY UPDATE
 `
@@ -106,12 +202,98 @@ func (q *Queries) GetAccountForUpdate(ctx context.Context, id int64) (Account, e
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
 	)
 	return i, err
 }
 
+const getOwnerAccount = `-- name: GetOwnerAccount :one
+SELECT id, owner, balance, currency, created_at, product, status, frozen_at, closed_at FROM accounts
+WHERE owner = $1 AND currency = $2 LIMIT 1
+`
+
+type GetOwnerAccountParams struct {
+	Owner    string `json:"owner"`
+	Currency string `json:"currency"`
+}
+
+func (q *Queries) GetOwnerAccount(ctx context.Context, arg GetOwnerAccountParams) (Account, error) {
+	row := q.db.QueryRow(ctx, getOwnerAccount, arg.Owner, arg.Currency)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const listAccountBalancesAfter = `-- name: ListAccountBalancesAfter :many
+SELECT
+  accounts.id,
+  accounts.owner,
+  accounts.currency,
+  accounts.balance,
+  COALESCE(SUM(entries.amount), 0)::bigint AS entries_balance
+FROM accounts
+LEFT JOIN entries ON entries.account_id = accounts.id
+WHERE accounts.id > $1
+GROUP BY accounts.id
+ORDER BY accounts.id
+LIMIT $2
+`
+
+type ListAccountBalancesAfterParams struct {
+	ID    int64 `json:"id"`
+	Limit int32 `json:"limit"`
+}
+
+type ListAccountBalancesAfterRow struct {
+	ID             int64  `json:"id"`
+	Owner          string `json:"owner"`
+	Currency       string `json:"currency"`
+	Balance        int64  `json:"balance"`
+	EntriesBalance int64  `json:"entries_balance"`
+}
+
+// Keyset pagination over all accounts with the balance their entries add up to
+func (q *Queries) ListAccountBalancesAfter(ctx context.Context, arg ListAccountBalancesAfterParams) ([]ListAccountBalancesAfterRow, error) {
+	rows, err := q.db.Query(ctx, listAccountBalancesAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountBalancesAfterRow{}
+	for rows.Next() {
+		var i ListAccountBalancesAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Currency,
+			&i.Balance,
+			&i.EntriesBalance,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listAccounts = `-- name: ListAccounts :many
-SELECT id, owner, balance, currency, created_at FROM accounts
+SELECT id, owner, balance, currency, created_at, product, status, frozen_at, closed_at FROM accounts
 WHERE owner = $1
 ORDER BY id
 LIMIT $2
@@ -139,6 +321,53 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 			&i.Balance,
 			&i.Currency,
 			&i.CreatedAt,
+			&i.Product,
+			&i.Status,
+			&i.FrozenAt,
+			&i.ClosedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listAccountsAfter = `-- name: ListAccountsAfter :many
+SELECT id, owner, balance, currency, created_at, product, status, frozen_at, closed_at FROM accounts
+WHERE id > $1
+ORDER BY id
+LIMIT $2
+`
+
+type ListAccountsAfterParams struct {
+	ID    int64 `json:"id"`
+	Limit int32 `json:"limit"`
+}
+
+// Keyset pagination over all accounts, for batch jobs
+func (q *Queries) ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error) {
+	rows, err := q.db.Query(ctx, listAccountsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Owner,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.Product,
+			&i.Status,
+			&i.FrozenAt,
+			&i.ClosedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -150,11 +379,90 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]A
 	return items, nil
 }
 
+const markAccountActive = `-- name: MarkAccountActive :one
+UPDATE accounts
+SET
+  status = 'active',
+  frozen_at = NULL
+WHERE id = $1
+RETURNING id, owner, balance, currency, created_at, product, status, frozen_at, closed_at
+`
+
+func (q *Queries) MarkAccountActive(ctx context.Context, id int64) (Account, error) {
+	row := q.db.QueryRow(ctx, markAccountActive, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const markAccountClosed = `-- name: MarkAccountClosed :one
+UPDATE accounts
+SET
+  status = 'closed',
+  frozen_at = NULL,
+  closed_at = now()
+WHERE id = $1
+RETURNING id, owner, balance, currency, created_at, product, status, frozen_at, closed_at
+`
+
+func (q *Queries) MarkAccountClosed(ctx context.Context, id int64) (Account, error) {
+	row := q.db.QueryRow(ctx, markAccountClosed, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
+const markAccountFrozen = `-- name: MarkAccountFrozen :one
+UPDATE accounts
+SET
+  status = 'frozen',
+  frozen_at = now()
+WHERE id = $1
+RETURNING id, owner, balance, currency, created_at, product, status, frozen_at, closed_at
+`
+
+func (q *Queries) MarkAccountFrozen(ctx context.Context, id int64) (Account, error) {
+	row := q.db.QueryRow(ctx, markAccountFrozen, id)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Owner,
+		&i.Balance,
+		&i.Currency,
+		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
+	)
+	return i, err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
 SET balance = $2
 WHERE id = $1
-RETURNING id, owner, balance, currency, created_at
+RETURNING id, owner, balance, currency, created_at, product, status, frozen_at, closed_at
 `
 
 type UpdateAccountParams struct {
@@ -171,6 +479,10 @@ func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (A
 		&i.Balance,
 		&i.Currency,
 		&i.CreatedAt,
+		&i.Product,
+		&i.Status,
+		&i.FrozenAt,
+		&i.ClosedAt,
 	)
 	return i, err
 }
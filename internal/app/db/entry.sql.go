@@ -7,36 +7,80 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countUnlinkedEntries = `-- name: CountUnlinkedEntries :one
+SELECT COUNT(*) FROM entries
+WHERE transfer_id IS NULL
+`
+
+func (q *Queries) CountUnlinkedEntries(ctx context.Context) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnlinkedEntries)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createEntry = `-- name: CreateEntry :one
 INSERT INTO entries (
   account_id,
-  amount
+  amount,
+  transfer_id,
+  journal_id
 ) VALUES (
-  $1, $2
-) RETURNING id, account_id, amount, created_at
+  $1, $2, $3, $4
+) RETURNING id, account_id, amount, created_at, transfer_id, journal_id
 `
 
 type CreateEntryParams struct {
-	AccountID int64 `json:"account_id"`
-	Amount    int64 `json:"amount"`
+	AccountID  int64       `json:"account_id"`
+	Amount     int64       `json:"amount"`
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	JournalID  int64       `json:"journal_id"`
 }
 
 func (q *Queries) CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error) {
-	row := q.db.QueryRow(ctx, createEntry, arg.AccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createEntry,
+		arg.AccountID,
+		arg.Amount,
+		arg.TransferID,
+		arg.JournalID,
+	)
 	var i Entry
 	err := row.Scan(
 		&i.ID,
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.JournalID,
 	)
 	return i, err
 }
 
+const getEntriesAmountSince = `-- name: GetEntriesAmountSince :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+FROM entries
+WHERE account_id = $1 AND created_at >= $2
+`
+
+type GetEntriesAmountSinceParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+}
+
+func (q *Queries) GetEntriesAmountSince(ctx context.Context, arg GetEntriesAmountSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getEntriesAmountSince, arg.AccountID, arg.Since)
+	var amount int64
+	err := row.Scan(&amount)
+	return amount, err
+}
+
 const getEntry = `-- name: GetEntry :one
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, journal_id FROM entries
 WHERE id = $1 LIMIT 1
 `
 
@@ -48,12 +92,170 @@ func (q *Queries) GetEntry(ctx context.Context, id int64) (Entry, error) {
 		&i.AccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.TransferID,
+		&i.JournalID,
 	)
 	return i, err
 }
 
+const getOutgoingAmountSince = `-- name: GetOutgoingAmountSince :one
+SELECT COALESCE(-SUM(entries.amount), 0)::bigint AS amount
+FROM entries
+JOIN journals ON journals.id = entries.journal_id
+WHERE entries.account_id = $1
+  AND entries.amount < 0
+  AND entries.created_at >= $2
+  AND journals.kind IN ('transfer', 'scheduled_transfer', 'hold_capture')
+`
+
+type GetOutgoingAmountSinceParams struct {
+	AccountID int64     `json:"account_id"`
+	Since     time.Time `json:"since"`
+}
+
+// Outgoing amount of transfers, scheduled transfers and hold captures, fees and reversals do not count
+func (q *Queries) GetOutgoingAmountSince(ctx context.Context, arg GetOutgoingAmountSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getOutgoingAmountSince, arg.AccountID, arg.Since)
+	var amount int64
+	err := row.Scan(&amount)
+	return amount, err
+}
+
+const getOwnerOutgoingAmountSince = `-- name: GetOwnerOutgoingAmountSince :one
+SELECT COALESCE(-SUM(entries.amount), 0)::bigint AS amount
+FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+JOIN journals ON journals.id = entries.journal_id
+WHERE accounts.owner = $1
+  AND accounts.currency = $2
+  AND entries.amount < 0
+  AND entries.created_at >= $3
+  AND journals.kind IN ('transfer', 'scheduled_transfer', 'hold_capture')
+`
+
+type GetOwnerOutgoingAmountSinceParams struct {
+	Owner    string    `json:"owner"`
+	Currency string    `json:"currency"`
+	Since    time.Time `json:"since"`
+}
+
+// Outgoing amount of all accounts of the owner in the currency, counted like GetOutgoingAmountSince
+func (q *Queries) GetOwnerOutgoingAmountSince(ctx context.Context, arg GetOwnerOutgoingAmountSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, getOwnerOutgoingAmountSince, arg.Owner, arg.Currency, arg.Since)
+	var amount int64
+	err := row.Scan(&amount)
+	return amount, err
+}
+
+const listAccountHistory = `-- name: ListAccountHistory :many
+SELECT
+  entries.id,
+  entries.amount,
+  entries.created_at,
+  entries.journal_id,
+  journals.kind AS journal_kind,
+  entries.transfer_id,
+  counterparty.id AS counterparty_account_id,
+  counterparty.owner AS counterparty_owner
+FROM entries
+JOIN journals ON journals.id = entries.journal_id
+LEFT JOIN transfers ON transfers.id = entries.transfer_id
+LEFT JOIN accounts AS counterparty ON counterparty.id = CASE
+  WHEN transfers.from_account_id = entries.account_id THEN transfers.to_account_id
+  ELSE transfers.from_account_id
+END
+WHERE entries.account_id = $1
+ORDER BY entries.id
+LIMIT $2
+OFFSET $3
+`
+
+type ListAccountHistoryParams struct {
+	AccountID int64 `json:"account_id"`
+	Limit     int32 `json:"limit"`
+	Offset    int32 `json:"offset"`
+}
+
+type ListAccountHistoryRow struct {
+	ID                    int64       `json:"id"`
+	Amount                int64       `json:"amount"`
+	CreatedAt             time.Time   `json:"created_at"`
+	JournalID             int64       `json:"journal_id"`
+	JournalKind           string      `json:"journal_kind"`
+	TransferID            pgtype.Int8 `json:"transfer_id"`
+	CounterpartyAccountID pgtype.Int8 `json:"counterparty_account_id"`
+	CounterpartyOwner     pgtype.Text `json:"counterparty_owner"`
+}
+
+// Entries of the account with the journal and the other account of the transfer booking them
+func (q *Queries) ListAccountHistory(ctx context.Context, arg ListAccountHistoryParams) ([]ListAccountHistoryRow, error) {
+	rows, err := q.db.Query(ctx, listAccountHistory, arg.AccountID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListAccountHistoryRow{}
+	for rows.Next() {
+		var i ListAccountHistoryRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Amount,
+			&i.CreatedAt,
+			&i.JournalID,
+			&i.JournalKind,
+			&i.TransferID,
+			&i.CounterpartyAccountID,
+			&i.CounterpartyOwner,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCurrencyEntryTotals = `-- name: ListCurrencyEntryTotals :many
+SELECT accounts.currency, SUM(entries.amount)::bigint AS total
+FROM entries
+JOIN accounts ON accounts.id = entries.account_id
+GROUP BY accounts.currency
+ORDER BY accounts.currency
+`
+
+type ListCurrencyEntryTotalsRow struct {
+	Currency string `json:"currency"`
+	Total    int64  `json:"total"`
+}
+
+// Every transfer debits and credits the same amount, the entries of a currency net to zero
+func (q *Queries) ListCurrencyEntryTotals(ctx context.Context) ([]ListCurrencyEntryTotalsRow, error) {
+	rows, err := q.db.Query(ctx, listCurrencyEntryTotals)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListCurrencyEntryTotalsRow{}
+	for rows.Next() {
+		var i ListCurrencyEntryTotalsRow
+		if err := rows.Scan(
+			&i.Currency,
+			&i.Total,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listEntries = `-- name: ListEntries :many
-SELECT id, account_id, amount, created_at FROM entries
+SELECT id, account_id, amount, created_at, transfer_id, journal_id FROM entries
 WHERE account_id = $1
 ORDER BY id
 LIMIT $2
@@ -80,6 +282,8 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 			&i.AccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.TransferID,
+			&i.JournalID,
 		); err != nil {
 			return nil, err
 		}
@@ -90,3 +294,16 @@ func (q *Queries) ListEntries(ctx context.Context, arg ListEntriesParams) ([]Ent
 	}
 	return items, nil
 }
+
+const sumAccountEntries = `-- name: SumAccountEntries :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS amount
+FROM entries
+WHERE account_id = $1
+`
+
+func (q *Queries) SumAccountEntries(ctx context.Context, accountID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, sumAccountEntries, accountID)
+	var amount int64
+	err := row.Scan(&amount)
+	return amount, err
+}
@@ -6,6 +6,8 @@ package db
 
 import (
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Account struct {
@@ -15,6 +17,88 @@ type Account struct {
 	Currency string `json:"currency"`
 	// timestamptz: to get timezone included
 	CreatedAt time.Time `json:"created_at"`
+	// decides interest rate and fee schedules of the account
+	Product string `json:"product"`
+	// active, frozen or closed, money only moves in and out of active accounts
+	Status string `json:"status"`
+	// when the account was frozen, empty unless frozen
+	FrozenAt pgtype.Timestamptz `json:"frozen_at"`
+	// when the account was closed, closed accounts stay closed
+	ClosedAt pgtype.Timestamptz `json:"closed_at"`
+}
+
+type AccountProduct struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// how far below zero the available balance may go
+	OverdraftLimit int64 `json:"overdraft_limit"`
+	// largest outgoing transfer, 0 for no limit
+	MaxWithdrawal int64 `json:"max_withdrawal"`
+	// outgoing transfers per calendar month, 0 for no limit
+	MonthlyWithdrawals int32 `json:"monthly_withdrawals"`
+	// annual interest rate in basis points, 150 is 1.50%
+	InterestRateBps int32 `json:"interest_rate_bps"`
+	// whether an owner can have several accounts of the product in a currency
+	MultiplePerCurrency bool      `json:"multiple_per_currency"`
+	UpdatedAt           time.Time `json:"updated_at"`
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+type AccountProductRate struct {
+	Product string `json:"product"`
+	// annual interest rate in basis points, 150 is 1.50%
+	AnnualRateBps int32 `json:"annual_rate_bps"`
+	// the rate applies from then on until the next rate of the product
+	ValidFrom time.Time `json:"valid_from"`
+}
+
+type AccrualPosting struct {
+	ID            int64       `json:"id"`
+	AccountID     int64       `json:"account_id"`
+	Kind          string      `json:"kind"`
+	FeeScheduleID pgtype.Int8 `json:"fee_schedule_id"`
+	// first day of the posted month
+	PeriodStart pgtype.Date `json:"period_start"`
+	Amount      int64       `json:"amount"`
+	// empty when the amount rounds to zero
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	// start of the key, shown to tell keys apart
+	Prefix string `json:"prefix"`
+	// sha256 of the key, the key itself is only shown once when created
+	KeyHash string `json:"key_hash"`
+	// routes the key may call, e.g. read:accounts or write:transfers
+	Scopes    []string  `json:"scopes"`
+	ExpiredAt time.Time `json:"expired_at"`
+	// the key is refused from then on
+	RevokedAt pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// user making the change, anonymous for unauthenticated requests and system for background jobs
+	Actor      string `json:"actor"`
+	Ip         string `json:"ip"`
+	RequestID  string `json:"request_id"`
+	Action     string `json:"action"`
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+	// state before the change, empty when created; json keeps the hashed text as is
+	Before []byte `json:"before"`
+	// state after the change
+	After []byte `json:"after"`
+	// hash of the previous event, empty for the first event
+	PrevHash string `json:"prev_hash"`
+	// sha256 of the previous hash and the event, changing or removing an event breaks the chain
+	Hash string `json:"hash"`
 }
 
 type Entry struct {
@@ -23,6 +107,182 @@ type Entry struct {
 	// can be both negative and positive, depending on withdraw or deposit of money
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// transfer booking the entry, every transfer has one debit and one credit entry
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	JournalID  int64       `json:"journal_id"`
+}
+
+type FeeSchedule struct {
+	ID      int64  `json:"id"`
+	Product string `json:"product"`
+	Name    string `json:"name"`
+	// monthly fee in the currency of the charged account
+	Amount    int64     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Hold struct {
+	ID        int64 `json:"id"`
+	AccountID int64 `json:"account_id"`
+	// reserved amount, must be positive
+	Amount         int64 `json:"amount"`
+	CapturedAmount int64 `json:"captured_amount"`
+	// active, captured, released or expired, only active holds reduce the available balance
+	Status string `json:"status"`
+	// the transfer settling a captured hold
+	TransferID pgtype.Int8 `json:"transfer_id"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type InterestAccrual struct {
+	AccountID   int64       `json:"account_id"`
+	AccrualDate pgtype.Date `json:"accrual_date"`
+	// end of day balance
+	Balance       int64 `json:"balance"`
+	AnnualRateBps int32 `json:"annual_rate_bps"`
+	// accrued interest in millionths of the currency minor unit
+	AmountMicros int64     `json:"amount_micros"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type Job struct {
+	ID int64 `json:"id"`
+	// task of the job, workers only claim kinds they have handlers for
+	Kind    string `json:"kind"`
+	Payload []byte `json:"payload"`
+	// due jobs of higher priority run first
+	Priority int32 `json:"priority"`
+	// pending until run, dead once out of attempts
+	Status string `json:"status"`
+	// at most one pending job of a kind has the key, null for jobs that are not unique
+	UniqueKey   pgtype.Text `json:"unique_key"`
+	Attempts    int32       `json:"attempts"`
+	MaxAttempts int32       `json:"max_attempts"`
+	// delayed jobs run once due, failed jobs are retried with exponential backoff
+	RunAt      time.Time          `json:"run_at"`
+	LastError  string             `json:"last_error"`
+	FinishedAt pgtype.Timestamptz `json:"finished_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type Journal struct {
+	ID int64 `json:"id"`
+	// transfer, scheduled_transfer, reversal, hold_capture, account_sweep, accrual or legacy
+	Kind      string    `json:"kind"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type LoginThrottle struct {
+	// username or client_ip
+	Scope string `json:"scope"`
+	Key   string `json:"key"`
+	// consecutive failed logins, counting again from one after a failure free window
+	Failures     int32     `json:"failures"`
+	LastFailedAt time.Time `json:"last_failed_at"`
+	// logins of the key are refused until then
+	LockedUntil pgtype.Timestamptz `json:"locked_until"`
+}
+
+type Outbox struct {
+	ID            int64  `json:"id"`
+	AggregateType string `json:"aggregate_type"`
+	// events of an aggregate are published in order
+	AggregateID string `json:"aggregate_id"`
+	EventType   string `json:"event_type"`
+	// version of the payload schema of the event type
+	EventVersion int32     `json:"event_version"`
+	Payload      []byte    `json:"payload"`
+	CreatedAt    time.Time `json:"created_at"`
+	Attempts     int32     `json:"attempts"`
+	// failed publishing is retried with exponential backoff
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	// empty until published
+	PublishedAt pgtype.Timestamptz `json:"published_at"`
+}
+
+type PasswordReset struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// address the token is sent to, the token is invalid once the user changes email
+	Email string `json:"email"`
+	// sha256 of the reset token, null until the token is sent, the token itself is only in the email
+	TokenHash pgtype.Text        `json:"token_hash"`
+	IsUsed    bool               `json:"is_used"`
+	SentAt    pgtype.Timestamptz `json:"sent_at"`
+	CreatedAt time.Time          `json:"created_at"`
+	ExpiredAt time.Time          `json:"expired_at"`
+}
+
+type PasswordResetRequest struct {
+	ID        int64     `json:"id"`
+	Email     string    `json:"email"`
+	ClientIp  string    `json:"client_ip"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RateLimitBucket struct {
+	// client of the bucket, e.g. ip:10.0.0.1 or user:alice, with the route of route limits
+	Key string `json:"key"`
+	// requests left at updated_at, refilled at the rate of the limit
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// the bucket is full again from then on, and can be deleted
+	FullAt time.Time `json:"full_at"`
+}
+
+type RecoveryCode struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// sha256 of the recovery code, the code itself is only shown once when TOTP is enabled
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type ScheduledTransfer struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// cron expression or RRULE, empty for a single transfer at start_at
+	Recurrence string    `json:"recurrence"`
+	Status     string    `json:"status"`
+	StartAt    time.Time `json:"start_at"`
+	// the occurrence to execute next
+	NextOccurrenceAt time.Time `json:"next_occurrence_at"`
+	// when the next occurrence is due, later than next_occurrence_at when retrying
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	// failed attempts of the next occurrence
+	Attempts  int32     `json:"attempts"`
+	UpdatedAt time.Time `json:"updated_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ScheduledTransferRun struct {
+	ID                  int64       `json:"id"`
+	ScheduledTransferID int64       `json:"scheduled_transfer_id"`
+	OccurrenceAt        time.Time   `json:"occurrence_at"`
+	Attempt             int32       `json:"attempt"`
+	Status              string      `json:"status"`
+	TransferID          pgtype.Int8 `json:"transfer_id"`
+	Error               string      `json:"error"`
+	CreatedAt           time.Time   `json:"created_at"`
+}
+
+type Session struct {
+	// id of the payload of the refresh token
+	ID           string `json:"id"`
+	Username     string `json:"username"`
+	RefreshToken string `json:"refresh_token"`
+	UserAgent    string `json:"user_agent"`
+	ClientIp     string `json:"client_ip"`
+	// a blocked session can not renew access tokens, e.g. once the password changes
+	IsBlocked bool      `json:"is_blocked"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type Transfer struct {
@@ -32,6 +292,30 @@ type Transfer struct {
 	// must be a positive amount
 	Amount    int64     `json:"amount"`
 	CreatedAt time.Time `json:"created_at"`
+	// the original transfer when this transfer is a reversal (refund)
+	ReversalOf     pgtype.Int8 `json:"reversal_of"`
+	ReversalReason string      `json:"reversal_reason"`
+	JournalID      int64       `json:"journal_id"`
+}
+
+type TransferLimit struct {
+	ID int64 `json:"id"`
+	// limits of the accounts of the product, empty for a user override
+	Product pgtype.Text `json:"product"`
+	// user override replacing the limits of the products of the user, empty for product limits
+	Owner pgtype.Text `json:"owner"`
+	// largest outgoing transfer, 0 for no limit
+	MaxAmount int64 `json:"max_amount"`
+	// outgoing amount of an account per UTC day, 0 for no limit
+	AccountDaily int64 `json:"account_daily"`
+	// outgoing amount of an account per UTC calendar month, 0 for no limit
+	AccountMonthly int64 `json:"account_monthly"`
+	// outgoing amount of the accounts of a user in a currency per UTC day, 0 for no limit
+	UserDaily int64 `json:"user_daily"`
+	// outgoing amount of the accounts of a user in a currency per UTC calendar month, 0 for no limit
+	UserMonthly int64     `json:"user_monthly"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type User struct {
@@ -41,4 +325,61 @@ type User struct {
 	Email             string    `json:"email"`
 	PasswordChangedAt time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time `json:"created_at"`
+	IsEmailVerified   bool      `json:"is_email_verified"`
+	// customer, support or admin, decides what the user may do with resources of other users
+	Role string `json:"role"`
+	// access tokens issued before a change of the password or the role are refused
+	RoleChangedAt time.Time `json:"role_changed_at"`
+}
+
+type UserTotp struct {
+	Username string `json:"username"`
+	// TOTP secret encrypted with the key from config, authenticated with the username
+	EncryptedSecret []byte `json:"encrypted_secret"`
+	// false until confirmed with a first code, login and transfers above the threshold then require a code
+	IsEnabled bool `json:"is_enabled"`
+	// time step of the last accepted code, codes of earlier or the same steps are rejected
+	LastUsedStep int64              `json:"last_used_step"`
+	CreatedAt    time.Time          `json:"created_at"`
+	EnabledAt    pgtype.Timestamptz `json:"enabled_at"`
+}
+
+type VerifyEmail struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// address verified, the code is invalid once the user changes email
+	Email      string `json:"email"`
+	SecretCode string `json:"secret_code"`
+	IsUsed     bool   `json:"is_used"`
+	// null until the verification email is sent
+	SentAt    pgtype.Timestamptz `json:"sent_at"`
+	CreatedAt time.Time          `json:"created_at"`
+	ExpiredAt time.Time          `json:"expired_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64 `json:"id"`
+	SubscriptionID int64 `json:"subscription_id"`
+	EventID        int64 `json:"event_id"`
+	// pending until delivered, dead once out of attempts
+	Status   string `json:"status"`
+	Attempts int32  `json:"attempts"`
+	// failed deliveries are retried with exponential backoff
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	LastError     string    `json:"last_error"`
+	// HTTP status of the last attempt, 0 when there was no response
+	ResponseStatus int32              `json:"response_status"`
+	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
+	CreatedAt      time.Time          `json:"created_at"`
+}
+
+type WebhookSubscription struct {
+	ID    int64  `json:"id"`
+	Owner string `json:"owner"`
+	Url   string `json:"url"`
+	// event types delivered, empty for all
+	EventTypes []string `json:"event_types"`
+	// key of the HMAC-SHA256 signature of deliveries
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"created_at"`
 }
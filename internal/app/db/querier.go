@@ -6,25 +6,189 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
 	AddAccountBalance(ctx context.Context, arg AddAccountBalanceParams) (Account, error)
+	AdvanceScheduledTransfer(ctx context.Context, arg AdvanceScheduledTransferParams) (ScheduledTransfer, error)
+	BlockUserSessions(ctx context.Context, username string) (int64, error)
+	CancelAccountScheduledTransfers(ctx context.Context, accountID int64) (int64, error)
+	// Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+	ClaimDueScheduledTransfer(ctx context.Context) (ScheduledTransfer, error)
+	// Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+	ClaimJob(ctx context.Context, kinds []string) (Job, error)
+	// Claims the oldest pending event that is due, later events of its aggregate wait until it is published.
+	// Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+	ClaimOutboxEvent(ctx context.Context) (Outbox, error)
+	// Replicas claim different rows, a claimed row stays locked until the claiming transaction ends
+	ClaimWebhookDelivery(ctx context.Context) (ClaimWebhookDeliveryRow, error)
+	CountLoginFailure(ctx context.Context, arg CountLoginFailureParams) (LoginThrottle, error)
+	CountOwnerAccounts(ctx context.Context, arg CountOwnerAccountsParams) (int64, error)
+	CountPasswordResetRequestsByClientIP(ctx context.Context, arg CountPasswordResetRequestsByClientIPParams) (int64, error)
+	CountPasswordResetRequestsByEmail(ctx context.Context, arg CountPasswordResetRequestsByEmailParams) (int64, error)
+	CountPendingOutboxEvents(ctx context.Context) (int64, error)
+	CountUnlinkedEntries(ctx context.Context) (int64, error)
+	CountUserAccounts(ctx context.Context, owner string) (int64, error)
+	// Reversals are not withdrawals of the account
+	CountWithdrawalsSince(ctx context.Context, arg CountWithdrawalsSinceParams) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (Account, error)
+	CreateAccountProduct(ctx context.Context, arg CreateAccountProductParams) (AccountProduct, error)
+	CreateAccrualPosting(ctx context.Context, arg CreateAccrualPostingParams) (AccrualPosting, error)
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (AuditEvent, error)
 	CreateEntry(ctx context.Context, arg CreateEntryParams) (Entry, error)
+	CreateFeeSchedule(ctx context.Context, arg CreateFeeScheduleParams) (FeeSchedule, error)
+	CreateHold(ctx context.Context, arg CreateHoldParams) (Hold, error)
+	CreateInterestAccrual(ctx context.Context, arg CreateInterestAccrualParams) (InterestAccrual, error)
+	// Opens the internal account of a bank owner in a currency unless it exists, waiting for concurrent openings
+	CreateInternalAccount(ctx context.Context, arg CreateInternalAccountParams) error
+	// A unique job is not created while a pending job of the kind has the key, no row is returned then
+	CreateJob(ctx context.Context, arg CreateJobParams) (Job, error)
+	CreateJournal(ctx context.Context, kind string) (Journal, error)
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (Outbox, error)
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
+	CreatePasswordResetRequest(ctx context.Context, arg CreatePasswordResetRequestParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	CreateReversal(ctx context.Context, arg CreateReversalParams) (Transfer, error)
+	CreateScheduledTransfer(ctx context.Context, arg CreateScheduledTransferParams) (ScheduledTransfer, error)
+	CreateScheduledTransferRun(ctx context.Context, arg CreateScheduledTransferRunParams) (ScheduledTransferRun, error)
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
-	DeleteAccount(ctx context.Context, id int64) error
+	CreateUserTOTP(ctx context.Context, arg CreateUserTOTPParams) (UserTotp, error)
+	CreateVerifyEmail(ctx context.Context, arg CreateVerifyEmailParams) (VerifyEmail, error)
+	// Fans an outbox event out to the matching subscriptions of the owners
+	CreateWebhookDeliveries(ctx context.Context, arg CreateWebhookDeliveriesParams) error
+	CreateWebhookSubscription(ctx context.Context, arg CreateWebhookSubscriptionParams) (WebhookSubscription, error)
+	DeleteFullRateLimitBuckets(ctx context.Context) (int64, error)
+	DeleteLoginThrottle(ctx context.Context, arg DeleteLoginThrottleParams) (LoginThrottle, error)
+	DeleteUserRecoveryCodes(ctx context.Context, username string) error
+	DeleteUserTOTP(ctx context.Context, username string) error
+	DeleteUserTransferLimit(ctx context.Context, owner string) error
+	DeleteWebhookSubscription(ctx context.Context, id int64) error
+	EnableUserTOTP(ctx context.Context, arg EnableUserTOTPParams) (UserTotp, error)
+	ExpireHolds(ctx context.Context) (int64, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error)
+	GetAPIKeyForUpdate(ctx context.Context, id int64) (ApiKey, error)
 	GetAccount(ctx context.Context, id int64) (Account, error)
+	// Balance of an account with its latest entry, read together so the balance includes the entry
+	GetAccountBalanceEvent(ctx context.Context, id int64) (GetAccountBalanceEventRow, error)
 	GetAccountForUpdate(ctx context.Context, id int64) (Account, error)
+	GetAccountProduct(ctx context.Context, name string) (AccountProduct, error)
+	GetEntriesAmountSince(ctx context.Context, arg GetEntriesAmountSinceParams) (int64, error)
 	GetEntry(ctx context.Context, id int64) (Entry, error)
+	GetHeldAmount(ctx context.Context, accountID int64) (int64, error)
+	GetHold(ctx context.Context, id int64) (Hold, error)
+	GetHoldForUpdate(ctx context.Context, id int64) (Hold, error)
+	GetJob(ctx context.Context, id int64) (Job, error)
+	GetJournal(ctx context.Context, id int64) (Journal, error)
+	GetLastAuditEvent(ctx context.Context) (AuditEvent, error)
+	GetLastInterestAccrual(ctx context.Context, accountID int64) (InterestAccrual, error)
+	// Outgoing amount of transfers, scheduled transfers and hold captures, fees and reversals do not count
+	GetOutgoingAmountSince(ctx context.Context, arg GetOutgoingAmountSinceParams) (int64, error)
+	GetOwnerAccount(ctx context.Context, arg GetOwnerAccountParams) (Account, error)
+	// Outgoing amount of all accounts of the owner in the currency, counted like GetOutgoingAmountSince
+	GetOwnerOutgoingAmountSince(ctx context.Context, arg GetOwnerOutgoingAmountSinceParams) (int64, error)
+	GetPasswordResetByTokenForUpdate(ctx context.Context, tokenHash pgtype.Text) (PasswordReset, error)
+	GetPasswordResetToSend(ctx context.Context, id int64) (GetPasswordResetToSendRow, error)
+	GetReversedAmount(ctx context.Context, transferID int64) (int64, error)
+	GetScheduledTransfer(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetScheduledTransferForUpdate(ctx context.Context, id int64) (ScheduledTransfer, error)
+	GetSession(ctx context.Context, id string) (Session, error)
 	GetTransfer(ctx context.Context, id int64) (Transfer, error)
+	GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error)
+	// A user override takes precedence over the limits of the product
+	GetTransferLimit(ctx context.Context, arg GetTransferLimitParams) (TransferLimit, error)
 	GetUser(ctx context.Context, username string) (User, error)
+	// Access tokens issued before the last change of the password or the role of the user are refused
+	GetUserAuthChangedAt(ctx context.Context, username string) (time.Time, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserForUpdate(ctx context.Context, username string) (User, error)
+	GetUserTOTP(ctx context.Context, username string) (UserTotp, error)
+	GetUserTOTPForUpdate(ctx context.Context, username string) (UserTotp, error)
+	GetVerifyEmailForUpdate(ctx context.Context, id int64) (VerifyEmail, error)
+	GetVerifyEmailToSend(ctx context.Context, id int64) (GetVerifyEmailToSendRow, error)
+	GetWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	GetWebhookSubscription(ctx context.Context, id int64) (WebhookSubscription, error)
+	InvalidateUserPasswordResets(ctx context.Context, username string) (int64, error)
+	ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error)
+	// Keyset pagination over all accounts with the balance their entries add up to
+	ListAccountBalancesAfter(ctx context.Context, arg ListAccountBalancesAfterParams) ([]ListAccountBalancesAfterRow, error)
+	// Entries of the account with the journal and the other account of the transfer booking them
+	ListAccountHistory(ctx context.Context, arg ListAccountHistoryParams) ([]ListAccountHistoryRow, error)
+	ListAccountProductRates(ctx context.Context, product string) ([]AccountProductRate, error)
+	ListAccountProducts(ctx context.Context) ([]AccountProduct, error)
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]Account, error)
+	// Keyset pagination over all accounts, for batch jobs
+	ListAccountsAfter(ctx context.Context, arg ListAccountsAfterParams) ([]Account, error)
+	ListAccrualPostings(ctx context.Context, arg ListAccrualPostingsParams) ([]AccrualPosting, error)
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]AuditEvent, error)
+	// Keyset pagination over the chain in order
+	ListAuditEventsAfter(ctx context.Context, arg ListAuditEventsAfterParams) ([]AuditEvent, error)
+	// Every transfer debits and credits the same amount, the entries of a currency net to zero
+	ListCurrencyEntryTotals(ctx context.Context) ([]ListCurrencyEntryTotalsRow, error)
 	ListEntries(ctx context.Context, arg ListEntriesParams) ([]Entry, error)
+	ListFeeAccrualPostings(ctx context.Context, accountID int64) ([]AccrualPosting, error)
+	ListFeeSchedules(ctx context.Context, product string) ([]FeeSchedule, error)
+	ListHolds(ctx context.Context, arg ListHoldsParams) ([]Hold, error)
+	ListJobs(ctx context.Context, arg ListJobsParams) ([]Job, error)
+	// Throttles with the most recent failures first, only those locked out now when locked is true
+	ListLoginThrottles(ctx context.Context, arg ListLoginThrottlesParams) ([]LoginThrottle, error)
+	ListLoginThrottlesByKeys(ctx context.Context, arg ListLoginThrottlesByKeysParams) ([]LoginThrottle, error)
+	ListScheduledTransferRuns(ctx context.Context, arg ListScheduledTransferRunsParams) ([]ScheduledTransferRun, error)
+	ListScheduledTransfers(ctx context.Context, arg ListScheduledTransfersParams) ([]ScheduledTransfer, error)
+	// Keyset pagination over all transfers with the entries booking them
+	ListTransferEntryCountsAfter(ctx context.Context, arg ListTransferEntryCountsAfterParams) ([]ListTransferEntryCountsAfterRow, error)
+	ListTransferLimits(ctx context.Context) ([]TransferLimit, error)
 	ListTransfers(ctx context.Context, arg ListTransfersParams) ([]Transfer, error)
+	// Months with accrued interest before the given date that have not been posted
+	ListUnpostedInterestPeriods(ctx context.Context, arg ListUnpostedInterestPeriodsParams) ([]pgtype.Date, error)
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]WebhookDelivery, error)
+	ListWebhookSubscriptions(ctx context.Context, arg ListWebhookSubscriptionsParams) ([]WebhookSubscription, error)
+	// Serializes appends to the hash chain until the transaction ends
+	LockAuditChain(ctx context.Context) error
+	LockLoginThrottle(ctx context.Context, arg LockLoginThrottleParams) (LoginThrottle, error)
+	// Locks the bucket of the key, created full when missing
+	LockRateLimitBucket(ctx context.Context, arg LockRateLimitBucketParams) (RateLimitBucket, error)
+	MarkAPIKeyRevoked(ctx context.Context, id int64) (ApiKey, error)
+	MarkAccountActive(ctx context.Context, id int64) (Account, error)
+	MarkAccountClosed(ctx context.Context, id int64) (Account, error)
+	MarkAccountFrozen(ctx context.Context, id int64) (Account, error)
+	MarkHoldCaptured(ctx context.Context, arg MarkHoldCapturedParams) (Hold, error)
+	MarkHoldReleased(ctx context.Context, id int64) (Hold, error)
+	MarkJobSucceeded(ctx context.Context, id int64) (Job, error)
+	MarkOutboxEventPublished(ctx context.Context, id int64) (Outbox, error)
+	MarkVerifyEmailSent(ctx context.Context, id int64) (VerifyEmail, error)
+	MarkVerifyEmailUsed(ctx context.Context, id int64) (VerifyEmail, error)
+	MarkWebhookDeliverySucceeded(ctx context.Context, arg MarkWebhookDeliverySucceededParams) (WebhookDelivery, error)
+	// Replaces the hash of the password of a user by another hash of the same password, unless the password changed meanwhile
+	RehashUserPassword(ctx context.Context, arg RehashUserPasswordParams) (int64, error)
+	// Delivers again from the first attempt, e.g. a dead delivery once the receiver is fixed
+	ReplayWebhookDelivery(ctx context.Context, id int64) (WebhookDelivery, error)
+	// Runs a dead job again from the first attempt, e.g. once the failure is fixed
+	RequeueJob(ctx context.Context, id int64) (Job, error)
+	ResetLoginThrottle(ctx context.Context, username string) error
+	// Records a failed attempt, the job runs again at run_at unless dead
+	RetryJob(ctx context.Context, arg RetryJobParams) (Job, error)
+	RetryOutboxEvent(ctx context.Context, arg RetryOutboxEventParams) (Outbox, error)
+	RetryScheduledTransfer(ctx context.Context, arg RetryScheduledTransferParams) (ScheduledTransfer, error)
+	// Records a failed attempt, the delivery is retried at next_attempt_at unless dead
+	RetryWebhookDelivery(ctx context.Context, arg RetryWebhookDeliveryParams) (WebhookDelivery, error)
+	SetPasswordResetToken(ctx context.Context, arg SetPasswordResetTokenParams) (PasswordReset, error)
+	SetProductTransferLimit(ctx context.Context, arg SetProductTransferLimitParams) (TransferLimit, error)
+	SetUserTransferLimit(ctx context.Context, arg SetUserTransferLimitParams) (TransferLimit, error)
+	SumAccountEntries(ctx context.Context, accountID int64) (int64, error)
+	SumInterestAccruals(ctx context.Context, arg SumInterestAccrualsParams) (int64, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
+	UpdateAccountProduct(ctx context.Context, arg UpdateAccountProductParams) (AccountProduct, error)
+	UpdateRateLimitBucket(ctx context.Context, arg UpdateRateLimitBucketParams) error
+	UpdateScheduledTransfer(ctx context.Context, arg UpdateScheduledTransferParams) (ScheduledTransfer, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseUserTOTPStep(ctx context.Context, arg UseUserTOTPStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -7,26 +7,99 @@ package db
 
 import (
 	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countWithdrawalsSince = `-- name: CountWithdrawalsSince :one
+SELECT COUNT(*) FROM transfers
+WHERE from_account_id = $1 AND created_at >= $2 AND reversal_of IS NULL
+`
+
+type CountWithdrawalsSinceParams struct {
+	FromAccountID int64     `json:"from_account_id"`
+	Since         time.Time `json:"since"`
+}
+
+// Reversals are not withdrawals of the account
+func (q *Queries) CountWithdrawalsSince(ctx context.Context, arg CountWithdrawalsSinceParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countWithdrawalsSince, arg.FromAccountID, arg.Since)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createReversal = `-- name: CreateReversal :one
+INSERT INTO transfers (
+  from_account_id,
+  to_account_id,
+  amount,
+  reversal_of,
+  reversal_reason,
+  journal_id
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+) RETURNING id, from_account_id, to_account_id, amount, created_at, reversal_of, reversal_reason, journal_id
+`
+
+type CreateReversalParams struct {
+	FromAccountID  int64       `json:"from_account_id"`
+	ToAccountID    int64       `json:"to_account_id"`
+	Amount         int64       `json:"amount"`
+	ReversalOf     pgtype.Int8 `json:"reversal_of"`
+	ReversalReason string      `json:"reversal_reason"`
+	JournalID      int64       `json:"journal_id"`
+}
+
+func (q *Queries) CreateReversal(ctx context.Context, arg CreateReversalParams) (Transfer, error) {
+	row := q.db.QueryRow(ctx, createReversal,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.ReversalOf,
+		arg.ReversalReason,
+		arg.JournalID,
+	)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversalOf,
+		&i.ReversalReason,
+		&i.JournalID,
+	)
+	return i, err
+}
+
 const createTransfer = `-- name: CreateTransfer :one
 INSERT INTO transfers (
   from_account_id,
   to_account_id,
-  amount
+  amount,
+  journal_id
 ) VALUES (
-  $1, $2, $3
-) RETURNING id, from_account_id, to_account_id, amount, created_at
+  $1, $2, $3, $4
+) RETURNING id, from_account_id, to_account_id, amount, created_at, reversal_of, reversal_reason, journal_id
 `
 
 type CreateTransferParams struct {
 	FromAccountID int64 `json:"from_account_id"`
 	ToAccountID   int64 `json:"to_account_id"`
 	Amount        int64 `json:"amount"`
+	JournalID     int64 `json:"journal_id"`
 }
 
 func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams) (Transfer, error) {
-	row := q.db.QueryRow(ctx, createTransfer, arg.FromAccountID, arg.ToAccountID, arg.Amount)
+	row := q.db.QueryRow(ctx, createTransfer,
+		arg.FromAccountID,
+		arg.ToAccountID,
+		arg.Amount,
+		arg.JournalID,
+	)
 	var i Transfer
 	err := row.Scan(
 		&i.ID,
@@ -34,12 +107,28 @@ func (q *Queries) CreateTransfer(ctx context.Context, arg CreateTransferParams)
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversalOf,
+		&i.ReversalReason,
+		&i.JournalID,
 	)
 	return i, err
 }
 
+const getReversedAmount = `-- name: GetReversedAmount :one
+SELECT COALESCE(SUM(amount), 0)::bigint AS reversed_amount
+FROM transfers
+WHERE reversal_of = $1::bigint
+`
+
+func (q *Queries) GetReversedAmount(ctx context.Context, transferID int64) (int64, error) {
+	row := q.db.QueryRow(ctx, getReversedAmount, transferID)
+	var reversed_amount int64
+	err := row.Scan(&reversed_amount)
+	return reversed_amount, err
+}
+
 const getTransfer = `-- name: GetTransfer :one
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, reversal_of, reversal_reason, journal_id FROM transfers
 WHERE id = $1 LIMIT 1
 `
 
@@ -52,12 +141,102 @@ func (q *Queries) GetTransfer(ctx context.Context, id int64) (Transfer, error) {
 		&i.ToAccountID,
 		&i.Amount,
 		&i.CreatedAt,
+		&i.ReversalOf,
+		&i.ReversalReason,
+		&i.JournalID,
+	)
+	return i, err
+}
+
+const getTransferForUpdate = `-- name: GetTransferForUpdate :one
+SELECT id, from_account_id, to_account_id, amount, created_at, reversal_of, reversal_reason, journal_id FROM transfers
+WHERE id = $1 LIMIT 1
+FOR NO KEY UPDATE
+`
+
+func (q *Queries) GetTransferForUpdate(ctx context.Context, id int64) (Transfer, error) {
+	row := q.db.QueryRow(ctx, getTransferForUpdate, id)
+	var i Transfer
+	err := row.Scan(
+		&i.ID,
+		&i.FromAccountID,
+		&i.ToAccountID,
+		&i.Amount,
+		&i.CreatedAt,
+		&i.ReversalOf,
+		&i.ReversalReason,
+		&i.JournalID,
 	)
 	return i, err
 }
 
+const listTransferEntryCountsAfter = `-- name: ListTransferEntryCountsAfter :many
+SELECT
+  transfers.id,
+  transfers.from_account_id,
+  transfers.to_account_id,
+  transfers.amount,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.from_account_id AND entries.amount = -transfers.amount
+  ) AS debits,
+  COUNT(entries.id) FILTER (
+    WHERE entries.account_id = transfers.to_account_id AND entries.amount = transfers.amount
+  ) AS credits,
+  COUNT(entries.id) AS entries
+FROM transfers
+LEFT JOIN entries ON entries.transfer_id = transfers.id
+WHERE transfers.id > $1
+GROUP BY transfers.id
+ORDER BY transfers.id
+LIMIT $2
+`
+
+type ListTransferEntryCountsAfterParams struct {
+	ID    int64 `json:"id"`
+	Limit int32 `json:"limit"`
+}
+
+type ListTransferEntryCountsAfterRow struct {
+	ID            int64 `json:"id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	Debits        int64 `json:"debits"`
+	Credits       int64 `json:"credits"`
+	Entries       int64 `json:"entries"`
+}
+
+// Keyset pagination over all transfers with the entries booking them
+func (q *Queries) ListTransferEntryCountsAfter(ctx context.Context, arg ListTransferEntryCountsAfterParams) ([]ListTransferEntryCountsAfterRow, error) {
+	rows, err := q.db.Query(ctx, listTransferEntryCountsAfter, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTransferEntryCountsAfterRow{}
+	for rows.Next() {
+		var i ListTransferEntryCountsAfterRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.Amount,
+			&i.Debits,
+			&i.Credits,
+			&i.Entries,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransfers = `-- name: ListTransfers :many
-SELECT id, from_account_id, to_account_id, amount, created_at FROM transfers
+SELECT id, from_account_id, to_account_id, amount, created_at, reversal_of, reversal_reason, journal_id FROM transfers
 WHERE 
     from_account_id = $1 OR
     to_account_id = $2
@@ -93,6 +272,9 @@ func (q *Queries) ListTransfers(ctx context.Context, arg ListTransfersParams) ([
 			&i.ToAccountID,
 			&i.Amount,
 			&i.CreatedAt,
+			&i.ReversalOf,
+			&i.ReversalReason,
+			&i.JournalID,
 		); err != nil {
 			return nil, err
 		}
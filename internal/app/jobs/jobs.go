@@ -0,0 +1,177 @@
+// Package jobs runs background work, e.g. sending email, from a queue of jobs in Postgres. Jobs are enqueued
+// with the querier of a transaction so they only run once the business data they belong to commits, and
+// workers of several replicas claim due jobs side by side without running a job twice.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DefaultMaxAttempts is the number of attempts of a job of a task without MaxAttempts
+const DefaultMaxAttempts = 5
+
+// ErrDuplicateJob is returned when a unique job is enqueued while a pending job of its kind has the same key
+var ErrDuplicateJob = errors.New("a pending job of the kind has the unique key")
+
+// Task is a kind of job with a payload of type T, marshaled to JSON
+type Task[T any] struct {
+	// Kind names the task, workers run jobs of the kinds registered with them
+	Kind string
+	// Attempts of a job before it is dead, DefaultMaxAttempts when zero
+	MaxAttempts int32
+	// Due jobs of higher priority run first
+	Priority int32
+}
+
+// EnqueueOptions changes how a job is enqueued, the zero value runs the job at once
+type EnqueueOptions struct {
+	// The job runs once due, at once when zero
+	RunAt time.Time
+	// Overrides the priority of the task when not zero
+	Priority int32
+	// At most one pending job of the task has the key, the job is not unique when empty
+	UniqueKey string
+}
+
+// Enqueue creates a job of the task with q, e.g. the querier of a transaction so the job commits together
+// with the business data it belongs to. Returns ErrDuplicateJob when the job is unique and a pending job
+// of the task has the key.
+func (t Task[T]) Enqueue(ctx context.Context, q db.Querier, payload T, opts EnqueueOptions) (db.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return db.Job{}, fmt.Errorf("enqueue %s: %w", t.Kind, err)
+	}
+
+	arg := db.CreateJobParams{
+		Kind:        t.Kind,
+		Payload:     data,
+		Priority:    t.Priority,
+		UniqueKey:   pgtype.Text{String: opts.UniqueKey, Valid: opts.UniqueKey != ""},
+		MaxAttempts: t.MaxAttempts,
+		RunAt:       pgtype.Timestamptz{Time: opts.RunAt, Valid: !opts.RunAt.IsZero()},
+	}
+
+	if opts.Priority != 0 {
+		arg.Priority = opts.Priority
+	}
+
+	if arg.MaxAttempts == 0 {
+		arg.MaxAttempts = DefaultMaxAttempts
+	}
+
+	job, err := q.CreateJob(ctx, arg)
+	if errors.Is(err, db.ErrRecordNotFound) {
+		return job, ErrDuplicateJob
+	}
+
+	return job, err
+}
+
+// handler runs a job with the payload as stored
+type handler func(ctx context.Context, q db.Querier, payload []byte) error
+
+// Registry holds the handlers of the tasks a worker runs
+type Registry struct {
+	handlers map[string]handler
+}
+
+// NewRegistry creates a registry without tasks.
+func NewRegistry() *Registry {
+	return &Registry{handlers: make(map[string]handler)}
+}
+
+// Register registers the handler of the jobs of a task. The handler runs with the querier of the transaction
+// of the job, its database work commits when it succeeds and is rolled back when it fails. Register panics
+// when the kind of the task is already registered.
+func Register[T any](r *Registry, task Task[T], handle func(ctx context.Context, q db.Querier, payload T) error) {
+	if _, ok := r.handlers[task.Kind]; ok {
+		panic(fmt.Sprintf("jobs: task %q registered twice", task.Kind))
+	}
+
+	r.handlers[task.Kind] = func(ctx context.Context, q db.Querier, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("payload: %w", err)
+		}
+
+		return handle(ctx, q, payload)
+	}
+}
+
+// Kinds returns the registered kinds in order.
+func (r *Registry) Kinds() []string {
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	sort.Strings(kinds)
+	return kinds
+}
+
+// run runs a job with the handler of its kind, a panicking handler fails the job.
+func (r *Registry) run(ctx context.Context, q db.Querier, job db.Job) (err error) {
+	handle, ok := r.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler of task %q", job.Kind)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+
+	return handle(ctx, q, job.Payload)
+}
+
+// RunResult counts the jobs run by a run
+type RunResult struct {
+	Succeeded int `json:"succeeded"`
+	// Failed jobs are retried later
+	Failed int `json:"failed"`
+	// Dead jobs are out of attempts
+	Dead int `json:"dead"`
+}
+
+// Run runs due jobs of the registered tasks until none is due or ctx is done, failed jobs are retried with
+// exponential backoff starting at retryDelay. Several workers can run side by side, each job is claimed by
+// one of them.
+func Run(ctx context.Context, b bank.Bank, registry *Registry, retryDelay time.Duration) (RunResult, error) {
+	var result RunResult
+
+	kinds := registry.Kinds()
+	if len(kinds) == 0 {
+		return result, nil
+	}
+
+	for ctx.Err() == nil {
+		job, err := b.RunJob(ctx, kinds, retryDelay, registry.run)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		switch job.Status {
+		case bank.JobSucceeded:
+			result.Succeeded++
+		case bank.JobDead:
+			result.Dead++
+		default:
+			result.Failed++
+		}
+	}
+
+	return result, ctx.Err()
+}
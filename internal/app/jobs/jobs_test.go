@@ -0,0 +1,186 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+var greetTask = Task[greeting]{Kind: "greet", Priority: 5}
+
+func TestEnqueue(t *testing.T) {
+	runAt := time.Now().Add(time.Hour)
+
+	testCases := []struct {
+		name      string
+		opts      EnqueueOptions
+		expected  db.CreateJobParams
+		createErr error
+		expectErr error
+	}{
+		{
+			name: "Defaults",
+			expected: db.CreateJobParams{
+				Kind:        greetTask.Kind,
+				Payload:     []byte(`{"name":"alice"}`),
+				Priority:    greetTask.Priority,
+				MaxAttempts: DefaultMaxAttempts,
+			},
+		},
+		{
+			name: "Options",
+			opts: EnqueueOptions{RunAt: runAt, Priority: 9, UniqueKey: "alice"},
+			expected: db.CreateJobParams{
+				Kind:        greetTask.Kind,
+				Payload:     []byte(`{"name":"alice"}`),
+				Priority:    9,
+				UniqueKey:   pgtype.Text{String: "alice", Valid: true},
+				MaxAttempts: DefaultMaxAttempts,
+				RunAt:       pgtype.Timestamptz{Time: runAt, Valid: true},
+			},
+		},
+		{
+			name: "Duplicate",
+			opts: EnqueueOptions{UniqueKey: "alice"},
+			expected: db.CreateJobParams{
+				Kind:        greetTask.Kind,
+				Payload:     []byte(`{"name":"alice"}`),
+				Priority:    greetTask.Priority,
+				UniqueKey:   pgtype.Text{String: "alice", Valid: true},
+				MaxAttempts: DefaultMaxAttempts,
+			},
+			createErr: db.ErrRecordNotFound,
+			expectErr: ErrDuplicateJob,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				CreateJob(gomock.Any(), gomock.Eq(tc.expected)).
+				Times(1).
+				Return(db.Job{ID: 1, Kind: tc.expected.Kind}, tc.createErr)
+
+			job, err := greetTask.Enqueue(context.Background(), store, greeting{Name: "alice"}, tc.opts)
+			if tc.expectErr != nil {
+				require.ErrorIs(t, err, tc.expectErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, int64(1), job.ID)
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	var greeted []string
+	Register(registry, greetTask, func(ctx context.Context, q db.Querier, payload greeting) error {
+		greeted = append(greeted, payload.Name)
+		return nil
+	})
+	Register(registry, Task[int]{Kind: "panic"}, func(ctx context.Context, q db.Querier, payload int) error {
+		panic("boom")
+	})
+
+	require.Equal(t, []string{"greet", "panic"}, registry.Kinds())
+
+	require.Panics(t, func() {
+		Register(registry, greetTask, func(ctx context.Context, q db.Querier, payload greeting) error {
+			return nil
+		})
+	})
+
+	ctx := context.Background()
+
+	require.NoError(t, registry.run(ctx, nil, db.Job{Kind: "greet", Payload: []byte(`{"name":"bob"}`)}))
+	require.Equal(t, []string{"bob"}, greeted)
+
+	require.Error(t, registry.run(ctx, nil, db.Job{Kind: "greet", Payload: []byte(`[]`)}))
+	require.Error(t, registry.run(ctx, nil, db.Job{Kind: "unknown", Payload: []byte(`{}`)}))
+
+	err := registry.run(ctx, nil, db.Job{Kind: "panic", Payload: []byte(`1`)})
+	require.ErrorContains(t, err, "boom")
+}
+
+func TestRun(t *testing.T) {
+	payload := func(name string) []byte {
+		data, err := json.Marshal(greeting{Name: name})
+		require.NoError(t, err)
+		return data
+	}
+
+	records := []db.Job{
+		{ID: 1, Kind: greetTask.Kind, Payload: payload("alice"), MaxAttempts: 3},
+		{ID: 2, Kind: greetTask.Kind, Payload: payload("bob"), MaxAttempts: 3},
+		{ID: 3, Kind: greetTask.Kind, Payload: payload("bob"), Attempts: 2, MaxAttempts: 3},
+	}
+
+	registry := NewRegistry()
+	Register(registry, greetTask, func(ctx context.Context, q db.Querier, payload greeting) error {
+		if payload.Name == "bob" {
+			return errors.New("bob is away")
+		}
+		return nil
+	})
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The bank hands out each due job once, and marks it by the outcome of running it
+	next := 0
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().
+		RunJob(gomock.Any(), gomock.Eq([]string{greetTask.Kind}), gomock.Eq(time.Minute), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ []string, _ time.Duration, run func(context.Context, db.Querier, db.Job) error) (db.Job, error) {
+			if next == len(records) {
+				return db.Job{}, db.ErrRecordNotFound
+			}
+
+			job := records[next]
+			next++
+
+			job.Attempts++
+			if err := run(ctx, nil, job); err != nil {
+				job.Status = bank.JobPending
+				if job.Attempts >= job.MaxAttempts {
+					job.Status = bank.JobDead
+				}
+				return job, nil
+			}
+
+			job.Status = bank.JobSucceeded
+			return job, nil
+		}).
+		Times(len(records) + 1)
+
+	result, err := Run(context.Background(), store, registry, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, RunResult{Succeeded: 1, Failed: 1, Dead: 1}, result)
+
+	// Nothing is claimed without registered tasks
+	result, err = Run(context.Background(), store, NewRegistry(), time.Minute)
+	require.NoError(t, err)
+	require.Zero(t, result)
+}
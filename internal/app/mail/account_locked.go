@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+)
+
+// AccountLockedMessage returns the email telling a user that too many failed logins locked the account out
+// until lockedUntil.
+func AccountLockedMessage(from string, user db.User, lockedUntil time.Time) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s,\n\n", user.FullName)
+	fmt.Fprintf(&body, "There were too many failed logins to your account, so logins are refused until %s.\n\n", lockedUntil.UTC().Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&body, "If it was not you, someone may be guessing your password. Consider changing it, and enabling two-factor authentication.\n")
+
+	return Message{
+		From:    from,
+		To:      []string{user.Email},
+		Subject: "Your account is temporarily locked",
+		Body:    body.String(),
+	}
+}
+
+// AccountLockedPayload is the payload of the job telling a user about a lockout
+type AccountLockedPayload struct {
+	Username    string    `json:"username"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// AccountLockedTask tells a user about a lockout after failed logins, retried for about the lockout
+var AccountLockedTask = jobs.Task[AccountLockedPayload]{
+	Kind:        "email:account_locked",
+	MaxAttempts: 4,
+	Priority:    10,
+}
+
+// SendAccountLocked returns the handler of AccountLockedTask, sending with mailer from the sender address.
+// A lockout that is over is not told about anymore.
+func SendAccountLocked(mailer Mailer, from string) func(ctx context.Context, q db.Querier, payload AccountLockedPayload) error {
+	return func(ctx context.Context, q db.Querier, payload AccountLockedPayload) error {
+		if !time.Now().Before(payload.LockedUntil) {
+			return nil
+		}
+
+		user, err := q.GetUser(ctx, payload.Username)
+		if err != nil {
+			return err
+		}
+
+		return mailer.Send(ctx, AccountLockedMessage(from, user, payload.LockedUntil))
+	}
+}
@@ -0,0 +1,102 @@
+package mail
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomLockedUser() db.User {
+	return db.User{
+		Username: random.Owner(),
+		FullName: random.Owner(),
+		Email:    random.Email(),
+	}
+}
+
+func TestAccountLockedMessage(t *testing.T) {
+	user := randomLockedUser()
+	lockedUntil := time.Date(2023, 11, 20, 10, 30, 0, 0, time.UTC)
+
+	msg := AccountLockedMessage("bank@example.com", user, lockedUntil)
+
+	require.Equal(t, "bank@example.com", msg.From)
+	require.Equal(t, []string{user.Email}, msg.To)
+	require.NotEmpty(t, msg.Subject)
+	require.Contains(t, msg.Body, user.FullName)
+	require.Contains(t, msg.Body, "2023-11-20 10:30 UTC")
+}
+
+func TestSendAccountLocked(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lockedUntil time.Time
+		failing     bool
+		expectSent  bool
+		expectErr   bool
+	}{
+		{
+			name:        "OK",
+			lockedUntil: time.Now().Add(15 * time.Minute),
+			expectSent:  true,
+		},
+		{
+			name:        "LockoutOver",
+			lockedUntil: time.Now().Add(-time.Minute),
+		},
+		{
+			name:        "MailerFails",
+			lockedUntil: time.Now().Add(15 * time.Minute),
+			failing:     true,
+			expectErr:   true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			user := randomLockedUser()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var getTimes int
+			if tc.expectSent || tc.failing {
+				getTimes = 1
+			}
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				GetUser(gomock.Any(), gomock.Eq(user.Username)).
+				Times(getTimes).
+				Return(user, nil)
+
+			mailer := &recordingMailer{}
+			if tc.failing {
+				mailer.failing = user.Email
+			}
+
+			send := SendAccountLocked(mailer, "bank@example.com")
+			err := send(context.Background(), store, AccountLockedPayload{Username: user.Username, LockedUntil: tc.lockedUntil})
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tc.expectSent {
+				require.Len(t, mailer.sent, 1)
+				require.Equal(t, []string{user.Email}, mailer.sent[0].To)
+			} else {
+				require.Empty(t, mailer.sent)
+			}
+		})
+	}
+}
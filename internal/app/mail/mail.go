@@ -0,0 +1,92 @@
+// Package mail sends email to users of the bank, e.g. the verification email of a new user.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+)
+
+// Message is an email of plain text
+type Message struct {
+	From    string   `json:"from"`
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+// Mailer sends email
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// VerifyEmailMessage returns the verification email of a verification, linking to verifyURL with
+// the id and secret code of the verification as query parameters.
+func VerifyEmailMessage(from string, verifyURL string, verifyEmail db.GetVerifyEmailToSendRow) (Message, error) {
+	link, err := url.Parse(verifyURL)
+	if err != nil {
+		return Message{}, fmt.Errorf("verify email url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("id", strconv.FormatInt(verifyEmail.ID, 10))
+	query.Set("code", verifyEmail.SecretCode)
+	link.RawQuery = query.Encode()
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s,\n\n", verifyEmail.FullName)
+	fmt.Fprintf(&body, "Thank you for registering with us! Please verify your email address by opening\n\n%s\n\n", link)
+	fmt.Fprintf(&body, "The link expires at %s.\n", verifyEmail.ExpiredAt.UTC().Format("2006-01-02 15:04 MST"))
+
+	return Message{
+		From:    from,
+		To:      []string{verifyEmail.Email},
+		Subject: "Welcome to the bank, verify your email",
+		Body:    body.String(),
+	}, nil
+}
+
+// VerifyEmailPayload is the payload of the job sending a verification email
+type VerifyEmailPayload struct {
+	VerifyEmailID int64 `json:"verify_email_id"`
+}
+
+// VerifyEmailTask sends the verification email of a new user, retried for about the validity of the code
+var VerifyEmailTask = jobs.Task[VerifyEmailPayload]{
+	Kind:        "email:verify",
+	MaxAttempts: 6,
+	Priority:    10,
+}
+
+// SendVerifyEmail returns the handler of VerifyEmailTask, sending with mailer from the sender address and
+// linking to verifyURL. A verification that is used, expired or already sent is not sent again.
+func SendVerifyEmail(mailer Mailer, from string, verifyURL string) func(ctx context.Context, q db.Querier, payload VerifyEmailPayload) error {
+	return func(ctx context.Context, q db.Querier, payload VerifyEmailPayload) error {
+		verifyEmail, err := q.GetVerifyEmailToSend(ctx, payload.VerifyEmailID)
+		if err != nil {
+			return err
+		}
+
+		if verifyEmail.IsUsed || verifyEmail.SentAt.Valid || !time.Now().Before(verifyEmail.ExpiredAt) {
+			return nil
+		}
+
+		msg, err := VerifyEmailMessage(from, verifyURL, verifyEmail)
+		if err != nil {
+			return err
+		}
+
+		if err := mailer.Send(ctx, msg); err != nil {
+			return err
+		}
+
+		_, err = q.MarkVerifyEmailSent(ctx, verifyEmail.ID)
+		return err
+	}
+}
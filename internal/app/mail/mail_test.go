@@ -0,0 +1,214 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func randomVerifyEmail(id int64) db.GetVerifyEmailToSendRow {
+	return db.GetVerifyEmailToSendRow{
+		ID:         id,
+		Username:   random.Owner(),
+		Email:      random.Email(),
+		SecretCode: random.String(32),
+		ExpiredAt:  time.Now().Add(15 * time.Minute),
+		FullName:   random.Owner(),
+	}
+}
+
+// recordingMailer keeps sent messages, and fails sending to the failing address
+type recordingMailer struct {
+	mu      sync.Mutex
+	failing string
+	sent    []Message
+}
+
+func (m *recordingMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if msg.To[0] == m.failing {
+		return errors.New("mailbox unavailable")
+	}
+
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestVerifyEmailMessage(t *testing.T) {
+	verifyEmail := randomVerifyEmail(42)
+
+	msg, err := VerifyEmailMessage("bank@example.com", "https://bank.example.com/verify_email", verifyEmail)
+	require.NoError(t, err)
+
+	require.Equal(t, "bank@example.com", msg.From)
+	require.Equal(t, []string{verifyEmail.Email}, msg.To)
+	require.NotEmpty(t, msg.Subject)
+	require.Contains(t, msg.Body, verifyEmail.FullName)
+
+	// The link carries the id and secret code of the verification
+	var link string
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if strings.HasPrefix(line, "https://") {
+			link = line
+		}
+	}
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	require.Equal(t, "/verify_email", u.Path)
+	require.Equal(t, "42", u.Query().Get("id"))
+	require.Equal(t, verifyEmail.SecretCode, u.Query().Get("code"))
+
+	_, err = VerifyEmailMessage("bank@example.com", "://invalid", verifyEmail)
+	require.Error(t, err)
+}
+
+func TestFileMailer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.jsonl")
+
+	mailer, err := NewFileMailer(path)
+	require.NoError(t, err)
+
+	msg := Message{From: "bank@example.com", To: []string{random.Email()}, Subject: "subject", Body: "body\n"}
+	require.NoError(t, mailer.Send(context.Background(), msg))
+	require.NoError(t, mailer.Send(context.Background(), msg))
+	require.NoError(t, mailer.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var got Message
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &got))
+		require.Equal(t, msg, got)
+		lines++
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, 2, lines)
+}
+
+func TestLogMailer(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	mailer := NewLogMailer(zap.New(core))
+
+	msg := Message{From: "bank@example.com", To: []string{random.Email()}, Subject: "subject", Body: "code " + random.String(32)}
+	require.NoError(t, mailer.Send(context.Background(), msg))
+
+	entries := logs.All()
+	require.Len(t, entries, 1)
+
+	fields := entries[0].ContextMap()
+	require.Equal(t, msg.Subject, fields["subject"])
+	require.NotContains(t, fields, "body")
+	require.NotContains(t, fmt.Sprint(fields), msg.Body)
+}
+
+func TestSendVerifyEmail(t *testing.T) {
+	testCases := []struct {
+		name       string
+		change     func(verifyEmail *db.GetVerifyEmailToSendRow)
+		failing    bool
+		expectSent bool
+		expectErr  bool
+	}{
+		{
+			name:       "OK",
+			expectSent: true,
+		},
+		{
+			name: "Used",
+			change: func(verifyEmail *db.GetVerifyEmailToSendRow) {
+				verifyEmail.IsUsed = true
+			},
+		},
+		{
+			name: "Expired",
+			change: func(verifyEmail *db.GetVerifyEmailToSendRow) {
+				verifyEmail.ExpiredAt = time.Now().Add(-time.Minute)
+			},
+		},
+		{
+			name: "AlreadySent",
+			change: func(verifyEmail *db.GetVerifyEmailToSendRow) {
+				verifyEmail.SentAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			},
+		},
+		{
+			name:      "MailerFails",
+			failing:   true,
+			expectErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			verifyEmail := randomVerifyEmail(random.Int(1000) + 1)
+			if tc.change != nil {
+				tc.change(&verifyEmail)
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				GetVerifyEmailToSend(gomock.Any(), gomock.Eq(verifyEmail.ID)).
+				Times(1).
+				Return(verifyEmail, nil)
+
+			var markTimes int
+			if tc.expectSent {
+				markTimes = 1
+			}
+			store.EXPECT().
+				MarkVerifyEmailSent(gomock.Any(), gomock.Eq(verifyEmail.ID)).
+				Times(markTimes).
+				Return(db.VerifyEmail{ID: verifyEmail.ID}, nil)
+
+			mailer := &recordingMailer{}
+			if tc.failing {
+				mailer.failing = verifyEmail.Email
+			}
+
+			send := SendVerifyEmail(mailer, "bank@example.com", "http://localhost/verify_email")
+			err := send(context.Background(), store, VerifyEmailPayload{VerifyEmailID: verifyEmail.ID})
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tc.expectSent {
+				require.Len(t, mailer.sent, 1)
+				require.Equal(t, []string{verifyEmail.Email}, mailer.sent[0].To)
+			} else {
+				require.Empty(t, mailer.sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// SMTPMailer sends email through an SMTP server, authenticating with PLAIN auth when a username is set
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates a mailer sending through the SMTP server at host and port.
+func NewSMTPMailer(host string, port int, username string, password string) *SMTPMailer {
+	m := &SMTPMailer{addr: net.JoinHostPort(host, strconv.Itoa(port))}
+
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+// Send sends the message, the SMTP conversation does not observe ctx once started.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var data strings.Builder
+	fmt.Fprintf(&data, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&data, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&data, "Subject: %s\r\n", msg.Subject)
+	data.WriteString("MIME-Version: 1.0\r\n")
+	data.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	data.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	if err := smtp.SendMail(m.addr, m.auth, msg.From, msg.To, []byte(data.String())); err != nil {
+		return fmt.Errorf("smtp mailer: %w", err)
+	}
+
+	return nil
+}
+
+// FileMailer appends sent email to a file, one JSON document per line, e.g. for local development
+type FileMailer struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileMailer creates a mailer appending email to the file at path, created when missing.
+func NewFileMailer(path string) (*FileMailer, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("file mailer: %w", err)
+	}
+
+	return &FileMailer{file: file}, nil
+}
+
+// Send appends the message to the file.
+func (m *FileMailer) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, err := m.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("file mailer: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the file.
+func (m *FileMailer) Close() error {
+	return m.file.Close()
+}
+
+// LogMailer logs email instead of sending it, e.g. for local development. The body is left out, it holds
+// secrets such as reset tokens and verification codes, the file mailer keeps it.
+type LogMailer struct {
+	logger *zap.Logger
+}
+
+// NewLogMailer creates a mailer logging email with logger.
+func NewLogMailer(logger *zap.Logger) *LogMailer {
+	return &LogMailer{logger: logger}
+}
+
+// Send logs the message without its body.
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.logger.Info(
+		"mail: send",
+		zap.String("from", msg.From),
+		zap.Strings("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.Int("body_bytes", len(msg.Body)),
+	)
+
+	return nil
+}
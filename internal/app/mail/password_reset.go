@@ -0,0 +1,88 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/jobs"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PasswordResetTokenLength is the length of a password reset token
+const PasswordResetTokenLength = 32
+
+// PasswordResetMessage returns the email of a password reset, linking to resetURL with the token as query
+// parameter.
+func PasswordResetMessage(from string, resetURL string, reset db.GetPasswordResetToSendRow, token string) (Message, error) {
+	link, err := url.Parse(resetURL)
+	if err != nil {
+		return Message{}, fmt.Errorf("password reset url: %w", err)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %s,\n\n", reset.FullName)
+	fmt.Fprintf(&body, "A password reset was requested for your account. Choose a new password by opening\n\n%s\n\n", link)
+	fmt.Fprintf(&body, "The link expires at %s. If you did not request it, you can ignore this email.\n", reset.ExpiredAt.UTC().Format("2006-01-02 15:04 MST"))
+
+	return Message{
+		From:    from,
+		To:      []string{reset.Email},
+		Subject: "Reset your password",
+		Body:    body.String(),
+	}, nil
+}
+
+// PasswordResetPayload is the payload of the job sending a password reset
+type PasswordResetPayload struct {
+	PasswordResetID int64 `json:"password_reset_id"`
+}
+
+// PasswordResetTask sends the token of a password reset, retried for about the validity of the reset
+var PasswordResetTask = jobs.Task[PasswordResetPayload]{
+	Kind:        "email:password_reset",
+	MaxAttempts: 6,
+	Priority:    10,
+}
+
+// SendPasswordReset returns the handler of PasswordResetTask, sending with mailer from the sender address
+// and linking to resetURL. The token is created when sent, only its hash is stored, so a failed send leaves
+// no valid token behind. A reset that is used, expired or already sent is not sent again.
+func SendPasswordReset(mailer Mailer, from string, resetURL string) func(ctx context.Context, q db.Querier, payload PasswordResetPayload) error {
+	return func(ctx context.Context, q db.Querier, payload PasswordResetPayload) error {
+		reset, err := q.GetPasswordResetToSend(ctx, payload.PasswordResetID)
+		if err != nil {
+			return err
+		}
+
+		if reset.IsUsed || reset.SentAt.Valid || !time.Now().Before(reset.ExpiredAt) {
+			return nil
+		}
+
+		token := random.String(PasswordResetTokenLength)
+
+		msg, err := PasswordResetMessage(from, resetURL, reset, token)
+		if err != nil {
+			return err
+		}
+
+		_, err = q.SetPasswordResetToken(ctx, db.SetPasswordResetTokenParams{
+			ID:        reset.ID,
+			TokenHash: pgtype.Text{String: password.HashToken(token), Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+
+		return mailer.Send(ctx, msg)
+	}
+}
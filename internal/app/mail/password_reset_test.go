@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/password"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomPasswordReset(id int64) db.GetPasswordResetToSendRow {
+	return db.GetPasswordResetToSendRow{
+		ID:        id,
+		Username:  random.Owner(),
+		Email:     random.Email(),
+		ExpiredAt: time.Now().Add(30 * time.Minute),
+		FullName:  random.Owner(),
+	}
+}
+
+// resetLink returns the link of a password reset email
+func resetLink(t *testing.T, msg Message) *url.URL {
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if strings.HasPrefix(line, "https://") || strings.HasPrefix(line, "http://") {
+			u, err := url.Parse(line)
+			require.NoError(t, err)
+			return u
+		}
+	}
+
+	t.Fatal("no link in message")
+	return nil
+}
+
+func TestPasswordResetMessage(t *testing.T) {
+	reset := randomPasswordReset(42)
+	token := random.String(PasswordResetTokenLength)
+
+	msg, err := PasswordResetMessage("bank@example.com", "https://bank.example.com/reset_password", reset, token)
+	require.NoError(t, err)
+
+	require.Equal(t, "bank@example.com", msg.From)
+	require.Equal(t, []string{reset.Email}, msg.To)
+	require.NotEmpty(t, msg.Subject)
+	require.Contains(t, msg.Body, reset.FullName)
+
+	link := resetLink(t, msg)
+	require.Equal(t, "/reset_password", link.Path)
+	require.Equal(t, token, link.Query().Get("token"))
+
+	_, err = PasswordResetMessage("bank@example.com", "://invalid", reset, token)
+	require.Error(t, err)
+}
+
+func TestSendPasswordReset(t *testing.T) {
+	testCases := []struct {
+		name       string
+		change     func(reset *db.GetPasswordResetToSendRow)
+		failing    bool
+		expectSent bool
+		expectErr  bool
+	}{
+		{
+			name:       "OK",
+			expectSent: true,
+		},
+		{
+			name: "Used",
+			change: func(reset *db.GetPasswordResetToSendRow) {
+				reset.IsUsed = true
+			},
+		},
+		{
+			name: "Expired",
+			change: func(reset *db.GetPasswordResetToSendRow) {
+				reset.ExpiredAt = time.Now().Add(-time.Minute)
+			},
+		},
+		{
+			name: "AlreadySent",
+			change: func(reset *db.GetPasswordResetToSendRow) {
+				reset.SentAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			},
+		},
+		{
+			name:      "MailerFails",
+			failing:   true,
+			expectErr: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			reset := randomPasswordReset(random.Int(1000) + 1)
+			if tc.change != nil {
+				tc.change(&reset)
+			}
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+			store.EXPECT().
+				GetPasswordResetToSend(gomock.Any(), gomock.Eq(reset.ID)).
+				Times(1).
+				Return(reset, nil)
+
+			var tokenHash pgtype.Text
+			var setTimes int
+			if tc.expectSent || tc.failing {
+				setTimes = 1
+			}
+			store.EXPECT().
+				SetPasswordResetToken(gomock.Any(), gomock.Any()).
+				Times(setTimes).
+				DoAndReturn(func(_ context.Context, arg db.SetPasswordResetTokenParams) (db.PasswordReset, error) {
+					require.Equal(t, reset.ID, arg.ID)
+					tokenHash = arg.TokenHash
+					return db.PasswordReset{ID: reset.ID, TokenHash: arg.TokenHash}, nil
+				})
+
+			mailer := &recordingMailer{}
+			if tc.failing {
+				mailer.failing = reset.Email
+			}
+
+			send := SendPasswordReset(mailer, "bank@example.com", "http://localhost/reset_password")
+			err := send(context.Background(), store, PasswordResetPayload{PasswordResetID: reset.ID})
+			if tc.expectErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+
+			if tc.expectSent {
+				require.Len(t, mailer.sent, 1)
+				require.Equal(t, []string{reset.Email}, mailer.sent[0].To)
+
+				// Only the hash of the sent token is stored
+				token := resetLink(t, mailer.sent[0]).Query().Get("token")
+				require.Len(t, token, PasswordResetTokenLength)
+				require.Equal(t, password.HashToken(token), tokenHash.String)
+			} else {
+				require.Empty(t, mailer.sent)
+			}
+		})
+	}
+}
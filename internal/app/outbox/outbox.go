@@ -0,0 +1,81 @@
+// Package outbox relays the domain events the bank writes to its outbox to publishers, so that downstream
+// systems learn about changes without events getting lost when the service crashes.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Event is a published domain event
+type Event struct {
+	// Unique and increasing, consumers deduplicate redelivered events on the ID
+	ID            int64  `json:"id"`
+	AggregateType string `json:"aggregate_type"`
+	AggregateID   string `json:"aggregate_id"`
+	Type          string `json:"type"`
+	// Version of the payload schema of the event type
+	Version    int32           `json:"version"`
+	Payload    json.RawMessage `json:"payload"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// NewEvent returns the event of an outbox record.
+func NewEvent(record db.Outbox) Event {
+	return Event{
+		ID:            record.ID,
+		AggregateType: record.AggregateType,
+		AggregateID:   record.AggregateID,
+		Type:          record.EventType,
+		Version:       record.EventVersion,
+		Payload:       record.Payload,
+		OccurredAt:    record.CreatedAt,
+	}
+}
+
+// Publisher delivers events to downstream systems
+type Publisher interface {
+	// Publish delivers an event, an error has the event published again later
+	Publish(ctx context.Context, event Event) error
+}
+
+// RelayResult counts the events handled by a relay run
+type RelayResult struct {
+	Published int `json:"published"`
+	// Failed events are retried later
+	Failed int `json:"failed"`
+}
+
+// Relay publishes due events of the outbox until none is due or ctx is done. Events are published in order
+// per aggregate and at least once, failed events are retried after retryDelay, doubled for every following
+// attempt. Several replicas can relay side by side, each event is claimed by one of them.
+func Relay(ctx context.Context, b bank.Bank, publisher Publisher, retryDelay time.Duration) (RelayResult, error) {
+	var result RelayResult
+
+	publish := func(ctx context.Context, record db.Outbox) error {
+		return publisher.Publish(ctx, NewEvent(record))
+	}
+
+	for ctx.Err() == nil {
+		record, err := b.RelayOutboxEvent(ctx, retryDelay, publish)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if record.PublishedAt.Valid {
+			result.Published++
+		} else {
+			result.Failed++
+		}
+	}
+
+	return result, ctx.Err()
+}
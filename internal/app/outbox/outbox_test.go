@@ -0,0 +1,169 @@
+package outbox
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func randomEvent(id int64) Event {
+	return Event{
+		ID:            id,
+		AggregateType: "account",
+		AggregateID:   "1",
+		Type:          "account.created",
+		Version:       1,
+		Payload:       json.RawMessage(`{"account_id":1}`),
+		OccurredAt:    time.Now().UTC().Truncate(time.Microsecond),
+	}
+}
+
+func TestMemoryPublisher(t *testing.T) {
+	publisher := NewMemoryPublisher()
+
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent(1)))
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent(2)))
+
+	events := publisher.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, int64(1), events[0].ID)
+	require.Equal(t, int64(2), events[1].ID)
+}
+
+func TestFilePublisher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+
+	publisher, err := NewFilePublisher(path)
+	require.NoError(t, err)
+
+	event := randomEvent(1)
+	require.NoError(t, publisher.Publish(context.Background(), event))
+	require.NoError(t, publisher.Publish(context.Background(), randomEvent(2)))
+	require.NoError(t, publisher.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var lines []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var got Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &got))
+		lines = append(lines, got)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, lines, 2)
+	require.Equal(t, event.ID, lines[0].ID)
+	require.JSONEq(t, string(event.Payload), string(lines[0].Payload))
+	require.True(t, event.OccurredAt.Equal(lines[0].OccurredAt))
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "OK", status: http.StatusNoContent},
+		{name: "ServerError", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got Event
+
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, http.MethodPost, r.Method)
+				require.Equal(t, "1", r.Header.Get(EventIDHeader))
+				require.Equal(t, "account.created", r.Header.Get(EventTypeHeader))
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			err := NewHTTPPublisher(receiver.URL, time.Second).Publish(context.Background(), randomEvent(1))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, int64(1), got.ID)
+		})
+	}
+}
+
+// failingPublisher fails events of one aggregate
+type failingPublisher struct {
+	MemoryPublisher
+	aggregateID string
+}
+
+func (p *failingPublisher) Publish(ctx context.Context, event Event) error {
+	if event.AggregateID == p.aggregateID {
+		return errors.New("unavailable")
+	}
+
+	return p.MemoryPublisher.Publish(ctx, event)
+}
+
+func TestRelay(t *testing.T) {
+	records := []db.Outbox{
+		{ID: 1, AggregateType: "account", AggregateID: "1", EventType: "account.created", EventVersion: 1, Payload: []byte(`{}`)},
+		{ID: 2, AggregateType: "account", AggregateID: "2", EventType: "account.created", EventVersion: 1, Payload: []byte(`{}`)},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The bank hands out each due record once, and marks it by the outcome of publishing
+	next := 0
+	store := mockdb.NewMockBank(ctrl)
+	store.EXPECT().
+		RelayOutboxEvent(gomock.Any(), gomock.Eq(time.Minute), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ time.Duration, publish func(context.Context, db.Outbox) error) (db.Outbox, error) {
+			if next == len(records) {
+				return db.Outbox{}, db.ErrRecordNotFound
+			}
+
+			record := records[next]
+			next++
+
+			record.Attempts++
+			if err := publish(ctx, record); err != nil {
+				record.LastError = err.Error()
+				return record, nil
+			}
+
+			record.PublishedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+			return record, nil
+		}).
+		Times(len(records) + 1)
+
+	publisher := &failingPublisher{aggregateID: "2"}
+
+	result, err := Relay(context.Background(), store, publisher, time.Minute)
+	require.NoError(t, err)
+	require.Equal(t, RelayResult{Published: 1, Failed: 1}, result)
+
+	events := publisher.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, NewEvent(records[0]), events[0])
+}
@@ -0,0 +1,41 @@
+package reconcile
+
+import (
+	"expvar"
+)
+
+// metrics of the last reconciliation run, served with the other expvar variables
+var metrics = expvar.NewMap("reconcile")
+
+// publish updates the metrics with the outcome of a run.
+func publish(report Report, err error) {
+	metrics.Add("runs", 1)
+
+	if err != nil {
+		metrics.Add("failures", 1)
+		return
+	}
+
+	var repaired int64
+	for _, drift := range report.AccountDrift {
+		if drift.Repaired {
+			repaired++
+		}
+	}
+
+	gauge("accounts_checked", report.AccountsChecked)
+	gauge("transfers_checked", report.TransfersChecked)
+	gauge("account_drift", int64(len(report.AccountDrift)))
+	gauge("account_drift_repaired", repaired)
+	gauge("transfer_mismatches", int64(len(report.TransferMismatches)))
+	gauge("currency_drift", int64(len(report.CurrencyDrift)))
+	gauge("unlinked_entries", report.UnlinkedEntries)
+	gauge("duration_ms", report.FinishedAt.Sub(report.StartedAt).Milliseconds())
+	gauge("last_success_unix", report.FinishedAt.Unix())
+}
+
+func gauge(name string, value int64) {
+	v := new(expvar.Int)
+	v.Set(value)
+	metrics.Set(name, v)
+}
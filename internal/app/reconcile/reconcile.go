@@ -0,0 +1,211 @@
+// Package reconcile checks that the ledger of the bank is consistent. The balance of every account must equal
+// the sum of its entries, every transfer must be booked by exactly one debit and one credit entry, and the
+// entries of every currency must net to zero.
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+)
+
+// Options of a reconciliation run
+type Options struct {
+	// Accounts and transfers read per query
+	BatchSize int32
+	// Repair sets the balance of drifting accounts to the sum of their entries. Transfer and
+	// currency problems are only reported, they need a person to decide what is right.
+	Repair bool
+}
+
+// AccountDrift is an account whose balance differs from the sum of its entries
+type AccountDrift struct {
+	AccountID int64  `json:"account_id"`
+	Owner     string `json:"owner"`
+	Currency  string `json:"currency"`
+	// Balance of the account
+	Balance int64 `json:"balance"`
+	// Sum of the entries of the account
+	EntriesBalance int64 `json:"entries_balance"`
+	// Balance minus the sum of the entries
+	Drift int64 `json:"drift"`
+	// Whether the balance was repaired
+	Repaired bool `json:"repaired"`
+}
+
+// TransferMismatch is a transfer not booked by exactly one debit and one credit entry
+type TransferMismatch struct {
+	TransferID    int64 `json:"transfer_id"`
+	FromAccountID int64 `json:"from_account_id"`
+	ToAccountID   int64 `json:"to_account_id"`
+	Amount        int64 `json:"amount"`
+	// Entries moving the amount out of the from account
+	Debits int64 `json:"debits"`
+	// Entries moving the amount in to the to account
+	Credits int64 `json:"credits"`
+	// All entries linked to the transfer
+	Entries int64 `json:"entries"`
+}
+
+// CurrencyDrift is a currency whose entries do not net to zero
+type CurrencyDrift struct {
+	Currency string `json:"currency"`
+	Total    int64  `json:"total"`
+}
+
+// Report is the result of a reconciliation run
+type Report struct {
+	StartedAt        time.Time `json:"started_at"`
+	FinishedAt       time.Time `json:"finished_at"`
+	Repair           bool      `json:"repair"`
+	AccountsChecked  int64     `json:"accounts_checked"`
+	TransfersChecked int64     `json:"transfers_checked"`
+	// Entries not linked to a transfer
+	UnlinkedEntries    int64              `json:"unlinked_entries"`
+	AccountDrift       []AccountDrift     `json:"account_drift"`
+	TransferMismatches []TransferMismatch `json:"transfer_mismatches"`
+	CurrencyDrift      []CurrencyDrift    `json:"currency_drift"`
+}
+
+// Consistent tells whether the run left the ledger consistent, i.e. found no problems or repaired all of them.
+func (r Report) Consistent() bool {
+	for _, drift := range r.AccountDrift {
+		if !drift.Repaired {
+			return false
+		}
+	}
+
+	return len(r.TransferMismatches) == 0 && len(r.CurrencyDrift) == 0 && r.UnlinkedEntries == 0
+}
+
+// Run scans all accounts and transfers of the bank in batches and reports what is inconsistent. The outcome
+// is also published as metrics. Each batch is read by a single statement, balances and entries of a batch
+// are from the same snapshot and transfers running meanwhile do not show up as drift.
+func Run(ctx context.Context, b bank.Bank, opts Options) (Report, error) {
+	report := Report{
+		StartedAt: time.Now(),
+		Repair:    opts.Repair,
+	}
+
+	err := checkAccounts(ctx, b, opts, &report)
+	if err == nil {
+		err = checkTransfers(ctx, b, opts, &report)
+	}
+	if err == nil {
+		err = checkCurrencies(ctx, b, &report)
+	}
+
+	report.FinishedAt = time.Now()
+	publish(report, err)
+
+	return report, err
+}
+
+// checkAccounts compares the balance of every account with the sum of its entries.
+func checkAccounts(ctx context.Context, b bank.Bank, opts Options, report *Report) error {
+	var afterID int64
+
+	for {
+		accounts, err := b.ListAccountBalancesAfter(ctx, db.ListAccountBalancesAfterParams{
+			ID:    afterID,
+			Limit: opts.BatchSize,
+		})
+		if err != nil || len(accounts) == 0 {
+			return err
+		}
+
+		for _, account := range accounts {
+			report.AccountsChecked++
+
+			if account.Balance == account.EntriesBalance {
+				continue
+			}
+
+			drift := AccountDrift{
+				AccountID:      account.ID,
+				Owner:          account.Owner,
+				Currency:       account.Currency,
+				Balance:        account.Balance,
+				EntriesBalance: account.EntriesBalance,
+				Drift:          account.Balance - account.EntriesBalance,
+			}
+
+			if opts.Repair {
+				if _, err := b.RepairAccountBalance(ctx, account.ID); err != nil {
+					return err
+				}
+				drift.Repaired = true
+			}
+
+			report.AccountDrift = append(report.AccountDrift, drift)
+		}
+
+		afterID = accounts[len(accounts)-1].ID
+	}
+}
+
+// checkTransfers checks that every transfer is booked by one debit and one credit entry, and counts the
+// entries not booked by any transfer.
+func checkTransfers(ctx context.Context, b bank.Bank, opts Options, report *Report) error {
+	var afterID int64
+
+	for {
+		transfers, err := b.ListTransferEntryCountsAfter(ctx, db.ListTransferEntryCountsAfterParams{
+			ID:    afterID,
+			Limit: opts.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(transfers) == 0 {
+			break
+		}
+
+		for _, transfer := range transfers {
+			report.TransfersChecked++
+
+			if transfer.Debits == 1 && transfer.Credits == 1 && transfer.Entries == 2 {
+				continue
+			}
+
+			report.TransferMismatches = append(report.TransferMismatches, TransferMismatch{
+				TransferID:    transfer.ID,
+				FromAccountID: transfer.FromAccountID,
+				ToAccountID:   transfer.ToAccountID,
+				Amount:        transfer.Amount,
+				Debits:        transfer.Debits,
+				Credits:       transfer.Credits,
+				Entries:       transfer.Entries,
+			})
+		}
+
+		afterID = transfers[len(transfers)-1].ID
+	}
+
+	var err error
+	report.UnlinkedEntries, err = b.CountUnlinkedEntries(ctx)
+
+	return err
+}
+
+// checkCurrencies checks that the entries of every currency, over customer and internal accounts, net to zero.
+func checkCurrencies(ctx context.Context, b bank.Bank, report *Report) error {
+	totals, err := b.ListCurrencyEntryTotals(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, total := range totals {
+		if total.Total != 0 {
+			report.CurrencyDrift = append(report.CurrencyDrift, CurrencyDrift{
+				Currency: total.Currency,
+				Total:    total.Total,
+			})
+		}
+	}
+
+	return nil
+}
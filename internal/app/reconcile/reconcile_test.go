@@ -0,0 +1,104 @@
+package reconcile
+
+import (
+	"context"
+	"testing"
+
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRun(t *testing.T) {
+	accounts := []db.ListAccountBalancesAfterRow{
+		{ID: 1, Owner: "alice", Currency: "SEK", Balance: 100, EntriesBalance: 100},
+		{ID: 2, Owner: "bob", Currency: "SEK", Balance: 150, EntriesBalance: 100},
+	}
+	transfers := []db.ListTransferEntryCountsAfterRow{
+		{ID: 1, FromAccountID: 1, ToAccountID: 2, Amount: 10, Debits: 1, Credits: 1, Entries: 2},
+		{ID: 2, FromAccountID: 2, ToAccountID: 1, Amount: 20, Debits: 1, Credits: 0, Entries: 1},
+	}
+
+	testCases := []struct {
+		name        string
+		repair      bool
+		buildStubs  func(store *mockdb.MockBank)
+		checkReport func(report Report)
+	}{
+		{
+			name: "Report",
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().RepairAccountBalance(gomock.Any(), gomock.Any()).Times(0)
+				store.EXPECT().ListCurrencyEntryTotals(gomock.Any()).
+					Return([]db.ListCurrencyEntryTotalsRow{{Currency: "EUR", Total: 0}, {Currency: "SEK", Total: -20}}, nil)
+				store.EXPECT().CountUnlinkedEntries(gomock.Any()).Return(int64(3), nil)
+			},
+			checkReport: func(report Report) {
+				require.False(t, report.Consistent())
+				require.Equal(t, []AccountDrift{
+					{AccountID: 2, Owner: "bob", Currency: "SEK", Balance: 150, EntriesBalance: 100, Drift: 50},
+				}, report.AccountDrift)
+				require.Equal(t, []TransferMismatch{
+					{TransferID: 2, FromAccountID: 2, ToAccountID: 1, Amount: 20, Debits: 1, Credits: 0, Entries: 1},
+				}, report.TransferMismatches)
+				require.Equal(t, []CurrencyDrift{{Currency: "SEK", Total: -20}}, report.CurrencyDrift)
+				require.Equal(t, int64(3), report.UnlinkedEntries)
+			},
+		},
+		{
+			name:   "Repair",
+			repair: true,
+			buildStubs: func(store *mockdb.MockBank) {
+				store.EXPECT().RepairAccountBalance(gomock.Any(), gomock.Eq(int64(2))).
+					Times(1).
+					Return(db.Account{ID: 2, Balance: 100}, nil)
+				store.EXPECT().ListCurrencyEntryTotals(gomock.Any()).Return(nil, nil)
+				store.EXPECT().CountUnlinkedEntries(gomock.Any()).Return(int64(0), nil)
+			},
+			checkReport: func(report Report) {
+				require.Len(t, report.AccountDrift, 1)
+				require.True(t, report.AccountDrift[0].Repaired)
+				// The transfer mismatch is not repaired
+				require.False(t, report.Consistent())
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockBank(ctrl)
+
+			// Two batches of two, then an empty batch
+			store.EXPECT().ListAccountBalancesAfter(gomock.Any(), gomock.Eq(db.ListAccountBalancesAfterParams{ID: 0, Limit: 2})).
+				Return(accounts, nil)
+			store.EXPECT().ListAccountBalancesAfter(gomock.Any(), gomock.Eq(db.ListAccountBalancesAfterParams{ID: 2, Limit: 2})).
+				Return(nil, nil)
+			store.EXPECT().ListTransferEntryCountsAfter(gomock.Any(), gomock.Eq(db.ListTransferEntryCountsAfterParams{ID: 0, Limit: 2})).
+				Return(transfers, nil)
+			store.EXPECT().ListTransferEntryCountsAfter(gomock.Any(), gomock.Eq(db.ListTransferEntryCountsAfterParams{ID: 2, Limit: 2})).
+				Return(nil, nil)
+			tc.buildStubs(store)
+
+			report, err := Run(context.Background(), store, Options{BatchSize: 2, Repair: tc.repair})
+			require.NoError(t, err)
+			require.Equal(t, int64(2), report.AccountsChecked)
+			require.Equal(t, int64(2), report.TransfersChecked)
+			require.Equal(t, tc.repair, report.Repair)
+
+			tc.checkReport(report)
+		})
+	}
+}
+
+func TestReportConsistent(t *testing.T) {
+	require.True(t, Report{}.Consistent())
+	require.True(t, Report{AccountDrift: []AccountDrift{{AccountID: 1, Repaired: true}}}.Consistent())
+	require.False(t, Report{AccountDrift: []AccountDrift{{AccountID: 1}}}.Consistent())
+	require.False(t, Report{UnlinkedEntries: 1}.Consistent())
+}
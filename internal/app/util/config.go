@@ -2,6 +2,7 @@ package util
 
 import (
 	"log"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -10,11 +11,123 @@ import (
 // The values are read by viper from a config file or environment variable.
 // Viper uses the mapstructure package under the hood for unmarshal of values.
 type Config struct {
-	Environment       string `mapstructure:"ENVIRONMENT"`
-	DBSource          string `mapstructure:"DB_SOURCE"`
-	MigrationURL      string `mapstructure:"MIGRATION_URL"`
-	LogLevel          string `mapstructure:"LOG_LEVEL"`
-	HTTPServerAddress string `mapstructure:"HTTP_SERVER_ADDRESS"`
+	Environment        string        `mapstructure:"ENVIRONMENT"`
+	DBSource           string        `mapstructure:"DB_SOURCE"`
+	MigrationURL       string        `mapstructure:"MIGRATION_URL"`
+	LogLevel           string        `mapstructure:"LOG_LEVEL"`
+	HTTPServerAddress  string        `mapstructure:"HTTP_SERVER_ADDRESS"`
+	HoldDuration       time.Duration `mapstructure:"HOLD_DURATION"`
+	HoldExpiryInterval time.Duration `mapstructure:"HOLD_EXPIRY_INTERVAL"`
+	// The HTTP server serves TLS with the certificate and key files when set, of at least the min version,
+	// 1.2 or 1.3. Clients may authenticate with certificates of the client CA, identifying internal callers
+	// by the subject. The files are reloaded when changed. Internal callers of the comma separated subjects,
+	// e.g. payments,reporting, are authenticated without a token, with the permissions of the internal role.
+	TLSCertFile         string `mapstructure:"TLS_CERT_FILE"`
+	TLSKeyFile          string `mapstructure:"TLS_KEY_FILE"`
+	TLSMinVersion       string `mapstructure:"TLS_MIN_VERSION"`
+	TLSClientCAFile     string `mapstructure:"TLS_CLIENT_CA_FILE"`
+	TLSInternalSubjects string `mapstructure:"TLS_INTERNAL_SUBJECTS"`
+	TLSInternalRole     string `mapstructure:"TLS_INTERNAL_ROLE"`
+	// Client IPs are read from the X-Forwarded-For header only of requests from the trusted proxies, comma
+	// separated IPs or CIDRs, e.g. 10.0.0.0/8. None are trusted when empty, the client IP is the remote address.
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+	// Scheduled transfers are polled for due occurrences every interval, failed
+	// occurrences are retried with exponential backoff starting at the retry delay.
+	ScheduledTransferInterval    time.Duration `mapstructure:"SCHEDULED_TRANSFER_INTERVAL"`
+	ScheduledTransferMaxAttempts int32         `mapstructure:"SCHEDULED_TRANSFER_MAX_ATTEMPTS"`
+	ScheduledTransferRetryDelay  time.Duration `mapstructure:"SCHEDULED_TRANSFER_RETRY_DELAY"`
+	// Interest is accrued and posted, and fees charged, every interval for batches of accounts.
+	// Basis is ACT/365 or 30/360, rounding is half-up, half-even or down.
+	AccrualInterval  time.Duration `mapstructure:"ACCRUAL_INTERVAL"`
+	AccrualBatchSize int32         `mapstructure:"ACCRUAL_BATCH_SIZE"`
+	AccrualBasis     string        `mapstructure:"ACCRUAL_BASIS"`
+	AccrualRounding  string        `mapstructure:"ACCRUAL_ROUNDING"`
+	// The ledger is reconciled every interval, reading batches of accounts and transfers.
+	ReconcileInterval  time.Duration `mapstructure:"RECONCILE_INTERVAL"`
+	ReconcileBatchSize int32         `mapstructure:"RECONCILE_BATCH_SIZE"`
+	// Domain events of the outbox are relayed every interval to the publisher, memory, file or http, and
+	// relaying is off when no publisher is set. Failed events are retried with exponential backoff.
+	OutboxPublisher  string        `mapstructure:"OUTBOX_PUBLISHER"`
+	OutboxFile       string        `mapstructure:"OUTBOX_FILE"`
+	OutboxURL        string        `mapstructure:"OUTBOX_URL"`
+	OutboxInterval   time.Duration `mapstructure:"OUTBOX_INTERVAL"`
+	OutboxRetryDelay time.Duration `mapstructure:"OUTBOX_RETRY_DELAY"`
+	// Webhook deliveries are polled every interval, each attempt is given up after the timeout. Failed
+	// deliveries are retried with exponential backoff starting at the retry delay, and dead once out of attempts.
+	WebhookInterval    time.Duration `mapstructure:"WEBHOOK_INTERVAL"`
+	WebhookTimeout     time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts int32         `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookRetryDelay  time.Duration `mapstructure:"WEBHOOK_RETRY_DELAY"`
+	// New password hashes are of the algorithm, argon2id or bcrypt, with its parameters. Argon2id memory is in
+	// KiB. The optional pepper is mixed into argon2id hashes and kept out of the database. Hashes of other
+	// parameters are replaced when users log in.
+	PasswordHashAlgorithm     string `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	PasswordArgon2Memory      uint32 `mapstructure:"PASSWORD_ARGON2_MEMORY"`
+	PasswordArgon2Iterations  uint32 `mapstructure:"PASSWORD_ARGON2_ITERATIONS"`
+	PasswordArgon2Parallelism uint8  `mapstructure:"PASSWORD_ARGON2_PARALLELISM"`
+	PasswordBcryptCost        int    `mapstructure:"PASSWORD_BCRYPT_COST"`
+	PasswordPepper            string `mapstructure:"PASSWORD_PEPPER"`
+	// New passwords must have a length in characters within the min and max length, a strength score of at
+	// least the min score from 0 to 4, and must not be in the breached passwords file of SHA-1 hashes, when set.
+	PasswordMinLength    int    `mapstructure:"PASSWORD_MIN_LENGTH"`
+	PasswordMaxLength    int    `mapstructure:"PASSWORD_MAX_LENGTH"`
+	PasswordMinScore     int    `mapstructure:"PASSWORD_MIN_SCORE"`
+	PasswordBreachedFile string `mapstructure:"PASSWORD_BREACHED_FILE"`
+	// Access and refresh tokens are signed with the symmetric key, of at least 32 characters. The refresh
+	// token of a session renews access tokens until it expires.
+	TokenSymmetricKey    string        `mapstructure:"TOKEN_SYMMETRIC_KEY"`
+	AccessTokenDuration  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	RefreshTokenDuration time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+	// TOTP secrets are encrypted at rest with the encryption key, of exactly 32 characters, and shown in
+	// authenticator apps under the issuer. Users with TOTP enabled log in with a code within the MFA token
+	// duration after their password, and transfers of amounts above the threshold require a code.
+	TOTPEncryptionKey     string        `mapstructure:"TOTP_ENCRYPTION_KEY"`
+	TOTPIssuer            string        `mapstructure:"TOTP_ISSUER"`
+	MFATokenDuration      time.Duration `mapstructure:"MFA_TOKEN_DURATION"`
+	TransferTOTPThreshold int64         `mapstructure:"TRANSFER_TOTP_THRESHOLD"`
+	// Failed logins within the window are counted per username and per client IP. From the delay threshold
+	// on logins are delayed, doubling from the delay up to the max delay, and from the lockout thresholds on
+	// logins are refused for the lockout duration.
+	LoginFailureWindow         time.Duration `mapstructure:"LOGIN_FAILURE_WINDOW"`
+	LoginDelayThreshold        int32         `mapstructure:"LOGIN_DELAY_THRESHOLD"`
+	LoginDelay                 time.Duration `mapstructure:"LOGIN_DELAY"`
+	LoginMaxDelay              time.Duration `mapstructure:"LOGIN_MAX_DELAY"`
+	LoginLockoutThreshold      int32         `mapstructure:"LOGIN_LOCKOUT_THRESHOLD"`
+	LoginLockoutThresholdPerIP int32         `mapstructure:"LOGIN_LOCKOUT_THRESHOLD_PER_IP"`
+	LoginLockoutDuration       time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+	// Requests are rate limited per client, the API key, user or else IP, with token buckets in the store,
+	// memory or postgres, or not at all when off. The default limit, e.g. 300/m, is shared by all routes of
+	// a client, except for routes with their own limit, e.g. POST /users=10/h,POST /transfers=60/m. Full
+	// buckets of the postgres store are deleted every cleanup interval. Requests to authenticated routes are
+	// also limited per client IP before their credentials are checked, by the pre auth limit unless empty.
+	RateLimitStore           string        `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitDefault         string        `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitRoutes          string        `mapstructure:"RATE_LIMIT_ROUTES"`
+	RateLimitPreAuth         string        `mapstructure:"RATE_LIMIT_PRE_AUTH"`
+	RateLimitCleanupInterval time.Duration `mapstructure:"RATE_LIMIT_CLEANUP_INTERVAL"`
+	// Balance events buffered per subscriber, subscribers falling further behind are disconnected
+	BalanceEventBuffer int `mapstructure:"BALANCE_EVENT_BUFFER"`
+	// Email is sent by the mailer, file, smtp or log, from the sender address. The log mailer leaves out the
+	// body and is only allowed in development. Verification emails link to the verify email URL, password
+	// reset emails to the password reset URL.
+	Mailer         string `mapstructure:"MAILER"`
+	MailFile       string `mapstructure:"MAIL_FILE"`
+	MailFrom       string `mapstructure:"MAIL_FROM"`
+	SMTPHost       string `mapstructure:"SMTP_HOST"`
+	SMTPPort       int    `mapstructure:"SMTP_PORT"`
+	SMTPUsername   string `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword   string `mapstructure:"SMTP_PASSWORD"`
+	VerifyEmailURL string `mapstructure:"VERIFY_EMAIL_URL"`
+	// Password resets requested within the window are limited per email and per client IP
+	PasswordResetURL         string        `mapstructure:"PASSWORD_RESET_URL"`
+	PasswordResetWindow      time.Duration `mapstructure:"PASSWORD_RESET_WINDOW"`
+	PasswordResetMaxPerEmail int64         `mapstructure:"PASSWORD_RESET_MAX_PER_EMAIL"`
+	PasswordResetMaxPerIP    int64         `mapstructure:"PASSWORD_RESET_MAX_PER_IP"`
+	// Job workers, as many as the concurrency, poll for due jobs every interval. Failed jobs are retried
+	// with exponential backoff starting at the retry delay.
+	JobConcurrency  int           `mapstructure:"JOB_CONCURRENCY"`
+	JobPollInterval time.Duration `mapstructure:"JOB_POLL_INTERVAL"`
+	JobRetryDelay   time.Duration `mapstructure:"JOB_RETRY_DELAY"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -38,6 +151,87 @@ func LoadConfig(path string) (config Config, err error) {
 	viper.SetDefault("MIGRATION_URL", "file://migrations")
 	viper.SetDefault("LOG_LEVEL", "INFO")
 	viper.SetDefault("HTTP_SERVER_ADDRESS", "0.0.0.0:8080")
+	viper.SetDefault("TRUSTED_PROXIES", "")
+	viper.SetDefault("TLS_MIN_VERSION", "1.2")
+	viper.SetDefault("HOLD_DURATION", 7*24*time.Hour)
+	viper.SetDefault("HOLD_EXPIRY_INTERVAL", time.Minute)
+	viper.SetDefault("SCHEDULED_TRANSFER_INTERVAL", 30*time.Second)
+	viper.SetDefault("SCHEDULED_TRANSFER_MAX_ATTEMPTS", 3)
+	viper.SetDefault("SCHEDULED_TRANSFER_RETRY_DELAY", 5*time.Minute)
+	viper.SetDefault("ACCRUAL_INTERVAL", time.Hour)
+	viper.SetDefault("ACCRUAL_BATCH_SIZE", 100)
+	viper.SetDefault("ACCRUAL_BASIS", "ACT/365")
+	viper.SetDefault("ACCRUAL_ROUNDING", "half-even")
+	viper.SetDefault("RECONCILE_INTERVAL", 24*time.Hour)
+	viper.SetDefault("RECONCILE_BATCH_SIZE", 500)
+	viper.SetDefault("OUTBOX_PUBLISHER", "")
+	viper.SetDefault("OUTBOX_FILE", "outbox.jsonl")
+	viper.SetDefault("OUTBOX_URL", "")
+	viper.SetDefault("OUTBOX_INTERVAL", 5*time.Second)
+	viper.SetDefault("OUTBOX_RETRY_DELAY", 10*time.Second)
+	viper.SetDefault("WEBHOOK_INTERVAL", 5*time.Second)
+	viper.SetDefault("WEBHOOK_TIMEOUT", 10*time.Second)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_RETRY_DELAY", 30*time.Second)
+	viper.SetDefault("PASSWORD_HASH_ALGORITHM", "argon2id")
+	viper.SetDefault("PASSWORD_ARGON2_MEMORY", 19*1024)
+	viper.SetDefault("PASSWORD_ARGON2_ITERATIONS", 2)
+	viper.SetDefault("PASSWORD_ARGON2_PARALLELISM", 1)
+	viper.SetDefault("PASSWORD_BCRYPT_COST", 10)
+	viper.SetDefault("PASSWORD_MIN_LENGTH", 8)
+	viper.SetDefault("PASSWORD_MAX_LENGTH", 128)
+	viper.SetDefault("PASSWORD_MIN_SCORE", 2)
+	viper.SetDefault("ACCESS_TOKEN_DURATION", 15*time.Minute)
+	viper.SetDefault("REFRESH_TOKEN_DURATION", 24*time.Hour)
+	viper.SetDefault("TOTP_ISSUER", "Bank")
+	viper.SetDefault("MFA_TOKEN_DURATION", 5*time.Minute)
+	viper.SetDefault("TRANSFER_TOTP_THRESHOLD", 1000)
+	viper.SetDefault("LOGIN_FAILURE_WINDOW", time.Hour)
+	viper.SetDefault("LOGIN_DELAY_THRESHOLD", 3)
+	viper.SetDefault("LOGIN_DELAY", time.Second)
+	viper.SetDefault("LOGIN_MAX_DELAY", 30*time.Second)
+	viper.SetDefault("LOGIN_LOCKOUT_THRESHOLD", 10)
+	viper.SetDefault("LOGIN_LOCKOUT_THRESHOLD_PER_IP", 100)
+	viper.SetDefault("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+	viper.SetDefault("RATE_LIMIT_STORE", "memory")
+	viper.SetDefault("RATE_LIMIT_DEFAULT", "300/m")
+	viper.SetDefault("RATE_LIMIT_ROUTES", "POST /users=10/h,POST /users/login=30/m,POST /users/forgot_password=10/h,POST /transfers=60/m")
+	viper.SetDefault("RATE_LIMIT_PRE_AUTH", "600/m")
+	viper.SetDefault("RATE_LIMIT_CLEANUP_INTERVAL", 10*time.Minute)
+	viper.SetDefault("BALANCE_EVENT_BUFFER", 16)
+	viper.SetDefault("MAILER", "file")
+	viper.SetDefault("MAIL_FILE", "mail.jsonl")
+	viper.SetDefault("MAIL_FROM", "no-reply@bank.local")
+	viper.SetDefault("SMTP_PORT", 587)
+	viper.SetDefault("VERIFY_EMAIL_URL", "http://localhost:8080/verify_email")
+	viper.SetDefault("PASSWORD_RESET_URL", "http://localhost:8080/reset_password")
+	viper.SetDefault("PASSWORD_RESET_WINDOW", time.Hour)
+	viper.SetDefault("PASSWORD_RESET_MAX_PER_EMAIL", 3)
+	viper.SetDefault("PASSWORD_RESET_MAX_PER_IP", 10)
+	viper.SetDefault("JOB_CONCURRENCY", 4)
+	viper.SetDefault("JOB_POLL_INTERVAL", time.Second)
+	viper.SetDefault("JOB_RETRY_DELAY", 10*time.Second)
+
+	// Keys without a default, e.g. secrets, are bound to their environment variable. AutomaticEnv only
+	// overrides keys viper already knows, Unmarshal would leave them empty.
+	for _, key := range []string{
+		"TOKEN_SYMMETRIC_KEY",
+		"TOTP_ENCRYPTION_KEY",
+		"TLS_CERT_FILE",
+		"TLS_KEY_FILE",
+		"TLS_CLIENT_CA_FILE",
+		"TLS_INTERNAL_SUBJECTS",
+		"TLS_INTERNAL_ROLE",
+		"PASSWORD_PEPPER",
+		"PASSWORD_BREACHED_FILE",
+		"SMTP_HOST",
+		"SMTP_USERNAME",
+		"SMTP_PASSWORD",
+	} {
+		if err = viper.BindEnv(key); err != nil {
+			return
+		}
+	}
 
 	// Tell Viper to read config from file.
 	if err := viper.ReadInConfig(); err != nil {
@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigEnv(t *testing.T) {
+	// Keys without a default are read from the environment, no config file is needed
+	t.Setenv("TOKEN_SYMMETRIC_KEY", "12345678901234567890123456789012")
+	t.Setenv("TOTP_ENCRYPTION_KEY", "abcdefghijklmnopqrstuvwxyz123456")
+	t.Setenv("TLS_CERT_FILE", "/etc/bank/tls/bank.crt")
+	t.Setenv("TLS_KEY_FILE", "/etc/bank/tls/bank.key")
+	t.Setenv("TLS_CLIENT_CA_FILE", "/etc/bank/tls/client-ca.crt")
+	t.Setenv("TLS_INTERNAL_SUBJECTS", "payments")
+	t.Setenv("TLS_INTERNAL_ROLE", "support")
+	t.Setenv("PASSWORD_PEPPER", "pepper")
+	t.Setenv("PASSWORD_BREACHED_FILE", "/etc/bank/breached.txt")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_USERNAME", "bank")
+	t.Setenv("SMTP_PASSWORD", "secret")
+
+	config, err := LoadConfig(t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, "12345678901234567890123456789012", config.TokenSymmetricKey)
+	require.Equal(t, "abcdefghijklmnopqrstuvwxyz123456", config.TOTPEncryptionKey)
+	require.Equal(t, "/etc/bank/tls/bank.crt", config.TLSCertFile)
+	require.Equal(t, "/etc/bank/tls/bank.key", config.TLSKeyFile)
+	require.Equal(t, "/etc/bank/tls/client-ca.crt", config.TLSClientCAFile)
+	require.Equal(t, "payments", config.TLSInternalSubjects)
+	require.Equal(t, "support", config.TLSInternalRole)
+	require.Equal(t, "pepper", config.PasswordPepper)
+	require.Equal(t, "/etc/bank/breached.txt", config.PasswordBreachedFile)
+	require.Equal(t, "smtp.example.com", config.SMTPHost)
+	require.Equal(t, "bank", config.SMTPUsername)
+	require.Equal(t, "secret", config.SMTPPassword)
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/outbox"
+)
+
+// ErrAddressNotAllowed is returned for deliveries to addresses of the host itself or of private networks
+var ErrAddressNotAllowed = errors.New("webhook: address not allowed")
+
+// Sender posts deliveries to the URLs of subscriptions
+type Sender struct {
+	client *http.Client
+}
+
+// NewSender creates a sender giving up each delivery attempt after timeout. URLs are chosen by customers,
+// so deliveries only dial public addresses and redirects are not followed, the response to the URL counts.
+func NewSender(timeout time.Duration) *Sender {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would dial the receiver unchecked
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: timeout, Control: dialPublic}).DialContext
+
+	return NewSenderClient(&http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	})
+}
+
+// NewSenderClient creates a sender posting deliveries with client, which decides the addresses dialed,
+// e.g. receivers of tests on the host itself.
+func NewSenderClient(client *http.Client) *Sender {
+	return &Sender{client: client}
+}
+
+// dialPublic refuses to connect to loopback, private, link-local or unspecified addresses. It checks the
+// resolved address, so names resolving to internal services are refused as well.
+func dialPublic(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil ||
+		ip.IsLoopback() ||
+		ip.IsPrivate() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrAddressNotAllowed, host)
+	}
+
+	return nil
+}
+
+// Deliver posts the event of a delivery as JSON, the same document as published from the outbox, signed with
+// the secret of the subscription. It returns the HTTP status of the receiver, any status but 2xx fails the
+// delivery. Only https URLs are delivered to.
+func (s *Sender) Deliver(ctx context.Context, delivery db.ClaimWebhookDeliveryRow) (int32, error) {
+	u, err := url.Parse(delivery.Url)
+	if err != nil {
+		return 0, err
+	}
+	if u.Scheme != "https" {
+		return 0, fmt.Errorf("webhook: %s is not an https URL", delivery.Url)
+	}
+
+	body, err := json.Marshal(outbox.Event{
+		ID:            delivery.EventID,
+		AggregateType: delivery.AggregateType,
+		AggregateID:   delivery.AggregateID,
+		Type:          delivery.EventType,
+		Version:       delivery.EventVersion,
+		Payload:       delivery.Payload,
+		OccurredAt:    delivery.CreatedAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, body))
+	req.Header.Set(EventIDHeader, strconv.FormatInt(delivery.EventID, 10))
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+
+	rsp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer rsp.Body.Close()
+
+	status := int32(rsp.StatusCode)
+	if status < 200 || status > 299 {
+		return status, fmt.Errorf("webhook: %s responded %s", delivery.Url, rsp.Status)
+	}
+
+	return status, nil
+}
+
+// RunResult counts the deliveries attempted by a run
+type RunResult struct {
+	Delivered int `json:"delivered"`
+	// Failed deliveries are retried later
+	Failed int `json:"failed"`
+	// Dead deliveries are out of attempts
+	Dead int `json:"dead"`
+}
+
+// Run delivers due deliveries until none is due or ctx is done, failed deliveries are retried according
+// to policy. Several replicas can run side by side, each delivery is claimed by one of them.
+func Run(ctx context.Context, b bank.Bank, sender *Sender, policy bank.RetryPolicy) (RunResult, error) {
+	var result RunResult
+
+	for ctx.Err() == nil {
+		delivery, err := b.DeliverWebhook(ctx, policy, sender.Deliver)
+		if errors.Is(err, db.ErrRecordNotFound) {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		switch delivery.Status {
+		case bank.WebhookDeliverySucceeded:
+			result.Delivered++
+		case bank.WebhookDeliveryDead:
+			result.Dead++
+		default:
+			result.Failed++
+		}
+	}
+
+	return result, ctx.Err()
+}
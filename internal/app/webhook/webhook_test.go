@@ -0,0 +1,217 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank"
+	mockdb "github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/bank/mock"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/db"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/app/outbox"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVerify(t *testing.T) {
+	secret, err := NewSecret()
+	require.NoError(t, err)
+
+	body := []byte(`{"id":1}`)
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign(secret, now.Unix(), body)
+
+	require.NoError(t, Verify(secret, timestamp, signature, body, time.Minute, now))
+
+	testCases := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		now       time.Time
+		wantErr   error
+	}{
+		{name: "WrongSecret", secret: "other", timestamp: timestamp, body: body, now: now, wantErr: ErrInvalidSignature},
+		{name: "ChangedBody", secret: secret, timestamp: timestamp, body: []byte(`{"id":2}`), now: now, wantErr: ErrInvalidSignature},
+		{name: "ChangedTimestamp", secret: secret, timestamp: strconv.FormatInt(now.Unix()+1, 10), body: body, now: now, wantErr: ErrInvalidSignature},
+		{name: "InvalidTimestamp", secret: secret, timestamp: "now", body: body, now: now, wantErr: ErrInvalidSignature},
+		{name: "Expired", secret: secret, timestamp: timestamp, body: body, now: now.Add(2 * time.Minute), wantErr: ErrExpiredTimestamp},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.secret, tc.timestamp, signature, tc.body, time.Minute, tc.now)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func randomDelivery(url string) db.ClaimWebhookDeliveryRow {
+	return db.ClaimWebhookDeliveryRow{
+		ID:             1,
+		SubscriptionID: 2,
+		Url:            url,
+		Secret:         "secret",
+		EventID:        3,
+		AggregateType:  bank.AggregateTransfer,
+		AggregateID:    "4",
+		EventType:      bank.EventTransferCreated,
+		EventVersion:   bank.TransferCreatedVersion,
+		Payload:        []byte(`{"transfer_id":4}`),
+		CreatedAt:      time.Now().UTC(),
+	}
+}
+
+func TestSenderDeliver(t *testing.T) {
+	testCases := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "OK", status: http.StatusOK},
+		{name: "Rejected", status: http.StatusBadRequest, wantErr: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			var got outbox.Event
+
+			receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				err = Verify("secret", r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body, time.Minute, time.Now())
+				require.NoError(t, err)
+				require.Equal(t, "3", r.Header.Get(EventIDHeader))
+				require.Equal(t, bank.EventTransferCreated, r.Header.Get(EventTypeHeader))
+
+				require.NoError(t, json.Unmarshal(body, &got))
+				w.WriteHeader(tc.status)
+			}))
+			defer receiver.Close()
+
+			// The receiver listens on the host itself, its client dials it
+			status, err := NewSenderClient(receiver.Client()).Deliver(context.Background(), randomDelivery(receiver.URL))
+			require.Equal(t, int32(tc.status), status)
+			require.Equal(t, int64(3), got.ID)
+			require.JSONEq(t, `{"transfer_id":4}`, string(got.Payload))
+
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestSenderDeliverUnreachable(t *testing.T) {
+	receiver := httptest.NewTLSServer(http.NotFoundHandler())
+	receiver.Close()
+
+	status, err := NewSenderClient(receiver.Client()).Deliver(context.Background(), randomDelivery(receiver.URL))
+	require.Error(t, err)
+	require.Zero(t, status)
+}
+
+func TestSenderDeliverRefused(t *testing.T) {
+	called := false
+	receiver := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer receiver.Close()
+
+	// The default sender never dials the host itself
+	status, err := NewSender(time.Second).Deliver(context.Background(), randomDelivery(receiver.URL))
+	require.ErrorIs(t, err, ErrAddressNotAllowed)
+	require.Zero(t, status)
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer plain.Close()
+
+	status, err = NewSenderClient(plain.Client()).Deliver(context.Background(), randomDelivery(plain.URL))
+	require.Error(t, err)
+	require.Zero(t, status)
+	require.False(t, called)
+}
+
+func TestSenderRedirect(t *testing.T) {
+	sender := NewSender(time.Second)
+	require.ErrorIs(t, sender.client.CheckRedirect(nil, nil), http.ErrUseLastResponse)
+}
+
+func TestDialPublic(t *testing.T) {
+	testCases := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "93.184.216.34:443", allowed: true},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443", allowed: true},
+		{address: "127.0.0.1:443"},
+		{address: "[::1]:443"},
+		{address: "10.1.2.3:443"},
+		{address: "172.16.0.1:443"},
+		{address: "192.168.1.1:443"},
+		{address: "[fd00::1]:443"},
+		{address: "169.254.169.254:80"},
+		{address: "[fe80::1]:443"},
+		{address: "0.0.0.0:443"},
+		{address: "[::]:443"},
+		{address: "[::ffff:127.0.0.1]:443"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.address, func(t *testing.T) {
+			err := dialPublic("tcp", tc.address, nil)
+			if tc.allowed {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrAddressNotAllowed)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	policy := bank.RetryPolicy{MaxAttempts: 3, Delay: time.Minute}
+
+	store := mockdb.NewMockBank(ctrl)
+	gomock.InOrder(
+		store.EXPECT().DeliverWebhook(gomock.Any(), gomock.Eq(policy), gomock.Any()).
+			Return(db.WebhookDelivery{Status: bank.WebhookDeliverySucceeded}, nil),
+		store.EXPECT().DeliverWebhook(gomock.Any(), gomock.Eq(policy), gomock.Any()).
+			Return(db.WebhookDelivery{Status: bank.WebhookDeliveryPending}, nil),
+		store.EXPECT().DeliverWebhook(gomock.Any(), gomock.Eq(policy), gomock.Any()).
+			Return(db.WebhookDelivery{Status: bank.WebhookDeliveryDead}, nil),
+		store.EXPECT().DeliverWebhook(gomock.Any(), gomock.Eq(policy), gomock.Any()).
+			Return(db.WebhookDelivery{}, db.ErrRecordNotFound),
+	)
+
+	result, err := Run(context.Background(), store, NewSender(time.Second), policy)
+	require.NoError(t, err)
+	require.Equal(t, RunResult{Delivered: 1, Failed: 1, Dead: 1}, result)
+
+	store.EXPECT().DeliverWebhook(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(db.WebhookDelivery{}, errors.New("db down"))
+
+	_, err = Run(context.Background(), store, NewSender(time.Second), policy)
+	require.Error(t, err)
+}
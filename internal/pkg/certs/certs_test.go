@@ -0,0 +1,206 @@
+package certs
+
+import (
+	"crypto/tls"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/certs/certstest"
+	"github.com/stretchr/testify/require"
+)
+
+// handshake runs a handshake of a client with a server, returning the states of the server and client
+func handshake(
+	t *testing.T,
+	serverConfig *tls.Config,
+	clientConfig *tls.Config,
+) (server tls.ConnectionState, client tls.ConnectionState, err error) {
+	// Connections over loopback rather than a pipe, whose writes block until read, as both ends may
+	// write at once when a handshake fails
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	serverConn, err := listener.Accept()
+	require.NoError(t, err)
+	defer serverConn.Close()
+
+	serverTLS := tls.Server(serverConn, serverConfig)
+	clientTLS := tls.Client(clientConn, clientConfig)
+
+	clientErr := make(chan error, 1)
+	go func() {
+		err := clientTLS.Handshake()
+		// Unblocks the server when the client gives up
+		clientConn.Close()
+		clientErr <- err
+	}()
+
+	serverErr := serverTLS.Handshake()
+	if err := <-clientErr; err != nil {
+		return server, client, err
+	}
+	if serverErr != nil {
+		return server, client, serverErr
+	}
+
+	return serverTLS.ConnectionState(), clientTLS.ConnectionState(), nil
+}
+
+func TestParseVersion(t *testing.T) {
+	version, err := ParseVersion("1.2")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), version)
+
+	version, err = ParseVersion("1.3")
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), version)
+
+	for _, s := range []string{"", "1.1", "TLS1.3"} {
+		_, err := ParseVersion(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "ca")
+
+	certPEM, keyPEM := ca.Issue(t, "server-1")
+	certFile := certstest.WriteFile(t, dir, "server.crt", certPEM)
+	keyFile := certstest.WriteFile(t, dir, "server.key", keyPEM)
+
+	reloader, err := NewReloader(certFile, keyFile, "")
+	require.NoError(t, err)
+
+	serverConfig := reloader.TLSConfig(tls.VersionTLS12)
+	clientConfig := &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"}
+
+	serverCommonName := func() string {
+		_, state, err := handshake(t, serverConfig, clientConfig.Clone())
+		require.NoError(t, err)
+		return state.PeerCertificates[0].Subject.CommonName
+	}
+	require.Equal(t, "server-1", serverCommonName())
+
+	// Changed files are served from the next handshake on
+	certPEM, keyPEM = ca.Issue(t, "server-2")
+	certstest.WriteFile(t, dir, "server.crt", certPEM)
+	certstest.WriteFile(t, dir, "server.key", keyPEM)
+	require.Equal(t, "server-2", serverCommonName())
+
+	// Files that do not load keep the previous certificate
+	certstest.WriteFile(t, dir, "server.key", []byte("half written"))
+	require.Error(t, reloader.Reload())
+	require.Equal(t, "server-2", serverCommonName())
+
+	certstest.WriteFile(t, dir, "server.key", keyPEM)
+	require.NoError(t, reloader.Reload())
+	require.Equal(t, "server-2", serverCommonName())
+}
+
+func TestReloaderMinVersion(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "ca")
+
+	certPEM, keyPEM := ca.Issue(t, "server")
+	reloader, err := NewReloader(
+		certstest.WriteFile(t, dir, "server.crt", certPEM),
+		certstest.WriteFile(t, dir, "server.key", keyPEM),
+		"",
+	)
+	require.NoError(t, err)
+
+	serverConfig := reloader.TLSConfig(tls.VersionTLS13)
+
+	_, _, err = handshake(t, serverConfig, &tls.Config{
+		RootCAs:    ca.Pool(),
+		ServerName: "localhost",
+		MaxVersion: tls.VersionTLS12,
+	})
+	require.Error(t, err)
+
+	state, _, err := handshake(t, serverConfig, &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost"})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), state.Version)
+}
+
+func TestReloaderClientCA(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "ca")
+	clientCA := certstest.NewCA(t, "client-ca")
+
+	certPEM, keyPEM := ca.Issue(t, "server")
+	reloader, err := NewReloader(
+		certstest.WriteFile(t, dir, "server.crt", certPEM),
+		certstest.WriteFile(t, dir, "server.key", keyPEM),
+		certstest.WriteFile(t, dir, "client-ca.crt", clientCA.PEM),
+	)
+	require.NoError(t, err)
+
+	serverConfig := reloader.TLSConfig(tls.VersionTLS12)
+	clientConfig := func(certs ...tls.Certificate) *tls.Config {
+		return &tls.Config{RootCAs: ca.Pool(), ServerName: "localhost", Certificates: certs}
+	}
+
+	// Clients of the client CA are verified
+	state, _, err := handshake(t, serverConfig, clientConfig(clientCA.IssueTLS(t, "payments")))
+	require.NoError(t, err)
+	require.Len(t, state.VerifiedChains, 1)
+	require.Equal(t, "payments", state.VerifiedChains[0][0].Subject.CommonName)
+
+	// Clients without a certificate are not
+	state, _, err = handshake(t, serverConfig, clientConfig())
+	require.NoError(t, err)
+	require.Empty(t, state.VerifiedChains)
+
+	// Clients of other CAs are refused, when they send their certificate anyway
+	refusedConfig := func(cert tls.Certificate) *tls.Config {
+		config := clientConfig()
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return &cert, nil
+		}
+		return config
+	}
+	_, _, err = handshake(t, serverConfig, refusedConfig(ca.IssueTLS(t, "payments")))
+	require.Error(t, err)
+
+	// A replaced client CA verifies clients from the next handshake on
+	otherCA := certstest.NewCA(t, "other-client-ca")
+	certstest.WriteFile(t, dir, "client-ca.crt", otherCA.PEM)
+
+	_, _, err = handshake(t, serverConfig, refusedConfig(clientCA.IssueTLS(t, "payments")))
+	require.Error(t, err)
+
+	state, _, err = handshake(t, serverConfig, clientConfig(otherCA.IssueTLS(t, "payments")))
+	require.NoError(t, err)
+	require.Len(t, state.VerifiedChains, 1)
+}
+
+func TestNewReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := certstest.NewCA(t, "ca")
+
+	certPEM, keyPEM := ca.Issue(t, "server")
+	certFile := certstest.WriteFile(t, dir, "server.crt", certPEM)
+	keyFile := certstest.WriteFile(t, dir, "server.key", keyPEM)
+
+	_, err := NewReloader(certFile, "", "")
+	require.Error(t, err)
+
+	_, err = NewReloader(certFile, filepath.Join(dir, "missing.key"), "")
+	require.Error(t, err)
+
+	// The key must be of the certificate
+	_, otherKeyPEM := ca.Issue(t, "other")
+	_, err = NewReloader(certFile, certstest.WriteFile(t, dir, "other.key", otherKeyPEM), "")
+	require.Error(t, err)
+
+	_, err = NewReloader(certFile, keyFile, certstest.WriteFile(t, dir, "client-ca.crt", []byte("no certificates")))
+	require.Error(t, err)
+}
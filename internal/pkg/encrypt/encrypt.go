@@ -0,0 +1,67 @@
+// Package encrypt encrypts secrets at rest, e.g. the TOTP secrets of users, with AES-256-GCM.
+//
+// A ciphertext is the random nonce followed by the sealed plaintext. Additional data, e.g. the owner of a
+// secret, is authenticated but not stored, so a ciphertext only decrypts with the data it was encrypted with.
+package encrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the size of a key, in bytes
+const KeySize = 32
+
+var ErrInvalidCiphertext = errors.New("ciphertext is invalid or was encrypted with another key or data")
+
+// Cipher encrypts and decrypts with a symmetric key
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// NewCipher creates a cipher with the key, of exactly KeySize bytes.
+func NewCipher(key string) (*Cipher, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size: must be exactly %d characters", KeySize)
+	}
+
+	block, err := aes.NewCipher([]byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cipher{aead: aead}, nil
+}
+
+// Encrypt encrypts the plaintext, authenticating the additional data.
+func (c *Cipher) Encrypt(plaintext []byte, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// Decrypt decrypts a ciphertext encrypted with the key and the additional data.
+func (c *Cipher) Decrypt(ciphertext []byte, additionalData []byte) ([]byte, error) {
+	size := c.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := c.aead.Open(nil, ciphertext[:size], ciphertext[size:], additionalData)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,61 @@
+package encrypt
+
+import (
+	"testing"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCipher(t *testing.T) {
+	c, err := NewCipher(random.String(KeySize))
+	require.NoError(t, err)
+
+	plaintext := []byte(random.String(20))
+	data := []byte(random.Owner())
+
+	ciphertext, err := c.Encrypt(plaintext, data)
+	require.NoError(t, err)
+	require.NotContains(t, string(ciphertext), string(plaintext))
+
+	again, err := c.Encrypt(plaintext, data)
+	require.NoError(t, err)
+	require.NotEqual(t, ciphertext, again)
+
+	got, err := c.Decrypt(ciphertext, data)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, got)
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	c, err := NewCipher(random.String(KeySize))
+	require.NoError(t, err)
+
+	ciphertext, err := c.Encrypt([]byte("secret"), []byte("alice"))
+	require.NoError(t, err)
+
+	_, err = c.Decrypt(ciphertext, []byte("bob"))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	other, err := NewCipher(random.String(KeySize))
+	require.NoError(t, err)
+
+	_, err = other.Decrypt(ciphertext, []byte("alice"))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = c.Decrypt(tampered, []byte("alice"))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = c.Decrypt([]byte("short"), []byte("alice"))
+	require.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestInvalidKeySize(t *testing.T) {
+	_, err := NewCipher(random.String(KeySize - 1))
+	require.Error(t, err)
+
+	_, err = NewCipher(random.String(KeySize + 1))
+	require.Error(t, err)
+}
@@ -0,0 +1,123 @@
+// Package interest computes daily interest accruals under a day count basis, and rounds
+// accrued amounts to whole minor units of a currency.
+//
+// Amounts are integers in minor units, e.g. cents, and accruals are kept in micros,
+// millionths of a minor unit, so that small daily amounts add up without losing precision.
+package interest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// MicrosPerUnit is the number of micros in a minor unit.
+const MicrosPerUnit = 1_000_000
+
+// ErrUnknownBasis is returned when a day count basis is not supported.
+var ErrUnknownBasis = errors.New("unknown day count basis")
+
+// ErrUnknownRounding is returned when a rounding mode is not supported.
+var ErrUnknownRounding = errors.New("unknown rounding mode")
+
+// Basis is a day count convention, deciding the fraction of the annual rate accrued per day.
+type Basis string
+
+const (
+	// Actual365 accrues 1/365 of the annual rate every calendar day, also in leap years.
+	Actual365 Basis = "ACT/365"
+	// Thirty360 accrues as if every month had 30 days and the year 360 days. The 31st of a
+	// month accrues nothing and the last day of february accrues the missing days up to 30.
+	Thirty360 Basis = "30/360"
+)
+
+// ParseBasis parses a day count basis, ACT/365 or 30/360.
+func ParseBasis(s string) (Basis, error) {
+	switch b := Basis(s); b {
+	case Actual365, Thirty360:
+		return b, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownBasis, s)
+	}
+}
+
+// days returns the number of days accrued on day and the number of days in a year.
+func (b Basis) days(day time.Time) (accrued int64, year int64) {
+	if b != Thirty360 {
+		return 1, 365
+	}
+
+	last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+
+	switch {
+	case day.Day() == 31:
+		return 0, 360
+	case day.Month() == time.February && day.Day() == last:
+		return int64(30 - last + 1), 360
+	default:
+		return 1, 360
+	}
+}
+
+// Rounding decides how accrued micros are rounded to whole minor units.
+type Rounding string
+
+const (
+	// HalfUp rounds to the nearest unit, halves away from zero.
+	HalfUp Rounding = "half-up"
+	// HalfEven rounds to the nearest unit, halves to the even unit (bankers rounding).
+	HalfEven Rounding = "half-even"
+	// Down truncates towards zero.
+	Down Rounding = "down"
+)
+
+// ParseRounding parses a rounding mode, half-up, half-even or down.
+func ParseRounding(s string) (Rounding, error) {
+	switch r := Rounding(s); r {
+	case HalfUp, HalfEven, Down:
+		return r, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnknownRounding, s)
+	}
+}
+
+// Round rounds micros to whole minor units.
+func (r Rounding) Round(micros int64) int64 {
+	units, rest := micros/MicrosPerUnit, micros%MicrosPerUnit
+
+	sign := int64(1)
+	if micros < 0 {
+		sign, rest = -1, -rest
+	}
+
+	switch {
+	case r == Down || rest < MicrosPerUnit/2:
+		return units
+	case rest > MicrosPerUnit/2 || r == HalfUp:
+		return units + sign
+	case units%2 != 0:
+		// Exactly half, half-even rounds to the even unit
+		return units + sign
+	default:
+		return units
+	}
+}
+
+// DailyMicros returns the interest in micros accrued on day for an end of day balance at an
+// annual rate in basis points. Negative balances accrue no interest.
+func DailyMicros(balance int64, rateBps int32, day time.Time, basis Basis) int64 {
+	if balance <= 0 || rateBps <= 0 {
+		return 0
+	}
+
+	accrued, year := basis.days(day)
+
+	// balance * rate / 10000 * accrued / year in micros, big ints avoid overflow of large balances
+	micros := new(big.Int).SetInt64(balance)
+	micros.Mul(micros, big.NewInt(int64(rateBps)))
+	micros.Mul(micros, big.NewInt(accrued*MicrosPerUnit))
+	micros.Quo(micros, big.NewInt(10000*year))
+
+	return micros.Int64()
+}
@@ -0,0 +1,153 @@
+//go:build !integration
+
+package interest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDailyMicros(t *testing.T) {
+	testCases := []struct {
+		name     string
+		balance  int64
+		rateBps  int32
+		day      time.Time
+		basis    Basis
+		expected int64
+	}{
+		{
+			name:     "Actual365",
+			balance:  365_000,
+			rateBps:  100,
+			day:      day("2023-10-31"),
+			basis:    Actual365,
+			expected: 10 * MicrosPerUnit,
+		},
+		{
+			name:     "Actual365Fraction",
+			balance:  1000,
+			rateBps:  150,
+			day:      day("2023-10-12"),
+			basis:    Actual365,
+			expected: 41095,
+		},
+		{
+			name:     "Thirty360",
+			balance:  360_000,
+			rateBps:  100,
+			day:      day("2023-10-12"),
+			basis:    Thirty360,
+			expected: 10 * MicrosPerUnit,
+		},
+		{
+			name:     "Thirty360ThirtyFirst",
+			balance:  360_000,
+			rateBps:  100,
+			day:      day("2023-10-31"),
+			basis:    Thirty360,
+			expected: 0,
+		},
+		{
+			name:     "Thirty360EndOfFebruary",
+			balance:  360_000,
+			rateBps:  100,
+			day:      day("2023-02-28"),
+			basis:    Thirty360,
+			expected: 30 * MicrosPerUnit,
+		},
+		{
+			name:     "Thirty360EndOfFebruaryLeapYear",
+			balance:  360_000,
+			rateBps:  100,
+			day:      day("2024-02-29"),
+			basis:    Thirty360,
+			expected: 20 * MicrosPerUnit,
+		},
+		{
+			name:     "NegativeBalance",
+			balance:  -365_000,
+			rateBps:  100,
+			day:      day("2023-10-12"),
+			basis:    Actual365,
+			expected: 0,
+		},
+		{
+			name:     "LargeBalance",
+			balance:  1_000_000_000_000_000,
+			rateBps:  10000,
+			day:      day("2023-10-12"),
+			basis:    Actual365,
+			expected: 2739726027397260273,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, DailyMicros(tc.balance, tc.rateBps, tc.day, tc.basis))
+		})
+	}
+}
+
+func TestThirty360Month(t *testing.T) {
+	// Every month accrues 30 days
+	for month := time.January; month <= time.December; month++ {
+		var micros int64
+		for d := time.Date(2023, month, 1, 0, 0, 0, 0, time.UTC); d.Month() == month; d = d.AddDate(0, 0, 1) {
+			micros += DailyMicros(360_000, 100, d, Thirty360)
+		}
+		require.Equal(t, int64(300*MicrosPerUnit), micros, month.String())
+	}
+}
+
+func TestRound(t *testing.T) {
+	testCases := []struct {
+		micros   int64
+		halfUp   int64
+		halfEven int64
+		down     int64
+	}{
+		{micros: 0, halfUp: 0, halfEven: 0, down: 0},
+		{micros: 1_499_999, halfUp: 1, halfEven: 1, down: 1},
+		{micros: 1_500_000, halfUp: 2, halfEven: 2, down: 1},
+		{micros: 2_500_000, halfUp: 3, halfEven: 2, down: 2},
+		{micros: 2_500_001, halfUp: 3, halfEven: 3, down: 2},
+		{micros: -2_500_000, halfUp: -3, halfEven: -2, down: -2},
+		{micros: -1_500_000, halfUp: -2, halfEven: -2, down: -1},
+		{micros: 999_999, halfUp: 1, halfEven: 1, down: 0},
+	}
+
+	for _, tc := range testCases {
+		require.Equal(t, tc.halfUp, HalfUp.Round(tc.micros), "half-up %d", tc.micros)
+		require.Equal(t, tc.halfEven, HalfEven.Round(tc.micros), "half-even %d", tc.micros)
+		require.Equal(t, tc.down, Down.Round(tc.micros), "down %d", tc.micros)
+	}
+}
+
+func TestParse(t *testing.T) {
+	basis, err := ParseBasis("30/360")
+	require.NoError(t, err)
+	require.Equal(t, Thirty360, basis)
+
+	_, err = ParseBasis("ACT/360")
+	require.ErrorIs(t, err, ErrUnknownBasis)
+
+	rounding, err := ParseRounding("half-even")
+	require.NoError(t, err)
+	require.Equal(t, HalfEven, rounding)
+
+	_, err = ParseRounding("up")
+	require.ErrorIs(t, err, ErrUnknownRounding)
+}
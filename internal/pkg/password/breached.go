@@ -0,0 +1,90 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// rangePrefixLength is the length of the hex prefix of hashes grouping them into ranges
+const rangePrefixLength = 5
+
+// BreachedList holds the SHA-1 hashes of breached passwords, grouped into ranges by their first five hex
+// digits like the k-anonymity range API of Have I Been Pwned. The list is read from a local file, so
+// passwords are checked without asking an external service.
+type BreachedList struct {
+	// Sorted hash suffixes by hash prefix
+	ranges map[string][]string
+	size   int
+}
+
+// LoadBreachedList reads the breached list from the file at path, see ReadBreachedList.
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached passwords: %w", err)
+	}
+	defer f.Close()
+
+	return ReadBreachedList(f)
+}
+
+// ReadBreachedList reads a breached list of a SHA-1 hash in hex per line, optionally followed by a colon and
+// the number of breaches, like the downloads of Have I Been Pwned. Empty lines and lines starting with # are
+// skipped.
+func ReadBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{ranges: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(text, ":")
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 2*sha1.Size {
+			return nil, fmt.Errorf("breached passwords line %d: not a SHA-1 hash", line)
+		}
+
+		hash = strings.ToUpper(hash)
+		prefix := hash[:rangePrefixLength]
+		list.ranges[prefix] = append(list.ranges[prefix], hash[rangePrefixLength:])
+		list.size++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read breached passwords: %w", err)
+	}
+
+	for _, suffixes := range list.ranges {
+		sort.Strings(suffixes)
+	}
+
+	return list, nil
+}
+
+// Len returns the number of hashes in the list
+func (l *BreachedList) Len() int {
+	return l.size
+}
+
+// Range returns the sorted suffixes of the hashes with the prefix of five hex digits.
+func (l *BreachedList) Range(prefix string) []string {
+	return l.ranges[strings.ToUpper(prefix)]
+}
+
+// Contains tells whether the password is in the list, by looking up the suffix of its hash in the range of
+// its prefix.
+func (l *BreachedList) Contains(password string) bool {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes := l.Range(hash[:rangePrefixLength])
+	i := sort.SearchStrings(suffixes, hash[rangePrefixLength:])
+	return i < len(suffixes) && suffixes[i] == hash[rangePrefixLength:]
+}
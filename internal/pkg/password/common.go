@@ -0,0 +1,19 @@
+package password
+
+// commonPasswords are among the most common passwords in breaches, most common first, and words common in
+// passwords of a bank
+var commonPasswords = []string{
+	"123456", "password", "123456789", "12345678", "12345", "qwerty", "1234567", "111111", "1234567890",
+	"123123", "abc123", "1234", "password1", "iloveyou", "1q2w3e4r", "000000", "qwerty123", "zaq12wsx",
+	"dragon", "sunshine", "princess", "letmein", "654321", "monkey", "27653", "1qaz2wsx", "123321",
+	"qwertyuiop", "superman", "asdfghjkl", "trustno1", "welcome", "football", "baseball", "master", "shadow",
+	"michael", "jennifer", "hunter", "jordan", "harley", "ranger", "buster", "soccer", "hockey", "killer",
+	"george", "charlie", "andrew", "thomas", "daniel", "robert", "jessica", "pepper", "freedom", "whatever",
+	"access", "login", "admin", "administrator", "passw0rd", "starwars", "batman", "hello", "secret", "summer",
+	"winter", "spring", "autumn", "flower", "computer", "internet", "cheese", "banana", "orange", "purple",
+	"maggie", "ginger", "tigger", "cookie", "chocolate", "mustang", "corvette", "ferrari", "porsche", "bailey",
+	"silver", "golden", "diamond", "matrix", "samsung", "apple", "google", "facebook", "yankees", "dallas",
+	"london", "paris", "berlin", "america", "welcome1", "changeme", "default", "guest", "test", "root", "user",
+	"demo", "money", "bank", "banking", "account", "credit", "love", "lovely", "angel", "angels", "friends",
+	"family", "forever", "blessed", "jesus", "christ",
+}
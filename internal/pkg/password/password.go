@@ -1,21 +1,312 @@
+// Package password hashes passwords of users.
+//
+// Hashes are PHC strings naming their algorithm and parameters, argon2id e.g.
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>, or bcrypt hashes such as those from before argon2id. Any
+// hash is checked with the parameters it names, and NeedsRehash tells hashes from other parameters apart, so
+// they can be replaced at the next login.
+//
+// An optional pepper, a secret kept out of the database, is mixed into argon2id hashes with HMAC-SHA256. The
+// hash records an id of the pepper as keyid parameter.
 package password
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
+	"golang.org/x/crypto/argon2"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword returns the bcrypt hash of the password
+// Algorithms of new hashes
+const (
+	Argon2id = "argon2id"
+	Bcrypt   = "bcrypt"
+)
+
+const (
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+	// bcrypt ignores passwords beyond this length
+	bcryptMaxLength = 72
+)
+
+var (
+	ErrMismatchedPassword = errors.New("password does not match")
+	ErrUnknownHash        = errors.New("unknown password hash format")
+	ErrUnknownPepper      = errors.New("password hash was peppered with another pepper")
+	ErrPasswordTooLong    = fmt.Errorf("password is longer than %d bytes, the maximum of bcrypt", bcryptMaxLength)
+)
+
+// Params are the parameters of new hashes
+type Params struct {
+	// Argon2id or Bcrypt
+	Algorithm string
+	// Memory in KiB, iterations and parallelism of argon2id
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+	// Secret mixed into argon2id hashes, none when empty
+	Pepper string
+}
+
+// DefaultParams returns argon2id with the minimum parameters recommended by OWASP, without a pepper.
+func DefaultParams() Params {
+	return Params{
+		Algorithm:         Argon2id,
+		Argon2Memory:      19 * 1024,
+		Argon2Iterations:  2,
+		Argon2Parallelism: 1,
+		BcryptCost:        bcrypt.DefaultCost,
+	}
+}
+
+// Hasher hashes passwords with its parameters, and checks hashes of any parameters
+type Hasher struct {
+	params Params
+	// Id of the pepper recorded in hashes, empty without a pepper
+	pepperID string
+}
+
+// NewHasher creates a hasher of new hashes with the params.
+func NewHasher(params Params) (*Hasher, error) {
+	switch params.Algorithm {
+	case Argon2id:
+		if params.Argon2Memory < 8*uint32(params.Argon2Parallelism) || params.Argon2Iterations < 1 || params.Argon2Parallelism < 1 {
+			return nil, fmt.Errorf("invalid argon2id parameters m=%d,t=%d,p=%d", params.Argon2Memory, params.Argon2Iterations, params.Argon2Parallelism)
+		}
+	case Bcrypt:
+		if params.BcryptCost < bcrypt.MinCost || params.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("invalid bcrypt cost %d", params.BcryptCost)
+		}
+		// The bcrypt format has no room to record a pepper
+		if params.Pepper != "" {
+			return nil, fmt.Errorf("pepper requires algorithm %s", Argon2id)
+		}
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", params.Algorithm)
+	}
+
+	hasher := &Hasher{params: params}
+	if params.Pepper != "" {
+		sum := sha256.Sum256([]byte("pepper:" + params.Pepper))
+		hasher.pepperID = base64.RawStdEncoding.EncodeToString(sum[:6])
+	}
+
+	return hasher, nil
+}
+
+// defaultHasher hashes with DefaultParams
+var defaultHasher, _ = NewHasher(DefaultParams())
+
+// HashPassword returns the hash of the password with DefaultParams
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+	return defaultHasher.Hash(password)
+}
+
+// CheckPassword checks if the provided password is correct or not, for hashes without a pepper
+func CheckPassword(password string, hashedPassword string) error {
+	return defaultHasher.Check(password, hashedPassword)
+}
+
+// Hash returns the PHC string of a hash of the password with the params of the hasher.
+func (h *Hasher) Hash(password string) (string, error) {
+	if h.params.Algorithm == Bcrypt {
+		if len(password) > bcryptMaxLength {
+			return "", ErrPasswordTooLong
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), h.params.BcryptCost)
+		if err != nil {
+			return "", fmt.Errorf("failed to hash password: %w", err)
+		}
+		return string(hashedPassword), nil
+	}
+
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
-	return string(hashedPassword), nil
+
+	hash := argon2Hash{
+		memory:      h.params.Argon2Memory,
+		iterations:  h.params.Argon2Iterations,
+		parallelism: h.params.Argon2Parallelism,
+		keyID:       h.pepperID,
+		salt:        salt,
+	}
+	hash.key = hash.derive(h.pepper(password, hash.keyID))
+
+	return hash.String(), nil
 }
 
-// CheckPassword checks if the provided password is correct or not
-func CheckPassword(password string, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+// Check returns ErrMismatchedPassword unless the hash is of the password, with the parameters in the hash.
+func (h *Hasher) Check(password string, hashedPassword string) error {
+	if isBcrypt(hashedPassword) {
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return ErrMismatchedPassword
+		}
+		return err
+	}
+
+	hash, err := parseArgon2Hash(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	if hash.keyID != "" && hash.keyID != h.pepperID {
+		return ErrUnknownPepper
+	}
+
+	key := hash.derive(h.pepper(password, hash.keyID))
+	if subtle.ConstantTimeCompare(key, hash.key) != 1 {
+		return ErrMismatchedPassword
+	}
+
+	return nil
+}
+
+// NeedsRehash tells whether the hash is of another algorithm, other parameters or another pepper than new
+// hashes of the hasher, so it should be replaced once the password is known.
+func (h *Hasher) NeedsRehash(hashedPassword string) bool {
+	if isBcrypt(hashedPassword) {
+		if h.params.Algorithm != Bcrypt {
+			return true
+		}
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		return err != nil || cost != h.params.BcryptCost
+	}
+
+	hash, err := parseArgon2Hash(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return h.params.Algorithm != Argon2id ||
+		hash.memory != h.params.Argon2Memory ||
+		hash.iterations != h.params.Argon2Iterations ||
+		hash.parallelism != h.params.Argon2Parallelism ||
+		hash.keyID != h.pepperID ||
+		len(hash.key) != argon2KeySize
+}
+
+// pepper mixes the pepper into the password when the hash records a pepper id.
+func (h *Hasher) pepper(password string, keyID string) []byte {
+	if keyID == "" {
+		return []byte(password)
+	}
+
+	mac := hmac.New(sha256.New, []byte(h.params.Pepper))
+	mac.Write([]byte(password))
+	return mac.Sum(nil)
+}
+
+func isBcrypt(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+// argon2Hash is an argon2id hash and its parameters
+type argon2Hash struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	keyID       string
+	salt        []byte
+	key         []byte
+}
+
+func (a argon2Hash) derive(password []byte) []byte {
+	keySize := uint32(len(a.key))
+	if keySize == 0 {
+		keySize = argon2KeySize
+	}
+	return argon2.IDKey(password, a.salt, a.iterations, a.memory, a.parallelism, keySize)
+}
+
+// String returns the PHC string of the hash
+func (a argon2Hash) String() string {
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", a.memory, a.iterations, a.parallelism)
+	if a.keyID != "" {
+		params += ",keyid=" + a.keyID
+	}
+
+	return fmt.Sprintf("$%s$v=%d$%s$%s$%s",
+		Argon2id,
+		argon2.Version,
+		params,
+		base64.RawStdEncoding.EncodeToString(a.salt),
+		base64.RawStdEncoding.EncodeToString(a.key),
+	)
+}
+
+func parseArgon2Hash(hashedPassword string) (argon2Hash, error) {
+	var hash argon2Hash
+
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != Argon2id || parts[2] != fmt.Sprintf("v=%d", argon2.Version) {
+		return hash, ErrUnknownHash
+	}
+
+	for _, param := range strings.Split(parts[3], ",") {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok {
+			return hash, ErrUnknownHash
+		}
+
+		var err error
+		switch name {
+		case "m":
+			hash.memory, err = parseUint32(value)
+		case "t":
+			hash.iterations, err = parseUint32(value)
+		case "p":
+			var p uint64
+			p, err = strconv.ParseUint(value, 10, 8)
+			hash.parallelism = uint8(p)
+		case "keyid":
+			hash.keyID = value
+		default:
+			err = ErrUnknownHash
+		}
+		if err != nil {
+			return hash, ErrUnknownHash
+		}
+	}
+
+	if hash.memory == 0 || hash.iterations == 0 || hash.parallelism == 0 {
+		return hash, ErrUnknownHash
+	}
+
+	var err error
+	if hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return hash, ErrUnknownHash
+	}
+	if hash.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(hash.key) == 0 {
+		return hash, ErrUnknownHash
+	}
+
+	return hash, nil
+}
+
+func parseUint32(value string) (uint32, error) {
+	n, err := strconv.ParseUint(value, 10, 32)
+	return uint32(n), err
+}
+
+// HashToken returns the hex encoded sha256 hash of a random token, e.g. a password reset token. Unlike a
+// password a random token is hard to guess, so its hash needs no salt and can be looked up.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
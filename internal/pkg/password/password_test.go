@@ -0,0 +1,170 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams are cheap argon2id parameters
+func testParams() Params {
+	return Params{
+		Algorithm:         Argon2id,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        bcrypt.MinCost,
+	}
+}
+
+func newTestHasher(t *testing.T, change func(params *Params)) *Hasher {
+	params := testParams()
+	if change != nil {
+		change(&params)
+	}
+
+	hasher, err := NewHasher(params)
+	require.NoError(t, err)
+	return hasher
+}
+
+func TestArgon2id(t *testing.T) {
+	hasher := newTestHasher(t, nil)
+	password := random.String(10)
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$argon2id$v=19$m=64,t=1,p=1$"), hashedPassword)
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.ErrorIs(t, hasher.Check(random.String(10), hashedPassword), ErrMismatchedPassword)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// Each hash has its own salt
+	again, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.NotEqual(t, hashedPassword, again)
+
+	// Hashes are checked with their own parameters
+	require.NoError(t, CheckPassword(password, hashedPassword))
+}
+
+func TestLongPassword(t *testing.T) {
+	hasher := newTestHasher(t, nil)
+	password := strings.Repeat("a", 100)
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+
+	// Unlike bcrypt, argon2id does not truncate passwords
+	require.ErrorIs(t, hasher.Check(strings.Repeat("a", 90), hashedPassword), ErrMismatchedPassword)
+
+	_, err = newTestHasher(t, func(params *Params) { params.Algorithm = Bcrypt }).Hash(password)
+	require.ErrorIs(t, err, ErrPasswordTooLong)
+}
+
+func TestBcrypt(t *testing.T) {
+	hasher := newTestHasher(t, func(params *Params) { params.Algorithm = Bcrypt })
+	password := random.String(10)
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(hashedPassword, "$2a$"))
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.ErrorIs(t, hasher.Check(random.String(10), hashedPassword), ErrMismatchedPassword)
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	require.True(t, newTestHasher(t, func(params *Params) {
+		params.Algorithm = Bcrypt
+		params.BcryptCost = bcrypt.MinCost + 1
+	}).NeedsRehash(hashedPassword))
+
+	// Hashes from before argon2id are checked, and replaced at the next login
+	argon2Hasher := newTestHasher(t, nil)
+	require.NoError(t, argon2Hasher.Check(password, hashedPassword))
+	require.True(t, argon2Hasher.NeedsRehash(hashedPassword))
+}
+
+func TestPepper(t *testing.T) {
+	pepper := random.String(32)
+	hasher := newTestHasher(t, func(params *Params) { params.Pepper = pepper })
+	password := random.String(10)
+
+	hashedPassword, err := hasher.Hash(password)
+	require.NoError(t, err)
+	require.Contains(t, hashedPassword, ",keyid=")
+	require.NotContains(t, hashedPassword, pepper)
+
+	require.NoError(t, hasher.Check(password, hashedPassword))
+	require.False(t, hasher.NeedsRehash(hashedPassword))
+
+	// Without the pepper the hash can not be checked
+	require.ErrorIs(t, CheckPassword(password, hashedPassword), ErrUnknownPepper)
+	other := newTestHasher(t, func(params *Params) { params.Pepper = random.String(32) })
+	require.ErrorIs(t, other.Check(password, hashedPassword), ErrUnknownPepper)
+	require.True(t, other.NeedsRehash(hashedPassword))
+
+	// Hashes without a pepper are checked, and get the pepper at the next login
+	unpeppered, err := newTestHasher(t, nil).Hash(password)
+	require.NoError(t, err)
+	require.NoError(t, hasher.Check(password, unpeppered))
+	require.True(t, hasher.NeedsRehash(unpeppered))
+
+	_, err = NewHasher(Params{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost, Pepper: pepper})
+	require.Error(t, err)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hashedPassword, err := newTestHasher(t, nil).Hash(random.String(10))
+	require.NoError(t, err)
+
+	for _, change := range []func(params *Params){
+		func(params *Params) { params.Argon2Memory *= 2 },
+		func(params *Params) { params.Argon2Iterations++ },
+		func(params *Params) { params.Argon2Parallelism++ },
+		func(params *Params) { params.Algorithm = Bcrypt },
+	} {
+		require.True(t, newTestHasher(t, change).NeedsRehash(hashedPassword))
+	}
+}
+
+func TestInvalidHash(t *testing.T) {
+	hasher := newTestHasher(t, nil)
+
+	for _, hashedPassword := range []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=16$m=64,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=64,t=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=64,t=1,p=1,x=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=64,t=1,p=1$c2FsdHNhbHQ$",
+		"$argon2id$v=19$m=64,t=1,p=1$!$aGFzaGhhc2g",
+	} {
+		require.ErrorIs(t, hasher.Check("secret", hashedPassword), ErrUnknownHash, hashedPassword)
+		require.True(t, hasher.NeedsRehash(hashedPassword), hashedPassword)
+	}
+}
+
+func TestNewHasher(t *testing.T) {
+	for _, change := range []func(params *Params){
+		func(params *Params) { params.Algorithm = "scrypt" },
+		func(params *Params) { params.Argon2Iterations = 0 },
+		func(params *Params) { params.Argon2Parallelism = 0 },
+		func(params *Params) { params.Argon2Memory = 4 },
+		func(params *Params) {
+			params.Algorithm = Bcrypt
+			params.BcryptCost = bcrypt.MaxCost + 1
+		},
+	} {
+		params := testParams()
+		change(&params)
+
+		_, err := NewHasher(params)
+		require.Error(t, err)
+	}
+}
@@ -0,0 +1,103 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rules of a password policy
+const (
+	RuleMinLength        = "min_length"
+	RuleMaxLength        = "max_length"
+	RuleStrength         = "strength"
+	RuleContainsUsername = "contains_username"
+	RuleContainsEmail    = "contains_email"
+	RuleBreached         = "breached"
+)
+
+var ErrPolicyViolation = errors.New("password violates the password policy")
+
+// Policy decides which new passwords users may choose. Zero lengths and scores are not enforced, neither
+// are breached passwords without a list.
+type Policy struct {
+	// Lengths in characters
+	MinLength int
+	MaxLength int
+	// Minimum score of Strength, from ScoreTooGuessable to ScoreVeryUnguessable
+	MinScore int
+	Breached *BreachedList
+}
+
+// Identity is what is known about the user of a password, that the password must not contain
+type Identity struct {
+	Username string
+	Email    string
+}
+
+// Violation is a rule of the policy broken by a password
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError is returned when a password breaks rules of the policy, it matches ErrPolicyViolation.
+type PolicyError struct {
+	Violations []Violation `json:"violations"`
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return fmt.Sprintf("%v: %s", ErrPolicyViolation, strings.Join(messages, "; "))
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyViolation
+}
+
+// Validate returns a *PolicyError with every rule of the policy the password of the user breaks.
+func (p Policy) Validate(password string, user Identity) error {
+	var violations []Violation
+	violate := func(rule string, format string, args ...any) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.MinLength > 0 && length < p.MinLength {
+		violate(RuleMinLength, "must be at least %d characters", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violate(RuleMaxLength, "must be at most %d characters", p.MaxLength)
+	}
+
+	lower := strings.ToLower(password)
+	username := strings.ToLower(user.Username)
+	email := strings.ToLower(user.Email)
+	localPart, _, _ := strings.Cut(email, "@")
+
+	if len(username) >= 3 && strings.Contains(lower, username) {
+		violate(RuleContainsUsername, "must not contain the username")
+	}
+	if email != "" && (strings.Contains(lower, email) || len(localPart) >= 3 && strings.Contains(lower, localPart)) {
+		violate(RuleContainsEmail, "must not contain the email")
+	}
+
+	if p.MinScore > 0 {
+		if score, _ := Strength(password, user.Username, localPart); score < p.MinScore {
+			violate(RuleStrength, "is too easy to guess, strength %d of %d is below %d", score, ScoreVeryUnguessable, p.MinScore)
+		}
+	}
+
+	if p.Breached != nil && p.Breached.Contains(password) {
+		violate(RuleBreached, "appeared in a data breach")
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &PolicyError{Violations: violations}
+}
@@ -0,0 +1,91 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func sha1Hex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestPolicyValidate(t *testing.T) {
+	breached, err := ReadBreachedList(strings.NewReader(fmt.Sprintf("%s:3861493\n", strings.ToUpper(sha1Hex("Tr0ub4dor&3")))))
+	require.NoError(t, err)
+
+	policy := Policy{MinLength: 8, MaxLength: 64, MinScore: ScoreSomewhatGuessable, Breached: breached}
+	user := Identity{Username: "johnsmith", Email: "john.smith@example.com"}
+
+	testCases := []struct {
+		name     string
+		password string
+		rules    []string
+	}{
+		{"OK", "xk8#Lq2!vz", nil},
+		{"TooShort", "xk8#Lq", []string{RuleMinLength}},
+		{"TooLong", strings.Repeat("xk8#Lq2!vz", 7), []string{RuleMaxLength}},
+		{"TooGuessable", "password1", []string{RuleStrength}},
+		{"ContainsUsername", "xk8#JohnSmith", []string{RuleContainsUsername, RuleStrength}},
+		{"ContainsEmail", "xk8#john.smith", []string{RuleContainsEmail, RuleStrength}},
+		{"Breached", "Tr0ub4dor&3", []string{RuleBreached}},
+		{"Several", "johnsmith", []string{RuleContainsUsername, RuleStrength}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Validate(tc.password, user)
+			if tc.rules == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.ErrorIs(t, err, ErrPolicyViolation)
+
+			var policyErr *PolicyError
+			require.ErrorAs(t, err, &policyErr)
+
+			rules := make([]string, 0, len(policyErr.Violations))
+			for _, v := range policyErr.Violations {
+				require.NotEmpty(t, v.Message)
+				rules = append(rules, v.Rule)
+			}
+			require.Equal(t, tc.rules, rules)
+		})
+	}
+
+	// The zero policy enforces nothing
+	require.NoError(t, Policy{}.Validate("a", user))
+}
+
+func TestBreachedList(t *testing.T) {
+	input := strings.Join([]string{
+		"# breached passwords",
+		strings.ToUpper(sha1Hex("password")) + ":9545824",
+		"",
+		sha1Hex("123456"),
+	}, "\n")
+
+	list, err := ReadBreachedList(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Equal(t, 2, list.Len())
+
+	require.True(t, list.Contains("password"))
+	require.True(t, list.Contains("123456"))
+	require.False(t, list.Contains("Password"))
+
+	// A range holds the suffixes of the hashes with its prefix
+	hash := strings.ToUpper(sha1Hex("password"))
+	require.Equal(t, []string{hash[5:]}, list.Range(hash[:5]))
+
+	_, err = ReadBreachedList(strings.NewReader("password:1\n"))
+	require.Error(t, err)
+
+	_, err = LoadBreachedList("does-not-exist.txt")
+	require.Error(t, err)
+}
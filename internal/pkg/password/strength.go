@@ -0,0 +1,279 @@
+package password
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Scores of Strength, like those of zxcvbn
+const (
+	ScoreTooGuessable      = 0
+	ScoreVeryGuessable     = 1
+	ScoreSomewhatGuessable = 2
+	ScoreSafelyUnguessable = 3
+	ScoreVeryUnguessable   = 4
+)
+
+// Guesses, in log10, from which each score is given
+var scoreThresholds = []float64{3, 6, 8, 10}
+
+// Strength estimates the guesses, in log10, an attacker needs for the password, and scores it from
+// ScoreTooGuessable to ScoreVeryUnguessable. Like zxcvbn it splits the password into the cheapest sequence of
+// patterns: common passwords, the user inputs, e.g. the username, keyboard walks, repeats, sequences, years,
+// and otherwise brute force of 10 guesses per character.
+func Strength(password string, userInputs ...string) (score int, guesses float64) {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return ScoreTooGuessable, 0
+	}
+
+	matches := findMatches(runes, userInputs)
+
+	// best[j] is the fewest guesses of the first j runes, found from left to right
+	best := make([]float64, len(runes)+1)
+	for j := 1; j <= len(runes); j++ {
+		best[j] = best[j-1] + 1
+		for _, m := range matches {
+			if m.j == j && best[m.i]+m.guesses < best[j] {
+				best[j] = best[m.i] + m.guesses
+			}
+		}
+	}
+
+	guesses = best[len(runes)]
+	for _, threshold := range scoreThresholds {
+		if guesses < threshold {
+			break
+		}
+		score++
+	}
+
+	return score, guesses
+}
+
+// match is a pattern of the runes from i up to j, guessed in the log10 guesses
+type match struct {
+	i, j    int
+	guesses float64
+}
+
+func findMatches(runes []rune, userInputs []string) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, userInputs)...)
+	matches = append(matches, repeatMatches(runes)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, keyboardMatches(runes)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+// l33t substitutions undone before looking up words
+var unl33t = map[rune]rune{'4': 'a', '@': 'a', '8': 'b', '3': 'e', '6': 'g', '1': 'i', '!': 'i', '0': 'o', '$': 's', '5': 's', '7': 't', '2': 'z'}
+
+// dictionaryMatches finds common passwords and user inputs, also reversed or with l33t substitutions. A
+// word is guessed by its rank, the user inputs coming first.
+func dictionaryMatches(runes []rune, userInputs []string) []match {
+	ranks := make(map[string]int, len(commonPasswords)+len(userInputs))
+	for i, input := range userInputs {
+		if input = strings.ToLower(input); len(input) >= 3 {
+			ranks[input] = i + 1
+		}
+	}
+	for i, word := range commonPasswords {
+		if _, ok := ranks[word]; !ok {
+			ranks[word] = len(userInputs) + i + 1
+		}
+	}
+
+	lower := make([]rune, len(runes))
+	plain := make([]rune, len(runes))
+	for k, r := range runes {
+		lower[k] = unicode.ToLower(r)
+		plain[k] = lower[k]
+		if p, ok := unl33t[lower[k]]; ok {
+			plain[k] = p
+		}
+	}
+
+	var matches []match
+	for i := range runes {
+		for j := i + 3; j <= len(runes); j++ {
+			guesses, ok := lookupWord(ranks, string(lower[i:j]))
+			if l33t, found := lookupWord(ranks, string(plain[i:j])); found && (!ok || l33t+math.Log10(2) < guesses) {
+				guesses, ok = l33t+math.Log10(2), true
+			}
+			if !ok {
+				continue
+			}
+
+			guesses += uppercaseVariations(runes[i:j])
+			matches = append(matches, match{i, j, math.Max(guesses, 1)})
+		}
+	}
+
+	return matches
+}
+
+// lookupWord returns the log10 guesses of the word by its rank, doubled when reversed.
+func lookupWord(ranks map[string]int, word string) (float64, bool) {
+	if rank, ok := ranks[word]; ok {
+		return math.Log10(float64(rank)), true
+	}
+	if rank, ok := ranks[reverse(word)]; ok {
+		return math.Log10(float64(rank)) + math.Log10(2), true
+	}
+	return 0, false
+}
+
+// uppercaseVariations returns the log10 guesses of the capitalization of a word
+func uppercaseVariations(word []rune) float64 {
+	var upper, lower int
+	for _, r := range word {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	switch {
+	case upper == 0:
+		return 0
+	case lower == 0, upper == 1 && unicode.IsUpper(word[0]):
+		return math.Log10(2)
+	}
+
+	var variations float64
+	for k := 1; k <= upper && k <= lower; k++ {
+		variations += binomial(upper+lower, k)
+	}
+	return math.Log10(variations)
+}
+
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// repeatMatches finds runs of the same rune, guessed as the rune times the length
+func repeatMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes); {
+		j := i + 1
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		if j-i >= 3 {
+			matches = append(matches, match{i, j, math.Log10(cardinality(runes[i]) * float64(j-i))})
+		}
+		i = j
+	}
+	return matches
+}
+
+// sequenceMatches finds runs of runes ascending or descending by one, e.g. abcd or 9876
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+1 < len(runes); {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		for j < len(runes) && runes[j]-runes[j-1] == delta && (delta == 1 || delta == -1) {
+			j++
+		}
+		if j-i >= 3 && (delta == 1 || delta == -1) {
+			start := 26.0
+			if strings.ContainsRune("aA1z9", runes[i]) {
+				start = 4
+			} else if unicode.IsDigit(runes[i]) {
+				start = 10
+			}
+			guesses := start * float64(j-i)
+			if delta == -1 {
+				guesses *= 2
+			}
+			matches = append(matches, match{i, j, math.Log10(guesses)})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+// Rows of a qwerty keyboard, walked left or right
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// keyboardMatches finds walks of at least four keys along a keyboard row, e.g. qwer or lkjh
+func keyboardMatches(runes []rune) []match {
+	lower := strings.ToLower(string(runes))
+	lowerRunes := []rune(lower)
+
+	var matches []match
+	for i := range lowerRunes {
+		for j := len(lowerRunes); j >= i+4; j-- {
+			walk := string(lowerRunes[i:j])
+			if !onKeyboardRow(walk) {
+				continue
+			}
+			// 47 keys to start from, 2 directions, and the length
+			guesses := math.Log10(47*2*float64(j-i)) + uppercaseVariations(runes[i:j])
+			matches = append(matches, match{i, j, guesses})
+			break
+		}
+	}
+	return matches
+}
+
+func onKeyboardRow(walk string) bool {
+	for _, row := range keyboardRows {
+		if strings.Contains(row, walk) || strings.Contains(reverse(row), walk) {
+			return true
+		}
+	}
+	return false
+}
+
+// yearMatches finds years from 1900 to 2099, guessed as one of about 200 years
+func yearMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(runes); i++ {
+		year := string(runes[i : i+4])
+		if (strings.HasPrefix(year, "19") || strings.HasPrefix(year, "20")) && isDigits(year) {
+			matches = append(matches, match{i, i + 4, math.Log10(200)})
+		}
+	}
+	return matches
+}
+
+func cardinality(r rune) float64 {
+	switch {
+	case unicode.IsDigit(r):
+		return 10
+	case unicode.IsLower(r), unicode.IsUpper(r):
+		return 26
+	default:
+		return 33
+	}
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func reverse(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
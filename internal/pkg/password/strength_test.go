@@ -0,0 +1,52 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrength(t *testing.T) {
+	testCases := []struct {
+		password string
+		score    int
+	}{
+		{"", ScoreTooGuessable},
+		{"password", ScoreTooGuessable},
+		{"Password1", ScoreTooGuessable},
+		{"p@ssw0rd", ScoreTooGuessable},
+		{"drowssap", ScoreTooGuessable},
+		{"qwerty123", ScoreTooGuessable},
+		{"aaaaaaaaaa", ScoreTooGuessable},
+		{"abcdefgh", ScoreTooGuessable},
+		{"zxcvbnm,./", ScoreTooGuessable},
+		{"dragon2023", ScoreVeryGuessable},
+		{"Summer2023!", ScoreVeryGuessable},
+		{"johnsmith99", ScoreVeryGuessable},
+		{"xk8#Lq2!vz", ScoreVeryUnguessable},
+		{"correcthorsebatterystaple", ScoreVeryUnguessable},
+	}
+
+	for _, tc := range testCases {
+		score, _ := Strength(tc.password, "johnsmith")
+		require.Equal(t, tc.score, score, tc.password)
+	}
+
+	// Passwords are only as strong as the user inputs are unknown
+	score, _ := Strength("johnsmith99")
+	require.Equal(t, ScoreVeryUnguessable, score)
+}
+
+func TestStrengthGuesses(t *testing.T) {
+	// Random characters are guessed by brute force
+	_, guesses := Strength("fjdkslaowi")
+	require.Equal(t, float64(10), guesses)
+
+	// Patterns are cheaper to guess than their characters
+	_, sequence := Strength("abcdefghij")
+	require.Less(t, sequence, guesses)
+
+	_, capitalized := Strength("Dragon")
+	_, lower := Strength("dragon")
+	require.Greater(t, capitalized, lower)
+}
@@ -0,0 +1,185 @@
+// Package ratelimit limits requests of clients with token buckets.
+//
+// A bucket of a client holds up to the requests of its limit, one taken per request, and is refilled at
+// the requests per period. A client can burst the whole limit at once, and then continue at the rate.
+// Buckets are kept in a Store, in memory or shared by replicas, e.g. in the database.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is the requests a client may make per period
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Periods of a limit as written by ParseLimit
+var periods = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+}
+
+// ParseLimit parses a limit written as requests per period, the period s, m, h or d, e.g. 60/m.
+func ParseLimit(s string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, expected requests/period", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, requests must be a positive number", s)
+	}
+
+	per, ok := periods[period]
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid rate limit %q, period must be s, m, h or d", s)
+	}
+
+	return Limit{Requests: n, Per: per}, nil
+}
+
+func (l Limit) String() string {
+	for name, per := range periods {
+		if per == l.Per {
+			return fmt.Sprintf("%d/%s", l.Requests, name)
+		}
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// rate returns the tokens refilled per second
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Per.Seconds()
+}
+
+// Bucket is the state of the bucket of a client
+type Bucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+// Result is the outcome of taking a token, reported to clients in RateLimit headers
+type Result struct {
+	Allowed bool
+	// Requests of the limit
+	Limit int
+	// Whole tokens left in the bucket
+	Remaining int
+	// Time until the bucket is full again
+	Reset time.Duration
+	// Time until a token is available, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Take refills the bucket up to now and takes a token from it when there is one. A zero bucket is full.
+func (l Limit) Take(bucket Bucket, now time.Time) (Bucket, Result) {
+	tokens := float64(l.Requests)
+	if !bucket.UpdatedAt.IsZero() {
+		elapsed := now.Sub(bucket.UpdatedAt).Seconds()
+		tokens = math.Min(tokens, bucket.Tokens+math.Max(elapsed, 0)*l.rate())
+	}
+
+	result := Result{Limit: l.Requests}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - tokens)
+	}
+
+	result.Remaining = int(tokens)
+	result.Reset = l.duration(float64(l.Requests) - tokens)
+
+	return Bucket{Tokens: tokens, UpdatedAt: now}, result
+}
+
+// duration returns the time to refill the tokens
+func (l Limit) duration(tokens float64) time.Duration {
+	return time.Duration(math.Ceil(tokens / l.rate() * float64(time.Second)))
+}
+
+// Store takes tokens from the buckets of clients
+type Store interface {
+	// Take takes a token from the bucket of the key with the limit.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// StoreFunc adapts a function to a Store
+type StoreFunc func(ctx context.Context, key string, limit Limit) (Result, error)
+
+func (f StoreFunc) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return f(ctx, key, limit)
+}
+
+// sweepInterval is how often a MemoryStore drops full buckets
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in memory, limits hold per process only.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]memoryBucket
+	sweptAt time.Time
+	now     func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	// When the bucket is full again, and can be dropped
+	fullAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]memoryBucket),
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket of the key with the limit.
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	bucket, result := limit.Take(s.buckets[key].Bucket, now)
+	s.buckets[key] = memoryBucket{Bucket: bucket, fullAt: now.Add(result.Reset)}
+
+	return result, nil
+}
+
+// sweep drops the buckets that are full by now, they are the same as missing buckets.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.sweptAt) < sweepInterval {
+		return
+	}
+
+	for key, bucket := range s.buckets {
+		if !now.Before(bucket.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+
+	s.sweptAt = now
+}
+
+// Len returns the number of buckets in the store
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.buckets)
+}
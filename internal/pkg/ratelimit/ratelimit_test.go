@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("60/m")
+	require.NoError(t, err)
+	require.Equal(t, Limit{Requests: 60, Per: time.Minute}, limit)
+	require.Equal(t, "60/m", limit.String())
+
+	limit, err = ParseLimit(" 5/s ")
+	require.NoError(t, err)
+	require.Equal(t, Limit{Requests: 5, Per: time.Second}, limit)
+
+	for _, s := range []string{"", "60", "60/w", "0/m", "-1/m", "x/m", "/m"} {
+		_, err := ParseLimit(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestTake(t *testing.T) {
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	now := time.Now()
+
+	// A new bucket is full, the whole limit can be used at once
+	var bucket Bucket
+	var result Result
+	for remaining := 2; remaining >= 0; remaining-- {
+		bucket, result = limit.Take(bucket, now)
+		require.True(t, result.Allowed)
+		require.Equal(t, 3, result.Limit)
+		require.Equal(t, remaining, result.Remaining)
+		require.Zero(t, result.RetryAfter)
+	}
+	require.Equal(t, 3*time.Second, result.Reset)
+
+	bucket, result = limit.Take(bucket, now)
+	require.False(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+	require.Equal(t, time.Second, result.RetryAfter)
+
+	// A token is refilled per second
+	bucket, result = limit.Take(bucket, now.Add(500*time.Millisecond))
+	require.False(t, result.Allowed)
+	require.Equal(t, 500*time.Millisecond, result.RetryAfter)
+
+	bucket, result = limit.Take(bucket, now.Add(time.Second))
+	require.True(t, result.Allowed)
+	require.Equal(t, 0, result.Remaining)
+
+	// The bucket holds no more than the limit
+	_, result = limit.Take(bucket, now.Add(time.Hour))
+	require.True(t, result.Allowed)
+	require.Equal(t, 2, result.Remaining)
+	require.Equal(t, time.Second, result.Reset)
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Requests: 2, Per: time.Minute}
+
+	now := time.Now()
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(ctx, "a", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+	}
+
+	result, err := store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, 30*time.Second, result.RetryAfter)
+
+	// Buckets are per key
+	result, err = store.Take(ctx, "b", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 2, store.Len())
+
+	// Full buckets are dropped
+	now = now.Add(2 * time.Minute)
+	result, err = store.Take(ctx, "a", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Equal(t, 1, store.Len())
+}
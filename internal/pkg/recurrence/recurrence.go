@@ -0,0 +1,62 @@
+// Package recurrence computes occurrences of recurring events, described either as a
+// cron expression or as an iCalendar RRULE (RFC 5545).
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidRule is returned when a recurrence rule can not be parsed.
+var ErrInvalidRule = errors.New("invalid recurrence rule")
+
+// Schedule computes the occurrences of a recurrence rule.
+type Schedule interface {
+	// Next returns the first occurrence after t, or the zero time when there are no more occurrences.
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a recurrence rule anchored at start, no occurrence is ever before start.
+//
+// The rule is one of
+//   - empty, a single occurrence at start
+//   - a five field cron expression "minute hour day-of-month month day-of-week", or one of
+//     the descriptors @hourly, @daily, @weekly, @monthly and @yearly
+//   - an RRULE, e.g. "FREQ=MONTHLY;BYMONTHDAY=1", optionally prefixed with "RRULE:"
+//
+// Occurrences are computed in the location of start.
+func Parse(rule string, start time.Time) (Schedule, error) {
+	rule = strings.TrimSpace(rule)
+
+	switch {
+	case rule == "":
+		return once{at: start}, nil
+	case strings.HasPrefix(strings.ToUpper(rule), "RRULE:") || strings.Contains(strings.ToUpper(rule), "FREQ="):
+		return parseRRule(rule, start)
+	default:
+		return parseCron(rule, start)
+	}
+}
+
+// First returns the first occurrence of the schedule, at or after start.
+func First(schedule Schedule, start time.Time) time.Time {
+	return schedule.Next(start.Add(-time.Nanosecond))
+}
+
+// once is a schedule with a single occurrence.
+type once struct {
+	at time.Time
+}
+
+func (o once) Next(t time.Time) time.Time {
+	if o.at.After(t) {
+		return o.at
+	}
+	return time.Time{}
+}
+
+func invalidRule(format string, a ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidRule, fmt.Sprintf(format, a...))
+}
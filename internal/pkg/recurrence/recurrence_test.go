@@ -0,0 +1,180 @@
+//go:build !integration
+
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestNext(t *testing.T) {
+	start := date("2023-10-05T09:30:00Z")
+
+	testCases := []struct {
+		name     string
+		rule     string
+		after    time.Time
+		expected []time.Time
+	}{
+		{
+			name:     "Once",
+			rule:     "",
+			after:    start.Add(-time.Hour),
+			expected: []time.Time{start, {}},
+		},
+		{
+			name:  "CronFirstOfMonth",
+			rule:  "0 8 1 * *",
+			after: start,
+			expected: []time.Time{
+				date("2023-11-01T08:00:00Z"),
+				date("2023-12-01T08:00:00Z"),
+				date("2024-01-01T08:00:00Z"),
+			},
+		},
+		{
+			name:  "CronWeekdaysStep",
+			rule:  "*/30 9-10 * * 1-5",
+			after: date("2023-10-06T10:15:00Z"), // friday
+			expected: []time.Time{
+				date("2023-10-06T10:30:00Z"),
+				date("2023-10-09T09:00:00Z"),
+				date("2023-10-09T09:30:00Z"),
+			},
+		},
+		{
+			name:  "CronDescriptor",
+			rule:  "@daily",
+			after: start,
+			expected: []time.Time{
+				date("2023-10-06T00:00:00Z"),
+				date("2023-10-07T00:00:00Z"),
+			},
+		},
+		{
+			name:  "CronBeforeStart",
+			rule:  "30 9 * * *",
+			after: date("2023-01-01T00:00:00Z"),
+			expected: []time.Time{
+				start,
+				date("2023-10-06T09:30:00Z"),
+			},
+		},
+		{
+			name:     "CronNeverOccurs",
+			rule:     "0 0 30 2 *",
+			after:    start,
+			expected: []time.Time{{}},
+		},
+		{
+			name:  "RRuleMonthly",
+			rule:  "RRULE:FREQ=MONTHLY;BYMONTHDAY=1;BYHOUR=8;BYMINUTE=0",
+			after: start,
+			expected: []time.Time{
+				date("2023-11-01T08:00:00Z"),
+				date("2023-12-01T08:00:00Z"),
+			},
+		},
+		{
+			name:  "RRuleLastDayOfMonth",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			after: start,
+			expected: []time.Time{
+				date("2023-10-31T09:30:00Z"),
+				date("2023-11-30T09:30:00Z"),
+				date("2023-12-31T09:30:00Z"),
+				date("2024-01-31T09:30:00Z"),
+				date("2024-02-29T09:30:00Z"),
+			},
+		},
+		{
+			name:  "RRuleWeeklyInterval",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR",
+			after: start,
+			expected: []time.Time{
+				date("2023-10-06T09:30:00Z"),
+				date("2023-10-16T09:30:00Z"),
+				date("2023-10-20T09:30:00Z"),
+				date("2023-10-30T09:30:00Z"),
+			},
+		},
+		{
+			name:  "RRuleCount",
+			rule:  "FREQ=DAILY;COUNT=2",
+			after: start.Add(-time.Second),
+			expected: []time.Time{
+				start,
+				date("2023-10-06T09:30:00Z"),
+				{},
+			},
+		},
+		{
+			name:  "RRuleUntil",
+			rule:  "FREQ=YEARLY;UNTIL=20251231T000000Z",
+			after: start,
+			expected: []time.Time{
+				date("2024-10-05T09:30:00Z"),
+				date("2025-10-05T09:30:00Z"),
+				{},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			schedule, err := Parse(tc.rule, start)
+			require.NoError(t, err)
+
+			after := tc.after
+			for _, expected := range tc.expected {
+				next := schedule.Next(after)
+				require.Equal(t, expected, next)
+				after = next
+			}
+		})
+	}
+}
+
+func TestFirst(t *testing.T) {
+	start := date("2023-10-05T09:30:00Z")
+
+	schedule, err := Parse("FREQ=DAILY", start)
+	require.NoError(t, err)
+	require.Equal(t, start, First(schedule, start))
+
+	schedule, err = Parse("0 8 1 * *", start)
+	require.NoError(t, err)
+	require.Equal(t, date("2023-11-01T08:00:00Z"), First(schedule, start))
+}
+
+func TestParseInvalid(t *testing.T) {
+	rules := []string{
+		"* * * *",
+		"60 * * * *",
+		"*/0 * * * *",
+		"a b c d e",
+		"FREQ=SECONDLY",
+		"BYDAY=MO",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=MONTHLY;BYDAY=1MO",
+		"FREQ=DAILY;COUNT=2;UNTIL=20231231",
+		"FREQ=DAILY;BYSETPOS=1",
+	}
+
+	for _, rule := range rules {
+		_, err := Parse(rule, time.Now())
+		require.ErrorIs(t, err, ErrInvalidRule, rule)
+	}
+}
@@ -0,0 +1,328 @@
+package recurrence
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rruleSearchPeriods limits the number of periods expanded when looking for the next occurrence,
+// e.g. "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30" never occurs.
+const rruleSearchPeriods = 100000
+
+type frequency int
+
+const (
+	daily frequency = iota
+	weekly
+	monthly
+	yearly
+)
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// rrule is a schedule described by a subset of an iCalendar RRULE: FREQ (DAILY, WEEKLY, MONTHLY
+// or YEARLY), INTERVAL, COUNT, UNTIL, BYMONTH, BYMONTHDAY, BYDAY (without ordinals), BYHOUR and
+// BYMINUTE. The start of the schedule is the RRULE DTSTART.
+type rrule struct {
+	start      time.Time
+	freq       frequency
+	interval   int
+	count      int
+	until      time.Time
+	byMonth    []int
+	byMonthDay []int
+	byDay      []time.Weekday
+	byHour     []int
+	byMinute   []int
+}
+
+func parseRRule(rule string, start time.Time) (Schedule, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(rule), "RRULE:")
+
+	r := &rrule{start: start, freq: -1, interval: 1}
+
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, invalidRule("rrule: invalid part %q", part)
+		}
+
+		var err error
+
+		switch key {
+		case "FREQ":
+			r.freq, err = parseFrequency(value)
+		case "INTERVAL":
+			r.interval, err = parsePositive(key, value)
+		case "COUNT":
+			r.count, err = parsePositive(key, value)
+		case "UNTIL":
+			r.until, err = parseUntil(value, start.Location())
+		case "BYMONTH":
+			r.byMonth, err = parseInts(key, value, 1, 12, false)
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseInts(key, value, 1, 31, true)
+		case "BYDAY":
+			r.byDay, err = parseWeekdays(value)
+		case "BYHOUR":
+			r.byHour, err = parseInts(key, value, 0, 23, false)
+		case "BYMINUTE":
+			r.byMinute, err = parseInts(key, value, 0, 59, false)
+		case "WKST":
+			// Weeks always start on monday
+			if value != "MO" {
+				err = invalidRule("rrule: only WKST=MO is supported")
+			}
+		default:
+			err = invalidRule("rrule: unsupported part %q", key)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if r.freq < 0 {
+		return nil, invalidRule("rrule: FREQ is required")
+	}
+
+	if r.count > 0 && !r.until.IsZero() {
+		return nil, invalidRule("rrule: COUNT and UNTIL can not be combined")
+	}
+
+	if len(r.byHour) == 0 {
+		r.byHour = []int{start.Hour()}
+	}
+
+	if len(r.byMinute) == 0 {
+		r.byMinute = []int{start.Minute()}
+	}
+
+	return r, nil
+}
+
+func (r *rrule) Next(t time.Time) time.Time {
+	occurrences := 0
+
+	for period := 0; period < rruleSearchPeriods; period++ {
+		for _, candidate := range r.expand(period) {
+			if candidate.Before(r.start) {
+				continue
+			}
+
+			if !r.until.IsZero() && candidate.After(r.until) {
+				return time.Time{}
+			}
+
+			occurrences++
+			if r.count > 0 && occurrences > r.count {
+				return time.Time{}
+			}
+
+			if candidate.After(t) {
+				return candidate
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// expand returns the sorted occurrences within the n:th period of the rule.
+func (r *rrule) expand(n int) []time.Time {
+	loc := r.start.Location()
+	step := n * r.interval
+	y, m, d := r.start.Date()
+
+	var days []time.Time
+
+	switch r.freq {
+	case daily:
+		days = []time.Time{time.Date(y, m, d+step, 0, 0, 0, 0, loc)}
+	case weekly:
+		// Monday of the week of start
+		offset := (int(r.start.Weekday()) + 6) % 7
+		monday := time.Date(y, m, d-offset+7*step, 0, 0, 0, 0, loc)
+		for i := 0; i < 7; i++ {
+			day := monday.AddDate(0, 0, i)
+			if len(r.byDay) > 0 || day.Weekday() == r.start.Weekday() {
+				days = append(days, day)
+			}
+		}
+	case monthly:
+		days = r.daysInMonth(y, m+time.Month(step), loc)
+	case yearly:
+		months := r.byMonth
+		if len(months) == 0 {
+			months = []int{int(m)}
+		}
+		for _, month := range months {
+			days = append(days, r.daysInMonth(y+step, time.Month(month), loc)...)
+		}
+	}
+
+	var candidates []time.Time
+
+	for _, day := range days {
+		if !r.dayMatches(day) {
+			continue
+		}
+		for _, hour := range r.byHour {
+			for _, minute := range r.byMinute {
+				candidates = append(candidates, time.Date(
+					day.Year(), day.Month(), day.Day(), hour, minute, r.start.Second(), 0, loc,
+				))
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+
+	return candidates
+}
+
+// daysInMonth returns the candidate days of a month, all days when the rule limits the days
+// by BYMONTHDAY or BYDAY, otherwise the day of month of start, if the month has such a day.
+func (r *rrule) daysInMonth(year int, month time.Month, loc *time.Location) []time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1).Day()
+
+	var days []time.Time
+
+	if len(r.byMonthDay) > 0 || len(r.byDay) > 0 {
+		for d := 1; d <= last; d++ {
+			days = append(days, time.Date(first.Year(), first.Month(), d, 0, 0, 0, 0, loc))
+		}
+		return days
+	}
+
+	if r.start.Day() <= last {
+		days = append(days, time.Date(first.Year(), first.Month(), r.start.Day(), 0, 0, 0, 0, loc))
+	}
+
+	return days
+}
+
+func (r *rrule) dayMatches(day time.Time) bool {
+	if len(r.byMonth) > 0 && !containsInt(r.byMonth, int(day.Month())) {
+		return false
+	}
+
+	if len(r.byMonthDay) > 0 {
+		last := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		match := false
+		for _, md := range r.byMonthDay {
+			// Negative days count from the end of the month, -1 is the last day
+			if md == day.Day() || (md < 0 && last+md+1 == day.Day()) {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+
+	if len(r.byDay) > 0 {
+		match := false
+		for _, wd := range r.byDay {
+			if wd == day.Weekday() {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parseFrequency(value string) (frequency, error) {
+	switch value {
+	case "DAILY":
+		return daily, nil
+	case "WEEKLY":
+		return weekly, nil
+	case "MONTHLY":
+		return monthly, nil
+	case "YEARLY":
+		return yearly, nil
+	default:
+		return -1, invalidRule("rrule: unsupported FREQ %q", value)
+	}
+}
+
+func parsePositive(key, value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, invalidRule("rrule: %s must be a positive integer", key)
+	}
+	return n, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		until, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		if strings.HasSuffix(value, "Z") {
+			until, _ = time.Parse(layout, value)
+		}
+		if layout == "20060102" {
+			// The whole day is included
+			until = until.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		}
+		return until, nil
+	}
+	return time.Time{}, invalidRule("rrule: invalid UNTIL %q", value)
+}
+
+func parseInts(key, value string, min, max int, allowNegative bool) ([]int, error) {
+	var values []int
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(s)
+		abs := n
+		if allowNegative && n < 0 {
+			abs = -n
+		}
+		if err != nil || abs < min || abs > max {
+			return nil, invalidRule("rrule: invalid %s %q", key, s)
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, s := range strings.Split(value, ",") {
+		wd, ok := weekdays[s]
+		if !ok {
+			return nil, invalidRule("rrule: unsupported BYDAY %q", s)
+		}
+		days = append(days, wd)
+	}
+	return days, nil
+}
+
+func containsInt(values []int, v int) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
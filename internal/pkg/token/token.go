@@ -0,0 +1,155 @@
+// Package token creates and verifies the signed access tokens of authenticated users.
+//
+// A token is the base64url encoded JSON payload and the base64url encoded HMAC-SHA256 of the encoded
+// payload, separated by a dot. The payload is readable by anyone holding the token, so it must not carry
+// secrets.
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MinKeySize is the smallest symmetric key accepted, in bytes
+const MinKeySize = 32
+
+var (
+	ErrInvalidToken = errors.New("token is invalid")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+// TokenType tells what a token is for, a token of one type is invalid as a token of another type
+type TokenType string
+
+const (
+	// TypeAccess authenticates requests of the user
+	TypeAccess TokenType = "access"
+	// TypeRefresh renews the access tokens of a session
+	TypeRefresh TokenType = "refresh"
+	// TypeMFA proves the password of a user with two-factor authentication, who logs in with it and a code
+	TypeMFA TokenType = "mfa"
+)
+
+// Payload is the content of a token
+type Payload struct {
+	// Unique per token
+	ID        string    `json:"id"`
+	Type      TokenType `json:"type"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// NewPayload returns the payload of a new token of the type for the user with the role, valid for duration.
+func NewPayload(username string, role string, tokenType TokenType, duration time.Duration) (*Payload, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	return &Payload{
+		ID:        hex.EncodeToString(id),
+		Type:      tokenType,
+		Username:  username,
+		Role:      role,
+		IssuedAt:  now,
+		ExpiredAt: now.Add(duration),
+	}, nil
+}
+
+// Valid checks that the token has not expired.
+func (payload *Payload) Valid() error {
+	if time.Now().After(payload.ExpiredAt) {
+		return ErrExpiredToken
+	}
+	return nil
+}
+
+// Maker creates and verifies tokens
+type Maker interface {
+	// CreateToken creates a token of the type for the user with the role, valid for duration.
+	CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error)
+	// VerifyToken checks that the token is of the type, signed by the maker and has not expired, and returns
+	// its payload.
+	VerifyToken(token string, tokenType TokenType) (*Payload, error)
+}
+
+// HMACMaker signs tokens with HMAC-SHA256 using a symmetric key
+type HMACMaker struct {
+	key []byte
+}
+
+// NewHMACMaker creates a maker signing with the key, of at least MinKeySize bytes.
+func NewHMACMaker(key string) (Maker, error) {
+	if len(key) < MinKeySize {
+		return nil, fmt.Errorf("invalid key size: must be at least %d characters", MinKeySize)
+	}
+
+	return &HMACMaker{key: []byte(key)}, nil
+}
+
+var encoding = base64.RawURLEncoding
+
+// CreateToken creates a token of the type for the user with the role, valid for duration.
+func (maker *HMACMaker) CreateToken(username string, role string, tokenType TokenType, duration time.Duration) (string, *Payload, error) {
+	payload, err := NewPayload(username, role, tokenType, duration)
+	if err != nil {
+		return "", nil, err
+	}
+
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return "", nil, err
+	}
+
+	encoded := encoding.EncodeToString(content)
+
+	return encoded + "." + encoding.EncodeToString(maker.sign(encoded)), payload, nil
+}
+
+// VerifyToken checks that the token is of the type, signed by the maker and has not expired, and returns
+// its payload.
+func (maker *HMACMaker) VerifyToken(token string, tokenType TokenType) (*Payload, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	mac, err := encoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, maker.sign(encoded)) {
+		return nil, ErrInvalidToken
+	}
+
+	content, err := encoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var payload Payload
+	if err := json.Unmarshal(content, &payload); err != nil || payload.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	if err := payload.Valid(); err != nil {
+		return nil, err
+	}
+
+	return &payload, nil
+}
+
+func (maker *HMACMaker) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, maker.key)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
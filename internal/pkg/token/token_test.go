@@ -0,0 +1,92 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHMACMaker(t *testing.T) {
+	maker, err := NewHMACMaker(random.String(32))
+	require.NoError(t, err)
+
+	username := random.Owner()
+	duration := time.Minute
+
+	issuedAt := time.Now()
+	expiredAt := issuedAt.Add(duration)
+
+	token, payload, err := maker.CreateToken(username, "admin", TypeAccess, duration)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	got, err := maker.VerifyToken(token, TypeAccess)
+	require.NoError(t, err)
+	require.Equal(t, payload.ID, got.ID)
+	require.Equal(t, TypeAccess, got.Type)
+	require.Equal(t, username, got.Username)
+	require.Equal(t, "admin", got.Role)
+	require.WithinDuration(t, issuedAt, got.IssuedAt, time.Second)
+	require.WithinDuration(t, expiredAt, got.ExpiredAt, time.Second)
+}
+
+func TestExpiredToken(t *testing.T) {
+	maker, err := NewHMACMaker(random.String(32))
+	require.NoError(t, err)
+
+	token, _, err := maker.CreateToken(random.Owner(), "customer", TypeAccess, -time.Minute)
+	require.NoError(t, err)
+
+	payload, err := maker.VerifyToken(token, TypeAccess)
+	require.ErrorIs(t, err, ErrExpiredToken)
+	require.Nil(t, payload)
+}
+
+func TestInvalidToken(t *testing.T) {
+	maker, err := NewHMACMaker(random.String(32))
+	require.NoError(t, err)
+
+	other, err := NewHMACMaker(random.String(32))
+	require.NoError(t, err)
+
+	token, _, err := other.CreateToken(random.Owner(), "customer", TypeAccess, time.Minute)
+	require.NoError(t, err)
+
+	refresh, _, err := maker.CreateToken(random.Owner(), "customer", TypeRefresh, time.Minute)
+	require.NoError(t, err)
+
+	mfa, _, err := maker.CreateToken(random.Owner(), "customer", TypeMFA, time.Minute)
+	require.NoError(t, err)
+
+	encoded, signature, _ := strings.Cut(token, ".")
+
+	testCases := []struct {
+		name  string
+		token string
+	}{
+		{name: "OtherKey", token: token},
+		{name: "NoSignature", token: encoded},
+		{name: "ChangedPayload", token: encoding.EncodeToString([]byte(`{"username":"admin"}`)) + "." + signature},
+		{name: "Garbage", token: "!.!"},
+		{name: "OtherType", token: refresh},
+		{name: "MFAType", token: mfa},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			payload, err := maker.VerifyToken(tc.token, TypeAccess)
+			require.ErrorIs(t, err, ErrInvalidToken)
+			require.Nil(t, payload)
+		})
+	}
+}
+
+func TestNewHMACMakerShortKey(t *testing.T) {
+	_, err := NewHMACMaker(random.String(MinKeySize - 1))
+	require.Error(t, err)
+}
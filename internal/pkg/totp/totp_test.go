@@ -0,0 +1,87 @@
+package totp
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// rfcSecret is the base32 encoded SHA1 secret "12345678901234567890" of the test vectors of RFC 6238
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCode(t *testing.T) {
+	// The 6 trailing digits of the 8 digit codes of RFC 6238 appendix B
+	testCases := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(rfcSecret, Step(time.Unix(tc.unix, 0)))
+		require.NoError(t, err)
+		require.Equal(t, tc.code, code, tc.unix)
+	}
+}
+
+func TestInvalidSecret(t *testing.T) {
+	_, err := Code("not base32!", 1)
+	require.Error(t, err)
+
+	_, ok := Validate("not base32!", "123456", time.Now())
+	require.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+
+	now := time.Now()
+	step := Step(now)
+
+	for _, s := range []int64{step - Skew, step, step + Skew} {
+		code, err := Code(secret, s)
+		require.NoError(t, err)
+
+		got, ok := Validate(secret, code, now)
+		require.True(t, ok)
+		require.Equal(t, s, got)
+	}
+
+	code, err := Code(secret, step-Skew-1)
+	require.NoError(t, err)
+
+	_, ok := Validate(secret, code, now)
+	require.False(t, ok)
+
+	other, err := GenerateSecret()
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+
+	code, err = Code(other, step)
+	require.NoError(t, err)
+
+	_, ok = Validate(secret, code, now)
+	require.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri, err := url.Parse(URI("Simple Bank", "alice", rfcSecret))
+	require.NoError(t, err)
+
+	require.Equal(t, "otpauth", uri.Scheme)
+	require.Equal(t, "totp", uri.Host)
+	require.Equal(t, "/Simple Bank:alice", uri.Path)
+	require.Equal(t, rfcSecret, uri.Query().Get("secret"))
+	require.Equal(t, "Simple Bank", uri.Query().Get("issuer"))
+	require.Equal(t, "6", uri.Query().Get("digits"))
+	require.Equal(t, "30", uri.Query().Get("period"))
+}
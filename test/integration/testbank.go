@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/hthunberg/course-golang-postgres-grpc-api/dbtest"
+	"github.com/hthunberg/course-golang-postgres-grpc-api/internal/pkg/random"
 	tc "github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
@@ -53,10 +54,12 @@ func TestBankContainerRequest(dbAddr, absoluteMigrationsPath string) tc.GenericC
 	dbSource := fmt.Sprintf("postgres://%s:%s@%s/%s?sslmode=disable", dbtest.DBUser, dbtest.DBPass, dbAddr, dbtest.DBName)
 
 	env := map[string]string{
-		"ENVIRONMENT":   "integrationtest",
-		"DB_SOURCE":     dbSource,
-		"MIGRATION_URL": "file://migrations", // Relative to /app/bin/migrations
-		"LOG_LEVEL":     "DEBUG",
+		"ENVIRONMENT":         "integrationtest",
+		"DB_SOURCE":           dbSource,
+		"MIGRATION_URL":       "file://migrations", // Relative to /app/bin/migrations
+		"LOG_LEVEL":           "DEBUG",
+		"TOKEN_SYMMETRIC_KEY": random.String(32),
+		"TOTP_ENCRYPTION_KEY": random.String(32),
 	}
 	port := "8080/tcp"
 